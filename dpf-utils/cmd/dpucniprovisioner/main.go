@@ -22,24 +22,35 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
-	"strconv"
-	"strings"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/nvidia/doca-platform/pkg/ipallocator"
-	"github.com/nvidia/doca-platform/pkg/utils/networkhelper"
 	dpucniprovisioner "github.com/nvidia/ovn-kubernetes-components/internal/cniprovisioner/dpu"
+	"github.com/nvidia/ovn-kubernetes-components/internal/cniprovisioner/dpu/config"
 	"github.com/nvidia/ovn-kubernetes-components/internal/readyz"
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/logging"
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/networkhelper"
 	"github.com/nvidia/ovn-kubernetes-components/internal/utils/ovsclient"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
 	k8sscheme "k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/clock"
 	kexec "k8s.io/utils/exec"
@@ -47,79 +58,66 @@ import (
 )
 
 const (
-	// vtepIPAllocationFilePath is the path to the file that contains the VTEP IP allocation done by the IP Allocator.
-	// We should ensure that the IP Allocation request name is vtep to have this file created correctly.
-	vtepIPAllocationFilePath = "/tmp/ips/vtep"
-	// pfIPAllocationFilePath is the path to the file that contains the PF IP allocation done by the IP Allocator.
-	// We should ensure that the IP Allocation request name is pf to have this file created correctly.
-	pfIPAllocationFilePath = "/tmp/ips/pf"
-	// hostClusterTokenFilePath is where cniprovisioner expects the host-cluster token to be mounted.
-	hostClusterTokenFilePath = "/host-cluster-access/token"
-	// hostClusterCAFilePath is where cniprovisioner expects the host-cluster CA bundle to be mounted.
-	hostClusterCAFilePath = "/host-cluster-access/ca.crt"
+	// metricsServerShutdownTimeout is how long the metrics server is given to shut down gracefully.
+	metricsServerShutdownTimeout = 5 * time.Second
+	// readyFileSyncInterval is how often the readyz file is updated with the readiness of the provisioner.
+	readyFileSyncInterval = 5 * time.Second
+	// planMode is the mode in which the provisioner only reports the changes it would make to the system
+	planMode dpucniprovisioner.Mode = "plan"
+	// cleanupMode is the mode in which the provisioner removes the state it configured and reports what it removed
+	cleanupMode dpucniprovisioner.Mode = "cleanup"
+	// eventComponent is the component the Events of the provisioner are reported by
+	eventComponent = "dpucniprovisioner"
 )
 
 func main() {
-	if len(os.Args) != 2 {
-		klog.Fatal("expecting mode to be specified via args")
-	}
-
-	modeRaw := os.Args[1]
-	mode, err := parseMode(modeRaw)
-	if err != nil {
-		klog.Fatalf("error while parsing mode: %s", err.Error())
-	}
-
-	klog.Info("Starting DPU CNI Provisioner")
-
-	node := os.Getenv("NODE_NAME")
-	if node == "" {
-		klog.Fatal("NODE_NAME environment variable is not found. This is supposed to be configured via Kubernetes Downward API in production")
-	}
-
-	var vtepIPNet *net.IPNet
-	var gateway net.IP
-	var pfIPNet *net.IPNet
-	var vtepCIDR *net.IPNet
-	var ovnMTU int
-	var gatewayDiscoveryNetwork *net.IPNet
-	if mode == dpucniprovisioner.InternalIPAM {
-		vtepIPNet, gateway, err = getInfoFromVTEPIPAllocation()
+	var configFilePath string
+	flag.StringVar(&configFilePath, "config", "", "Path to the YAML or JSON configuration file. The file is reloaded when "+
+		"it changes. Environment variables override its values.")
+	var reportOutput string
+	flag.StringVar(&reportOutput, "output", "text", "Format of the report printed in plan and cleanup mode, either text or json.")
+	var loggingOptions logging.Options
+	loggingOptions.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if err := loggingOptions.Apply(); err != nil {
+		logging.Fatal(err, "error while configuring logging")
+	}
+	if flag.NArg() > 1 {
+		logging.Fatal(errors.New("expecting at most the mode to be specified via args"), "error while parsing arguments")
+	}
+	var mode dpucniprovisioner.Mode
+	if flag.NArg() == 1 {
+		var err error
+		mode, err = parseMode(flag.Arg(0))
 		if err != nil {
-			klog.Fatalf("error while parsing info from the VTEP IP allocation file: %s", err.Error())
+			logging.Fatal(err, "error while parsing mode")
 		}
+	}
 
-		pfIPNet, err = getPFIP()
-		if err != nil {
-			klog.Fatalf("error while the PF IP from the allocation file: %s", err.Error())
-		}
+	klog.InfoS("Starting DPU CNI Provisioner")
 
-		ovnMTU, err = getOVNMTU()
-		if err != nil {
-			klog.Fatalf("error while parsing MTU %s", err.Error())
-		}
-	} else {
-		gatewayDiscoveryNetwork, err = getGatewayDiscoveryNetwork()
-		if err != nil {
-			klog.Fatalf("error while parsing the Gateway Discovery Network: %s", err.Error())
-		}
+	loader := &config.Loader{
+		Path: configFilePath,
 	}
-
-	vtepCIDR, err = getVTEPCIDR()
+	// In plan and cleanup mode, the IPAM mode is the one of the configuration
+	if mode != planMode && mode != cleanupMode {
+		loader.Mode = mode
+	}
+	cfg, err := loader.Load()
 	if err != nil {
-		klog.Fatalf("error while parsing VTEP CIDR: %s", err.Error())
+		logging.Fatal(err, "error while loading the configuration", "path", configFilePath)
 	}
 
-	hostCIDR, err := getHostCIDR()
+	settings, err := settingsFromConfiguration(cfg)
 	if err != nil {
-		klog.Fatalf("error while parsing Host CIDR %s", err.Error())
+		logging.Fatal(err, "error while computing the provisioner settings")
 	}
 
 	exec := kexec.New()
 
-	ovsClient, err := ovsclient.New(exec)
+	ovsClient, err := ovsclient.New(cfg.OVSClientBackend, exec)
 	if err != nil {
-		klog.Fatal(err)
+		logging.Fatal(err, "error while creating the OVS client", "backend", cfg.OVSClientBackend)
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -130,250 +128,595 @@ func main() {
 	// that points at another API (e.g. host cluster), which breaks Node label lookups.
 	restCfg, err := rest.InClusterConfig()
 	if err != nil {
-		klog.Fatalf("in-cluster Kubernetes config (DPU/tenant API): %v", err)
+		logging.Fatal(err, "error while getting the in-cluster Kubernetes config (DPU/tenant API)")
 	}
 	clientset, err := kubernetes.NewForConfig(restCfg)
 	if err != nil {
-		klog.Fatal(err)
+		logging.Fatal(err, "error while creating the Kubernetes clientset")
 	}
 
-	provisioner := dpucniprovisioner.New(ctx, mode, c, ovsClient, networkhelper.New(), exec, clientset, vtepIPNet, gateway, vtepCIDR, hostCIDR, pfIPNet, node, gatewayDiscoveryNetwork, ovnMTU)
-	provisioner.K8sAPIServer = os.Getenv("K8S_APISERVER")
-	if ok, renew, dur, err := parseDPUNodeLeaseFromEnv(); err != nil {
-		klog.Fatal(err)
-	} else if ok {
-		provisioner.SetDPUNodeLeaseForOVNConf(renew, dur)
+	primary := settings.IPFamilies[0]
+	provisioner := dpucniprovisioner.New(ctx, cfg.Mode, c, ovsClient, networkhelper.New(), exec, clientset, primary.VTEPIPNet, primary.Gateway, primary.VTEPCIDRs, primary.HostCIDRs, primary.PFIP, cfg.NodeName, primary.GatewayDiscoveryNetwork, settings.OVNMTU)
+	for _, f := range settings.IPFamilies[1:] {
+		if err := provisioner.AddIPFamily(f.VTEPIPNet, f.Gateway, f.VTEPCIDRs, f.HostCIDRs, f.PFIP, f.GatewayDiscoveryNetwork); err != nil {
+			logging.Fatal(err, "error while adding IP family")
+		}
+	}
+	provisioner.K8sAPIServer = cfg.HostCluster.APIServer
+	provisioner.StateDir = cfg.StateDir
+	provisioner.OVNKConfFragmentPath = cfg.OVNKConfFragmentPath
+	if err := provisioner.SetDHCPServerBackend(cfg.DHCPServerBackend); err != nil {
+		logging.Fatal(err, "error while setting the DHCP server backend")
+	}
+	if settings.DPUNodeLease != nil {
+		provisioner.SetDPUNodeLeaseForOVNConf(settings.DPUNodeLease.RenewInterval, settings.DPUNodeLease.Duration)
+	}
+	provisioner.SetOVNConfigNamespaceForOVNConf(settings.OVNConfigNamespace)
+	if err := provisioner.SetInterfaceOverrides(settings.Interfaces); err != nil {
+		logging.Fatal(err, "error while setting the interface overrides")
 	}
-	if ns := strings.TrimSpace(os.Getenv("OVNKUBE_NODE_LEASE_NAMESPACE")); ns != "" {
-		provisioner.SetOVNConfigNamespaceForOVNConf(ns)
+	if err := provisioner.SetUplinks(settings.Uplinks); err != nil {
+		logging.Fatal(err, "error while setting the uplinks")
 	}
-	if strings.TrimSpace(provisioner.K8sAPIServer) != "" {
-		hostClusterClient, err := newHostClusterClient(provisioner.K8sAPIServer)
+	if err := provisioner.SetGatewayDiscovery(settings.GatewayDiscovery); err != nil {
+		logging.Fatal(err, "error while setting the gateway discovery")
+	}
+	if err := provisioner.SetOVSProfile(settings.OVSProfile); err != nil {
+		logging.Fatal(err, "error while setting the OVS profile")
+	}
+	if probe := cfg.VTEPProbe; probe != nil {
+		if err := provisioner.SetVTEPProbe(dpucniprovisioner.VTEPProbe{
+			Interval:   time.Duration(probe.IntervalSeconds) * time.Second,
+			Timeout:    time.Duration(probe.TimeoutSeconds) * time.Second,
+			SampleSize: probe.SampleSize,
+		}); err != nil {
+			logging.Fatal(err, "error while setting the VTEP probe")
+		}
+	}
+	if notifier := newOVNKubeNodeNotifier(cfg, clientset); notifier != nil {
+		provisioner.SetOVNKubeNodeNotifier(notifier)
+	}
+	var hostClusterConfig *rest.Config
+	if cfg.HostCluster.APIServer != "" {
+		hostClusterConfig, err = newHostClusterConfig(cfg.HostCluster)
+		if err != nil {
+			logging.Fatal(err, "error while creating host cluster config")
+		}
+		hostClusterClient, err := client.New(hostClusterConfig, client.Options{Scheme: k8sscheme.Scheme})
 		if err != nil {
-			klog.Fatal(err)
+			logging.Fatal(err, "error while creating host cluster client")
 		}
 		provisioner.SetHostKubernetesClient(hostClusterClient)
 	} else {
-		klog.Info("K8S_APISERVER is not set; host-cluster Kubernetes client disabled (tenant stale chassis-id reconciliation skipped)")
+		klog.InfoS("K8S_APISERVER is not set; host-cluster Kubernetes client disabled (tenant stale chassis-id reconciliation skipped)")
+	}
+
+	switch mode {
+	case planMode:
+		err := printReport(provisioner.Plan(), reportOutput)
+		cancel()
+		if err != nil {
+			logging.Fatal(err, "error while printing the plan")
+		}
+		return
+	case cleanupMode:
+		report, cleanupErr := provisioner.Cleanup()
+		err := printReport(report, reportOutput)
+		cancel()
+		if err := errors.Join(cleanupErr, err); err != nil {
+			logging.Fatal(err, "error while cleaning up")
+		}
+		return
+	}
+
+	eventBroadcaster := newEventBroadcaster(clientset)
+	defer eventBroadcaster.Shutdown()
+	provisioner.SetEventRecorder(eventBroadcaster.NewRecorder(k8sscheme.Scheme, corev1.EventSource{Component: eventComponent, Host: cfg.NodeName}))
+	if hostClusterConfig != nil {
+		hostClusterClientset, err := kubernetes.NewForConfig(hostClusterConfig)
+		if err != nil {
+			logging.Fatal(err, "error while creating host cluster clientset")
+		}
+		hostEventBroadcaster := newEventBroadcaster(hostClusterClientset)
+		defer hostEventBroadcaster.Shutdown()
+		provisioner.SetHostEventRecorder(hostEventBroadcaster.NewRecorder(k8sscheme.Scheme, corev1.EventSource{Component: eventComponent, Host: cfg.NodeName}))
+	}
+
+	metricsServer, err := startMetricsServer(provisioner, cfg.MetricsBindAddress)
+	if err != nil {
+		logging.Fatal(err, "error while starting the metrics server")
+	}
+
+	provisioner.AddEventSource(dpucniprovisioner.NewNetlinkEventSource(provisioner.ManagedLinks))
+	provisioner.AddEventSource(dpucniprovisioner.NewNodeEventSource(clientset, cfg.NodeName))
+	provisioner.AddEventSource(ovsclient.NewOpenVSwitchMonitor(""))
+	if configFilePath != "" {
+		provisioner.AddEventSource(config.NewWatcher(loader, cfg, func(c *config.Configuration) error {
+			settings, err := settingsFromConfiguration(c)
+			if err != nil {
+				return fmt.Errorf("error while computing the provisioner settings: %w", err)
+			}
+			return provisioner.UpdateSettings(settings)
+		}))
 	}
 
 	err = provisioner.RunOnce()
 	if err != nil {
-		klog.Fatal(err)
+		logging.Fatal(err, "error while provisioning")
+	}
+
+	probes := readyz.NewRegistry()
+	if err := probes.AddHealthzCheck("ping", readyz.Ping); err != nil {
+		logging.Fatal(err, "error while adding the healthz check")
+	}
+	if err := probes.AddReadyzCheck("provisioner", provisioner.CheckReadiness); err != nil {
+		logging.Fatal(err, "error while adding the readyz check")
+	}
+	if cfg.HealthProbeBindAddress != "" {
+		if err := probes.Serve(ctx, cfg.HealthProbeBindAddress); err != nil {
+			logging.Fatal(err, "error while serving the health probes", "address", cfg.HealthProbeBindAddress)
+		}
+	} else {
+		klog.InfoS("HEALTH_PROBE_BIND_ADDRESS is not set; health probe server disabled")
 	}
 
 	var wg sync.WaitGroup
-	wg.Add(1)
+	wg.Add(3)
 	go func() {
 		defer wg.Done()
 		provisioner.EnsureConfiguration()
 	}()
+	go func() {
+		defer wg.Done()
+		provisioner.ProbeVTEPs()
+	}()
+	// The readyz file is kept for backward compatibility with file based probes
+	go func() {
+		defer wg.Done()
+		probes.SyncReadyFile(ctx, readyFileSyncInterval)
+	}()
 
-	err = readyz.ReportReady()
-	if err != nil {
-		klog.Fatal(err)
-	}
-
-	klog.Info("DPU CNI Provisioner is ready")
+	klog.InfoS("DPU CNI Provisioner is ready", logging.KeyDPUNode, cfg.NodeName, "mode", cfg.Mode)
 
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, os.Interrupt)
 	<-ch
-	klog.Info("Received termination signal, terminating.")
+	klog.InfoS("Received termination signal, terminating.")
 	cancel()
 	provisioner.Stop()
 	wg.Wait()
+	if metricsServer != nil {
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), metricsServerShutdownTimeout)
+		defer shutdownCancel()
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			klog.ErrorS(err, "error while shutting down metrics server")
+		}
+	}
 }
 
-// getInfoFromVTEPIPAllocation returns the VTEP IP and gateway from a file that contains the VTEP IP allocation done
-// by the IP Allocator component.
-func getInfoFromVTEPIPAllocation() (*net.IPNet, net.IP, error) {
-	content, err := os.ReadFile(vtepIPAllocationFilePath)
-	if err != nil {
-		return nil, nil, fmt.Errorf("error while reading file %s: %w", vtepIPAllocationFilePath, err)
+// parseMode parses the mode in which the binary should be started
+func parseMode(mode string) (dpucniprovisioner.Mode, error) {
+	m := map[dpucniprovisioner.Mode]struct{}{
+		dpucniprovisioner.InternalIPAM: {},
+		dpucniprovisioner.ExternalIPAM: {},
+		planMode:                       {},
+		cleanupMode:                    {},
+	}
+	modeTyped := dpucniprovisioner.Mode(mode)
+	if _, ok := m[modeTyped]; !ok {
+		return "", errors.New("unknown mode")
 	}
 
-	results := []ipallocator.NVIPAMIPAllocatorResult{}
-	if err := json.Unmarshal(content, &results); err != nil {
-		return nil, nil, fmt.Errorf("error while unmarshalling IP Allocator results: %w", err)
+	return modeTyped, nil
+}
+
+// printReport prints the report of the plan or cleanup to stdout in the given format
+func printReport(report fmt.Stringer, format string) error {
+	switch format {
+	case "text":
+		fmt.Print(report.String())
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("error while encoding the report: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported output %q, expecting text or json", format)
+	}
+	return nil
+}
+
+// startMetricsServer serves the provisioner metrics and the last applied state on the given address. Returns a nil
+// server when the address is empty, in which case nothing is served.
+func startMetricsServer(provisioner *dpucniprovisioner.DPUCNIProvisioner, bindAddress string) (*http.Server, error) {
+	if bindAddress == "" {
+		klog.InfoS("METRICS_BIND_ADDRESS is not set; metrics server disabled")
+		return nil, nil
 	}
 
-	if len(results) != 1 {
-		return nil, nil, fmt.Errorf("expecting exactly 1 IP allocation for VTEP")
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	if err := provisioner.RegisterMetrics(registry); err != nil {
+		return nil, fmt.Errorf("error while registering provisioner metrics: %w", err)
 	}
 
-	vtepIPRaw := results[0].IP
-	vtepIP, err := netlink.ParseIPNet(vtepIPRaw)
+	listener, err := net.Listen("tcp", bindAddress)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error while parsing VTEP IP to net.IPNet: %w", err)
+		return nil, fmt.Errorf("error while listening on metrics address %s: %w", bindAddress, err)
 	}
 
-	gatewayRaw := results[0].Gateway
-	gateway := net.ParseIP(gatewayRaw)
-	if gateway == nil {
-		return nil, nil, errors.New("error while parsing Gateway IP to net.IP: input is not valid")
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/state", func(w http.ResponseWriter, _ *http.Request) {
+		state := provisioner.LastAppliedState()
+		if state == nil {
+			http.Error(w, "no state applied yet", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(state); err != nil {
+			klog.ErrorS(err, "error while encoding the applied state")
+		}
+	})
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
 	}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			klog.ErrorS(err, "metrics server stopped")
+		}
+	}()
 
-	return vtepIP, gateway, nil
+	klog.InfoS("Serving metrics", "address", bindAddress)
+	return server, nil
 }
 
-// getPFIP() returns the PF IP from a file that contains the PF IP allocation done by the IP Allocator
-// component.
-func getPFIP() (*net.IPNet, error) {
-	content, err := os.ReadFile(pfIPAllocationFilePath)
+// settingsFromConfiguration computes the settings of the provisioner from the given configuration and, in
+// internal-ipam mode, the results of the IP Allocator
+func settingsFromConfiguration(cfg *config.Configuration) (dpucniprovisioner.Settings, error) {
+	vtepCIDRs, err := config.ParseCIDRs(cfg.VTEPCIDRs)
+	if err != nil {
+		return dpucniprovisioner.Settings{}, fmt.Errorf("error while parsing VTEP CIDR: %w", err)
+	}
+	hostCIDRs, err := config.ParseCIDRs(cfg.HostCIDRs)
 	if err != nil {
-		return nil, fmt.Errorf("error while reading file %s: %w", vtepIPAllocationFilePath, err)
+		return dpucniprovisioner.Settings{}, fmt.Errorf("error while parsing Host CIDR: %w", err)
 	}
 
-	results := []ipallocator.NVIPAMIPAllocatorResult{}
-	if err := json.Unmarshal(content, &results); err != nil {
-		return nil, fmt.Errorf("error while unmarshalling IP Allocator results: %w", err)
+	var vtepIPNets []*net.IPNet
+	var gateways []net.IP
+	var pfIPNets []*net.IPNet
+	var gatewayDiscoveryNetworks []*net.IPNet
+	if cfg.Mode == dpucniprovisioner.InternalIPAM {
+		vtepIPNets, gateways, err = getInfoFromVTEPIPAllocation(cfg.IPAllocation.VTEPFilePath)
+		if err != nil {
+			return dpucniprovisioner.Settings{}, fmt.Errorf("error while parsing info from the VTEP IP allocation file: %w", err)
+		}
+
+		pfIPNets, err = getPFIP(cfg.IPAllocation.PFFilePath)
+		if err != nil {
+			return dpucniprovisioner.Settings{}, fmt.Errorf("error while the PF IP from the allocation file: %w", err)
+		}
+	} else {
+		gatewayDiscoveryNetworks, err = config.ParseCIDRs(cfg.GatewayDiscoveryNetworks)
+		if err != nil {
+			return dpucniprovisioner.Settings{}, fmt.Errorf("error while parsing the Gateway Discovery Network: %w", err)
+		}
 	}
 
-	if len(results) != 1 {
-		return nil, fmt.Errorf("expecting exactly 1 IP allocation for PF")
+	ipFamilies, err := groupByIPFamily(cfg.Mode, vtepCIDRs, hostCIDRs, vtepIPNets, gateways, pfIPNets, gatewayDiscoveryNetworks)
+	if err != nil {
+		return dpucniprovisioner.Settings{}, fmt.Errorf("error while grouping the inputs by IP family: %w", err)
 	}
 
-	pfIPRaw := results[0].IP
-	pfIP, err := netlink.ParseIPNet(pfIPRaw)
+	uplinks, err := uplinksFromConfiguration(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("error while parsing PF IP to net.IPNet: %w", err)
+		return dpucniprovisioner.Settings{}, err
 	}
 
-	return pfIP, nil
+	settings := dpucniprovisioner.Settings{
+		IPFamilies:         ipFamilies,
+		OVNMTU:             cfg.OVNMTU,
+		OVNConfigNamespace: cfg.OVNConfigNamespace,
+		Interfaces: dpucniprovisioner.Interfaces{
+			OOB:     cfg.Interfaces.OOBBridge,
+			Flannel: cfg.Interfaces.FlannelInterface,
+			PFIndex: cfg.Interfaces.PFIndex,
+		},
+		Uplinks: uplinks,
+	}
+	if cfg.Mode == dpucniprovisioner.ExternalIPAM {
+		discoveryGateways, err := config.ParseIPs(cfg.GatewayDiscovery.Gateways)
+		if err != nil {
+			return dpucniprovisioner.Settings{}, fmt.Errorf("error while parsing the gateways of the gateway discovery: %w", err)
+		}
+		settings.GatewayDiscovery = dpucniprovisioner.GatewayDiscovery{
+			Strategy: cfg.GatewayDiscovery.Strategy,
+			Gateways: discoveryGateways,
+		}
+	}
+	if cfg.DPUNodeLease != nil {
+		settings.DPUNodeLease = &dpucniprovisioner.DPUNodeLease{
+			RenewInterval: cfg.DPUNodeLease.RenewIntervalSeconds,
+			Duration:      cfg.DPUNodeLease.DurationSeconds,
+		}
+	}
+	if profile := cfg.OVSProfile; profile != nil {
+		settings.OVSProfile = &dpucniprovisioner.OVSProfile{
+			OtherConfig:         profile.OtherConfig,
+			DOCAInit:            profile.DOCAInit,
+			BridgeDataPathTypes: profile.BridgeDataPathTypes,
+			RestartOVS:          profile.Restart.Enabled,
+			RestartCommand:      profile.Restart.Command,
+		}
+	}
+	return settings, nil
 }
 
-// getVTEPCIDR returns the VTEP CIDR to be used by the provisioner
-func getVTEPCIDR() (*net.IPNet, error) {
-	vtepCIDRRaw := os.Getenv("VTEP_CIDR")
-	if vtepCIDRRaw == "" {
-		return nil, errors.New("required VTEP_CIDR environment variable is not set")
+// uplinksFromConfiguration computes the uplinks of the provisioner from the given configuration and the results of the
+// IP Allocator of every additional uplink
+func uplinksFromConfiguration(cfg *config.Configuration) (dpucniprovisioner.Uplinks, error) {
+	uplinks := dpucniprovisioner.Uplinks{
+		PrimaryPort: cfg.Uplinks.PrimaryPort,
+		EncapMode:   cfg.Uplinks.EncapMode,
+	}
+	for _, u := range cfg.Uplinks.Additional {
+		vtepIPNets, gateways, err := getInfoFromVTEPIPAllocation(u.IPAllocation.VTEPFilePath)
+		if err != nil {
+			return dpucniprovisioner.Uplinks{}, fmt.Errorf("error while parsing info from the VTEP IP allocation file of uplink %s: %w", u.Bridge, err)
+		}
+		pfIPNets, err := getPFIP(u.IPAllocation.PFFilePath)
+		if err != nil {
+			return dpucniprovisioner.Uplinks{}, fmt.Errorf("error while reading the PF IP from the allocation file of uplink %s: %w", u.Bridge, err)
+		}
+
+		uplink := dpucniprovisioner.Uplink{
+			Bridge:  u.Bridge,
+			Port:    u.Port,
+			PFIndex: u.PFIndex,
+		}
+		for i, vtepIPNet := range vtepIPNets {
+			family := networkhelper.FamilyOf(vtepIPNet.IP)
+			j := findIPFamily(pfIPNets, family)
+			if j < 0 {
+				return dpucniprovisioner.Uplinks{}, fmt.Errorf("PF IP allocation of uplink %s has no %s IP while its VTEP IP allocation has one", u.Bridge, family)
+			}
+			uplink.IPFamilies = append(uplink.IPFamilies, dpucniprovisioner.UplinkIPFamily{
+				VTEPIPNet: vtepIPNet,
+				Gateway:   gateways[i],
+				PFIP:      pfIPNets[j],
+			})
+		}
+		uplinks.Additional = append(uplinks.Additional, uplink)
 	}
+	return uplinks, nil
+}
 
-	_, vtepCIDR, err := net.ParseCIDR(vtepCIDRRaw)
+// getInfoFromVTEPIPAllocation returns the VTEP IPs and gateways from a file that contains the VTEP IP allocation done
+// by the IP Allocator component. The allocation of a dual-stack pool contains one IP per IP family.
+func getInfoFromVTEPIPAllocation(path string) ([]*net.IPNet, []net.IP, error) {
+	results, err := readIPAllocationResults(path)
 	if err != nil {
-		klog.Fatalf("error while parsing VTEP CIDR %s as net.IPNet: %s", vtepCIDRRaw, err.Error())
+		return nil, nil, err
 	}
 
-	return vtepCIDR, nil
-}
+	vtepIPs := make([]*net.IPNet, 0, len(results))
+	gateways := make([]net.IP, 0, len(results))
+	for _, result := range results {
+		vtepIP, err := netlink.ParseIPNet(result.IP)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error while parsing VTEP IP to net.IPNet: %w", err)
+		}
 
-// parseDPUNodeLeaseFromEnv reads OVNKUBE_NODE_DPU_LEASE_RENEW_INTERVAL and OVNKUBE_NODE_DPU_LEASE_DURATION.
-// When the renew interval env is unset, ok is false and ovn_k8s.conf is left without an [ovnkubenode] lease block.
-func parseDPUNodeLeaseFromEnv() (ok bool, renewInterval int, leaseDuration int, err error) {
-	renewRaw := strings.TrimSpace(os.Getenv("OVNKUBE_NODE_DPU_LEASE_RENEW_INTERVAL"))
-	if renewRaw == "" {
-		return false, 0, 0, nil
+		gateway := net.ParseIP(result.Gateway)
+		if gateway == nil {
+			return nil, nil, errors.New("error while parsing Gateway IP to net.IP: input is not valid")
+		}
+
+		vtepIPs = append(vtepIPs, vtepIP)
+		gateways = append(gateways, gateway)
+	}
+
+	if err := validateOnePerIPFamily(vtepIPs); err != nil {
+		return nil, nil, fmt.Errorf("invalid VTEP IP allocation: %w", err)
 	}
-	renewInterval, err = strconv.Atoi(renewRaw)
+
+	return vtepIPs, gateways, nil
+}
+
+// getPFIP() returns the PF IPs from a file that contains the PF IP allocation done by the IP Allocator
+// component. The allocation of a dual-stack pool contains one IP per IP family.
+func getPFIP(path string) ([]*net.IPNet, error) {
+	results, err := readIPAllocationResults(path)
 	if err != nil {
-		return false, 0, 0, fmt.Errorf("invalid OVNKUBE_NODE_DPU_LEASE_RENEW_INTERVAL %q: %w", renewRaw, err)
+		return nil, err
 	}
-	durRaw := strings.TrimSpace(os.Getenv("OVNKUBE_NODE_DPU_LEASE_DURATION"))
-	if durRaw == "" {
-		leaseDuration = 40
-	} else {
-		leaseDuration, err = strconv.Atoi(durRaw)
+
+	pfIPs := make([]*net.IPNet, 0, len(results))
+	for _, result := range results {
+		pfIP, err := netlink.ParseIPNet(result.IP)
 		if err != nil {
-			return false, 0, 0, fmt.Errorf("invalid OVNKUBE_NODE_DPU_LEASE_DURATION %q: %w", durRaw, err)
+			return nil, fmt.Errorf("error while parsing PF IP to net.IPNet: %w", err)
 		}
+		pfIPs = append(pfIPs, pfIP)
 	}
-	if renewInterval <= 0 || leaseDuration <= 0 || leaseDuration <= renewInterval {
-		return false, 0, 0, fmt.Errorf("invalid lease config: renewInterval (%d) and leaseDuration (%d) must be > 0, and leaseDuration must be greater than renewInterval",
-			renewInterval, leaseDuration)
+
+	if err := validateOnePerIPFamily(pfIPs); err != nil {
+		return nil, fmt.Errorf("invalid PF IP allocation: %w", err)
 	}
-	return true, renewInterval, leaseDuration, nil
+
+	return pfIPs, nil
 }
 
-// getHostCIDR returns the Host CIDR to be used by the provisioner
-func getHostCIDR() (*net.IPNet, error) {
-	hostCIDRRaw := os.Getenv("HOST_CIDR")
-	if hostCIDRRaw == "" {
-		return nil, errors.New("required HOST_CIDR environment variable is not set")
+// readIPAllocationResults reads the results of the IP Allocator from the given file
+func readIPAllocationResults(path string) ([]ipallocator.NVIPAMIPAllocatorResult, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading file %s: %w", path, err)
 	}
 
-	_, hostCIDR, err := net.ParseCIDR(hostCIDRRaw)
-	if err != nil {
-		klog.Fatalf("error while parsing Host CIDR %s as net.IPNet: %s", hostCIDRRaw, err.Error())
+	results := []ipallocator.NVIPAMIPAllocatorResult{}
+	if err := json.Unmarshal(content, &results); err != nil {
+		return nil, fmt.Errorf("error while unmarshalling IP Allocator results: %w", err)
 	}
 
-	return hostCIDR, nil
+	return results, nil
 }
 
-func newHostClusterClient(apiServer string) (client.Client, error) {
-	if _, err := os.Stat(hostClusterTokenFilePath); err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("missing host-cluster access token at %s; required to reconcile host node chassis annotations", hostClusterTokenFilePath)
-		}
-		return nil, fmt.Errorf("error while checking host cluster token file %s: %w", hostClusterTokenFilePath, err)
+// validateOnePerIPFamily returns an error unless there is at least one network and at most one network per IP family
+func validateOnePerIPFamily(ipNets []*net.IPNet) error {
+	if len(ipNets) == 0 {
+		return errors.New("expecting at least 1 IP")
 	}
-	if _, err := os.Stat(hostClusterCAFilePath); err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("missing host-cluster CA bundle at %s; required to reconcile host node chassis annotations", hostClusterCAFilePath)
+	seen := map[networkhelper.Family]bool{}
+	for _, ipNet := range ipNets {
+		family := networkhelper.FamilyOf(ipNet.IP)
+		if seen[family] {
+			return fmt.Errorf("expecting at most 1 %s IP", family)
 		}
-		return nil, fmt.Errorf("error while checking host cluster CA file %s: %w", hostClusterCAFilePath, err)
+		seen[family] = true
 	}
+	return nil
+}
 
-	hostConfig := &rest.Config{
-		Host:            apiServer,
-		BearerTokenFile: hostClusterTokenFilePath,
-		TLSClientConfig: rest.TLSClientConfig{
-			CAFile: hostClusterCAFilePath,
-		},
+// groupByIPFamily groups the inputs by IP family. The IP families and their order are the ones of the VTEP CIDRs, the
+// first one being the primary IP family. The VTEP and host CIDRs may contain several CIDRs per IP family, e.g. one per
+// rack, while every other input must be given exactly once for each of these IP families.
+func groupByIPFamily(mode dpucniprovisioner.Mode, vtepCIDRs, hostCIDRs, vtepIPNets []*net.IPNet, gateways []net.IP, pfIPNets, gatewayDiscoveryNetworks []*net.IPNet) ([]dpucniprovisioner.IPFamilySettings, error) {
+	var families []networkhelper.Family
+	for _, vtepCIDR := range vtepCIDRs {
+		if family := networkhelper.FamilyOf(vtepCIDR.IP); !slices.Contains(families, family) {
+			families = append(families, family)
+		}
 	}
 
-	hostClient, err := client.New(hostConfig, client.Options{Scheme: k8sscheme.Scheme})
-	if err != nil {
-		return nil, fmt.Errorf("error while creating host cluster client: %w", err)
+	type input struct {
+		name   string
+		ipNets []*net.IPNet
 	}
-
-	return hostClient, nil
-}
-
-// getGatewayDiscoveryNetwork returns the Network to be used by the provisioner to discover the gateway
-func getGatewayDiscoveryNetwork() (*net.IPNet, error) {
-	gatewayDiscoveryNetworkRaw := os.Getenv("GATEWAY_DISCOVERY_NETWORK")
-	if gatewayDiscoveryNetworkRaw == "" {
-		return nil, errors.New("required GATEWAY_DISCOVERY_NETWORK environment variable is not set")
+	var inputs []input
+	if mode == dpucniprovisioner.InternalIPAM {
+		inputs = append(inputs, input{name: "VTEP IP allocation", ipNets: vtepIPNets}, input{name: "PF IP allocation", ipNets: pfIPNets})
+	} else if len(gatewayDiscoveryNetworks) > 0 {
+		// The gateway discovery networks are only required by the route gateway discovery strategy
+		inputs = append(inputs, input{name: "gatewayDiscoveryNetworks", ipNets: gatewayDiscoveryNetworks})
+	}
+	for _, in := range inputs {
+		if err := validateOnePerIPFamily(in.ipNets); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", in.name, err)
+		}
+		if len(in.ipNets) != len(families) {
+			return nil, fmt.Errorf("%s has %d IP families while vtepCIDRs has %d", in.name, len(in.ipNets), len(families))
+		}
+	}
+	for _, hostCIDR := range hostCIDRs {
+		if family := networkhelper.FamilyOf(hostCIDR.IP); !slices.Contains(families, family) {
+			return nil, fmt.Errorf("hostCIDRs has a %s network while vtepCIDRs has none", family)
+		}
 	}
 
-	_, gatewayDiscoveryNetwork, err := net.ParseCIDR(gatewayDiscoveryNetworkRaw)
-	if err != nil {
-		klog.Fatalf("error while parsing Gateway Discovery Network %s as net.IPNet: %s", gatewayDiscoveryNetwork, err.Error())
+	ipFamilies := make([]dpucniprovisioner.IPFamilySettings, 0, len(families))
+	for _, family := range families {
+		for _, in := range inputs {
+			if findIPFamily(in.ipNets, family) < 0 {
+				return nil, fmt.Errorf("%s has no %s network while vtepCIDRs has one", in.name, family)
+			}
+		}
+
+		f := dpucniprovisioner.IPFamilySettings{
+			VTEPCIDRs: filterIPFamily(vtepCIDRs, family),
+			HostCIDRs: filterIPFamily(hostCIDRs, family),
+		}
+		if len(f.HostCIDRs) == 0 {
+			return nil, fmt.Errorf("hostCIDRs has no %s network while vtepCIDRs has one", family)
+		}
+		if mode == dpucniprovisioner.InternalIPAM {
+			i := findIPFamily(vtepIPNets, family)
+			f.VTEPIPNet = vtepIPNets[i]
+			f.Gateway = gateways[i]
+			f.PFIP = pfIPNets[findIPFamily(pfIPNets, family)]
+		} else if i := findIPFamily(gatewayDiscoveryNetworks, family); i >= 0 {
+			f.GatewayDiscoveryNetwork = gatewayDiscoveryNetworks[i]
+		}
+		ipFamilies = append(ipFamilies, f)
 	}
 
-	return gatewayDiscoveryNetwork, nil
+	return ipFamilies, nil
 }
 
-// getOVNMTU returns the PF MTU to be used by the provisioner
-func getOVNMTU() (int, error) {
-	mtuString := os.Getenv("OVN_MTU")
-	if mtuString == "" {
-		return 0, errors.New("required OVN_MTU environment variable is not set")
+// filterIPFamily returns the networks of the given IP family
+func filterIPFamily(ipNets []*net.IPNet, family networkhelper.Family) []*net.IPNet {
+	var filtered []*net.IPNet
+	for _, ipNet := range ipNets {
+		if networkhelper.FamilyOf(ipNet.IP) == family {
+			filtered = append(filtered, ipNet)
+		}
 	}
+	return filtered
+}
 
-	mtu, err := strconv.Atoi(mtuString)
-	if err != nil {
-		return 0, fmt.Errorf("parse environment variable OVN_MTU %s as int: %v", mtuString, err)
+// findIPFamily returns the index of the first network of the given IP family or -1 if there is none
+func findIPFamily(ipNets []*net.IPNet, family networkhelper.Family) int {
+	for i, ipNet := range ipNets {
+		if networkhelper.FamilyOf(ipNet.IP) == family {
+			return i
+		}
 	}
+	return -1
+}
 
-	if mtu == 0 {
-		return 0, errors.New("invalid OVN_MTU value: 0")
+// newOVNKubeNodeNotifier returns the notifier of the hook of the given configuration, nil when ovnkube-node is not
+// notified
+func newOVNKubeNodeNotifier(cfg *config.Configuration, clientset kubernetes.Interface) dpucniprovisioner.OVNKubeNodeNotifier {
+	n := cfg.OVNKubeNodeNotification
+	switch n.Hook {
+	case config.SignalNotificationHook:
+		// The signal is validated when the configuration is loaded
+		return dpucniprovisioner.NewSignalNotifier(n.PIDFilePath, unix.SignalNum(n.Signal))
+	case config.TriggerFileNotificationHook:
+		return dpucniprovisioner.NewTriggerFileNotifier(n.TriggerFilePath)
+	case config.DeletePodNotificationHook:
+		return dpucniprovisioner.NewPodDeleteNotifier(clientset, n.PodNamespace, n.PodLabelSelector, cfg.NodeName)
+	default:
+		return nil
 	}
+}
 
-	return mtu, nil
+// newEventBroadcaster creates a broadcaster that records the Events in the cluster of the given clientset. Failing to
+// record an Event is only logged.
+func newEventBroadcaster(clientset kubernetes.Interface) record.EventBroadcaster {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartStructuredLogging(2)
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+	return broadcaster
 }
 
-// parseMode parses the mode in which the binary should be started
-func parseMode(mode string) (dpucniprovisioner.Mode, error) {
-	m := map[dpucniprovisioner.Mode]struct{}{
-		dpucniprovisioner.InternalIPAM: {},
-		dpucniprovisioner.ExternalIPAM: {},
+// newHostClusterConfig creates the config of a client of the host cluster
+func newHostClusterConfig(hostCluster config.HostCluster) (*rest.Config, error) {
+	if _, err := os.Stat(hostCluster.TokenFilePath); err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("missing host-cluster access token at %s; required to reconcile host node chassis annotations", hostCluster.TokenFilePath)
+		}
+		return nil, fmt.Errorf("error while checking host cluster token file %s: %w", hostCluster.TokenFilePath, err)
 	}
-	modeTyped := dpucniprovisioner.Mode(mode)
-	if _, ok := m[modeTyped]; !ok {
-		return "", errors.New("unknown mode")
+	if _, err := os.Stat(hostCluster.CAFilePath); err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("missing host-cluster CA bundle at %s; required to reconcile host node chassis annotations", hostCluster.CAFilePath)
+		}
+		return nil, fmt.Errorf("error while checking host cluster CA file %s: %w", hostCluster.CAFilePath, err)
 	}
 
-	return modeTyped, nil
+	hostConfig := &rest.Config{
+		Host:            hostCluster.APIServer,
+		BearerTokenFile: hostCluster.TokenFilePath,
+		TLSClientConfig: rest.TLSClientConfig{
+			CAFile: hostCluster.CAFilePath,
+		},
+	}
+
+	return hostConfig, nil
 }
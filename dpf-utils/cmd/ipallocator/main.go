@@ -17,14 +17,21 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/nvidia/doca-platform/pkg/ipallocator"
 	"github.com/nvidia/ovn-kubernetes-components/internal/readyz"
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/logging"
 
 	"github.com/containernetworking/cni/libcni"
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
@@ -40,6 +47,12 @@ var requiredEnvVariables = map[string]bool{
 	"K8S_POD_UID":           true,
 }
 
+// allocationResultDirectory is the directory the IP Allocator writes the result of each request to
+const allocationResultDirectory = "/tmp/ips"
+
+// readyFileSyncInterval is how often the readyz file is updated with the readiness of the IP Allocator
+const readyFileSyncInterval = 5 * time.Second
+
 type Mode string
 
 // String returns the string representation of the mode
@@ -53,20 +66,26 @@ const (
 )
 
 func main() {
-	if len(os.Args) != 2 {
-		klog.Fatal("expecting mode to be specified via args")
+	var loggingOptions logging.Options
+	loggingOptions.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if err := loggingOptions.Apply(); err != nil {
+		logging.Fatal(err, "error while configuring logging")
+	}
+	if flag.NArg() != 1 {
+		logging.Fatal(errors.New("expecting mode to be specified via args"), "error while parsing arguments")
 	}
 
-	modeRaw := os.Args[1]
+	modeRaw := flag.Arg(0)
 	mode, err := parseMode(modeRaw)
 	if err != nil {
-		klog.Fatalf("error while parsing mode: %s", err.Error())
+		logging.Fatal(err, "error while parsing mode", "mode", modeRaw)
 	}
 
-	klog.Info("Starting IP Allocator")
+	klog.InfoS("Starting IP Allocator", "mode", mode)
 	env, err := parseEnv()
 	if err != nil {
-		klog.Fatalf("error while parsing environment: %s", err.Error())
+		logging.Fatal(err, "error while parsing environment")
 	}
 
 	allocator := ipallocator.New(
@@ -78,17 +97,17 @@ func main() {
 
 	reqs, err := allocator.ParseRequests(env["IP_ALLOCATOR_REQUESTS"])
 	if err != nil {
-		klog.Fatalf("error while parsing IP requests: %s", err.Error())
+		logging.Fatal(err, "error while parsing IP requests")
 	}
 
 	switch mode {
 	case Allocator:
 		if err := runInAllocatorMode(allocator, reqs); err != nil {
-			klog.Fatal(err)
+			logging.Fatal(err, "error while allocating IPs")
 		}
 	case Deallocator:
 		if err := runInDeallocatorMode(allocator, reqs); err != nil {
-			klog.Fatal(err)
+			logging.Fatal(err, "error while deallocating IPs")
 		}
 	}
 }
@@ -118,20 +137,86 @@ func runInAllocatorMode(a *ipallocator.NVIPAMIPAllocator, reqs []ipallocator.NVI
 		}
 	}
 
-	if err := readyz.ReportReady(); err != nil {
+	allocationsValid, err := allocationsChecker(reqs)
+	if err != nil {
 		return err
 	}
 
-	klog.Info("IP allocation is done")
+	probes := readyz.NewRegistry()
+	if err := probes.AddHealthzCheck("ping", readyz.Ping); err != nil {
+		return err
+	}
+	if err := probes.AddReadyzCheck("allocations", allocationsValid); err != nil {
+		return err
+	}
+	if bindAddress := strings.TrimSpace(os.Getenv("HEALTH_PROBE_BIND_ADDRESS")); bindAddress != "" {
+		if err := probes.Serve(ctx, bindAddress); err != nil {
+			return err
+		}
+	} else {
+		klog.InfoS("HEALTH_PROBE_BIND_ADDRESS is not set; health probe server disabled")
+	}
+	// The readyz file is kept for backward compatibility with file based probes
+	go probes.SyncReadyFile(ctx, readyFileSyncInterval)
+
+	klog.InfoS("IP allocation is done", "requests", requestNames(reqs))
 
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, os.Interrupt)
 	<-ch
-	klog.Info("Received termination signal, terminating.")
+	klog.InfoS("Received termination signal, terminating.")
 
 	return nil
 }
 
+// allocationsChecker returns a readiness check that passes only while the result files of the given requests are still
+// the ones written during the allocation, so that consumers of these files read valid allocations.
+func allocationsChecker(reqs []ipallocator.NVIPAMIPAllocatorRequest) (readyz.Checker, error) {
+	allocations := make(map[string][]byte, len(reqs))
+	for _, req := range reqs {
+		content, err := readAllocationResult(req.Name)
+		if err != nil {
+			return nil, err
+		}
+		allocations[req.Name] = content
+	}
+
+	return func() error {
+		var errs []error
+		for name, expected := range allocations {
+			content, err := readAllocationResult(name)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if !bytes.Equal(content, expected) {
+				errs = append(errs, fmt.Errorf("allocation %s changed since it was made", name))
+			}
+		}
+		return kerrors.NewAggregate(errs)
+	}, nil
+}
+
+// readAllocationResult reads the result file of the request with the given name and ensures that it contains at least
+// one IP
+func readAllocationResult(name string) ([]byte, error) {
+	path := filepath.Join(allocationResultDirectory, name)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading file %s: %w", path, err)
+	}
+
+	results := []ipallocator.NVIPAMIPAllocatorResult{}
+	if err := json.Unmarshal(content, &results); err != nil {
+		return nil, fmt.Errorf("error while unmarshalling IP Allocator results from %s: %w", path, err)
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no IP allocated in %s", path)
+	}
+
+	return content, nil
+}
+
 // runInDeallocatorMode runs the allocator in Deallocator mode
 func runInDeallocatorMode(a *ipallocator.NVIPAMIPAllocator, reqs []ipallocator.NVIPAMIPAllocatorRequest) error {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -143,10 +228,19 @@ func runInDeallocatorMode(a *ipallocator.NVIPAMIPAllocator, reqs []ipallocator.N
 		}
 	}
 
-	klog.Info("IP deallocation is done")
+	klog.InfoS("IP deallocation is done", "requests", requestNames(reqs))
 	return nil
 }
 
+// requestNames returns the names of the given requests
+func requestNames(reqs []ipallocator.NVIPAMIPAllocatorRequest) []string {
+	names := make([]string, 0, len(reqs))
+	for _, req := range reqs {
+		names = append(names, req.Name)
+	}
+	return names
+}
+
 // parseEnv parses the required environment variables
 func parseEnv() (map[string]string, error) {
 	var errs []error
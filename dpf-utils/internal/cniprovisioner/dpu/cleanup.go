@@ -0,0 +1,334 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dpucniprovisioner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/ovsclient"
+)
+
+const (
+	// cleanupMarkerPath is a file that indicates that the state of the provisioner is being removed. A provisioner
+	// running in the same container, e.g. when the cleanup runs as a preStop hook or via kubectl exec, stops reconciling
+	// so that it doesn't restore what's removed, and reports not ready meanwhile.
+	cleanupMarkerPath = "/run/dpucniprovisioner/cleanup"
+	// cleanupPauseDuration is how long the provisioner stops reconciling after a cleanup. It's long enough for the pod
+	// to terminate when the cleanup runs as a preStop hook, after which the marker expires and the provisioner
+	// reconciles again.
+	cleanupPauseDuration = 10 * time.Minute
+)
+
+// CleanupReport is the report of the state the provisioner removed from the system
+type CleanupReport struct {
+	Mode    Mode         `json:"mode"`
+	Removed []PlanChange `json:"removed"`
+	// Errors are the errors of the objects that couldn't be removed
+	Errors []string `json:"errors,omitempty"`
+}
+
+// String returns a human readable representation of the report
+func (r *CleanupReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Cleanup in %s mode: %d object(s) removed\n", r.Mode, len(r.Removed))
+	writeChanges(&b, r.Removed)
+	for _, err := range r.Errors {
+		fmt.Fprintf(&b, "Error: %s\n", err)
+	}
+	return b.String()
+}
+
+// Cleanup removes the state the provisioner owns from the system: the OVS external IDs it sets, what the OVS profile
+// set, the routes and rules tagged with its protocol, the VTEP IPs of the uplinks and the files it writes. It carries
+// on when an object can't be removed so that as much state as possible is removed, in which case the errors are
+// returned joined. The report lists what was removed either way.
+func (p *DPUCNIProvisioner) Cleanup() (*CleanupReport, error) {
+	report := &CleanupReport{Mode: p.mode, Removed: []PlanChange{}}
+	var errs []error
+	record := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+			report.Errors = append(report.Errors, err.Error())
+		}
+	}
+
+	markerPath := filepath.Join(p.FileSystemRoot, cleanupMarkerPath)
+	if err := os.MkdirAll(filepath.Dir(markerPath), 0755); err != nil {
+		record(fmt.Errorf("error while creating directory for cleanup marker %s: %w", markerPath, err))
+	} else if err := os.WriteFile(markerPath, nil, 0644); err != nil {
+		record(fmt.Errorf("error while writing cleanup marker %s: %w", markerPath, err))
+	}
+
+	p.logger.Info("Removing OVS external IDs")
+	record(p.cleanupOVS(report))
+	p.logger.Info("Removing routes and rules")
+	record(p.cleanupRoutesAndRules(report))
+	p.logger.Info("Removing files")
+	record(p.cleanupFiles(report))
+	p.logger.Info("Removing VTEP IPs")
+	record(p.cleanupAddresses(report))
+
+	return report, errors.Join(errs...)
+}
+
+// cleanupStarted returns whether the state of the provisioner is being removed, i.e. whether a cleanup ran less than
+// cleanupPauseDuration ago
+func (p *DPUCNIProvisioner) cleanupStarted() bool {
+	age, ok := p.cleanupMarkerAge()
+	return ok && age < cleanupPauseDuration
+}
+
+// cleanupMarkerAge returns how long ago the cleanup marker was written, if it exists
+func (p *DPUCNIProvisioner) cleanupMarkerAge() (time.Duration, bool) {
+	info, err := os.Stat(filepath.Join(p.FileSystemRoot, cleanupMarkerPath))
+	if err != nil {
+		return 0, false
+	}
+	return p.clock.Since(info.ModTime()), true
+}
+
+// removeExpiredCleanupMarker removes the cleanup marker once it expired so that the provisioner reconciles again
+func (p *DPUCNIProvisioner) removeExpiredCleanupMarker() {
+	if age, ok := p.cleanupMarkerAge(); !ok || age < cleanupPauseDuration {
+		return
+	}
+	markerPath := filepath.Join(p.FileSystemRoot, cleanupMarkerPath)
+	if err := os.Remove(markerPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		p.baseLogger.Error(err, "error while removing expired cleanup marker", "path", markerPath)
+		return
+	}
+	p.baseLogger.Info("Cleanup marker expired, resuming reconciliation")
+}
+
+// cleanupOVS removes the external IDs of the Open_vSwitch table the provisioner sets, as well as the other_config keys
+// and the bridge datapath types the journal records as set by the OVS profile. The datapath type of the bridges is
+// reset to the default one. The removal of the other_config keys that only take effect once ovs-vswitchd restarts is
+// left to the next restart.
+func (p *DPUCNIProvisioner) cleanupOVS(report *CleanupReport) error {
+	externalIDs, err := p.ovsClient.GetOpenVSwitchExternalIDs()
+	if err != nil {
+		return fmt.Errorf("error while getting the external IDs of the Open_vSwitch table: %w", err)
+	}
+
+	ovsTxn := p.ovsClient.Transaction()
+	var removed []PlanChange
+	for _, id := range []struct {
+		key    string
+		remove func()
+	}{
+		{key: "ovn-encap-ip", remove: ovsTxn.RemoveOVNEncapIP},
+		{key: "host-k8s-nodename", remove: ovsTxn.RemoveKubernetesHostNodeName},
+		{key: "hostname", remove: ovsTxn.RemoveHostName},
+	} {
+		value, ok := externalIDs[id.key]
+		if !ok {
+			continue
+		}
+		id.remove()
+		removed = append(removed, PlanChange{Kind: PlanChangeOVS, Action: PlanActionDelete, Object: "Open_vSwitch external_ids:" + id.key, Current: value})
+	}
+	profileRemoved, err := p.cleanupOVSProfile(ovsTxn)
+	if err != nil {
+		return err
+	}
+	removed = append(removed, profileRemoved...)
+	if err := ovsTxn.Commit(); err != nil {
+		return fmt.Errorf("error while removing OVS external IDs: %w", err)
+	}
+	report.Removed = append(report.Removed, removed...)
+	return nil
+}
+
+// cleanupOVSProfile queues the removal of the other_config keys and the bridge datapath types the journal records as
+// set by the OVS profile in the given transaction and returns what's removed
+func (p *DPUCNIProvisioner) cleanupOVSProfile(ovsTxn ovsclient.Transaction) ([]PlanChange, error) {
+	p.loadAppliedState()
+	state := p.LastAppliedState()
+	if state == nil {
+		return nil, nil
+	}
+
+	var removed []PlanChange
+	if len(state.OVSOtherConfig) > 0 {
+		otherConfig, err := p.ovsClient.GetOpenVSwitchOtherConfig()
+		if err != nil {
+			return nil, fmt.Errorf("error while getting the other_config of the Open_vSwitch table: %w", err)
+		}
+		for _, key := range state.OVSOtherConfig {
+			value, ok := otherConfig[key]
+			if !ok {
+				continue
+			}
+			ovsTxn.RemoveOpenVSwitchOtherConfig(key)
+			removed = append(removed, PlanChange{Kind: PlanChangeOVS, Action: PlanActionDelete, Object: "Open_vSwitch other_config:" + key, Current: value})
+		}
+	}
+	for _, bridge := range state.BridgeDataPathTypes {
+		exists, err := p.ovsClient.BridgeExists(bridge)
+		if err != nil {
+			return nil, fmt.Errorf("error while checking if bridge %s exists: %w", bridge, err)
+		}
+		if !exists {
+			continue
+		}
+		dataPathType, err := p.ovsClient.GetBridgeDataPathType(bridge)
+		if err != nil {
+			return nil, fmt.Errorf("error while getting the datapath type of bridge %s: %w", bridge, err)
+		}
+		if dataPathType == "" || dataPathType == ovsclient.System {
+			continue
+		}
+		ovsTxn.SetBridgeDataPathType(bridge, ovsclient.System)
+		removed = append(removed, PlanChange{Kind: PlanChangeOVS, Action: PlanActionDelete, Object: "Bridge " + bridge + " datapath_type", Current: string(dataPathType)})
+	}
+	return removed, nil
+}
+
+// cleanupRoutesAndRules removes the routes and rules tagged with the protocol of the provisioner. The untagged ones
+// that a previous version added are tagged by the first run of the provisioning flow that desires them, hence they're
+// removed as well on a DPU that was upgraded.
+func (p *DPUCNIProvisioner) cleanupRoutesAndRules(report *CleanupReport) error {
+	var errs []error
+
+	routes, err := p.networkHelper.ListOwnedRoutes()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error while listing owned routes: %w", err))
+	}
+	for _, r := range routes {
+		key := routeKey(r.Network, r.Gateway, r.Device, &r.Table)
+		if err := p.networkHelper.DeleteRouteFromTable(r.Network, r.Gateway, r.Device, &r.Table); err != nil {
+			errs = append(errs, fmt.Errorf("error while deleting route %s: %w", key, err))
+			continue
+		}
+		report.Removed = append(report.Removed, PlanChange{Kind: PlanChangeRoute, Action: PlanActionDelete, Object: key})
+	}
+
+	rules, err := p.networkHelper.ListOwnedRules()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error while listing owned rules: %w", err))
+	}
+	for _, r := range rules {
+		key := ruleKey(r.Src, r.Table, r.Priority)
+		if err := p.networkHelper.DeleteRule(r.Src, r.Table, r.Priority); err != nil {
+			errs = append(errs, fmt.Errorf("error while deleting rule %s: %w", key, err))
+			continue
+		}
+		report.Removed = append(report.Removed, PlanChange{Kind: PlanChangeRule, Action: PlanActionDelete, Object: key})
+	}
+
+	return errors.Join(errs...)
+}
+
+// cleanupFiles removes the files the provisioner writes, including the state journal. netplan is applied once the
+// netplan file of br-ovn is removed so that br-ovn stops requesting addresses via DHCP.
+func (p *DPUCNIProvisioner) cleanupFiles(report *CleanupReport) error {
+	var errs []error
+	bootstrapPath := p.BootstrapKubeconfigPath
+	if bootstrapPath == "" {
+		bootstrapPath = hostBootstrapKubeconfigPath
+	}
+	hostNodeNamePath := p.HostNodeNameFilePath
+	if hostNodeNamePath == "" {
+		hostNodeNamePath = hostNodeNameFilePath
+	}
+	var paths []string
+	for _, path := range []string{ovnkInputPath, ovnkInputPath + ovnkInputPreviousSuffix, bootstrapPath, hostNodeNamePath, brOVNNetplanConfigPath, netplanApplyDonePath} {
+		paths = append(paths, filepath.Join(p.FileSystemRoot, path))
+	}
+	// The journal knows about the files that were written to paths that have been reconfigured since
+	p.loadAppliedState()
+	if state := p.LastAppliedState(); state != nil {
+		for _, f := range state.Files {
+			if !slices.Contains(paths, f.Path) {
+				paths = append(paths, f.Path)
+			}
+		}
+	}
+	if journalPath := p.stateJournalPath(); journalPath != "" {
+		paths = append(paths, journalPath)
+	}
+	if notifyPendingPath := p.ovnKubeNodeNotifyPendingPath(); notifyPendingPath != "" {
+		paths = append(paths, notifyPendingPath)
+	}
+	if ovsRestartPendingPath := p.ovsRestartPendingPath(); ovsRestartPendingPath != "" {
+		paths = append(paths, ovsRestartPendingPath)
+	}
+	for _, path := range paths {
+		err := os.Remove(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			continue
+		case err != nil:
+			errs = append(errs, fmt.Errorf("error while removing %s: %w", path, err))
+			continue
+		}
+		report.Removed = append(report.Removed, PlanChange{Kind: PlanChangeFile, Action: PlanActionDelete, Object: path})
+		if path == filepath.Join(p.FileSystemRoot, brOVNNetplanConfigPath) {
+			if err := p.netplanApply(); err != nil {
+				errs = append(errs, fmt.Errorf("error while applying netplan without %s: %w", path, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// cleanupAddresses removes the VTEP IPs of the uplinks. In External mode, these are the addresses br-ovn got via DHCP.
+func (p *DPUCNIProvisioner) cleanupAddresses(report *CleanupReport) error {
+	var errs []error
+	removeAddress := func(link string, ipNet fmt.Stringer, remove func() error) {
+		if err := remove(); err != nil {
+			errs = append(errs, fmt.Errorf("error while removing %s from %s: %w", ipNet, link, err))
+			return
+		}
+		report.Removed = append(report.Removed, PlanChange{Kind: PlanChangeAddress, Action: PlanActionDelete, Object: ipNet.String() + " dev " + link})
+	}
+
+	if p.mode == ExternalIPAM {
+		for _, c := range p.ipFamilies {
+			addrs, err := p.networkHelper.GetLinkIPAddressesByFamily(brOVN, c.family)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("error while getting IP addresses for link %s: %w", brOVN, err))
+				continue
+			}
+			for _, addr := range addrs {
+				removeAddress(brOVN, addr, func() error { return p.networkHelper.DeleteLinkIPAddress(brOVN, addr) })
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	for _, u := range p.uplinks() {
+		for _, c := range u.ipFamilies {
+			exists, err := p.networkHelper.LinkIPAddressExists(u.bridge, c.vtepIPNet)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("error checking whether IP %s exists on %s: %w", c.vtepIPNet, u.bridge, err))
+				continue
+			}
+			if !exists {
+				continue
+			}
+			removeAddress(u.bridge, c.vtepIPNet, func() error { return p.networkHelper.DeleteLinkIPAddress(u.bridge, c.vtepIPNet) })
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,577 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	dpucniprovisioner "github.com/nvidia/ovn-kubernetes-components/internal/cniprovisioner/dpu"
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/networkhelper"
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/ovsclient"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+)
+
+// Loader loads the configuration from an optional file and the environment variables that override it
+type Loader struct {
+	// Path is the path to the YAML or JSON configuration file. Only the environment variables are used when empty.
+	Path string
+	// Mode, when not empty, overrides the mode of the configuration and the IPAM_MODE environment variable. It's the
+	// mode given on the command line.
+	Mode dpucniprovisioner.Mode
+	// Getenv returns the value of an environment variable. Defaults to os.Getenv.
+	Getenv func(key string) string
+}
+
+// Load loads, defaults and validates the configuration. All the problems found are returned at once.
+func (l *Loader) Load() (*Configuration, error) {
+	c := &Configuration{}
+	var errs field.ErrorList
+	if l.Path != "" {
+		content, err := os.ReadFile(l.Path)
+		if err != nil {
+			return nil, fmt.Errorf("error while reading configuration file %s: %w", l.Path, err)
+		}
+		if err := yaml.UnmarshalStrict(content, c); err != nil {
+			return nil, fmt.Errorf("error while decoding configuration file %s: %w", l.Path, err)
+		}
+		errs = append(errs, validateTypeMeta(c)...)
+	}
+
+	getenv := l.Getenv
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	errs = append(errs, applyEnvOverrides(c, getenv)...)
+	if l.Mode != "" {
+		c.Mode = l.Mode
+	}
+
+	SetDefaults(c)
+	errs = append(errs, Validate(c)...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration: %w", errs.ToAggregate())
+	}
+	return c, nil
+}
+
+// validateTypeMeta validates that the configuration file is of a supported version
+func validateTypeMeta(c *Configuration) field.ErrorList {
+	var errs field.ErrorList
+	if c.APIVersion != APIVersion {
+		errs = append(errs, field.NotSupported(field.NewPath("apiVersion"), c.APIVersion, []string{APIVersion}))
+	}
+	if c.Kind != Kind {
+		errs = append(errs, field.NotSupported(field.NewPath("kind"), c.Kind, []string{Kind}))
+	}
+	return errs
+}
+
+// applyEnvOverrides overrides the configuration with the environment variables that are set
+func applyEnvOverrides(c *Configuration, getenv func(key string) string) field.ErrorList {
+	var errs field.ErrorList
+	lookup := func(key string) (string, bool) {
+		value := strings.TrimSpace(getenv(key))
+		return value, value != ""
+	}
+	overrideString := func(key string, target *string) {
+		if value, ok := lookup(key); ok {
+			*target = value
+		}
+	}
+	overrideList := func(key string, target *[]string) {
+		if value, ok := lookup(key); ok {
+			*target = splitList(value)
+		}
+	}
+	overrideInt := func(key string, target *int) {
+		value, ok := lookup(key)
+		if !ok {
+			return
+		}
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			errs = append(errs, field.Invalid(field.NewPath(key), value, "must be an integer"))
+			return
+		}
+		*target = i
+	}
+
+	if value, ok := lookup("IPAM_MODE"); ok {
+		c.Mode = dpucniprovisioner.Mode(value)
+	}
+	overrideString("NODE_NAME", &c.NodeName)
+	overrideList("VTEP_CIDR", &c.VTEPCIDRs)
+	overrideList("HOST_CIDR", &c.HostCIDRs)
+	overrideList("GATEWAY_DISCOVERY_NETWORK", &c.GatewayDiscoveryNetworks)
+	if value, ok := lookup("GATEWAY_DISCOVERY_STRATEGY"); ok {
+		c.GatewayDiscovery.Strategy = dpucniprovisioner.GatewayDiscoveryStrategy(value)
+	}
+	overrideList("GATEWAY_DISCOVERY_GATEWAYS", &c.GatewayDiscovery.Gateways)
+	overrideInt("OVN_MTU", &c.OVNMTU)
+	overrideString("K8S_APISERVER", &c.HostCluster.APIServer)
+	overrideString("OVNKUBE_NODE_LEASE_NAMESPACE", &c.OVNConfigNamespace)
+	overrideString("OVNK_CONF_FRAGMENT_PATH", &c.OVNKConfFragmentPath)
+	overrideString("METRICS_BIND_ADDRESS", &c.MetricsBindAddress)
+	overrideString("HEALTH_PROBE_BIND_ADDRESS", &c.HealthProbeBindAddress)
+	overrideString("STATE_DIR", &c.StateDir)
+	overrideString("OOB_BRIDGE", &c.Interfaces.OOBBridge)
+	overrideString("FLANNEL_INTERFACE", &c.Interfaces.FlannelInterface)
+	overrideString("PF_INDEX", &c.Interfaces.PFIndex)
+	if value, ok := lookup("OVS_CLIENT_BACKEND"); ok {
+		c.OVSClientBackend = ovsclient.Backend(value)
+	}
+	overrideString("UPLINK_PORT", &c.Uplinks.PrimaryPort)
+	if value, ok := lookup("ENCAP_MODE"); ok {
+		c.Uplinks.EncapMode = dpucniprovisioner.EncapMode(value)
+	}
+	if value, ok := lookup("OVNKUBE_NODE_NOTIFICATION_HOOK"); ok {
+		c.OVNKubeNodeNotification.Hook = OVNKubeNodeNotificationHook(value)
+	}
+	overrideString("OVNKUBE_NODE_NOTIFICATION_PID_FILE", &c.OVNKubeNodeNotification.PIDFilePath)
+	overrideString("OVNKUBE_NODE_NOTIFICATION_SIGNAL", &c.OVNKubeNodeNotification.Signal)
+	overrideString("OVNKUBE_NODE_NOTIFICATION_TRIGGER_FILE", &c.OVNKubeNodeNotification.TriggerFilePath)
+	overrideString("OVNKUBE_NODE_NOTIFICATION_POD_NAMESPACE", &c.OVNKubeNodeNotification.PodNamespace)
+	overrideString("OVNKUBE_NODE_NOTIFICATION_POD_SELECTOR", &c.OVNKubeNodeNotification.PodLabelSelector)
+	if value, ok := lookup("DHCP_SERVER_BACKEND"); ok {
+		c.DHCPServerBackend = dpucniprovisioner.DHCPServerBackend(value)
+	}
+	// The lease duration alone doesn't enable the lease
+	if _, ok := lookup("OVNKUBE_NODE_DPU_LEASE_RENEW_INTERVAL"); ok {
+		if c.DPUNodeLease == nil {
+			c.DPUNodeLease = &DPUNodeLease{}
+		}
+		overrideInt("OVNKUBE_NODE_DPU_LEASE_RENEW_INTERVAL", &c.DPUNodeLease.RenewIntervalSeconds)
+	}
+	if c.DPUNodeLease != nil {
+		overrideInt("OVNKUBE_NODE_DPU_LEASE_DURATION", &c.DPUNodeLease.DurationSeconds)
+	}
+	// The interval enables the VTEP probe
+	if _, ok := lookup("VTEP_PROBE_INTERVAL_SECONDS"); ok {
+		if c.VTEPProbe == nil {
+			c.VTEPProbe = &VTEPProbe{}
+		}
+		overrideInt("VTEP_PROBE_INTERVAL_SECONDS", &c.VTEPProbe.IntervalSeconds)
+	}
+	if c.VTEPProbe != nil {
+		overrideInt("VTEP_PROBE_TIMEOUT_SECONDS", &c.VTEPProbe.TimeoutSeconds)
+		overrideInt("VTEP_PROBE_SAMPLE_SIZE", &c.VTEPProbe.SampleSize)
+	}
+
+	return errs
+}
+
+// splitList splits a comma separated list
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		items = append(items, strings.TrimSpace(item))
+	}
+	return items
+}
+
+// SetDefaults sets the default values of the fields that are not set
+func SetDefaults(c *Configuration) {
+	if c.APIVersion == "" {
+		c.APIVersion = APIVersion
+	}
+	if c.Kind == "" {
+		c.Kind = Kind
+	}
+	if c.IPAllocation.VTEPFilePath == "" {
+		c.IPAllocation.VTEPFilePath = DefaultVTEPIPAllocationFilePath
+	}
+	if c.IPAllocation.PFFilePath == "" {
+		c.IPAllocation.PFFilePath = DefaultPFIPAllocationFilePath
+	}
+	if c.HostCluster.TokenFilePath == "" {
+		c.HostCluster.TokenFilePath = DefaultHostClusterTokenFilePath
+	}
+	if c.HostCluster.CAFilePath == "" {
+		c.HostCluster.CAFilePath = DefaultHostClusterCAFilePath
+	}
+	if c.DPUNodeLease != nil && c.DPUNodeLease.DurationSeconds == 0 {
+		c.DPUNodeLease.DurationSeconds = DefaultDPUNodeLeaseDuration
+	}
+	if c.VTEPProbe != nil && c.VTEPProbe.TimeoutSeconds == 0 {
+		c.VTEPProbe.TimeoutSeconds = DefaultVTEPProbeTimeoutSeconds
+	}
+	if c.VTEPProbe != nil && c.VTEPProbe.SampleSize == 0 {
+		c.VTEPProbe.SampleSize = DefaultVTEPProbeSampleSize
+	}
+	if c.OVSClientBackend == "" {
+		c.OVSClientBackend = ovsclient.VsctlBackend
+	}
+	if c.DHCPServerBackend == "" {
+		c.DHCPServerBackend = dpucniprovisioner.DNSMasqDHCPServer
+	}
+	if c.GatewayDiscovery.Strategy == "" {
+		c.GatewayDiscovery.Strategy = dpucniprovisioner.RouteGatewayDiscovery
+	}
+	if c.Uplinks.EncapMode == "" {
+		c.Uplinks.EncapMode = dpucniprovisioner.ActiveBackupEncap
+	}
+	if c.StateDir == "" {
+		c.StateDir = DefaultStateDir
+	}
+	if c.OVNKubeNodeNotification.Hook == "" {
+		c.OVNKubeNodeNotification.Hook = NoneNotificationHook
+	}
+	if c.OVNKubeNodeNotification.Hook == SignalNotificationHook && c.OVNKubeNodeNotification.Signal == "" {
+		c.OVNKubeNodeNotification.Signal = DefaultOVNKubeNodeNotificationSignal
+	}
+}
+
+// Validate validates a defaulted configuration
+func Validate(c *Configuration) field.ErrorList {
+	var errs field.ErrorList
+
+	switch c.Mode {
+	case dpucniprovisioner.InternalIPAM, dpucniprovisioner.ExternalIPAM:
+	case "":
+		errs = append(errs, field.Required(field.NewPath("mode"), ""))
+	default:
+		errs = append(errs, field.NotSupported(field.NewPath("mode"), c.Mode, []dpucniprovisioner.Mode{dpucniprovisioner.InternalIPAM, dpucniprovisioner.ExternalIPAM}))
+	}
+	if c.NodeName == "" {
+		errs = append(errs, field.Required(field.NewPath("nodeName"), "NODE_NAME is supposed to be configured via Kubernetes Downward API in production"))
+	}
+
+	vtepCIDRs, vtepErrs := validateCIDRs(field.NewPath("vtepCIDRs"), c.VTEPCIDRs)
+	errs = append(errs, vtepErrs...)
+	families := ipFamilies(vtepCIDRs)
+	hostCIDRs, hostErrs := validateCIDRs(field.NewPath("hostCIDRs"), c.HostCIDRs)
+	errs = append(errs, hostErrs...)
+	if len(hostErrs) == 0 && len(vtepErrs) == 0 {
+		errs = append(errs, validateSameIPFamilies(field.NewPath("hostCIDRs"), hostCIDRs, families)...)
+	}
+
+	if c.OVNMTU < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("ovnMTU"), c.OVNMTU, "must be greater than 0, or 0 to derive it from the MTU of the uplinks"))
+	}
+
+	switch c.Mode {
+	case dpucniprovisioner.InternalIPAM:
+		if c.DHCPServerBackend != dpucniprovisioner.DNSMasqDHCPServer && c.DHCPServerBackend != dpucniprovisioner.BuiltinDHCPServer {
+			errs = append(errs, field.NotSupported(field.NewPath("dhcpServerBackend"), c.DHCPServerBackend, []dpucniprovisioner.DHCPServerBackend{dpucniprovisioner.DNSMasqDHCPServer, dpucniprovisioner.BuiltinDHCPServer}))
+		}
+	case dpucniprovisioner.ExternalIPAM:
+		// Only the route strategy needs the networks, the other ones use them when given
+		if c.GatewayDiscovery.Strategy == dpucniprovisioner.RouteGatewayDiscovery || len(c.GatewayDiscoveryNetworks) > 0 {
+			path := field.NewPath("gatewayDiscoveryNetworks")
+			networks, networkErrs := validateCIDRs(path, c.GatewayDiscoveryNetworks)
+			errs = append(errs, networkErrs...)
+			if len(networkErrs) == 0 && len(vtepErrs) == 0 {
+				if len(ipFamilies(networks)) != len(networks) {
+					errs = append(errs, field.Invalid(path, c.GatewayDiscoveryNetworks, "at most 1 network per IP family is expected"))
+				}
+				errs = append(errs, validateSameIPFamilies(path, networks, families)...)
+			}
+		}
+		if len(vtepErrs) == 0 {
+			errs = append(errs, validateGatewayDiscovery(c, families)...)
+		}
+	}
+
+	if lease := c.DPUNodeLease; lease != nil {
+		path := field.NewPath("dpuNodeLease")
+		if lease.RenewIntervalSeconds <= 0 {
+			errs = append(errs, field.Invalid(path.Child("renewIntervalSeconds"), lease.RenewIntervalSeconds, "must be greater than 0"))
+		}
+		if lease.DurationSeconds <= lease.RenewIntervalSeconds {
+			errs = append(errs, field.Invalid(path.Child("durationSeconds"), lease.DurationSeconds, "must be greater than renewIntervalSeconds"))
+		}
+	}
+
+	if probe := c.VTEPProbe; probe != nil {
+		path := field.NewPath("vtepProbe")
+		if probe.IntervalSeconds <= 0 {
+			errs = append(errs, field.Invalid(path.Child("intervalSeconds"), probe.IntervalSeconds, "must be greater than 0"))
+		}
+		if probe.TimeoutSeconds <= 0 || probe.TimeoutSeconds >= probe.IntervalSeconds {
+			errs = append(errs, field.Invalid(path.Child("timeoutSeconds"), probe.TimeoutSeconds, "must be greater than 0 and less than intervalSeconds"))
+		}
+		if probe.SampleSize <= 0 {
+			errs = append(errs, field.Invalid(path.Child("sampleSize"), probe.SampleSize, "must be greater than 0"))
+		}
+	}
+
+	errs = append(errs, validateOVSProfile(c)...)
+
+	if pfIndex := c.Interfaces.PFIndex; pfIndex != "" && pfIndex != "0" && pfIndex != "1" {
+		errs = append(errs, field.NotSupported(field.NewPath("interfaces", "pfIndex"), pfIndex, []string{"0", "1"}))
+	}
+	errs = append(errs, validateUplinks(c)...)
+	errs = append(errs, validateOVNKubeNodeNotification(c)...)
+
+	if c.OVSClientBackend != ovsclient.VsctlBackend && c.OVSClientBackend != ovsclient.OVSDBBackend {
+		errs = append(errs, field.NotSupported(field.NewPath("ovsClientBackend"), c.OVSClientBackend, []ovsclient.Backend{ovsclient.VsctlBackend, ovsclient.OVSDBBackend}))
+	}
+
+	return errs
+}
+
+// validateOVSProfile validates the OVS profile of a defaulted configuration
+func validateOVSProfile(c *Configuration) field.ErrorList {
+	profile := c.OVSProfile
+	if profile == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	path := field.NewPath("ovsProfile")
+	for _, key := range slices.Sorted(maps.Keys(profile.OtherConfig)) {
+		switch key {
+		case "":
+			errs = append(errs, field.Invalid(path.Child("otherConfig"), key, "keys must not be empty"))
+		case "doca-init":
+			errs = append(errs, field.Forbidden(path.Child("otherConfig").Key(key), "set with docaInit"))
+		}
+	}
+	for _, bridge := range slices.Sorted(maps.Keys(profile.BridgeDataPathTypes)) {
+		if bridge == "" {
+			errs = append(errs, field.Invalid(path.Child("bridgeDatapathTypes"), bridge, "bridge names must not be empty"))
+			continue
+		}
+		if dataPathType := profile.BridgeDataPathTypes[bridge]; dataPathType != ovsclient.System && dataPathType != ovsclient.NetDev {
+			errs = append(errs, field.NotSupported(path.Child("bridgeDatapathTypes").Key(bridge), dataPathType, []ovsclient.BridgeDataPathType{ovsclient.System, ovsclient.NetDev}))
+		}
+	}
+	if profile.Restart.Enabled && len(profile.Restart.Command) == 0 {
+		errs = append(errs, field.Required(path.Child("restart", "command"), "required when restart is enabled"))
+	}
+	for i, arg := range profile.Restart.Command {
+		if arg == "" {
+			errs = append(errs, field.Invalid(path.Child("restart", "command").Index(i), arg, "must not be empty"))
+		}
+	}
+	return errs
+}
+
+// validateGatewayDiscovery validates the gateway discovery of a defaulted configuration against the given IP families
+func validateGatewayDiscovery(c *Configuration, families []networkhelper.Family) field.ErrorList {
+	var errs field.ErrorList
+	path := field.NewPath("gatewayDiscovery")
+	strategy := c.GatewayDiscovery.Strategy
+
+	counts := map[networkhelper.Family]int{}
+	gateways, err := ParseIPs(c.GatewayDiscovery.Gateways)
+	if err != nil {
+		for i, gateway := range c.GatewayDiscovery.Gateways {
+			if net.ParseIP(gateway) == nil {
+				errs = append(errs, field.Invalid(path.Child("gateways").Index(i), gateway, "must be an IP"))
+			}
+		}
+	}
+	for _, gateway := range gateways {
+		counts[networkhelper.FamilyOf(gateway)]++
+	}
+	for family := range counts {
+		if !slices.Contains(families, family) {
+			errs = append(errs, field.Invalid(path.Child("gateways"), c.GatewayDiscovery.Gateways, fmt.Sprintf("no %s gateway is expected as vtepCIDRs has none", family)))
+		}
+	}
+
+	switch strategy {
+	case dpucniprovisioner.RouteGatewayDiscovery, dpucniprovisioner.DHCPLeaseGatewayDiscovery:
+		if len(c.GatewayDiscovery.Gateways) > 0 {
+			errs = append(errs, field.Forbidden(path.Child("gateways"), fmt.Sprintf("not supported by the %s strategy", strategy)))
+		}
+		if strategy == dpucniprovisioner.DHCPLeaseGatewayDiscovery && slices.Contains(families, networkhelper.IPv6) {
+			errs = append(errs, field.Invalid(path.Child("strategy"), strategy, "only supported when vtepCIDRs are IPv4 only"))
+		}
+	case dpucniprovisioner.StaticGatewayDiscovery:
+		for _, family := range families {
+			if counts[family] != 1 {
+				errs = append(errs, field.Invalid(path.Child("gateways"), c.GatewayDiscovery.Gateways, fmt.Sprintf("exactly 1 %s gateway is expected by the static strategy", family)))
+			}
+		}
+	case dpucniprovisioner.ProbeGatewayDiscovery:
+		for _, family := range families {
+			if counts[family] == 0 {
+				errs = append(errs, field.Invalid(path.Child("gateways"), c.GatewayDiscovery.Gateways, fmt.Sprintf("at least 1 %s gateway is expected by the probe strategy", family)))
+			}
+		}
+	default:
+		errs = append(errs, field.NotSupported(path.Child("strategy"), strategy, []dpucniprovisioner.GatewayDiscoveryStrategy{dpucniprovisioner.RouteGatewayDiscovery, dpucniprovisioner.DHCPLeaseGatewayDiscovery, dpucniprovisioner.StaticGatewayDiscovery, dpucniprovisioner.ProbeGatewayDiscovery}))
+	}
+	return errs
+}
+
+// validateOVNKubeNodeNotification validates the notification of ovnkube-node of a defaulted configuration
+func validateOVNKubeNodeNotification(c *Configuration) field.ErrorList {
+	var errs field.ErrorList
+	path := field.NewPath("ovnkubeNodeNotification")
+	n := c.OVNKubeNodeNotification
+	switch n.Hook {
+	case NoneNotificationHook:
+	case SignalNotificationHook:
+		if n.PIDFilePath == "" {
+			errs = append(errs, field.Required(path.Child("pidFilePath"), "required by the signal hook"))
+		}
+		if !slices.Contains(SupportedOVNKubeNodeNotificationSignals, n.Signal) {
+			errs = append(errs, field.NotSupported(path.Child("signal"), n.Signal, SupportedOVNKubeNodeNotificationSignals))
+		}
+	case TriggerFileNotificationHook:
+		if n.TriggerFilePath == "" {
+			errs = append(errs, field.Required(path.Child("triggerFilePath"), "required by the trigger-file hook"))
+		}
+	case DeletePodNotificationHook:
+		if n.PodNamespace == "" {
+			errs = append(errs, field.Required(path.Child("podNamespace"), "required by the delete-pod hook"))
+		}
+		if n.PodLabelSelector == "" {
+			errs = append(errs, field.Required(path.Child("podLabelSelector"), "required by the delete-pod hook"))
+		} else if _, err := labels.Parse(n.PodLabelSelector); err != nil {
+			errs = append(errs, field.Invalid(path.Child("podLabelSelector"), n.PodLabelSelector, err.Error()))
+		}
+	default:
+		errs = append(errs, field.NotSupported(path.Child("hook"), n.Hook, []OVNKubeNodeNotificationHook{NoneNotificationHook, SignalNotificationHook, TriggerFileNotificationHook, DeletePodNotificationHook}))
+	}
+	return errs
+}
+
+// validateUplinks validates the uplinks of a defaulted configuration
+func validateUplinks(c *Configuration) field.ErrorList {
+	var errs field.ErrorList
+	path := field.NewPath("uplinks")
+	if mode := c.Uplinks.EncapMode; mode != dpucniprovisioner.ActiveBackupEncap && mode != dpucniprovisioner.ECMPEncap {
+		errs = append(errs, field.NotSupported(path.Child("encapMode"), mode, []dpucniprovisioner.EncapMode{dpucniprovisioner.ActiveBackupEncap, dpucniprovisioner.ECMPEncap}))
+	}
+	if len(c.Uplinks.Additional) > 0 && c.Mode != dpucniprovisioner.InternalIPAM {
+		errs = append(errs, field.Forbidden(path.Child("additional"), "only supported in internal-ipam mode"))
+	}
+
+	bridges := map[string]bool{"br-ovn": true}
+	pfIndexes := map[string]bool{}
+	if c.Interfaces.PFIndex != "" {
+		pfIndexes[c.Interfaces.PFIndex] = true
+	}
+	for i, u := range c.Uplinks.Additional {
+		uplinkPath := path.Child("additional").Index(i)
+		switch {
+		case u.Bridge == "":
+			errs = append(errs, field.Required(uplinkPath.Child("bridge"), ""))
+		case bridges[u.Bridge]:
+			errs = append(errs, field.Duplicate(uplinkPath.Child("bridge"), u.Bridge))
+		}
+		bridges[u.Bridge] = true
+		switch {
+		case u.PFIndex == "":
+			errs = append(errs, field.Required(uplinkPath.Child("pfIndex"), ""))
+		case u.PFIndex != "0" && u.PFIndex != "1":
+			errs = append(errs, field.NotSupported(uplinkPath.Child("pfIndex"), u.PFIndex, []string{"0", "1"}))
+		case pfIndexes[u.PFIndex]:
+			errs = append(errs, field.Duplicate(uplinkPath.Child("pfIndex"), u.PFIndex))
+		}
+		pfIndexes[u.PFIndex] = true
+		if u.IPAllocation.VTEPFilePath == "" {
+			errs = append(errs, field.Required(uplinkPath.Child("ipAllocation", "vtepFilePath"), ""))
+		}
+		if u.IPAllocation.PFFilePath == "" {
+			errs = append(errs, field.Required(uplinkPath.Child("ipAllocation", "pfFilePath"), ""))
+		}
+	}
+	return errs
+}
+
+// validateCIDRs validates that at least one CIDR is given and that all of them can be parsed
+func validateCIDRs(path *field.Path, cidrs []string) ([]*net.IPNet, field.ErrorList) {
+	if len(cidrs) == 0 {
+		return nil, field.ErrorList{field.Required(path, "")}
+	}
+	var errs field.ErrorList
+	ipNets, err := ParseCIDRs(cidrs)
+	if err != nil {
+		for i, cidr := range cidrs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				errs = append(errs, field.Invalid(path.Index(i), cidr, "must be a CIDR"))
+			}
+		}
+	}
+	return ipNets, errs
+}
+
+// validateSameIPFamilies validates that the given networks are of exactly the given IP families
+func validateSameIPFamilies(path *field.Path, ipNets []*net.IPNet, families []networkhelper.Family) field.ErrorList {
+	var errs field.ErrorList
+	got := ipFamilies(ipNets)
+	for _, family := range families {
+		if !slices.Contains(got, family) {
+			errs = append(errs, field.Invalid(path, networksToStrings(ipNets), fmt.Sprintf("an %s network is expected as vtepCIDRs has one", family)))
+		}
+	}
+	for _, family := range got {
+		if !slices.Contains(families, family) {
+			errs = append(errs, field.Invalid(path, networksToStrings(ipNets), fmt.Sprintf("no %s network is expected as vtepCIDRs has none", family)))
+		}
+	}
+	return errs
+}
+
+// ipFamilies returns the distinct IP families of the given networks in order of first appearance
+func ipFamilies(ipNets []*net.IPNet) []networkhelper.Family {
+	var families []networkhelper.Family
+	for _, ipNet := range ipNets {
+		if family := networkhelper.FamilyOf(ipNet.IP); !slices.Contains(families, family) {
+			families = append(families, family)
+		}
+	}
+	return families
+}
+
+// networksToStrings returns the string representation of the given networks
+func networksToStrings(ipNets []*net.IPNet) []string {
+	out := make([]string, 0, len(ipNets))
+	for _, ipNet := range ipNets {
+		out = append(out, ipNet.String())
+	}
+	return out
+}
+
+// ParseCIDRs parses the given CIDRs
+func ParseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	ipNets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("error while parsing %s as net.IPNet: %w", cidr, err)
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+	return ipNets, nil
+}
+
+// ParseIPs parses the given IPs
+func ParseIPs(ips []string) ([]net.IP, error) {
+	parsed := make([]net.IP, 0, len(ips))
+	for _, ip := range ips {
+		p := net.ParseIP(ip)
+		if p == nil {
+			return nil, fmt.Errorf("error while parsing %s as net.IP", ip)
+		}
+		parsed = append(parsed, p)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,500 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	dpucniprovisioner "github.com/nvidia/ovn-kubernetes-components/internal/cniprovisioner/dpu"
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/ovsclient"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const internalIPAMConfig = `
+apiVersion: dpucniprovisioner.ovn.nvidia.com/v1alpha1
+kind: DPUCNIProvisionerConfiguration
+mode: internal-ipam
+nodeName: dpu1
+vtepCIDRs: ["192.168.0.0/24", "192.168.1.0/24"]
+hostCIDRs: ["10.0.100.0/24"]
+ovnMTU: 1500
+`
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	NewWithT(t).Expect(os.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	defaulted := func(mutate func(c *Configuration)) *Configuration {
+		c := &Configuration{
+			TypeMeta:     metav1.TypeMeta{APIVersion: APIVersion, Kind: Kind},
+			Mode:         dpucniprovisioner.InternalIPAM,
+			NodeName:     "dpu1",
+			VTEPCIDRs:    []string{"192.168.0.0/24", "192.168.1.0/24"},
+			HostCIDRs:    []string{"10.0.100.0/24"},
+			OVNMTU:       1500,
+			IPAllocation: IPAllocation{VTEPFilePath: DefaultVTEPIPAllocationFilePath, PFFilePath: DefaultPFIPAllocationFilePath},
+			HostCluster: HostCluster{
+				TokenFilePath: DefaultHostClusterTokenFilePath,
+				CAFilePath:    DefaultHostClusterCAFilePath,
+			},
+			OVSClientBackend:        ovsclient.VsctlBackend,
+			DHCPServerBackend:       dpucniprovisioner.DNSMasqDHCPServer,
+			GatewayDiscovery:        GatewayDiscovery{Strategy: dpucniprovisioner.RouteGatewayDiscovery},
+			Uplinks:                 Uplinks{EncapMode: dpucniprovisioner.ActiveBackupEncap},
+			StateDir:                DefaultStateDir,
+			OVNKubeNodeNotification: OVNKubeNodeNotification{Hook: NoneNotificationHook},
+		}
+		if mutate != nil {
+			mutate(c)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name           string
+		file           string
+		mode           dpucniprovisioner.Mode
+		env            map[string]string
+		expected       *Configuration
+		expectedErrors []string
+	}{
+		{
+			name:     "file only",
+			file:     internalIPAMConfig,
+			expected: defaulted(nil),
+		},
+		{
+			name: "json file",
+			file: `{"apiVersion": "dpucniprovisioner.ovn.nvidia.com/v1alpha1", "kind": "DPUCNIProvisionerConfiguration", ` +
+				`"mode": "internal-ipam", "nodeName": "dpu1", "vtepCIDRs": ["192.168.0.0/24", "192.168.1.0/24"], ` +
+				`"hostCIDRs": ["10.0.100.0/24"], "ovnMTU": 1500}`,
+			expected: defaulted(nil),
+		},
+		{
+			name: "env only",
+			mode: dpucniprovisioner.InternalIPAM,
+			env: map[string]string{
+				"NODE_NAME": "dpu1",
+				"VTEP_CIDR": "192.168.0.0/24, 192.168.1.0/24",
+				"HOST_CIDR": "10.0.100.0/24",
+				"OVN_MTU":   "1500",
+			},
+			expected: defaulted(nil),
+		},
+		{
+			name: "mode from env",
+			env: map[string]string{
+				"IPAM_MODE": "internal-ipam",
+				"NODE_NAME": "dpu1",
+				"VTEP_CIDR": "192.168.0.0/24, 192.168.1.0/24",
+				"HOST_CIDR": "10.0.100.0/24",
+				"OVN_MTU":   "1500",
+			},
+			expected: defaulted(nil),
+		},
+		{
+			name: "env overrides file",
+			file: internalIPAMConfig,
+			env: map[string]string{
+				"HOST_CIDR":                             "10.0.100.0/24,10.0.101.0/24",
+				"OVN_MTU":                               "9000",
+				"K8S_APISERVER":                         "https://10.0.100.1:6443",
+				"OVNKUBE_NODE_DPU_LEASE_RENEW_INTERVAL": "10",
+				"OVNKUBE_NODE_LEASE_NAMESPACE":          "ovn-kubernetes",
+				"DHCP_SERVER_BACKEND":                   "builtin",
+				"PF_INDEX":                              "1",
+				"STATE_DIR":                             "/var/lib/ovn-kubernetes/dpucniprovisioner",
+				"OVNK_CONF_FRAGMENT_PATH":               "/etc/dpucniprovisioner/ovnk-conf/ovn_k8s.conf",
+				"VTEP_PROBE_INTERVAL_SECONDS":           "30",
+			},
+			expected: defaulted(func(c *Configuration) {
+				c.HostCIDRs = []string{"10.0.100.0/24", "10.0.101.0/24"}
+				c.OVNMTU = 9000
+				c.HostCluster.APIServer = "https://10.0.100.1:6443"
+				c.DPUNodeLease = &DPUNodeLease{RenewIntervalSeconds: 10, DurationSeconds: DefaultDPUNodeLeaseDuration}
+				c.OVNConfigNamespace = "ovn-kubernetes"
+				c.DHCPServerBackend = dpucniprovisioner.BuiltinDHCPServer
+				c.Interfaces.PFIndex = "1"
+				c.StateDir = "/var/lib/ovn-kubernetes/dpucniprovisioner"
+				c.OVNKConfFragmentPath = "/etc/dpucniprovisioner/ovnk-conf/ovn_k8s.conf"
+				c.VTEPProbe = &VTEPProbe{IntervalSeconds: 30, TimeoutSeconds: DefaultVTEPProbeTimeoutSeconds, SampleSize: DefaultVTEPProbeSampleSize}
+			}),
+		},
+		{
+			name: "mode overrides file and env",
+			file: internalIPAMConfig + "gatewayDiscoveryNetworks: [\"169.254.99.100/32\"]\n",
+			mode: dpucniprovisioner.ExternalIPAM,
+			env: map[string]string{
+				"IPAM_MODE": "internal-ipam",
+			},
+			expected: defaulted(func(c *Configuration) {
+				c.Mode = dpucniprovisioner.ExternalIPAM
+				c.GatewayDiscoveryNetworks = []string{"169.254.99.100/32"}
+			}),
+		},
+		{
+			name: "lease duration alone doesn't enable the lease",
+			file: internalIPAMConfig,
+			env: map[string]string{
+				"OVNKUBE_NODE_DPU_LEASE_DURATION": "60",
+			},
+			expected: defaulted(nil),
+		},
+		{
+			name: "additional uplinks",
+			file: internalIPAMConfig + `
+uplinks:
+  primaryPort: p0
+  additional:
+  - bridge: br-ovn-p1
+    port: p1
+    pfIndex: "1"
+    ipAllocation:
+      vtepFilePath: /tmp/ips/vtep-p1
+      pfFilePath: /tmp/ips/pf-p1
+`,
+			env: map[string]string{
+				"ENCAP_MODE": "ecmp",
+			},
+			expected: defaulted(func(c *Configuration) {
+				c.Uplinks = Uplinks{
+					PrimaryPort: "p0",
+					EncapMode:   dpucniprovisioner.ECMPEncap,
+					Additional: []Uplink{{
+						Bridge:       "br-ovn-p1",
+						Port:         "p1",
+						PFIndex:      "1",
+						IPAllocation: IPAllocation{VTEPFilePath: "/tmp/ips/vtep-p1", PFFilePath: "/tmp/ips/pf-p1"},
+					}},
+				}
+			}),
+		},
+		{
+			name: "invalid uplinks",
+			file: internalIPAMConfig + `
+interfaces:
+  pfIndex: "0"
+uplinks:
+  encapMode: round-robin
+  additional:
+  - bridge: br-ovn
+    pfIndex: "0"
+  - pfIndex: "2"
+    ipAllocation:
+      vtepFilePath: /tmp/ips/vtep-p1
+      pfFilePath: /tmp/ips/pf-p1
+`,
+			expectedErrors: []string{
+				`uplinks.encapMode: Unsupported value: "round-robin"`,
+				`uplinks.additional[0].bridge: Duplicate value: "br-ovn"`,
+				`uplinks.additional[0].pfIndex: Duplicate value: "0"`,
+				`uplinks.additional[0].ipAllocation.vtepFilePath: Required value`,
+				`uplinks.additional[0].ipAllocation.pfFilePath: Required value`,
+				`uplinks.additional[1].bridge: Required value`,
+				`uplinks.additional[1].pfIndex: Unsupported value: "2"`,
+			},
+		},
+		{
+			name: "additional uplinks require internal ipam",
+			file: internalIPAMConfig + `gatewayDiscoveryNetworks: ["169.254.99.100/32"]
+uplinks:
+  additional:
+  - bridge: br-ovn-p1
+    pfIndex: "1"
+    ipAllocation:
+      vtepFilePath: /tmp/ips/vtep-p1
+      pfFilePath: /tmp/ips/pf-p1
+`,
+			mode:           dpucniprovisioner.ExternalIPAM,
+			expectedErrors: []string{`uplinks.additional: Forbidden: only supported in internal-ipam mode`},
+		},
+		{
+			name:           "unknown field",
+			file:           internalIPAMConfig + "vtepCIDR: 192.168.0.0/24\n",
+			expectedErrors: []string{`unknown field "vtepCIDR"`},
+		},
+		{
+			name: "all errors are aggregated",
+			file: `
+apiVersion: dpucniprovisioner.ovn.nvidia.com/v2
+kind: DPUCNIProvisionerConfiguration
+mode: internal-ipam
+vtepCIDRs: ["192.168.0.0/24", "fd00::/64", "not-a-cidr"]
+hostCIDRs: ["10.0.100.0/24"]
+dpuNodeLease:
+  renewIntervalSeconds: 40
+interfaces:
+  pfIndex: "2"
+ovsClientBackend: ovsdb-server
+vtepProbe:
+  intervalSeconds: 5
+  timeoutSeconds: 5
+`,
+			env: map[string]string{
+				"OVN_MTU": "big",
+			},
+			expectedErrors: []string{
+				`apiVersion: Unsupported value: "dpucniprovisioner.ovn.nvidia.com/v2"`,
+				`OVN_MTU: Invalid value: "big": must be an integer`,
+				`nodeName: Required value`,
+				`vtepCIDRs[2]: Invalid value: "not-a-cidr": must be a CIDR`,
+				`dpuNodeLease.durationSeconds: Invalid value: 40: must be greater than renewIntervalSeconds`,
+				`vtepProbe.timeoutSeconds: Invalid value: 5: must be greater than 0 and less than intervalSeconds`,
+				`interfaces.pfIndex: Unsupported value: "2"`,
+				`ovsClientBackend: Unsupported value: "ovsdb-server"`,
+			},
+		},
+		{
+			name: "ip families of the host CIDRs must match the ones of the VTEP CIDRs",
+			file: internalIPAMConfig,
+			env: map[string]string{
+				"VTEP_CIDR": "192.168.0.0/24,fd00:1::/64",
+			},
+			expectedErrors: []string{`hostCIDRs: Invalid value: ["10.0.100.0/24"]: an IPv6 network is expected as vtepCIDRs has one`},
+		},
+		{
+			name: "external ipam requires one gateway discovery network per ip family",
+			file: internalIPAMConfig + "gatewayDiscoveryNetworks: [\"169.254.99.100/32\", \"169.254.99.101/32\"]\n",
+			mode: dpucniprovisioner.ExternalIPAM,
+			expectedErrors: []string{
+				`gatewayDiscoveryNetworks: Invalid value: ["169.254.99.100/32","169.254.99.101/32"]: at most 1 network per IP family is expected`,
+			},
+		},
+		{
+			name: "static gateway discovery doesn't require gateway discovery networks",
+			file: internalIPAMConfig,
+			mode: dpucniprovisioner.ExternalIPAM,
+			env: map[string]string{
+				"GATEWAY_DISCOVERY_STRATEGY": "static",
+				"GATEWAY_DISCOVERY_GATEWAYS": "192.168.0.254",
+			},
+			expected: defaulted(func(c *Configuration) {
+				c.Mode = dpucniprovisioner.ExternalIPAM
+				c.GatewayDiscovery = GatewayDiscovery{Strategy: dpucniprovisioner.StaticGatewayDiscovery, Gateways: []string{"192.168.0.254"}}
+			}),
+		},
+		{
+			name: "probe gateway discovery of a dual-stack configuration",
+			file: internalIPAMConfig + `gatewayDiscovery:
+  strategy: probe
+  gateways: ["192.168.0.254", "192.168.0.253", "fd00:1::fe"]
+`,
+			mode: dpucniprovisioner.ExternalIPAM,
+			env: map[string]string{
+				"VTEP_CIDR": "192.168.0.0/24,fd00:1::/64",
+				"HOST_CIDR": "10.0.100.0/24,fd00:100::/64",
+			},
+			expected: defaulted(func(c *Configuration) {
+				c.Mode = dpucniprovisioner.ExternalIPAM
+				c.VTEPCIDRs = []string{"192.168.0.0/24", "fd00:1::/64"}
+				c.HostCIDRs = []string{"10.0.100.0/24", "fd00:100::/64"}
+				c.GatewayDiscovery = GatewayDiscovery{Strategy: dpucniprovisioner.ProbeGatewayDiscovery, Gateways: []string{"192.168.0.254", "192.168.0.253", "fd00:1::fe"}}
+			}),
+		},
+		{
+			name: "invalid gateway discovery",
+			file: internalIPAMConfig + `gatewayDiscovery:
+  strategy: static
+  gateways: ["192.168.0.254", "192.168.0.253", "fd00:1::fe", "gateway"]
+`,
+			mode: dpucniprovisioner.ExternalIPAM,
+			expectedErrors: []string{
+				`gatewayDiscovery.gateways[3]: Invalid value: "gateway": must be an IP`,
+			},
+		},
+		{
+			name: "gateway discovery strategies validate the gateways against the ip families",
+			file: internalIPAMConfig + `gatewayDiscovery:
+  strategy: static
+  gateways: ["192.168.0.254", "192.168.0.253", "fd00:1::fe"]
+`,
+			mode: dpucniprovisioner.ExternalIPAM,
+			expectedErrors: []string{
+				`gatewayDiscovery.gateways: Invalid value: ["192.168.0.254","192.168.0.253","fd00:1::fe"]: no IPv6 gateway is expected as vtepCIDRs has none`,
+				`gatewayDiscovery.gateways: Invalid value: ["192.168.0.254","192.168.0.253","fd00:1::fe"]: exactly 1 IPv4 gateway is expected by the static strategy`,
+			},
+		},
+		{
+			name: "ovs profile",
+			file: internalIPAMConfig + `ovsProfile:
+  otherConfig:
+    hw-offload: "true"
+    dpdk-socket-mem: "1024,1024"
+  docaInit: true
+  bridgeDatapathTypes:
+    br-ovn: netdev
+  restart:
+    enabled: true
+    command: ["ovs-ctl", "restart"]
+`,
+			expected: defaulted(func(c *Configuration) {
+				docaInit := true
+				c.OVSProfile = &OVSProfile{
+					OtherConfig:         map[string]string{"hw-offload": "true", "dpdk-socket-mem": "1024,1024"},
+					DOCAInit:            &docaInit,
+					BridgeDataPathTypes: map[string]ovsclient.BridgeDataPathType{"br-ovn": ovsclient.NetDev},
+					Restart:             OVSRestart{Enabled: true, Command: []string{"ovs-ctl", "restart"}},
+				}
+			}),
+		},
+		{
+			name: "invalid ovs profile",
+			file: internalIPAMConfig + `ovsProfile:
+  otherConfig:
+    doca-init: "true"
+  bridgeDatapathTypes:
+    br-ovn: dpdk
+  restart:
+    command: ["ovs-ctl", ""]
+`,
+			expectedErrors: []string{
+				`ovsProfile.otherConfig[doca-init]: Forbidden: set with docaInit`,
+				`ovsProfile.bridgeDatapathTypes[br-ovn]: Unsupported value: "dpdk": supported values: "system", "netdev"`,
+				`ovsProfile.restart.command[1]: Invalid value: "": must not be empty`,
+			},
+		},
+		{
+			name: "ovs profile restart without command",
+			file: internalIPAMConfig + `ovsProfile:
+  docaInit: true
+  restart:
+    enabled: true
+`,
+			expectedErrors: []string{
+				`ovsProfile.restart.command: Required value: required when restart is enabled`,
+			},
+		},
+		{
+			name: "dhcp lease gateway discovery requires ipv4 only",
+			file: internalIPAMConfig,
+			mode: dpucniprovisioner.ExternalIPAM,
+			env: map[string]string{
+				"VTEP_CIDR":                  "192.168.0.0/24,fd00:1::/64",
+				"HOST_CIDR":                  "10.0.100.0/24,fd00:100::/64",
+				"GATEWAY_DISCOVERY_STRATEGY": "dhcp-lease",
+				"GATEWAY_DISCOVERY_GATEWAYS": "192.168.0.254",
+			},
+			expectedErrors: []string{
+				`gatewayDiscovery.gateways: Forbidden: not supported by the dhcp-lease strategy`,
+				`gatewayDiscovery.strategy: Invalid value: "dhcp-lease": only supported when vtepCIDRs are IPv4 only`,
+			},
+		},
+		{
+			name: "ovnkube-node notification via signal",
+			file: internalIPAMConfig + `ovnkubeNodeNotification:
+  hook: signal
+  pidFilePath: /var/run/ovn-kubernetes/ovnkube-node.pid
+`,
+			expected: defaulted(func(c *Configuration) {
+				c.OVNKubeNodeNotification = OVNKubeNodeNotification{
+					Hook:        SignalNotificationHook,
+					PIDFilePath: "/var/run/ovn-kubernetes/ovnkube-node.pid",
+					Signal:      DefaultOVNKubeNodeNotificationSignal,
+				}
+			}),
+		},
+		{
+			name: "ovnkube-node notification via pod deletion",
+			file: internalIPAMConfig,
+			env: map[string]string{
+				"OVNKUBE_NODE_NOTIFICATION_HOOK":          "delete-pod",
+				"OVNKUBE_NODE_NOTIFICATION_POD_NAMESPACE": "ovn-kubernetes",
+				"OVNKUBE_NODE_NOTIFICATION_POD_SELECTOR":  "app.kubernetes.io/component=ovnkube-node",
+			},
+			expected: defaulted(func(c *Configuration) {
+				c.OVNKubeNodeNotification = OVNKubeNodeNotification{
+					Hook:             DeletePodNotificationHook,
+					PodNamespace:     "ovn-kubernetes",
+					PodLabelSelector: "app.kubernetes.io/component=ovnkube-node",
+				}
+			}),
+		},
+		{
+			name: "invalid ovnkube-node notification",
+			file: internalIPAMConfig + `ovnkubeNodeNotification:
+  hook: signal
+  signal: SIGKILL
+`,
+			expectedErrors: []string{
+				`ovnkubeNodeNotification.pidFilePath: Required value: required by the signal hook`,
+				`ovnkubeNodeNotification.signal: Unsupported value: "SIGKILL"`,
+			},
+		},
+		{
+			name: "ovnkube-node notification hooks require their inputs",
+			file: internalIPAMConfig,
+			env: map[string]string{
+				"OVNKUBE_NODE_NOTIFICATION_HOOK":         "delete-pod",
+				"OVNKUBE_NODE_NOTIFICATION_POD_SELECTOR": "app in (",
+			},
+			expectedErrors: []string{
+				`ovnkubeNodeNotification.podNamespace: Required value: required by the delete-pod hook`,
+				`ovnkubeNodeNotification.podLabelSelector: Invalid value: "app in ("`,
+			},
+		},
+		{
+			name:           "unknown ovnkube-node notification hook",
+			file:           internalIPAMConfig,
+			env:            map[string]string{"OVNKUBE_NODE_NOTIFICATION_HOOK": "restart"},
+			expectedErrors: []string{`ovnkubeNodeNotification.hook: Unsupported value: "restart"`},
+		},
+		{
+			name:           "unknown gateway discovery strategy",
+			file:           internalIPAMConfig + "gatewayDiscoveryNetworks: [\"169.254.99.100/32\"]\n",
+			mode:           dpucniprovisioner.ExternalIPAM,
+			env:            map[string]string{"GATEWAY_DISCOVERY_STRATEGY": "arp"},
+			expectedErrors: []string{`gatewayDiscovery.strategy: Unsupported value: "arp"`},
+		},
+		{
+			name:           "unknown mode",
+			file:           internalIPAMConfig,
+			mode:           "ipam",
+			expectedErrors: []string{`mode: Unsupported value: "ipam"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			loader := &Loader{
+				Mode:   tt.mode,
+				Getenv: func(key string) string { return tt.env[key] },
+			}
+			if tt.file != "" {
+				loader.Path = writeConfig(t, tt.file)
+			}
+
+			c, err := loader.Load()
+			if len(tt.expectedErrors) > 0 {
+				g.Expect(err).To(HaveOccurred())
+				for _, expected := range tt.expectedErrors {
+					g.Expect(err.Error()).To(ContainSubstring(expected))
+				}
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(c).To(Equal(tt.expected))
+		})
+	}
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config contains the configuration file format of the DPU CNI Provisioner and the logic to load, default,
+// validate and watch it.
+package config
+
+import (
+	dpucniprovisioner "github.com/nvidia/ovn-kubernetes-components/internal/cniprovisioner/dpu"
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/ovsclient"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// APIVersion is the only supported version of the configuration file
+	APIVersion = "dpucniprovisioner.ovn.nvidia.com/v1alpha1"
+	// Kind is the kind of the configuration file
+	Kind = "DPUCNIProvisionerConfiguration"
+
+	// DefaultVTEPIPAllocationFilePath is the path to the file that contains the VTEP IP allocation done by the IP
+	// Allocator. We should ensure that the IP Allocation request name is vtep to have this file created correctly.
+	DefaultVTEPIPAllocationFilePath = "/tmp/ips/vtep"
+	// DefaultPFIPAllocationFilePath is the path to the file that contains the PF IP allocation done by the IP
+	// Allocator. We should ensure that the IP Allocation request name is pf to have this file created correctly.
+	DefaultPFIPAllocationFilePath = "/tmp/ips/pf"
+	// DefaultHostClusterTokenFilePath is where the host-cluster token is expected to be mounted.
+	DefaultHostClusterTokenFilePath = "/host-cluster-access/token"
+	// DefaultHostClusterCAFilePath is where the host-cluster CA bundle is expected to be mounted.
+	DefaultHostClusterCAFilePath = "/host-cluster-access/ca.crt"
+	// DefaultDPUNodeLeaseDuration is the DPU node lease duration in seconds used when only the renew interval is set.
+	DefaultDPUNodeLeaseDuration = 40
+	// DefaultStateDir is the directory the journal of the applied state is kept in. It's expected to be a host path so
+	// that the journal survives restarts of the provisioner.
+	DefaultStateDir = "/var/lib/dpucniprovisioner"
+	// DefaultOVNKubeNodeNotificationSignal is the signal sent by the signal hook when none is set
+	DefaultOVNKubeNodeNotificationSignal = "SIGHUP"
+	// DefaultVTEPProbeTimeoutSeconds is how long a VTEP is given to answer a probe when no timeout is set
+	DefaultVTEPProbeTimeoutSeconds = 1
+	// DefaultVTEPProbeSampleSize is how many VTEPs are probed every interval when no sample size is set
+	DefaultVTEPProbeSampleSize = 5
+)
+
+// Configuration is the configuration of the DPU CNI Provisioner
+type Configuration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Mode is the mode in which the provisioner runs
+	Mode dpucniprovisioner.Mode `json:"mode,omitempty"`
+	// NodeName is the name of the DPU Node the provisioner runs on
+	NodeName string `json:"nodeName,omitempty"`
+	// VTEPCIDRs are the CIDRs the VTEP IPs of all the DPUs belong to, at least one per IP family. The IP family of the
+	// first one is the primary IP family.
+	VTEPCIDRs []string `json:"vtepCIDRs,omitempty"`
+	// HostCIDRs are the CIDRs of the host machines, at least one per IP family
+	HostCIDRs []string `json:"hostCIDRs,omitempty"`
+	// GatewayDiscoveryNetworks are the networks from which the gateway is discovered, one per IP family. Required in
+	// external-ipam mode when the gateway is discovered via the route strategy.
+	GatewayDiscoveryNetworks []string `json:"gatewayDiscoveryNetworks,omitempty"`
+	// GatewayDiscovery is how the gateway of br-ovn is discovered in external-ipam mode
+	GatewayDiscovery GatewayDiscovery `json:"gatewayDiscovery,omitempty"`
+	// OVNMTU is the MTU that is configured for OVN. When unset, it's derived from the MTU of the uplinks.
+	OVNMTU int `json:"ovnMTU,omitempty"`
+	// IPAllocation is where the results of the IP Allocator are found in internal-ipam mode
+	IPAllocation IPAllocation `json:"ipAllocation,omitempty"`
+	// HostCluster is how the host cluster is accessed
+	HostCluster HostCluster `json:"hostCluster,omitempty"`
+	// DPUNodeLease, when set, configures the ovnkube-node DPU lease in ovn_k8s.conf
+	DPUNodeLease *DPUNodeLease `json:"dpuNodeLease,omitempty"`
+	// OVNConfigNamespace, when set, is the namespace written to ovn_k8s.conf for the OVN Kubernetes config objects
+	OVNConfigNamespace string `json:"ovnConfigNamespace,omitempty"`
+	// OVNKConfFragmentPath, when set, is an INI file whose sections and keys are merged into ovn_k8s.conf. The file is
+	// optional and can't set the keys the provisioner manages.
+	OVNKConfFragmentPath string `json:"ovnkConfFragmentPath,omitempty"`
+	// Interfaces are the interfaces of the DPU that are used instead of the discovered ones
+	Interfaces Interfaces `json:"interfaces,omitempty"`
+	// Uplinks are the uplinks of the DPU in addition to br-ovn and how the geneve tunnels use them
+	Uplinks Uplinks `json:"uplinks,omitempty"`
+	// OVSClientBackend is the mechanism used to talk to OVS
+	OVSClientBackend ovsclient.Backend `json:"ovsClientBackend,omitempty"`
+	// DHCPServerBackend is the implementation of the DHCP server used in internal-ipam mode
+	DHCPServerBackend dpucniprovisioner.DHCPServerBackend `json:"dhcpServerBackend,omitempty"`
+	// MetricsBindAddress is the address the metrics are served on. Metrics are not served when empty.
+	MetricsBindAddress string `json:"metricsBindAddress,omitempty"`
+	// HealthProbeBindAddress is the address the health probes are served on. Probes are not served when empty.
+	HealthProbeBindAddress string `json:"healthProbeBindAddress,omitempty"`
+	// StateDir is the directory the journal of the applied state is kept in
+	StateDir string `json:"stateDir,omitempty"`
+	// OVNKubeNodeNotification is how ovnkube-node is notified of changes of ovn_k8s.conf
+	OVNKubeNodeNotification OVNKubeNodeNotification `json:"ovnkubeNodeNotification,omitempty"`
+	// VTEPProbe, when set, makes the provisioner probe the VTEPs of the other DPUs
+	VTEPProbe *VTEPProbe `json:"vtepProbe,omitempty"`
+	// OVSProfile, when set, is the datapath profile of OVS the provisioner applies
+	OVSProfile *OVSProfile `json:"ovsProfile,omitempty"`
+}
+
+// IPAllocation is where the results of the IP Allocator are found
+type IPAllocation struct {
+	// VTEPFilePath is the path to the VTEP IP allocation
+	VTEPFilePath string `json:"vtepFilePath,omitempty"`
+	// PFFilePath is the path to the PF IP allocation
+	PFFilePath string `json:"pfFilePath,omitempty"`
+}
+
+// HostCluster is how the host cluster is accessed
+type HostCluster struct {
+	// APIServer is the API server endpoint of the host cluster. The host cluster client is disabled when empty.
+	APIServer string `json:"apiServer,omitempty"`
+	// TokenFilePath is the path to the token used to authenticate against the host cluster
+	TokenFilePath string `json:"tokenFilePath,omitempty"`
+	// CAFilePath is the path to the CA bundle of the host cluster
+	CAFilePath string `json:"caFilePath,omitempty"`
+}
+
+// Interfaces are the interfaces of the DPU. Each one that is not set is discovered.
+type Interfaces struct {
+	// OOBBridge is the out of band bridge. Discovered from the default route of the primary IP family.
+	OOBBridge string `json:"oobBridge,omitempty"`
+	// FlannelInterface is the bridge of the DPU cluster CNI. Discovered from the CNI configuration of the DPU.
+	FlannelInterface string `json:"flannelInterface,omitempty"`
+	// PFIndex is the index of the PF the host uses to reach the DPU, "0" or "1". Discovered from the PF representor
+	// that is attached to OVS.
+	PFIndex string `json:"pfIndex,omitempty"`
+}
+
+// Uplinks are the uplinks of the DPU in addition to br-ovn and how the geneve tunnels use them
+type Uplinks struct {
+	// PrimaryPort is the physical port of br-ovn whose carrier decides whether br-ovn is healthy. br-ovn is always
+	// considered healthy when empty.
+	PrimaryPort string `json:"primaryPort,omitempty"`
+	// EncapMode is how the VTEP IPs of the uplinks are used as geneve encap IPs, active-backup or ecmp
+	EncapMode dpucniprovisioner.EncapMode `json:"encapMode,omitempty"`
+	// Additional are the uplinks in addition to br-ovn, in order of preference. Only supported in internal-ipam mode.
+	Additional []Uplink `json:"additional,omitempty"`
+}
+
+// Uplink is an uplink of the DPU in addition to br-ovn, e.g. the second port of a dual port DPU
+type Uplink struct {
+	// Bridge is the OVS bridge whose internal port acts as the VTEP of the uplink
+	Bridge string `json:"bridge,omitempty"`
+	// Port is the physical port of the uplink whose carrier decides whether the uplink is healthy
+	Port string `json:"port,omitempty"`
+	// PFIndex is the index of the PF the host uses to reach the DPU through the uplink, "0" or "1"
+	PFIndex string `json:"pfIndex,omitempty"`
+	// IPAllocation is where the results of the IP Allocator for the VTEP and the PF of the uplink are found. Both
+	// paths are required.
+	IPAllocation IPAllocation `json:"ipAllocation,omitempty"`
+}
+
+// GatewayDiscovery is how the gateway of br-ovn is discovered in external-ipam mode
+type GatewayDiscovery struct {
+	// Strategy is one of route, dhcp-lease, static or probe. Defaults to route.
+	Strategy dpucniprovisioner.GatewayDiscoveryStrategy `json:"strategy,omitempty"`
+	// Gateways are the gateways of the static strategy, exactly one per IP family, or the candidates of the probe
+	// strategy in order of preference, at least one per IP family
+	Gateways []string `json:"gateways,omitempty"`
+}
+
+// OVNKubeNodeNotificationHook is how ovnkube-node is notified of changes of ovn_k8s.conf
+type OVNKubeNodeNotificationHook string
+
+const (
+	// NoneNotificationHook doesn't notify ovnkube-node
+	NoneNotificationHook OVNKubeNodeNotificationHook = "none"
+	// SignalNotificationHook sends a signal to the process whose PID is in a PID file
+	SignalNotificationHook OVNKubeNodeNotificationHook = "signal"
+	// TriggerFileNotificationHook touches a file
+	TriggerFileNotificationHook OVNKubeNodeNotificationHook = "trigger-file"
+	// DeletePodNotificationHook deletes the ovnkube-node Pod of the DPU Node via the API of the DPU cluster
+	DeletePodNotificationHook OVNKubeNodeNotificationHook = "delete-pod"
+)
+
+// SupportedOVNKubeNodeNotificationSignals are the signals the signal hook can send
+var SupportedOVNKubeNodeNotificationSignals = []string{"SIGHUP", "SIGINT", "SIGTERM", "SIGUSR1", "SIGUSR2"}
+
+// OVNKubeNodeNotification is how ovnkube-node is notified of changes of ovn_k8s.conf
+type OVNKubeNodeNotification struct {
+	// Hook is one of none, signal, trigger-file or delete-pod. Defaults to none.
+	Hook OVNKubeNodeNotificationHook `json:"hook,omitempty"`
+	// PIDFilePath is the PID file of ovnkube-node. Required by the signal hook.
+	PIDFilePath string `json:"pidFilePath,omitempty"`
+	// Signal is the signal sent by the signal hook, e.g. SIGHUP. Defaults to SIGHUP.
+	Signal string `json:"signal,omitempty"`
+	// TriggerFilePath is the file touched by the trigger-file hook. Required by the trigger-file hook.
+	TriggerFilePath string `json:"triggerFilePath,omitempty"`
+	// PodNamespace is the namespace of the ovnkube-node Pods. Required by the delete-pod hook.
+	PodNamespace string `json:"podNamespace,omitempty"`
+	// PodLabelSelector selects the ovnkube-node Pods. Required by the delete-pod hook.
+	PodLabelSelector string `json:"podLabelSelector,omitempty"`
+}
+
+// DPUNodeLease are the ovnkube-node DPU lease intervals
+type DPUNodeLease struct {
+	// RenewIntervalSeconds is how often the lease is renewed
+	RenewIntervalSeconds int `json:"renewIntervalSeconds,omitempty"`
+	// DurationSeconds is the duration of the lease. Must be greater than the renew interval.
+	DurationSeconds int `json:"durationSeconds,omitempty"`
+}
+
+// VTEPProbe is how the VTEPs of the other DPUs are probed. Each probe checks that a VTEP is reachable and that it
+// answers packets of the size of the geneve traffic sent with the DF bit set.
+type VTEPProbe struct {
+	// IntervalSeconds is how often a sample of the VTEPs is probed
+	IntervalSeconds int `json:"intervalSeconds,omitempty"`
+	// TimeoutSeconds is how long a VTEP is given to answer a probe. Must be less than the interval.
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+	// SampleSize is how many VTEPs are probed every interval
+	SampleSize int `json:"sampleSize,omitempty"`
+}
+
+// OVSProfile is the datapath profile of OVS: the other_config keys of the Open_vSwitch row and the datapath type of
+// bridges. The keys that ovs-vswitchd only reads when it starts, e.g. hw-offload or dpdk-socket-mem, take effect once
+// it's restarted.
+type OVSProfile struct {
+	// OtherConfig are the other_config keys of the Open_vSwitch row, e.g. pmd-cpu-mask or max-idle. An empty value
+	// removes the key.
+	OtherConfig map[string]string `json:"otherConfig,omitempty"`
+	// DOCAInit, when set, is the doca-init key of the other_config of the Open_vSwitch row
+	DOCAInit *bool `json:"docaInit,omitempty"`
+	// BridgeDataPathTypes are the datapath types of bridges by name, either system or netdev. A bridge that doesn't
+	// exist is skipped until it does.
+	BridgeDataPathTypes map[string]ovsclient.BridgeDataPathType `json:"bridgeDatapathTypes,omitempty"`
+	// Restart is how ovs-vswitchd is restarted for the changes that require it to take effect
+	Restart OVSRestart `json:"restart,omitempty"`
+}
+
+// OVSRestart is how ovs-vswitchd is restarted for the changes of the OVS profile that require it to take effect
+type OVSRestart struct {
+	// Enabled makes the provisioner restart ovs-vswitchd. Otherwise the restart is reported as pending on the DPU Node
+	// until ovs-vswitchd is restarted.
+	Enabled bool `json:"enabled,omitempty"`
+	// Command is the command that restarts ovs-vswitchd, e.g. ovs-ctl restart. Required when Enabled is set.
+	Command []string `json:"command,omitempty"`
+}
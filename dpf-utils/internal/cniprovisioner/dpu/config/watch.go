@@ -0,0 +1,133 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+
+	dpucniprovisioner "github.com/nvidia/ovn-kubernetes-components/internal/cniprovisioner/dpu"
+
+	"github.com/fsnotify/fsnotify"
+	"k8s.io/klog/v2"
+)
+
+// Watcher is an EventSource that reloads the configuration file when it changes. Every valid configuration that
+// differs from the current one is handed to the apply function and a reconcile is requested once it's applied. Invalid
+// configurations are logged and the current one is kept.
+type Watcher struct {
+	loader  *Loader
+	current *Configuration
+	apply   func(*Configuration) error
+}
+
+var _ dpucniprovisioner.EventSource = &Watcher{}
+
+// NewWatcher returns a Watcher of the file of the given loader. The current configuration is the one already applied.
+func NewWatcher(loader *Loader, current *Configuration, apply func(*Configuration) error) *Watcher {
+	return &Watcher{
+		loader:  loader,
+		current: current,
+		apply:   apply,
+	}
+}
+
+// Run watches the configuration file until the context is cancelled
+func (w *Watcher) Run(ctx context.Context, notify func(reason string)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("error while creating file watcher: %w", err)
+	}
+	logger := klog.FromContext(ctx)
+	defer func() {
+		if err := watcher.Close(); err != nil {
+			logger.Error(err, "error while closing file watcher")
+		}
+	}()
+
+	// The directory is watched so that the symlink swaps the kubelet does when updating ConfigMap volumes are observed
+	dir := filepath.Dir(w.loader.Path)
+	if err := watcher.Add(dir); err != nil {
+		return fmt.Errorf("error while watching %s: %w", dir, err)
+	}
+	// Changes may have happened while the file was not watched
+	w.reload(logger, notify)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return fmt.Errorf("file watcher of %s stopped", dir)
+			}
+			w.reload(logger, notify)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return fmt.Errorf("file watcher of %s stopped", dir)
+			}
+			return fmt.Errorf("error while watching %s: %w", dir, err)
+		}
+	}
+}
+
+// reload loads the configuration and applies it if it changed
+func (w *Watcher) reload(logger klog.Logger, notify func(reason string)) {
+	c, err := w.loader.Load()
+	if err != nil {
+		logger.Error(err, "error while reloading configuration, keeping the current one", "path", w.loader.Path)
+		return
+	}
+	if reflect.DeepEqual(c, w.current) {
+		return
+	}
+	if fields := RestartRequired(w.current, c); len(fields) > 0 {
+		logger.Info("Some changes take effect only after a restart", "fields", fields)
+	}
+	if err := w.apply(c); err != nil {
+		logger.Error(err, "error while applying configuration, keeping the current one", "path", w.loader.Path)
+		return
+	}
+	w.current = c
+	notify("configuration changed")
+}
+
+// RestartRequired returns the fields that differ between the given configurations and that can't be changed while the
+// provisioner is running
+func RestartRequired(old, new *Configuration) []string {
+	var fields []string
+	for _, f := range []struct {
+		name     string
+		old, new any
+	}{
+		{name: "mode", old: old.Mode, new: new.Mode},
+		{name: "nodeName", old: old.NodeName, new: new.NodeName},
+		{name: "hostCluster", old: old.HostCluster, new: new.HostCluster},
+		{name: "ovsClientBackend", old: old.OVSClientBackend, new: new.OVSClientBackend},
+		{name: "dhcpServerBackend", old: old.DHCPServerBackend, new: new.DHCPServerBackend},
+		{name: "metricsBindAddress", old: old.MetricsBindAddress, new: new.MetricsBindAddress},
+		{name: "healthProbeBindAddress", old: old.HealthProbeBindAddress, new: new.HealthProbeBindAddress},
+		{name: "vtepProbe", old: old.VTEPProbe, new: new.VTEPProbe},
+	} {
+		if !reflect.DeepEqual(f.old, f.new) {
+			fields = append(fields, f.name)
+		}
+	}
+	return fields
+}
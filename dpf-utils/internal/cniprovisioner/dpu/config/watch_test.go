@@ -0,0 +1,99 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestWatcher(t *testing.T) {
+	g := NewWithT(t)
+	path := writeConfig(t, internalIPAMConfig)
+	loader := &Loader{Path: path, Getenv: func(string) string { return "" }}
+	current, err := loader.Load()
+	g.Expect(err).ToNot(HaveOccurred())
+
+	var lock sync.Mutex
+	var applied []*Configuration
+	var reasons []string
+	w := NewWatcher(loader, current, func(c *Configuration) error {
+		lock.Lock()
+		defer lock.Unlock()
+		applied = append(applied, c)
+		return nil
+	})
+	appliedMTUs := func() []int {
+		lock.Lock()
+		defer lock.Unlock()
+		mtus := make([]int, 0, len(applied))
+		for _, c := range applied {
+			mtus = append(mtus, c.OVNMTU)
+		}
+		return mtus
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx, func(reason string) {
+			lock.Lock()
+			defer lock.Unlock()
+			reasons = append(reasons, reason)
+		})
+	}()
+	defer func() {
+		cancel()
+		g.Expect(<-done).To(Succeed())
+	}()
+
+	// An unchanged configuration is not applied
+	g.Consistently(appliedMTUs, 200*time.Millisecond).Should(BeEmpty())
+
+	// A changed configuration is applied and a reconcile is requested
+	g.Expect(os.WriteFile(path, []byte(strings.Replace(internalIPAMConfig, "ovnMTU: 1500", "ovnMTU: 9000", 1)), 0644)).To(Succeed())
+	g.Eventually(appliedMTUs).Should(Equal([]int{9000}))
+	lock.Lock()
+	g.Expect(reasons).To(Equal([]string{"configuration changed"}))
+	lock.Unlock()
+
+	// An invalid configuration is not applied
+	g.Expect(os.WriteFile(path, []byte(strings.Replace(internalIPAMConfig, "ovnMTU: 1500", "ovnMTU: -1", 1)), 0644)).To(Succeed())
+	g.Consistently(appliedMTUs, 200*time.Millisecond).Should(Equal([]int{9000}))
+
+	// The configuration that is applied next is compared against the last applied one
+	g.Expect(os.WriteFile(path, []byte(internalIPAMConfig), 0644)).To(Succeed())
+	g.Eventually(appliedMTUs).Should(Equal([]int{9000, 1500}))
+}
+
+func TestRestartRequired(t *testing.T) {
+	g := NewWithT(t)
+	old := &Configuration{NodeName: "dpu1", OVNMTU: 1500, HostCluster: HostCluster{APIServer: "https://10.0.100.1:6443"}}
+	new := &Configuration{NodeName: "dpu1", OVNMTU: 9000, HostCluster: HostCluster{APIServer: "https://10.0.100.1:6443"}}
+	g.Expect(RestartRequired(old, new)).To(BeEmpty())
+
+	new.NodeName = "dpu2"
+	new.HostCluster.APIServer = "https://10.0.100.2:6443"
+	new.MetricsBindAddress = ":9090"
+	g.Expect(RestartRequired(old, new)).To(Equal([]string{"nodeName", "hostCluster", "metricsBindAddress"}))
+}
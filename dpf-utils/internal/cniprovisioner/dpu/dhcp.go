@@ -0,0 +1,522 @@
+/*
+Copyright 2024 NVIDIA.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dpucniprovisioner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nvidia/ovn-kubernetes-components/internal/dhcpserver"
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/logging"
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/networkhelper"
+
+	"k8s.io/klog/v2"
+	kexec "k8s.io/utils/exec"
+)
+
+// DHCPServerBackend is the implementation of the DHCP server that serves the PF on the host in InternalIPAM mode
+type DHCPServerBackend string
+
+const (
+	// DNSMasqDHCPServer runs dnsmasq as a child process
+	DNSMasqDHCPServer DHCPServerBackend = "dnsmasq"
+	// BuiltinDHCPServer runs the DHCP server of the dhcpserver package in process
+	BuiltinDHCPServer DHCPServerBackend = "builtin"
+)
+
+const (
+	// dhcpServerInitialBackoff is the time the provisioner waits before restarting the DHCP server after it exited
+	dhcpServerInitialBackoff = time.Second
+	// dhcpServerMaxBackoff is the maximum time the provisioner waits before restarting the DHCP server
+	dhcpServerMaxBackoff = time.Minute
+	// dhcpServerBackoffResetDuration is how long the DHCP server has to run for the restart backoff to be reset
+	dhcpServerBackoffResetDuration = 2 * time.Minute
+	// dnsmasqRAInterval is the interval in seconds between the unsolicited router advertisements sent by dnsmasq
+	dnsmasqRAInterval = 60
+)
+
+// dhcpServerProcess is a running DHCP server. kexec.Cmd satisfies it.
+type dhcpServerProcess interface {
+	// Wait waits for the DHCP server to exit
+	Wait() error
+	// Stop stops the DHCP server
+	Stop()
+}
+
+// dhcpServer is a DHCP server supervised by the provisioner
+type dhcpServer struct {
+	// process is the running DHCP server
+	process dhcpServerProcess
+	// config is the configuration the DHCP server was started with, one per uplink
+	config []pfDHCPConfig
+	// stopped is set when the process is stopped on purpose and must not be restarted
+	stopped bool
+}
+
+// pfDHCPConfig is the configuration the DHCP server serves a PF on the host with
+type pfDHCPConfig struct {
+	// bridge is the bridge of the uplink the PF is reached through
+	bridge string
+	// v4 is the DHCPv4 configuration. Nil when IPv4 is not configured.
+	v4 *dhcpserver.Config
+	// v6 is the DHCPv6 and router advertisement configuration. Nil when IPv6 is not configured.
+	v6 *dhcpv6Config
+}
+
+// dhcpv6Config is the configuration of the DHCPv6 server and of its router advertisements. DHCPv6 has no option for
+// routes, so unlike with IPv4 the VTEP CIDR is not pushed to the PF on the host.
+type dhcpv6Config struct {
+	// network is the network the bindings belong to. It's advertised as the on-link prefix.
+	network *net.IPNet
+	// mtu is the MTU sent in the router advertisements
+	mtu int
+	// bindings are the clients the server answers to
+	bindings []dhcpserver.Binding
+}
+
+// builtinDHCPServer runs a dhcpserver.Server per uplink, each in a goroutine. All of them stop as soon as one stops.
+type builtinDHCPServer struct {
+	servers []*dhcpserver.Server
+	cancel  context.CancelFunc
+	done    chan struct{}
+	err     error
+}
+
+// Wait waits for the servers to stop serving and returns the error of the first one that stopped
+func (s *builtinDHCPServer) Wait() error {
+	<-s.done
+	return s.err
+}
+
+// Stop stops the server
+func (s *builtinDHCPServer) Stop() {
+	s.cancel()
+}
+
+// SetDHCPServerBackend sets the implementation of the DHCP server used in InternalIPAM mode. Call before RunOnce.
+func (p *DPUCNIProvisioner) SetDHCPServerBackend(backend DHCPServerBackend) error {
+	switch backend {
+	case DNSMasqDHCPServer, BuiltinDHCPServer:
+		p.dhcpServerBackend = backend
+		return nil
+	default:
+		return fmt.Errorf("unknown DHCP server backend %q", backend)
+	}
+}
+
+// DHCPLeases returns the leases handed out by the DHCP server. Only the builtin DHCP server exposes its leases, nil is
+// returned for any other backend or when the server is not running.
+func (p *DPUCNIProvisioner) DHCPLeases() []dhcpserver.Lease {
+	p.dhcpServerLock.Lock()
+	defer p.dhcpServerLock.Unlock()
+
+	if p.dhcpServer == nil {
+		return nil
+	}
+	builtin, ok := p.dhcpServer.process.(*builtinDHCPServer)
+	if !ok {
+		return nil
+	}
+	var leases []dhcpserver.Lease
+	for _, server := range builtin.servers {
+		leases = append(leases, server.Leases()...)
+	}
+	return leases
+}
+
+// startDHCPServer starts a DHCP Server to enable the PF on the host to get an IP. The server is restarted whenever it
+// exits and whenever its arguments change.
+func (p *DPUCNIProvisioner) startDHCPServer() error {
+	return p.ensureDHCPServer(true)
+}
+
+// reconcileDHCPServer restarts the DHCP server if its arguments changed. It's a no-op if the server was never started.
+func (p *DPUCNIProvisioner) reconcileDHCPServer() error {
+	return p.ensureDHCPServer(false)
+}
+
+// ensureDHCPServer ensures that a DHCP server runs with the configuration derived from the current inputs. A server
+// that isn't running yet is started only when start is true.
+func (p *DPUCNIProvisioner) ensureDHCPServer(start bool) error {
+	config, err := p.dhcpServerConfig()
+	if err != nil {
+		return err
+	}
+
+	p.dhcpServerLock.Lock()
+	defer p.dhcpServerLock.Unlock()
+
+	if p.dhcpServer == nil && !start {
+		return nil
+	}
+	if p.dhcpServer != nil {
+		if reflect.DeepEqual(p.dhcpServer.config, config) {
+			p.logger.V(2).Info("DHCP Server already running")
+			return nil
+		}
+		p.logger.Info("DHCP Server configuration changed, restarting", "previous", fmt.Sprintf("%+v", p.dhcpServer.config), "current", fmt.Sprintf("%+v", config))
+		p.dhcpServer.stopped = true
+		p.dhcpServer.process.Stop()
+		p.dhcpServer = nil
+	}
+
+	process, err := p.runDHCPServer(config)
+	if err != nil {
+		return err
+	}
+	server := &dhcpServer{process: process, config: config}
+	p.dhcpServer = server
+	p.recordDHCPServerStart()
+	go p.superviseDHCPServer(server)
+	return nil
+}
+
+// stopDHCPServer stops the DHCP server without restarting it
+func (p *DPUCNIProvisioner) stopDHCPServer() {
+	p.dhcpServerLock.Lock()
+	defer p.dhcpServerLock.Unlock()
+
+	if p.dhcpServer == nil {
+		return
+	}
+	p.dhcpServer.stopped = true
+	p.dhcpServer.process.Stop()
+}
+
+// superviseDHCPServer waits for the given DHCP server to exit and restarts it with an exponential backoff until it's
+// stopped or replaced.
+func (p *DPUCNIProvisioner) superviseDHCPServer(server *dhcpServer) {
+	backoff := dhcpServerInitialBackoff
+	for {
+		startedAt := p.clock.Now()
+		err := server.process.Wait()
+		if !p.isSupervisedDHCPServer(server) {
+			return
+		}
+
+		p.baseLogger.Error(err, "DHCP server exited", "exit", describeExit(err))
+		p.recordDHCPServerExit(err)
+		p.metrics.dhcpServerExits.WithLabelValues(exitReason(err)).Inc()
+		if p.clock.Since(startedAt) >= dhcpServerBackoffResetDuration {
+			backoff = dhcpServerInitialBackoff
+		}
+
+		for {
+			p.baseLogger.Info("Restarting DHCP server", "backoff", backoff.String())
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-p.clock.After(backoff):
+			}
+			backoff = min(2*backoff, dhcpServerMaxBackoff)
+
+			restarted, err := p.restartDHCPServer(server)
+			if err != nil {
+				p.baseLogger.Error(err, "error while restarting DHCP server")
+				continue
+			}
+			if !restarted {
+				return
+			}
+			break
+		}
+	}
+}
+
+// restartDHCPServer starts the given DHCP server again with the configuration it was last started with. Changes to the
+// inputs are left to the next reconciliation, as the supervisor can't read them while the provisioner configures the
+// DPU. Returns false if the server was stopped or replaced in the meantime.
+func (p *DPUCNIProvisioner) restartDHCPServer(server *dhcpServer) (bool, error) {
+	p.dhcpServerLock.Lock()
+	defer p.dhcpServerLock.Unlock()
+
+	if server.stopped || p.dhcpServer != server || p.ctx.Err() != nil {
+		return false, nil
+	}
+	process, err := p.runDHCPServer(server.config)
+	if err != nil {
+		return true, err
+	}
+	server.process = process
+	p.metrics.dhcpServerRestarts.Inc()
+	p.recordDHCPServerStart()
+	p.baseLogger.Info("DHCP server restarted")
+	return true, nil
+}
+
+// isSupervisedDHCPServer returns whether the given DHCP server is still supervised, i.e. it was neither stopped nor
+// replaced and the provisioner is not stopping.
+func (p *DPUCNIProvisioner) isSupervisedDHCPServer(server *dhcpServer) bool {
+	p.dhcpServerLock.Lock()
+	defer p.dhcpServerLock.Unlock()
+	return !server.stopped && p.dhcpServer == server && p.ctx.Err() == nil
+}
+
+// runDHCPServer starts a DHCP server with the given configuration using the configured backend
+func (p *DPUCNIProvisioner) runDHCPServer(configs []pfDHCPConfig) (dhcpServerProcess, error) {
+	if p.dhcpServerBackend == BuiltinDHCPServer {
+		for _, config := range configs {
+			if config.v4 == nil || config.v6 != nil {
+				return nil, fmt.Errorf("the %s DHCP server supports IPv4 only, use the %s one for IPv6", BuiltinDHCPServer, DNSMasqDHCPServer)
+			}
+		}
+		return p.runBuiltinDHCPServer(configs)
+	}
+
+	cmd := p.exec.Command("dnsmasq", p.dnsmasqArgs(configs)...)
+
+	cmd.SetStdout(os.Stdout)
+	cmd.SetStderr(os.Stderr)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("error while starting the DHCP server: %w", err)
+	}
+
+	return cmd, nil
+}
+
+// runBuiltinDHCPServer starts an in process DHCP server on the bridge of every uplink
+func (p *DPUCNIProvisioner) runBuiltinDHCPServer(configs []pfDHCPConfig) (dhcpServerProcess, error) {
+	servers := make([]*dhcpserver.Server, 0, len(configs))
+	conns := make([]net.PacketConn, 0, len(configs))
+	closeConns := func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}
+	for _, config := range configs {
+		server, err := dhcpserver.New(*config.v4, p.clock)
+		if err != nil {
+			closeConns()
+			return nil, err
+		}
+		conn, err := dhcpserver.Listen(config.bridge)
+		if err != nil {
+			closeConns()
+			return nil, fmt.Errorf("error while starting the DHCP server: %w", err)
+		}
+		servers = append(servers, server)
+		conns = append(conns, conn)
+	}
+
+	ctx, cancel := context.WithCancel(p.ctx)
+	builtin := &builtinDHCPServer{
+		servers: servers,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	var wg sync.WaitGroup
+	var once sync.Once
+	for i, server := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serverCtx := klog.NewContext(ctx, klog.LoggerWithValues(p.baseLogger, logging.KeyLink, configs[i].bridge))
+			err := server.Serve(serverCtx, conns[i])
+			once.Do(func() {
+				builtin.err = err
+				cancel()
+			})
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(builtin.done)
+	}()
+	return builtin, nil
+}
+
+// dhcpServerConfig renders the configuration of the DHCP server from the current inputs, one per uplink. No router is
+// sent so that the PFs don't get a default route, each VTEP CIDR is reachable via a classless static route instead.
+func (p *DPUCNIProvisioner) dhcpServerConfig() ([]pfDHCPConfig, error) {
+	// Add the geneve header size to the MTU resolved by the provisioning flow.
+	pfMTU := p.resolvedMTU + geneveHeaderSize
+
+	if pfMTU == geneveHeaderSize || pfMTU > maxMTUSize {
+		return nil, errors.New("invalid PF MTU: it must be greater than 60 and less than or equal to 9216")
+	}
+
+	uplinks := p.uplinks()
+	configs := make([]pfDHCPConfig, 0, len(uplinks))
+	pfBridges := map[string]string{}
+	for _, u := range uplinks {
+		if bridge, ok := pfBridges[u.pfIndex]; ok {
+			return nil, fmt.Errorf("PF %s is served via both %s and %s, select another PF for %s", u.pfIndex, bridge, u.bridge, brOVN)
+		}
+		pfBridges[u.pfIndex] = u.bridge
+
+		config, err := p.pfDHCPConfig(u, pfMTU)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+// pfDHCPConfig renders the configuration the DHCP server serves the PF of the given uplink with
+func (p *DPUCNIProvisioner) pfDHCPConfig(u *uplinkConfig, pfMTU int) (pfDHCPConfig, error) {
+	mac, err := p.networkHelper.GetHostPFMACAddressDPU(u.pfIndex)
+	if err != nil {
+		return pfDHCPConfig{}, fmt.Errorf("error while parsing MAC address of the PF on the host: %w", err)
+	}
+
+	config := pfDHCPConfig{bridge: u.bridge}
+	for _, c := range u.ipFamilies {
+		vtepNetwork, err := c.vtepNetwork()
+		if err != nil {
+			return pfDHCPConfig{}, err
+		}
+		bindings := []dhcpserver.Binding{{MAC: mac, IP: c.pfIP.IP}}
+
+		if c.family == networkhelper.IPv6 {
+			config.v6 = &dhcpv6Config{
+				network:  vtepNetwork,
+				mtu:      pfMTU,
+				bindings: bindings,
+			}
+			continue
+		}
+
+		config.v4 = &dhcpserver.Config{
+			ServerIP:   c.vtepIPNet.IP,
+			SubnetMask: vtepNetwork.Mask,
+			MTU:        pfMTU,
+			Bindings:   bindings,
+		}
+		remoteVTEPCIDRs, err := c.remoteVTEPCIDRs()
+		if err != nil {
+			return pfDHCPConfig{}, err
+		}
+		for _, vtepCIDR := range remoteVTEPCIDRs {
+			config.v4.Routes = append(config.v4.Routes, dhcpserver.Route{Destination: vtepCIDR, Gateway: c.gateway})
+		}
+	}
+
+	return config, nil
+}
+
+// dnsmasqArgs renders the dnsmasq arguments that serve the given configurations. The router advertisements don't
+// announce the DPU as a default router, like the DHCPv4 configuration that sends no router. With several uplinks, the
+// ranges and options of each one are tagged with its bridge so that every PF gets the options of its own uplink.
+func (p *DPUCNIProvisioner) dnsmasqArgs(configs []pfDHCPConfig) []string {
+	args := []string{
+		"--keep-in-foreground",
+		"--port=0",         // Disable DNS Server
+		"--log-facility=-", // Log to stderr
+	}
+	for _, config := range configs {
+		args = append(args, fmt.Sprintf("--interface=%s", config.bridge))
+	}
+
+	// tags returns the prefixes that set and match the tag of the uplink of the given configuration
+	tags := func(config pfDHCPConfig) (string, string) {
+		if len(configs) == 1 {
+			return "", ""
+		}
+		return "set:" + config.bridge + ",", "tag:" + config.bridge + ","
+	}
+
+	var bindings []dhcpserver.Binding
+	for _, config := range configs {
+		setTag, matchTag := tags(config)
+		if config.v4 != nil {
+			args = append(args,
+				"--dhcp-option="+matchTag+"option:router",
+				fmt.Sprintf("--dhcp-option=%soption:mtu,%d", matchTag, config.v4.MTU),
+				fmt.Sprintf("--dhcp-range=%s%s,static", setTag, config.v4.ServerIP.Mask(config.v4.SubnetMask).String()),
+			)
+			bindings = append(bindings, config.v4.Bindings...)
+		}
+		if config.v6 != nil {
+			prefixLength, _ := config.v6.network.Mask.Size()
+			args = append(args,
+				fmt.Sprintf("--dhcp-range=%s%s,static,%d", setTag, config.v6.network.IP.String(), prefixLength),
+				"--enable-ra",
+				fmt.Sprintf("--ra-param=%s,mtu:%d,%d,0", config.bridge, config.v6.mtu, dnsmasqRAInterval),
+			)
+			bindings = append(bindings, config.v6.bindings...)
+		}
+	}
+
+	// dnsmasq expects all the addresses of a host in a single entry
+	var macs []string
+	addresses := map[string][]string{}
+	for _, binding := range bindings {
+		mac := binding.MAC.String()
+		if _, ok := addresses[mac]; !ok {
+			macs = append(macs, mac)
+		}
+		address := binding.IP.String()
+		if networkhelper.FamilyOf(binding.IP) == networkhelper.IPv6 {
+			address = "[" + address + "]"
+		}
+		addresses[mac] = append(addresses[mac], address)
+	}
+	for _, mac := range macs {
+		args = append(args, fmt.Sprintf("--dhcp-host=%s,%s", mac, strings.Join(addresses[mac], ",")))
+	}
+
+	// All the routes have to be sent in a single option, dnsmasq keeps only the last one of repeated options.
+	for _, config := range configs {
+		if config.v4 == nil || len(config.v4.Routes) == 0 {
+			continue
+		}
+		_, matchTag := tags(config)
+		routes := make([]string, 0, len(config.v4.Routes))
+		for _, route := range config.v4.Routes {
+			routes = append(routes, route.Destination.String(), route.Gateway.String())
+		}
+		args = append(args, "--dhcp-option="+matchTag+"option:classless-static-route,"+strings.Join(routes, ","))
+	}
+
+	return args
+}
+
+// describeExit returns a human readable description of the way a process exited
+func describeExit(err error) string {
+	var exitErr kexec.ExitError
+	switch {
+	case err == nil:
+		return "exit status 0"
+	case errors.As(err, &exitErr) && exitErr.Exited():
+		return fmt.Sprintf("exit status %d", exitErr.ExitStatus())
+	default:
+		return err.Error()
+	}
+}
+
+// exitReason returns the value of the reason label of the metrics for the way a process exited
+func exitReason(err error) string {
+	var exitErr kexec.ExitError
+	switch {
+	case err == nil:
+		return "exit_code_0"
+	case errors.As(err, &exitErr) && exitErr.Exited():
+		return fmt.Sprintf("exit_code_%d", exitErr.ExitStatus())
+	case errors.As(err, &exitErr):
+		return "signaled"
+	default:
+		return "error"
+	}
+}
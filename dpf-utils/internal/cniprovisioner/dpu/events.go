@@ -0,0 +1,143 @@
+/*
+Copyright 2024 NVIDIA.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dpucniprovisioner
+
+import (
+	"context"
+	"time"
+
+	provisioningv1 "github.com/nvidia/doca-platform/api/provisioning/v1alpha1"
+	"github.com/nvidia/ovn-kubernetes-components/internal/constants"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// reconcileDebounceDuration is how long the provisioner waits after an event before reconciling so that a burst of
+	// events (e.g. netplan apply flushing and re-adding addresses) results in a single reconcile.
+	reconcileDebounceDuration = time.Second
+	// eventSourceRetryInterval is how long the provisioner waits before restarting an event source that failed.
+	eventSourceRetryInterval = 5 * time.Second
+)
+
+// EventSource notifies the provisioner when state that it owns may have drifted
+type EventSource interface {
+	// Run watches for changes and calls notify for each of them until the context is cancelled. It returns an error if
+	// it can't keep watching.
+	Run(ctx context.Context, notify func(reason string)) error
+}
+
+// AddEventSource registers an EventSource that triggers a reconcile on every event. Call before EnsureConfiguration.
+func (p *DPUCNIProvisioner) AddEventSource(source EventSource) {
+	p.eventSources = append(p.eventSources, source)
+}
+
+// requestReconcile requests a reconcile. Requests that arrive while another one is pending are merged.
+func (p *DPUCNIProvisioner) requestReconcile(reason string) {
+	select {
+	case p.reconcileRequests <- reason:
+	default:
+	}
+}
+
+// runEventSource runs the given EventSource until the provisioner is stopped, restarting it if it fails. The source
+// gets the logger of the provisioner through its context.
+func (p *DPUCNIProvisioner) runEventSource(source EventSource) {
+	ctx := klog.NewContext(p.ctx, p.baseLogger)
+	for {
+		err := source.Run(ctx, p.requestReconcile)
+		if p.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			p.baseLogger.Error(err, "event source failed, restarting", "backoff", eventSourceRetryInterval.String())
+		}
+
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-p.clock.After(eventSourceRetryInterval):
+		}
+		// Events may have been missed while the source was not running
+		p.requestReconcile("event source restarted")
+	}
+}
+
+// nodeEventSource is an EventSource that watches the DPU Node for changes to the labels the provisioner consumes
+type nodeEventSource struct {
+	client   kubernetes.Interface
+	nodeName string
+}
+
+// NewNodeEventSource returns an EventSource that fires when the labels that map the DPU Node to its host change
+func NewNodeEventSource(client kubernetes.Interface, nodeName string) EventSource {
+	return &nodeEventSource{
+		client:   client,
+		nodeName: nodeName,
+	}
+}
+
+// Run watches the DPU Node until the context is cancelled
+func (s *nodeEventSource) Run(ctx context.Context, notify func(reason string)) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(s.client, 0, informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+		o.FieldSelector = fields.OneTermEqualSelector("metadata.name", s.nodeName).String()
+	}))
+	defer factory.Shutdown()
+
+	informer := factory.Core().V1().Nodes().Informer()
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldNode, ok := oldObj.(*corev1.Node)
+			if !ok {
+				return
+			}
+			newNode, ok := newObj.(*corev1.Node)
+			if !ok {
+				return
+			}
+			if hostNameLabelsChanged(oldNode, newNode) {
+				notify("DPU Node host name labels changed")
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	factory.Start(ctx.Done())
+	<-ctx.Done()
+	return nil
+}
+
+// hostNameLabelsChanged returns whether the labels used by findAndSetKubernetesHostNameInOVS differ between the two
+// versions of the Node
+func hostNameLabelsChanged(oldNode *corev1.Node, newNode *corev1.Node) bool {
+	for _, key := range []string{provisioningv1.DPUNodeNameLabel, constants.HostNameDPULabelKey} {
+		oldValue, oldOK := oldNode.Labels[key]
+		newValue, newOK := newNode.Labels[key]
+		if oldOK != newOK || oldValue != newValue {
+			return true
+		}
+	}
+	return false
+}
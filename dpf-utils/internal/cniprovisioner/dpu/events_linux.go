@@ -0,0 +1,170 @@
+/*
+Copyright 2024 NVIDIA.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dpucniprovisioner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// netlinkEventSource is an EventSource that watches the routes, addresses, rules and link states the provisioner
+// manages
+type netlinkEventSource struct {
+	// links returns the links whose routes and addresses the provisioner manages
+	links func() []string
+	// tables are the route tables the provisioner manages
+	tables map[int]struct{}
+	// operStates are the last seen operational states of the links by index
+	operStates map[int]netlink.LinkOperState
+}
+
+// NewNetlinkEventSource returns an EventSource that fires when a route, address or rule the provisioner manages changes
+// and when a link it manages goes up or down, e.g. an uplink port losing its carrier. The links are looked up on every
+// event since the provisioner may select different ones over time.
+func NewNetlinkEventSource(links func() []string) EventSource {
+	return &netlinkEventSource{
+		links:      links,
+		tables:     map[int]struct{}{sourceRoutingTable: {}},
+		operStates: map[int]netlink.LinkOperState{},
+	}
+}
+
+// Run subscribes to netlink updates until the context is cancelled
+func (s *netlinkEventSource) Run(ctx context.Context, notify func(reason string)) error {
+	done := make(chan struct{})
+	defer close(done)
+
+	errCh := make(chan error, 1)
+	onError := func(err error) {
+		select {
+		case errCh <- err:
+		default:
+		}
+	}
+
+	routeCh := make(chan netlink.RouteUpdate, 64)
+	if err := netlink.RouteSubscribeWithOptions(routeCh, done, netlink.RouteSubscribeOptions{ErrorCallback: onError}); err != nil {
+		return fmt.Errorf("error while subscribing to route updates: %w", err)
+	}
+	addrCh := make(chan netlink.AddrUpdate, 64)
+	if err := netlink.AddrSubscribeWithOptions(addrCh, done, netlink.AddrSubscribeOptions{ErrorCallback: onError}); err != nil {
+		return fmt.Errorf("error while subscribing to address updates: %w", err)
+	}
+	linkCh := make(chan netlink.LinkUpdate, 64)
+	if err := netlink.LinkSubscribeWithOptions(linkCh, done, netlink.LinkSubscribeOptions{ErrorCallback: onError}); err != nil {
+		return fmt.Errorf("error while subscribing to link updates: %w", err)
+	}
+	// The netlink library has no helper for rules, so we listen on the rule multicast groups directly. The route and
+	// address subscriptions above already cover both IP families.
+	ruleSocket, err := nl.Subscribe(unix.NETLINK_ROUTE, unix.RTNLGRP_IPV4_RULE, unix.RTNLGRP_IPV6_RULE)
+	if err != nil {
+		return fmt.Errorf("error while subscribing to rule updates: %w", err)
+	}
+	defer ruleSocket.Close()
+	ruleCh := make(chan struct{}, 1)
+	go func() {
+		for {
+			msgs, _, err := ruleSocket.Receive()
+			if err != nil {
+				onError(err)
+				return
+			}
+			for _, msg := range msgs {
+				if msg.Header.Type != unix.RTM_NEWRULE && msg.Header.Type != unix.RTM_DELRULE {
+					continue
+				}
+				select {
+				case ruleCh <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errCh:
+			return fmt.Errorf("netlink subscription failed: %w", err)
+		case update, ok := <-routeCh:
+			if !ok {
+				return errors.New("route subscription closed")
+			}
+			if s.isManagedRoute(update.Route) {
+				notify(fmt.Sprintf("route %s changed", update.Route.String()))
+			}
+		case update, ok := <-addrCh:
+			if !ok {
+				return errors.New("address subscription closed")
+			}
+			if s.isManagedLink(update.LinkIndex) {
+				notify(fmt.Sprintf("address %s changed", update.LinkAddress.String()))
+			}
+		case update, ok := <-linkCh:
+			if !ok {
+				return errors.New("link subscription closed")
+			}
+			if s.operStateChanged(update) {
+				notify(fmt.Sprintf("link %s is %s", update.Attrs().Name, update.Attrs().OperState))
+			}
+		case <-ruleCh:
+			notify("rules changed")
+		}
+	}
+}
+
+// isManagedRoute returns whether the route is in a table or on a link that the provisioner manages
+func (s *netlinkEventSource) isManagedRoute(route netlink.Route) bool {
+	if _, ok := s.tables[route.Table]; ok {
+		return true
+	}
+	return s.isManagedLink(route.LinkIndex)
+}
+
+// isManagedLink returns whether the link with the given index is one the provisioner manages. Links are looked up on
+// every call since they may be recreated with a different index.
+func (s *netlinkEventSource) isManagedLink(index int) bool {
+	for _, name := range s.links() {
+		link, err := netlink.LinkByName(name)
+		if err != nil {
+			continue
+		}
+		if link.Attrs().Index == index {
+			return true
+		}
+	}
+	return false
+}
+
+// operStateChanged returns whether the update changes the operational state of a link the provisioner manages. The
+// state of a link is unknown until its first update, which is hence considered a change.
+func (s *netlinkEventSource) operStateChanged(update netlink.LinkUpdate) bool {
+	attrs := update.Attrs()
+	if update.Header.Type == unix.RTM_DELLINK {
+		delete(s.operStates, attrs.Index)
+		return false
+	}
+	previous, seen := s.operStates[attrs.Index]
+	s.operStates[attrs.Index] = attrs.OperState
+	return (!seen || previous != attrs.OperState) && s.isManagedLink(attrs.Index)
+}
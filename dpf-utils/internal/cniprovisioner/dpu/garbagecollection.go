@@ -0,0 +1,95 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dpucniprovisioner
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/logging"
+
+	"golang.org/x/sys/unix"
+	"k8s.io/utils/ptr"
+)
+
+// routeKey returns a string that uniquely identifies a route. A nil table is the main table.
+func routeKey(network *net.IPNet, gateway net.IP, device string, table *int) string {
+	return fmt.Sprintf("%s via %s dev %s table %d", network, gateway, device, ptr.Deref(table, unix.RT_TABLE_MAIN))
+}
+
+// ruleKey returns a string that uniquely identifies a rule
+func ruleKey(src *net.IPNet, table int, priority int) string {
+	return fmt.Sprintf("from %s lookup %d priority %d", src, table, priority)
+}
+
+// desireRoute records that the given route is part of the configuration computed in the ongoing run of the
+// provisioning flow
+func (p *DPUCNIProvisioner) desireRoute(network *net.IPNet, gateway net.IP, device string, table *int) {
+	p.desiredRoutes[routeKey(network, gateway, device, table)] = true
+}
+
+// desireRule records that the given rule is part of the configuration computed in the ongoing run of the provisioning
+// flow
+func (p *DPUCNIProvisioner) desireRule(src *net.IPNet, table int, priority int) {
+	p.desiredRules[ruleKey(src, table, priority)] = true
+}
+
+// deleteStaleRoutesAndRules removes the routes and rules that the provisioner added in the past but that are no longer
+// part of the configuration, e.g. because a VTEP CIDR, the gateway or the flannel subnet changed. Only the entries
+// tagged with the protocol of the provisioner are considered so that the ones added by anyone else are left untouched.
+// The untagged entries that a previous version added are tagged, i.e. adopted, as soon as the flow desires them. It
+// must run only after every step of the provisioning flow succeeded, otherwise the desired state is incomplete.
+func (p *DPUCNIProvisioner) deleteStaleRoutesAndRules() error {
+	var errs []error
+
+	routes, err := p.networkHelper.ListOwnedRoutes()
+	if err != nil {
+		return fmt.Errorf("error while listing owned routes: %w", err)
+	}
+	for _, r := range routes {
+		key := routeKey(r.Network, r.Gateway, r.Device, &r.Table)
+		if p.desiredRoutes[key] {
+			continue
+		}
+		p.logger.Info("Deleting route that is no longer part of the configuration", "route", key, logging.KeyCIDR, r.Network.String(), logging.KeyLink, r.Device)
+		if err := p.networkHelper.DeleteRouteFromTable(r.Network, r.Gateway, r.Device, &r.Table); err != nil {
+			errs = append(errs, fmt.Errorf("error while deleting route %s: %w", key, err))
+			continue
+		}
+		p.metrics.staleDeletions.WithLabelValues("route").Inc()
+	}
+
+	rules, err := p.networkHelper.ListOwnedRules()
+	if err != nil {
+		return errors.Join(append(errs, fmt.Errorf("error while listing owned rules: %w", err))...)
+	}
+	for _, r := range rules {
+		key := ruleKey(r.Src, r.Table, r.Priority)
+		if p.desiredRules[key] {
+			continue
+		}
+		p.logger.Info("Deleting rule that is no longer part of the configuration", "rule", key, logging.KeyCIDR, r.Src.String())
+		if err := p.networkHelper.DeleteRule(r.Src, r.Table, r.Priority); err != nil {
+			errs = append(errs, fmt.Errorf("error while deleting rule %s: %w", key, err))
+			continue
+		}
+		p.metrics.staleDeletions.WithLabelValues("rule").Inc()
+	}
+
+	return errors.Join(errs...)
+}
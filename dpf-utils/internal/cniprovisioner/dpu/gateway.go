@@ -0,0 +1,241 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dpucniprovisioner
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/networkhelper"
+)
+
+// GatewayDiscoveryStrategy is how the gateway of br-ovn is discovered in ExternalIPAM mode
+type GatewayDiscoveryStrategy string
+
+const (
+	// RouteGatewayDiscovery uses the gateway of the route to the gateway discovery network of the IP family
+	RouteGatewayDiscovery GatewayDiscoveryStrategy = "route"
+	// DHCPLeaseGatewayDiscovery uses the router option of the DHCP lease br-ovn got. It's only supported for IPv4
+	// because DHCPv6 has no router option.
+	DHCPLeaseGatewayDiscovery GatewayDiscoveryStrategy = "dhcp-lease"
+	// StaticGatewayDiscovery uses the gateway given for the IP family
+	StaticGatewayDiscovery GatewayDiscoveryStrategy = "static"
+	// ProbeGatewayDiscovery uses the first of the gateways given for the IP family that answers an ARP or ND probe
+	// sent from br-ovn
+	ProbeGatewayDiscovery GatewayDiscoveryStrategy = "probe"
+)
+
+const (
+	// networkdLeasesDir is the directory in which systemd-networkd keeps the DHCP leases, one file per interface index
+	networkdLeasesDir = "/run/systemd/netif/leases"
+	// gatewayProbeTimeout is how long a gateway candidate has to answer the probe
+	gatewayProbeTimeout = time.Second
+)
+
+// GatewayDiscovery is how the gateway of br-ovn is discovered in ExternalIPAM mode
+type GatewayDiscovery struct {
+	// Strategy is the strategy that is used. Defaults to RouteGatewayDiscovery.
+	Strategy GatewayDiscoveryStrategy
+	// Gateways are the gateways of the StaticGatewayDiscovery strategy, exactly one per IP family, or the candidates of
+	// the ProbeGatewayDiscovery strategy, at least one per IP family
+	Gateways []net.IP
+}
+
+// gatewayDiscoverer discovers the gateway of br-ovn for an IP family
+type gatewayDiscoverer interface {
+	discoverGateway(c *ipFamilyConfig) (net.IP, error)
+}
+
+// validateGatewayDiscovery validates the given gateway discovery against the IP families of the provisioner
+func validateGatewayDiscovery(d GatewayDiscovery, ipFamilies []*ipFamilyConfig) error {
+	gateways := map[networkhelper.Family]int{}
+	for _, gateway := range d.Gateways {
+		family := networkhelper.FamilyOf(gateway)
+		if !slices.ContainsFunc(ipFamilies, func(c *ipFamilyConfig) bool { return c.family == family }) {
+			return fmt.Errorf("gateway %s doesn't belong to any of the IP families", gateway)
+		}
+		gateways[family]++
+	}
+
+	switch d.Strategy {
+	case "", RouteGatewayDiscovery, DHCPLeaseGatewayDiscovery:
+		if len(d.Gateways) > 0 {
+			return fmt.Errorf("gateways can't be given to the %s gateway discovery strategy", d.strategy())
+		}
+		if d.Strategy != DHCPLeaseGatewayDiscovery {
+			break
+		}
+		for _, c := range ipFamilies {
+			if c.family != networkhelper.IPv4 {
+				return fmt.Errorf("the %s gateway discovery strategy only supports %s", d.Strategy, networkhelper.IPv4)
+			}
+		}
+	case StaticGatewayDiscovery:
+		for _, c := range ipFamilies {
+			if gateways[c.family] != 1 {
+				return fmt.Errorf("exactly 1 %s gateway is expected by the %s gateway discovery strategy, got %d", c.family, d.Strategy, gateways[c.family])
+			}
+		}
+	case ProbeGatewayDiscovery:
+		for _, c := range ipFamilies {
+			if gateways[c.family] == 0 {
+				return fmt.Errorf("at least 1 %s gateway is expected by the %s gateway discovery strategy", c.family, d.Strategy)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown gateway discovery strategy %q", d.Strategy)
+	}
+	return nil
+}
+
+// strategy returns the strategy that is used, which is RouteGatewayDiscovery when none is set
+func (d GatewayDiscovery) strategy() GatewayDiscoveryStrategy {
+	if d.Strategy == "" {
+		return RouteGatewayDiscovery
+	}
+	return d.Strategy
+}
+
+// gatewaysOf returns the gateways of the given family in the order they were given
+func (d GatewayDiscovery) gatewaysOf(family networkhelper.Family) []net.IP {
+	var gateways []net.IP
+	for _, gateway := range d.Gateways {
+		if networkhelper.FamilyOf(gateway) == family {
+			gateways = append(gateways, gateway)
+		}
+	}
+	return gateways
+}
+
+// SetGatewayDiscovery sets how the gateway of br-ovn is discovered in ExternalIPAM mode. Call after AddIPFamily and
+// before RunOnce or EnsureConfiguration.
+func (p *DPUCNIProvisioner) SetGatewayDiscovery(d GatewayDiscovery) error {
+	if err := validateGatewayDiscovery(d, p.ipFamilies); err != nil {
+		return fmt.Errorf("error while setting gateway discovery: %w", err)
+	}
+	p.gatewayDiscovery = d
+	return nil
+}
+
+// gatewayDiscoverer returns the discoverer of the configured gateway discovery strategy
+func (p *DPUCNIProvisioner) gatewayDiscoverer() gatewayDiscoverer {
+	switch p.gatewayDiscovery.strategy() {
+	case DHCPLeaseGatewayDiscovery:
+		return &dhcpLeaseGatewayDiscoverer{networkHelper: p.networkHelper, leasesDir: filepath.Join(p.FileSystemRoot, networkdLeasesDir)}
+	case StaticGatewayDiscovery:
+		return &staticGatewayDiscoverer{gateways: p.gatewayDiscovery}
+	case ProbeGatewayDiscovery:
+		return &probeGatewayDiscoverer{networkHelper: p.networkHelper, candidates: p.gatewayDiscovery}
+	default:
+		return &routeGatewayDiscoverer{networkHelper: p.networkHelper}
+	}
+}
+
+// routeGatewayDiscoverer discovers the gateway from the route to the gateway discovery network
+type routeGatewayDiscoverer struct {
+	networkHelper networkhelper.NetworkHelper
+}
+
+func (d *routeGatewayDiscoverer) discoverGateway(c *ipFamilyConfig) (net.IP, error) {
+	if c.gatewayDiscoveryNetwork == nil {
+		return nil, fmt.Errorf("no %s gateway discovery network is given", c.family)
+	}
+	gateway, err := d.networkHelper.GetGateway(c.gatewayDiscoveryNetwork)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing gateway from gateway discovery network %s: %w", c.gatewayDiscoveryNetwork.String(), err)
+	}
+	return gateway, nil
+}
+
+// dhcpLeaseGatewayDiscoverer discovers the gateway from the router option of the DHCP lease systemd-networkd keeps for
+// br-ovn
+type dhcpLeaseGatewayDiscoverer struct {
+	networkHelper networkhelper.NetworkHelper
+	leasesDir     string
+}
+
+func (d *dhcpLeaseGatewayDiscoverer) discoverGateway(c *ipFamilyConfig) (net.IP, error) {
+	if c.family != networkhelper.IPv4 {
+		return nil, fmt.Errorf("the DHCP lease has no %s router", c.family)
+	}
+	index, err := d.networkHelper.GetLinkIndex(brOVN)
+	if err != nil {
+		return nil, fmt.Errorf("error while getting the index of link %s: %w", brOVN, err)
+	}
+	path := filepath.Join(d.leasesDir, strconv.Itoa(index))
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading DHCP lease %s: %w", path, err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		routers, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "ROUTER=")
+		if !ok {
+			continue
+		}
+		for _, router := range strings.Fields(routers) {
+			if ip := net.ParseIP(router); ip != nil && networkhelper.FamilyOf(ip) == networkhelper.IPv4 {
+				return ip, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("DHCP lease %s has no router", path)
+}
+
+// staticGatewayDiscoverer returns the gateway that is given for the IP family
+type staticGatewayDiscoverer struct {
+	gateways GatewayDiscovery
+}
+
+func (d *staticGatewayDiscoverer) discoverGateway(c *ipFamilyConfig) (net.IP, error) {
+	gateways := d.gateways.gatewaysOf(c.family)
+	if len(gateways) == 0 {
+		return nil, fmt.Errorf("no static %s gateway is given", c.family)
+	}
+	return gateways[0], nil
+}
+
+// probeGatewayDiscoverer discovers the gateway by probing the candidates given for the IP family in order and picking
+// the first one that answers
+type probeGatewayDiscoverer struct {
+	networkHelper networkhelper.NetworkHelper
+	candidates    GatewayDiscovery
+}
+
+func (d *probeGatewayDiscoverer) discoverGateway(c *ipFamilyConfig) (net.IP, error) {
+	var errs []error
+	for _, candidate := range d.candidates.gatewaysOf(c.family) {
+		answered, err := d.networkHelper.ProbeNeighbor(brOVN, candidate, gatewayProbeTimeout)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error while probing %s: %w", candidate, err))
+			continue
+		}
+		if answered {
+			return candidate, nil
+		}
+	}
+	return nil, errors.Join(append(errs, fmt.Errorf("none of the %s gateway candidates answered", c.family))...)
+}
@@ -0,0 +1,267 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dpucniprovisioner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/logging"
+)
+
+const (
+	// defaultFlannelInterface is the bridge the flannel CNI delegates to when its configuration doesn't name one
+	defaultFlannelInterface = "cni0"
+	// defaultOOBInterface is the bridge name used for the out of band interface by the provisioning controller
+	defaultOOBInterface = "br-comm-ch"
+	// defaultPFIndex is the index of the PF that connects the host to the DPU in most deployments
+	defaultPFIndex = "0"
+	// dpuCNIConfDir is the directory in which the CNI of the DPU cluster keeps its configuration
+	dpuCNIConfDir = "/etc/cni/net.d"
+)
+
+// pfIndexes are the indexes of the PFs a DPU exposes to the host
+var pfIndexes = []string{"0", "1"}
+
+// Interfaces are the interfaces of the DPU the provisioner works with
+type Interfaces struct {
+	// OOB is the out of band bridge of the DPU
+	OOB string `json:"oob,omitempty"`
+	// Flannel is the bridge of the DPU cluster CNI that holds the address of the local pod subnet
+	Flannel string `json:"flannel,omitempty"`
+	// PFIndex is the index of the PF ("0" or "1") the host uses to reach the DPU
+	PFIndex string `json:"pfIndex,omitempty"`
+}
+
+// interfaceSource is how an interface got selected
+type interfaceSource string
+
+const (
+	interfaceSourceOverride   interfaceSource = "override"
+	interfaceSourceDiscovered interfaceSource = "discovered"
+	interfaceSourceDefault    interfaceSource = "default"
+)
+
+// selectedInterfaces are the interfaces the ongoing run of the provisioning flow works with and how each one was
+// selected
+type selectedInterfaces struct {
+	Interfaces
+	oobSource     interfaceSource
+	flannelSource interfaceSource
+	pfIndexSource interfaceSource
+}
+
+// validateInterfaces validates the non empty fields of the given interfaces
+func validateInterfaces(i Interfaces) error {
+	if i.PFIndex != "" && !slices.Contains(pfIndexes, i.PFIndex) {
+		return fmt.Errorf("invalid PF index %q: one of %s is expected", i.PFIndex, strings.Join(pfIndexes, ", "))
+	}
+	return nil
+}
+
+// SetInterfaceOverrides sets the interfaces that are used instead of the discovered ones. Empty fields are discovered.
+// Call before RunOnce or EnsureConfiguration.
+func (p *DPUCNIProvisioner) SetInterfaceOverrides(overrides Interfaces) error {
+	if err := validateInterfaces(overrides); err != nil {
+		return err
+	}
+	p.interfaceOverrides = overrides
+	return nil
+}
+
+// ManagedLinks returns the links whose routes and addresses the provisioner manages. It's safe to call while
+// EnsureConfiguration is running.
+func (p *DPUCNIProvisioner) ManagedLinks() []string {
+	p.interfacesLock.Lock()
+	defer p.interfacesLock.Unlock()
+	links := []string{brOVN}
+	if p.interfaces.OOB != "" {
+		links = append(links, p.interfaces.OOB)
+	}
+	if p.interfaces.Flannel != "" {
+		links = append(links, p.interfaces.Flannel)
+	}
+	return append(links, p.uplinkLinks()...)
+}
+
+// selectInterfaces selects the interfaces the provisioning flow works with. Every interface that is not overridden is
+// discovered and falls back to the historical default when discovery fails, so that a DPU that is set up like before
+// keeps working. The selection is reported whenever it changes.
+func (p *DPUCNIProvisioner) selectInterfaces() error {
+	s := selectedInterfaces{
+		Interfaces:    p.interfaceOverrides,
+		oobSource:     interfaceSourceOverride,
+		flannelSource: interfaceSourceOverride,
+		pfIndexSource: interfaceSourceOverride,
+	}
+
+	if s.OOB == "" {
+		oob, err := p.discoverOOBInterface()
+		if err != nil {
+			p.logger.V(2).Info("Falling back to the default OOB interface", logging.KeyLink, defaultOOBInterface, "reason", err.Error())
+			s.OOB, s.oobSource = defaultOOBInterface, interfaceSourceDefault
+		} else {
+			s.OOB, s.oobSource = oob, interfaceSourceDiscovered
+		}
+	}
+
+	if s.Flannel == "" {
+		flannel, err := p.discoverFlannelInterface()
+		if err != nil {
+			p.logger.V(2).Info("Falling back to the default flannel interface", logging.KeyLink, defaultFlannelInterface, "reason", err.Error())
+			s.Flannel, s.flannelSource = defaultFlannelInterface, interfaceSourceDefault
+		} else {
+			s.Flannel, s.flannelSource = flannel, interfaceSourceDiscovered
+		}
+	}
+
+	// The PF is only needed for serving its address, which only happens in Internal mode
+	if s.PFIndex == "" && p.mode == InternalIPAM {
+		pfIndex, err := p.discoverPFIndex()
+		if err != nil {
+			p.logger.V(2).Info("Falling back to the default PF", "pfIndex", defaultPFIndex, "reason", err.Error())
+			s.PFIndex, s.pfIndexSource = defaultPFIndex, interfaceSourceDefault
+		} else {
+			s.PFIndex, s.pfIndexSource = pfIndex, interfaceSourceDiscovered
+		}
+	}
+
+	p.interfacesLock.Lock()
+	changed := p.interfaces != s
+	p.interfaces = s
+	p.interfacesLock.Unlock()
+	if changed {
+		p.logger.Info("Selected interfaces", "oob", s.OOB, "oobSource", s.oobSource, "flannel", s.Flannel, "flannelSource", s.flannelSource, "pfIndex", s.PFIndex, "pfIndexSource", s.pfIndexSource)
+		p.metrics.observeSelectedInterfaces(s)
+	}
+	return nil
+}
+
+// discoverOOBInterface returns the device of the preferred default route of the primary IP family. br-ovn is the VTEP
+// and never the OOB interface, hence it's skipped in case a DHCP server gave it a default route.
+func (p *DPUCNIProvisioner) discoverOOBInterface() (string, error) {
+	family := p.ipFamilies[0].family
+	devices, err := p.networkHelper.GetDefaultRouteDevices(family)
+	if err != nil {
+		return "", fmt.Errorf("error while getting the devices of the %s default routes: %w", family, err)
+	}
+	for _, device := range devices {
+		if device != brOVN {
+			return device, nil
+		}
+	}
+	return "", fmt.Errorf("no %s default route found", family)
+}
+
+// cniNetworkConfig is the subset of a CNI network configuration or configuration list that identifies the bridge the
+// DPU cluster CNI attaches the pods to
+type cniNetworkConfig struct {
+	cniPluginConfig
+	Plugins []cniPluginConfig `json:"plugins"`
+}
+
+// cniPluginConfig is the subset of a CNI plugin configuration that identifies the bridge the pods are attached to
+type cniPluginConfig struct {
+	Type     string `json:"type"`
+	Bridge   string `json:"bridge"`
+	Delegate struct {
+		Bridge string `json:"bridge"`
+	} `json:"delegate"`
+}
+
+// bridge returns the bridge of the plugin, if it attaches pods to one
+func (c cniPluginConfig) bridge() (string, bool) {
+	switch c.Type {
+	case "flannel":
+		if c.Delegate.Bridge != "" {
+			return c.Delegate.Bridge, true
+		}
+		return defaultFlannelInterface, true
+	case "bridge":
+		if c.Bridge != "" {
+			return c.Bridge, true
+		}
+		return defaultFlannelInterface, true
+	}
+	return "", false
+}
+
+// discoverFlannelInterface returns the bridge of the DPU cluster CNI. Like the kubelet, it considers the network
+// configurations in lexicographical order, and the first one that attaches pods to a bridge wins.
+func (p *DPUCNIProvisioner) discoverFlannelInterface() (string, error) {
+	dir := p.CNIConfDir
+	if dir == "" {
+		dir = dpuCNIConfDir
+	}
+	dir = filepath.Join(p.FileSystemRoot, dir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("error while reading CNI configuration directory %s: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !slices.Contains([]string{".conf", ".conflist", ".json"}, filepath.Ext(entry.Name())) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("error while reading CNI configuration %s: %w", path, err)
+		}
+		var config cniNetworkConfig
+		if err := json.Unmarshal(content, &config); err != nil {
+			p.logger.V(2).Info("Skipping CNI configuration that can't be parsed", "path", path, "reason", err.Error())
+			continue
+		}
+		for _, plugin := range append([]cniPluginConfig{config.cniPluginConfig}, config.Plugins...) {
+			if bridge, ok := plugin.bridge(); ok {
+				return bridge, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no CNI configuration in %s attaches pods to a bridge", dir)
+}
+
+// discoverPFIndex returns the index of the first PF whose representor is attached to an OVS bridge, i.e. the PF the
+// host traffic flows through. The PFs of the additional uplinks are skipped.
+func (p *DPUCNIProvisioner) discoverPFIndex() (string, error) {
+	var errs []error
+	for _, pfIndex := range pfIndexes {
+		if slices.Contains(p.additionalUplinkPFIndexes(), pfIndex) {
+			continue
+		}
+		representor, err := p.networkHelper.GetPFRepresentorDPU(pfIndex)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if representor == "" {
+			continue
+		}
+		bridge, err := p.ovsClient.InterfaceToBridge(representor)
+		if err != nil || bridge == "" {
+			continue
+		}
+		return pfIndex, nil
+	}
+	return "", errors.Join(append(errs, errors.New("no PF representor is attached to an OVS bridge"))...)
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2026 NVIDIA.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dpucniprovisioner
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/networkhelper"
+)
+
+// ipFamilyConfig is the addressing the provisioner manages for a single IP family. Single-stack deployments have one,
+// dual-stack deployments have one per IP family.
+type ipFamilyConfig struct {
+	// family is the IP family all the fields belong to
+	family networkhelper.Family
+	// vtepIPNet is the IP that should be added to the VTEP interface.
+	vtepIPNet *net.IPNet
+	// gateway is the gateway IP that is configured on the routes related to OVN Kubernetes reaching its peer nodes
+	// when traffic needs to go from one Pod running on Node A to another Pod running on Node B.
+	gateway net.IP
+	// vtepCIDRs are the CIDRs in which all the VTEP IPs of all the DPUs in the DPU cluster belong to, e.g. one per rack
+	// in a leaf-spine fabric. These CIDRs are configured on the routes related to traffic that needs to go from one Pod
+	// running on worker Node A to another Pod running on worker Node B.
+	vtepCIDRs []*net.IPNet
+	// hostCIDRs are the CIDRs of the host machines that are configured on the routes related to OVN Kubernetes reaching
+	// its peer nodes when traffic needs to go from one Pod running on worker Node A to another Pod running on control
+	// plane A (and vice versa).
+	hostCIDRs []*net.IPNet
+	// pfIP is the IP that should be added to the PF on the host
+	pfIP *net.IPNet
+	// gatewayDiscoveryNetwork is the network from which the DPUCNIProvisioner discovers the gateway that it should be
+	// on relevant underlying systems.
+	gatewayDiscoveryNetwork *net.IPNet
+	// gatewaySource is the gateway discovery strategy the gateway was discovered with. Empty when the gateway is an
+	// input.
+	gatewaySource GatewayDiscoveryStrategy
+}
+
+// newIPFamilyConfig creates the ipFamilyConfig of the given inputs. The family is derived from the first input that is
+// set and defaults to IPv4.
+func newIPFamilyConfig(vtepIPNet *net.IPNet, gateway net.IP, vtepCIDRs []*net.IPNet, hostCIDRs []*net.IPNet, pfIP *net.IPNet, gatewayDiscoveryNetwork *net.IPNet) *ipFamilyConfig {
+	c := &ipFamilyConfig{
+		family:                  networkhelper.IPv4,
+		vtepIPNet:               vtepIPNet,
+		gateway:                 gateway,
+		vtepCIDRs:               vtepCIDRs,
+		hostCIDRs:               hostCIDRs,
+		pfIP:                    pfIP,
+		gatewayDiscoveryNetwork: gatewayDiscoveryNetwork,
+	}
+	if ips := c.ips(); len(ips) > 0 {
+		c.family = networkhelper.FamilyOf(ips[0])
+	}
+	return c
+}
+
+// ips returns the IPs of all the inputs that are set
+func (c *ipFamilyConfig) ips() []net.IP {
+	var ips []net.IP
+	ipNets := append(append([]*net.IPNet{}, c.vtepCIDRs...), c.hostCIDRs...)
+	for _, ipNet := range append(ipNets, c.vtepIPNet, c.pfIP, c.gatewayDiscoveryNetwork) {
+		if ipNet != nil {
+			ips = append(ips, ipNet.IP)
+		}
+	}
+	if c.gateway != nil {
+		ips = append(ips, c.gateway)
+	}
+	return ips
+}
+
+// validate returns an error if any of the inputs doesn't belong to the family
+func (c *ipFamilyConfig) validate() error {
+	ips := c.ips()
+	if len(ips) == 0 {
+		return errors.New("no input is set")
+	}
+	for _, ip := range ips {
+		if networkhelper.FamilyOf(ip) != c.family {
+			return fmt.Errorf("%s is not an %s address", ip, c.family)
+		}
+	}
+	return nil
+}
+
+// vtepNetwork returns the network of the VTEP IP
+func (c *ipFamilyConfig) vtepNetwork() (*net.IPNet, error) {
+	_, vtepNetwork, err := net.ParseCIDR(c.vtepIPNet.String())
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing network from VTEP IP %s: %w", c.vtepIPNet.String(), err)
+	}
+	return vtepNetwork, nil
+}
+
+// remoteVTEPCIDRs returns the VTEP CIDRs that are reachable via the gateway, i.e. all of them except the one that is
+// the network of the VTEP IP and hence on-link.
+func (c *ipFamilyConfig) remoteVTEPCIDRs() ([]*net.IPNet, error) {
+	vtepNetwork, err := c.vtepNetwork()
+	if err != nil {
+		return nil, err
+	}
+	remote := make([]*net.IPNet, 0, len(c.vtepCIDRs))
+	for _, vtepCIDR := range c.vtepCIDRs {
+		if vtepCIDR.String() != vtepNetwork.String() {
+			remote = append(remote, vtepCIDR)
+		}
+	}
+	return remote, nil
+}
+
+// AddIPFamily configures the provisioner for a second IP family on top of the one given to New, i.e. makes it
+// dual-stack. The inputs follow the same rules as the ones of New and must all be of the IP family that is added. The
+// first IP family stays the primary one, which is the one used for the geneve tunnels. Call before RunOnce.
+func (p *DPUCNIProvisioner) AddIPFamily(vtepIPNet *net.IPNet, gateway net.IP, vtepCIDRs []*net.IPNet, hostCIDRs []*net.IPNet, pfIP *net.IPNet, gatewayDiscoveryNetwork *net.IPNet) error {
+	ipFamilies, err := appendIPFamily(p.ipFamilies, newIPFamilyConfig(vtepIPNet, gateway, vtepCIDRs, hostCIDRs, pfIP, gatewayDiscoveryNetwork))
+	if err != nil {
+		return fmt.Errorf("error while adding IP family: %w", err)
+	}
+	p.ipFamilies = ipFamilies
+	return nil
+}
+
+// appendIPFamily validates the given IP family and appends it to the given ones unless its family is already there
+func appendIPFamily(ipFamilies []*ipFamilyConfig, c *ipFamilyConfig) ([]*ipFamilyConfig, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	for _, existing := range ipFamilies {
+		if existing.family == c.family {
+			return nil, fmt.Errorf("%s is already configured", c.family)
+		}
+	}
+	return append(ipFamilies, c), nil
+}
+
+// primaryIPFamily returns the IP family the geneve tunnels use
+func (p *DPUCNIProvisioner) primaryIPFamily() *ipFamilyConfig {
+	return p.ipFamilies[0]
+}
+
+// ipFamily returns the configuration of the given IP family or nil if the family is not configured
+func (p *DPUCNIProvisioner) ipFamily(family networkhelper.Family) *ipFamilyConfig {
+	for _, c := range p.ipFamilies {
+		if c.family == family {
+			return c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,431 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dpucniprovisioner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// defaultStateDir is the directory the state journal is kept in
+	defaultStateDir = "/var/lib/dpucniprovisioner"
+	// stateJournalFileName is the name of the state journal in the state directory
+	stateJournalFileName = "state.json"
+	// stateJournalVersion is the version of the format of the state journal. A journal of another version is ignored.
+	stateJournalVersion = 1
+)
+
+// AppliedState is the desired state the last successful run of the provisioning flow applied
+type AppliedState struct {
+	// Version is the version of the format of the state
+	Version int `json:"version"`
+	// Generation is increased every time the applied state changes
+	Generation int64 `json:"generation"`
+	// AppliedAt is when the generation was applied
+	AppliedAt time.Time `json:"appliedAt"`
+	// Inputs are the inputs the state was computed from
+	Inputs StateInputs `json:"inputs"`
+	// Interfaces are the interfaces the provisioning flow worked with
+	Interfaces Interfaces `json:"interfaces"`
+	// HostName is the name of the host the DPU belongs to
+	HostName string `json:"hostName,omitempty"`
+	// IPFamilies are the resolved addressing per IP family
+	IPFamilies []AppliedIPFamily `json:"ipFamilies"`
+	// OVNMTU is the resolved MTU for OVN and OVNMTUSource is where it comes from
+	OVNMTU       int       `json:"ovnMTU,omitempty"`
+	OVNMTUSource MTUSource `json:"ovnMTUSource,omitempty"`
+	// Routes are the routes the provisioner owns
+	Routes []string `json:"routes"`
+	// Rules are the rules the provisioner owns
+	Rules []string `json:"rules"`
+	// Files are the files the provisioner wrote
+	Files []AppliedFile `json:"files"`
+	// OVSOtherConfig are the other_config keys of the Open_vSwitch row the OVS profile set
+	OVSOtherConfig []string `json:"ovsOtherConfig,omitempty"`
+	// BridgeDataPathTypes are the bridges whose datapath type the OVS profile set
+	BridgeDataPathTypes []string `json:"bridgeDataPathTypes,omitempty"`
+}
+
+// StateInputs are the inputs of the provisioner a state is computed from
+type StateInputs struct {
+	Mode       Mode                `json:"mode"`
+	IPFamilies []IPFamilyInputs    `json:"ipFamilies"`
+	OVNMTU     int                 `json:"ovnMTU,omitempty"`
+	Interfaces Interfaces          `json:"interfaces"`
+	Uplinks    []UplinkStateInputs `json:"uplinks,omitempty"`
+	EncapMode  EncapMode           `json:"encapMode,omitempty"`
+	// GatewayDiscoveryStrategy and DiscoveryGateways are only inputs in External mode
+	GatewayDiscoveryStrategy GatewayDiscoveryStrategy `json:"gatewayDiscoveryStrategy,omitempty"`
+	DiscoveryGateways        []string                 `json:"discoveryGateways,omitempty"`
+}
+
+// IPFamilyInputs are the inputs of a single IP family. The VTEP IP and the gateway are only inputs in Internal mode,
+// they're discovered in External mode.
+type IPFamilyInputs struct {
+	VTEPIP                  string   `json:"vtepIP,omitempty"`
+	Gateway                 string   `json:"gateway,omitempty"`
+	VTEPCIDRs               []string `json:"vtepCIDRs"`
+	HostCIDRs               []string `json:"hostCIDRs"`
+	PFIP                    string   `json:"pfIP,omitempty"`
+	GatewayDiscoveryNetwork string   `json:"gatewayDiscoveryNetwork,omitempty"`
+}
+
+// UplinkStateInputs are the inputs of an additional uplink
+type UplinkStateInputs struct {
+	Bridge   string   `json:"bridge"`
+	Port     string   `json:"port,omitempty"`
+	PFIndex  string   `json:"pfIndex"`
+	VTEPIPs  []string `json:"vtepIPs"`
+	Gateways []string `json:"gateways"`
+	PFIPs    []string `json:"pfIPs"`
+}
+
+// AppliedIPFamily is the resolved addressing of a single IP family
+type AppliedIPFamily struct {
+	Family  string `json:"family"`
+	VTEPIP  string `json:"vtepIP"`
+	Gateway string `json:"gateway"`
+	// GatewaySource is the gateway discovery strategy the gateway was discovered with. Empty when the gateway is an
+	// input.
+	GatewaySource GatewayDiscoveryStrategy `json:"gatewaySource,omitempty"`
+}
+
+// AppliedFile is a file the provisioner wrote
+type AppliedFile struct {
+	Path string `json:"path"`
+	// SHA256 is the checksum of the content that was written
+	SHA256 string `json:"sha256"`
+}
+
+// LastAppliedState returns the state the last successful run of the provisioning flow applied, including the one of a
+// previous process loaded from the journal. Returns nil when nothing was applied yet. It's safe to call while
+// EnsureConfiguration is running.
+func (p *DPUCNIProvisioner) LastAppliedState() *AppliedState {
+	p.appliedStateLock.Lock()
+	defer p.appliedStateLock.Unlock()
+	if p.appliedState == nil {
+		return nil
+	}
+	state := *p.appliedState
+	return &state
+}
+
+// stateJournalPath returns the path to the state journal. Empty when the journal is disabled.
+func (p *DPUCNIProvisioner) stateJournalPath() string {
+	if p.StateDir == "" {
+		return ""
+	}
+	return filepath.Join(p.FileSystemRoot, p.StateDir, stateJournalFileName)
+}
+
+// loadAppliedState loads the state of the journal once per process so that the first run of the provisioning flow
+// knows what a previous process applied. A journal that can't be read is ignored, in which case nothing of the
+// previous generation is cleaned up.
+func (p *DPUCNIProvisioner) loadAppliedState() {
+	if p.appliedStateLoaded {
+		return
+	}
+	p.appliedStateLoaded = true
+	path := p.stateJournalPath()
+	if path == "" {
+		return
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		p.logger.Error(err, "Ignoring state journal that can't be read", "path", path)
+		return
+	}
+	state := &AppliedState{}
+	if err := json.Unmarshal(content, state); err != nil {
+		p.logger.Error(err, "Ignoring state journal that can't be parsed", "path", path)
+		return
+	}
+	if state.Version != stateJournalVersion {
+		p.logger.Info("Ignoring state journal of another version", "path", path, "version", state.Version)
+		return
+	}
+
+	p.logger.Info("Loaded the applied state", "generation", state.Generation, "appliedAt", state.AppliedAt.Format(time.RFC3339))
+	p.appliedStateLock.Lock()
+	p.appliedState = state
+	p.appliedStateLock.Unlock()
+}
+
+// startDesiredState starts recording the desired state of the ongoing run of the provisioning flow and logs when the
+// inputs changed since the last applied state
+func (p *DPUCNIProvisioner) startDesiredState() {
+	p.loadAppliedState()
+	p.desiredState = &AppliedState{
+		Version: stateJournalVersion,
+		Inputs:  p.stateInputs(),
+	}
+	if previous := p.LastAppliedState(); previous != nil && !reflect.DeepEqual(previous.Inputs, p.desiredState.Inputs) {
+		p.logger.Info("Inputs changed since the applied state", "generation", previous.Generation)
+	}
+}
+
+// stateInputs returns the current inputs of the provisioner
+func (p *DPUCNIProvisioner) stateInputs() StateInputs {
+	inputs := StateInputs{
+		Mode:       p.mode,
+		OVNMTU:     p.ovnMTU,
+		Interfaces: p.interfaceOverrides,
+		EncapMode:  p.uplinkSettings.EncapMode,
+	}
+	for _, c := range p.ipFamilies {
+		i := IPFamilyInputs{
+			VTEPCIDRs:               ipNetStrings(c.vtepCIDRs),
+			HostCIDRs:               ipNetStrings(c.hostCIDRs),
+			PFIP:                    ipNetString(c.pfIP),
+			GatewayDiscoveryNetwork: ipNetString(c.gatewayDiscoveryNetwork),
+		}
+		if p.mode == InternalIPAM {
+			i.VTEPIP = ipNetString(c.vtepIPNet)
+			i.Gateway = ipString(c.gateway)
+		}
+		inputs.IPFamilies = append(inputs.IPFamilies, i)
+	}
+	if p.mode == ExternalIPAM {
+		inputs.GatewayDiscoveryStrategy = p.gatewayDiscovery.strategy()
+		for _, gateway := range p.gatewayDiscovery.Gateways {
+			inputs.DiscoveryGateways = append(inputs.DiscoveryGateways, ipString(gateway))
+		}
+	}
+	for _, u := range p.uplinkSettings.Additional {
+		i := UplinkStateInputs{Bridge: u.Bridge, Port: u.Port, PFIndex: u.PFIndex}
+		for _, f := range u.IPFamilies {
+			i.VTEPIPs = append(i.VTEPIPs, ipNetString(f.VTEPIPNet))
+			i.Gateways = append(i.Gateways, ipString(f.Gateway))
+			i.PFIPs = append(i.PFIPs, ipNetString(f.PFIP))
+		}
+		inputs.Uplinks = append(inputs.Uplinks, i)
+	}
+	return inputs
+}
+
+// desireFile records that the given file is part of the configuration computed in the ongoing run of the provisioning
+// flow
+func (p *DPUCNIProvisioner) desireFile(path string, content []byte) {
+	if p.desiredState == nil {
+		return
+	}
+	checksum := sha256.Sum256(content)
+	p.desiredState.Files = append(p.desiredState.Files, AppliedFile{Path: path, SHA256: hex.EncodeToString(checksum[:])})
+}
+
+// desireOVSOtherConfig records that the given other_config key of the Open_vSwitch row is set by the OVS profile in the
+// ongoing run of the provisioning flow
+func (p *DPUCNIProvisioner) desireOVSOtherConfig(key string) {
+	if p.desiredState == nil {
+		return
+	}
+	p.desiredState.OVSOtherConfig = append(p.desiredState.OVSOtherConfig, key)
+}
+
+// desireBridgeDataPathType records that the datapath type of the given bridge is set by the OVS profile in the ongoing
+// run of the provisioning flow
+func (p *DPUCNIProvisioner) desireBridgeDataPathType(bridge string) {
+	if p.desiredState == nil {
+		return
+	}
+	p.desiredState.BridgeDataPathTypes = append(p.desiredState.BridgeDataPathTypes, bridge)
+}
+
+// commitDesiredState removes the files of the previous generation that are no longer part of the configuration and
+// records the desired state of the ongoing run in the journal. The routes and rules of the previous generation are
+// already removed by the garbage collection, which doesn't need the journal as they are tagged with the protocol of
+// the provisioner. It must run only after every other step of the provisioning flow succeeded, otherwise the desired
+// state is incomplete.
+func (p *DPUCNIProvisioner) commitDesiredState(hostName string) error {
+	desired := p.desiredState
+	desired.HostName = hostName
+	desired.Interfaces = p.interfaces.Interfaces
+	for _, c := range p.ipFamilies {
+		desired.IPFamilies = append(desired.IPFamilies, AppliedIPFamily{
+			Family:        c.family.String(),
+			VTEPIP:        ipNetString(c.vtepIPNet),
+			Gateway:       ipString(c.gateway),
+			GatewaySource: c.gatewaySource,
+		})
+	}
+	desired.OVNMTU = p.resolvedMTU
+	desired.OVNMTUSource = p.resolvedMTUSource
+	desired.Routes = sortedKeys(p.desiredRoutes)
+	desired.Rules = sortedKeys(p.desiredRules)
+
+	previous := p.LastAppliedState()
+	if err := p.deletePreviousGenerationFiles(previous, desired); err != nil {
+		return err
+	}
+	if previous != nil {
+		desired.Generation = previous.Generation
+		desired.AppliedAt = previous.AppliedAt
+		if reflect.DeepEqual(previous, desired) {
+			return nil
+		}
+	}
+	desired.Generation++
+	desired.AppliedAt = p.clock.Now().UTC().Truncate(time.Second)
+
+	if p.plan != nil {
+		return nil
+	}
+	if path := p.stateJournalPath(); path != "" {
+		content, err := json.MarshalIndent(desired, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error while encoding the applied state: %w", err)
+		}
+		if err := writeFileAtomically(path, append(content, '\n'), 0600); err != nil {
+			return fmt.Errorf("error while writing state journal %s: %w", path, err)
+		}
+	}
+	p.logger.Info("Applied a new generation of the state", "generation", desired.Generation)
+	p.eventf(corev1.EventTypeNormal, EventReasonConfigurationApplied, "Applied generation %d of the state", desired.Generation)
+
+	p.appliedStateLock.Lock()
+	p.appliedState = desired
+	p.appliedStateLock.Unlock()
+	return nil
+}
+
+// deletePreviousGenerationFiles removes the files the previous generation wrote that the desired state doesn't write,
+// e.g. the netplan file of br-ovn after switching to Internal mode. A file that changed since it was written is left
+// alone as it's no longer owned by the provisioner.
+func (p *DPUCNIProvisioner) deletePreviousGenerationFiles(previous *AppliedState, desired *AppliedState) error {
+	if previous == nil {
+		return nil
+	}
+	var errs []error
+	for _, f := range previous.Files {
+		if slices.ContainsFunc(desired.Files, func(d AppliedFile) bool { return d.Path == f.Path }) {
+			continue
+		}
+		content, err := os.ReadFile(f.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error while reading %s: %w", f.Path, err))
+			continue
+		}
+		if checksum := sha256.Sum256(content); hex.EncodeToString(checksum[:]) != f.SHA256 {
+			p.logger.Info("Leaving file of the previous generation that changed since it was written", "path", f.Path, "generation", previous.Generation)
+			continue
+		}
+
+		if p.plan != nil {
+			p.plan.add(PlanChange{Kind: PlanChangeFile, Action: PlanActionDelete, Object: f.Path})
+			continue
+		}
+		p.logger.Info("Deleting file of the previous generation that is no longer part of the configuration", "path", f.Path, "generation", previous.Generation)
+		if err := os.Remove(f.Path); err != nil {
+			errs = append(errs, fmt.Errorf("error while removing %s: %w", f.Path, err))
+			continue
+		}
+		p.metrics.staleDeletions.WithLabelValues("file").Inc()
+		// br-ovn keeps requesting addresses via DHCP until netplan is applied without its configuration
+		if f.Path == filepath.Join(p.FileSystemRoot, brOVNNetplanConfigPath) {
+			if err := p.netplanApply(); err != nil {
+				errs = append(errs, fmt.Errorf("error while applying netplan without %s: %w", f.Path, err))
+				continue
+			}
+			if err := os.Remove(filepath.Join(p.FileSystemRoot, netplanApplyDonePath)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("error while removing %s: %w", netplanApplyDonePath, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writeFileAtomically writes the content to a temporary file that then replaces the file at the given path so that a
+// crash can't leave a partially written file behind
+func writeFileAtomically(path string, content []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// sortedKeys returns the keys of the given map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// ipNetStrings returns the string representation of the given networks
+func ipNetStrings(ipNets []*net.IPNet) []string {
+	out := make([]string, 0, len(ipNets))
+	for _, ipNet := range ipNets {
+		out = append(out, ipNetString(ipNet))
+	}
+	return out
+}
+
+// ipNetString returns the string representation of the given network, empty when nil
+func ipNetString(ipNet *net.IPNet) string {
+	if ipNet == nil {
+		return ""
+	}
+	return ipNet.String()
+}
+
+// ipString returns the string representation of the given IP, empty when nil
+func ipString(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
@@ -0,0 +1,261 @@
+/*
+Copyright 2024 NVIDIA.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dpucniprovisioner
+
+import (
+	"slices"
+	"time"
+
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/logging"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+)
+
+const metricsNamespace = "dpucniprovisioner"
+
+// Step is a step of the provisioning flow. It's used as a label of the per step metrics.
+type Step string
+
+const (
+	StepInterfaces         Step = "interfaces"
+	StepHostName           Step = "hostname"
+	StepBootstrapArtifacts Step = "bootstrap_artifacts"
+	StepChassisID          Step = "chassis_id"
+	StepBROVN              Step = "br_ovn"
+	StepMTU                Step = "mtu"
+	StepPodToPod           Step = "pod_to_pod"
+	StepOVSProfile         Step = "ovs_profile"
+	StepOVSConfiguration   Step = "ovs_configuration"
+	StepOVSRestart         Step = "ovs_restart"
+	StepOVNFiles           Step = "ovn_files"
+	StepSymmetricRouting   Step = "symmetric_routing"
+	StepGarbageCollection  Step = "garbage_collection"
+	StepStateJournal       Step = "state_journal"
+)
+
+// metrics holds the Prometheus collectors of the provisioner. The collectors are created per provisioner so that they
+// can be registered in any registry.
+type metrics struct {
+	reconciles         *prometheus.CounterVec
+	reconcileDuration  prometheus.Histogram
+	stepDuration       *prometheus.HistogramVec
+	stepFailures       *prometheus.CounterVec
+	driftCorrections   *prometheus.CounterVec
+	staleDeletions     *prometheus.CounterVec
+	dhcpServerRestarts prometheus.Counter
+	dhcpServerExits    *prometheus.CounterVec
+	netplanApplies     *prometheus.CounterVec
+	selectedInterfaces *prometheus.GaugeVec
+	encapUplinks       *prometheus.GaugeVec
+	vtepPeerReachable  *prometheus.GaugeVec
+	vtepPeerPathMTU    *prometheus.GaugeVec
+	ovsRestarts        *prometheus.CounterVec
+	ovsRestartPending  prometheus.Gauge
+}
+
+// newMetrics creates the metrics of the provisioner
+func newMetrics() *metrics {
+	return &metrics{
+		reconciles: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "reconciles_total",
+			Help:      "Number of runs of the provisioning flow by result.",
+		}, []string{"result"}),
+		reconcileDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "reconcile_duration_seconds",
+			Help:      "Duration of the runs of the provisioning flow.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		stepDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "step_duration_seconds",
+			Help:      "Duration of each step of the provisioning flow.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"step"}),
+		stepFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "step_failures_total",
+			Help:      "Number of failures of each step of the provisioning flow.",
+		}, []string{"step"}),
+		driftCorrections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "drift_corrections_total",
+			Help:      "Number of routes and rules that were found missing and had to be added again.",
+		}, []string{"kind"}),
+		staleDeletions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "stale_deletions_total",
+			Help:      "Number of routes, rules and files owned by the provisioner that were no longer needed and got deleted.",
+		}, []string{"kind"}),
+		dhcpServerRestarts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "dhcp_server_restarts_total",
+			Help:      "Number of times the DHCP server process had to be restarted.",
+		}),
+		dhcpServerExits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "dhcp_server_exits_total",
+			Help:      "Number of unexpected exits of the DHCP server process by reason.",
+		}, []string{"reason"}),
+		netplanApplies: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "netplan_apply_total",
+			Help:      "Number of netplan apply invocations by result.",
+		}, []string{"result"}),
+		selectedInterfaces: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "selected_interface_info",
+			Help:      "Interfaces the provisioner works with by role and how they were selected.",
+		}, []string{"role", "name", "source"}),
+		encapUplinks: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "uplink_encap_active",
+			Help:      "Whether the VTEP IP of each uplink is used as geneve encap IP (1) or not (0).",
+		}, []string{"bridge"}),
+		vtepPeerReachable: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "vtep_peer_reachable",
+			Help:      "Whether the VTEP of another DPU answered the last probe (1) or not (0).",
+		}, []string{"peer", "address"}),
+		vtepPeerPathMTU: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "vtep_peer_path_mtu_ok",
+			Help:      "Whether the VTEP of another DPU answered the last probe of the size of the geneve traffic sent with the DF bit set (1) or not (0).",
+		}, []string{"peer", "address"}),
+		ovsRestarts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "ovs_restarts_total",
+			Help:      "Number of restarts of ovs-vswitchd for changes of the OVS profile to take effect by result.",
+		}, []string{"result"}),
+		ovsRestartPending: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "ovs_restart_pending",
+			Help:      "Whether changes of the OVS profile wait for a restart of ovs-vswitchd to take effect (1) or not (0).",
+		}),
+	}
+}
+
+// collectors returns all the collectors of the provisioner
+func (m *metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.reconciles,
+		m.reconcileDuration,
+		m.stepDuration,
+		m.stepFailures,
+		m.driftCorrections,
+		m.staleDeletions,
+		m.dhcpServerRestarts,
+		m.dhcpServerExits,
+		m.netplanApplies,
+		m.selectedInterfaces,
+		m.encapUplinks,
+		m.vtepPeerReachable,
+		m.vtepPeerPathMTU,
+		m.ovsRestarts,
+		m.ovsRestartPending,
+	}
+}
+
+// observeReconcile records the outcome of a run of the provisioning flow
+func (m *metrics) observeReconcile(duration time.Duration, err error) {
+	m.reconciles.WithLabelValues(resultLabel(err)).Inc()
+	m.reconcileDuration.Observe(duration.Seconds())
+}
+
+// observeStep records the outcome of a step of the provisioning flow
+func (m *metrics) observeStep(step Step, duration time.Duration, err error) {
+	m.stepDuration.WithLabelValues(string(step)).Observe(duration.Seconds())
+	if err != nil {
+		m.stepFailures.WithLabelValues(string(step)).Inc()
+	}
+}
+
+// observeSelectedInterfaces records the interfaces the provisioner works with
+func (m *metrics) observeSelectedInterfaces(s selectedInterfaces) {
+	m.selectedInterfaces.Reset()
+	m.selectedInterfaces.WithLabelValues("oob", s.OOB, string(s.oobSource)).Set(1)
+	m.selectedInterfaces.WithLabelValues("flannel", s.Flannel, string(s.flannelSource)).Set(1)
+	if s.PFIndex != "" {
+		m.selectedInterfaces.WithLabelValues("pf", s.PFIndex, string(s.pfIndexSource)).Set(1)
+	}
+}
+
+// observeEncapUplinks records which of the given uplinks have their VTEP IP used as geneve encap IP
+func (m *metrics) observeEncapUplinks(uplinks []*uplinkConfig, encapBridges []string) {
+	m.encapUplinks.Reset()
+	for _, u := range uplinks {
+		active := 0.0
+		if slices.Contains(encapBridges, u.bridge) {
+			active = 1
+		}
+		m.encapUplinks.WithLabelValues(u.bridge).Set(active)
+	}
+}
+
+// observeVTEPProbe records the results of the last probe of the VTEPs of the other DPUs. The path MTU isn't recorded
+// when it wasn't probed.
+func (m *metrics) observeVTEPProbe(results []vtepProbeResult, pathMTUProbed bool) {
+	m.vtepPeerReachable.Reset()
+	m.vtepPeerPathMTU.Reset()
+	for _, r := range results {
+		m.vtepPeerReachable.WithLabelValues(r.peer.node, r.peer.ip.String()).Set(boolToFloat(r.reachable))
+		if pathMTUProbed {
+			m.vtepPeerPathMTU.WithLabelValues(r.peer.node, r.peer.ip.String()).Set(boolToFloat(r.pathMTU))
+		}
+	}
+}
+
+// boolToFloat returns the value of a gauge for the given boolean
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// resultLabel returns the value of the result label for the given error
+func resultLabel(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
+
+// RegisterMetrics registers the metrics of the provisioner in the given registry
+func (p *DPUCNIProvisioner) RegisterMetrics(registry prometheus.Registerer) error {
+	for _, c := range p.metrics.collectors() {
+		if err := registry.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runStep runs a step of the provisioning flow with a logger that adds the step and records its metrics
+func (p *DPUCNIProvisioner) runStep(step Step, f func() error) error {
+	logger := p.logger
+	p.logger = klog.LoggerWithValues(logger, logging.KeyStep, step)
+	defer func() {
+		p.logger = logger
+	}()
+	start := p.clock.Now()
+	err := f()
+	p.metrics.observeStep(step, p.clock.Since(start), err)
+	return err
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dpucniprovisioner
+
+import (
+	"fmt"
+
+	"github.com/nvidia/ovn-kubernetes-components/internal/dhcpserver"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// MTUSource is where the MTU configured for OVN comes from
+type MTUSource string
+
+const (
+	// ConfiguredMTU is the MTU given to the provisioner
+	ConfiguredMTU MTUSource = "configured"
+	// DerivedMTU is the MTU derived from the MTU of the uplinks, less the geneve overhead
+	DerivedMTU MTUSource = "derived"
+)
+
+// PFMTUStatus is whether the PFs of the host got the MTU via DHCP. It's only known in InternalIPAM mode.
+type PFMTUStatus string
+
+const (
+	// PFMTUVerified is when every PF holds a lease that was acknowledged with the MTU
+	PFMTUVerified PFMTUStatus = "verified"
+	// PFMTUPending is when a PF doesn't hold a lease of the running DHCP server yet
+	PFMTUPending PFMTUStatus = "pending"
+	// PFMTUMismatch is when a PF holds a lease that was acknowledged with another MTU. It gets the MTU once it renews
+	// its lease.
+	PFMTUMismatch PFMTUStatus = "mismatch"
+	// PFMTUUnknown is when the DHCP server doesn't tell which MTU its clients got, which is the case of dnsmasq
+	PFMTUUnknown PFMTUStatus = "unknown"
+)
+
+// MTUError is the error of an MTU for OVN that doesn't fit in the MTU of an uplink once encapsulated
+type MTUError struct {
+	// MTU is the MTU for OVN
+	MTU int
+	// Link and LinkMTU are the link of the uplink and its MTU
+	Link    string
+	LinkMTU int
+}
+
+// Error returns the description of the error
+func (e *MTUError) Error() string {
+	return fmt.Sprintf("the MTU %d for OVN plus the geneve overhead of %d bytes exceeds the MTU %d of %s", e.MTU, geneveHeaderSize, e.LinkMTU, e.Link)
+}
+
+// uplinkMTU is the MTU of an uplink
+type uplinkMTU struct {
+	// bridge is the bridge of the uplink
+	bridge string
+	// link is the link the MTU of the uplink is read from, which is the physical port of the uplink when known and its
+	// bridge otherwise
+	link string
+	mtu  int
+}
+
+// configureMTU resolves the MTU for OVN, validates that it fits in the MTU of every uplink once encapsulated and sets
+// the MTU of the bridges of the uplinks whose physical port is known. Without a physical port, the MTU of the bridge,
+// which OVS derives from its ports, is the MTU of the uplink and is left alone. In InternalIPAM mode, it then checks
+// whether the PFs got the MTU via DHCP.
+func (p *DPUCNIProvisioner) configureMTU() error {
+	var uplinks []uplinkMTU
+	for _, u := range p.uplinks() {
+		link := u.port
+		if link == "" {
+			link = u.bridge
+		}
+		mtu, err := p.networkHelper.GetLinkMTU(link)
+		if err != nil {
+			return fmt.Errorf("error while getting the MTU of link %s: %w", link, err)
+		}
+		uplinks = append(uplinks, uplinkMTU{bridge: u.bridge, link: link, mtu: mtu})
+	}
+
+	mtu, source := p.ovnMTU, ConfiguredMTU
+	if mtu == 0 {
+		source = DerivedMTU
+		mtu = maxMTUSize - geneveHeaderSize
+		for _, u := range uplinks {
+			if u.mtu <= geneveHeaderSize {
+				return fmt.Errorf("the MTU %d of %s leaves no room for the geneve overhead of %d bytes", u.mtu, u.link, geneveHeaderSize)
+			}
+			mtu = min(mtu, u.mtu-geneveHeaderSize)
+		}
+	}
+	encapMTU := mtu + geneveHeaderSize
+	if mtu <= 0 || encapMTU > maxMTUSize {
+		return fmt.Errorf("invalid MTU %d for OVN: it must be greater than 0 and less than or equal to %d", mtu, maxMTUSize-geneveHeaderSize)
+	}
+	for _, u := range uplinks {
+		if encapMTU > u.mtu {
+			return &MTUError{MTU: mtu, Link: u.link, LinkMTU: u.mtu}
+		}
+	}
+	if mtu != p.resolvedMTU || source != p.resolvedMTUSource {
+		p.logger.Info("Resolved the MTU for OVN", "mtu", mtu, "source", source)
+	}
+	p.resolvedMTU = mtu
+	p.resolvedMTUSource = source
+
+	for _, u := range uplinks {
+		if u.link == u.bridge {
+			continue
+		}
+		if err := p.setLinkMTUIfNotSet(u.bridge, encapMTU); err != nil {
+			return err
+		}
+	}
+
+	if p.mode == InternalIPAM {
+		p.updatePFMTUStatus(p.checkPFMTU(encapMTU), encapMTU)
+	}
+	return nil
+}
+
+// setLinkMTUIfNotSet sets the MTU of a link if it's not set already
+func (p *DPUCNIProvisioner) setLinkMTUIfNotSet(link string, mtu int) error {
+	current, err := p.networkHelper.GetLinkMTU(link)
+	if err != nil {
+		return fmt.Errorf("error while getting the MTU of link %s: %w", link, err)
+	}
+	if current == mtu {
+		return nil
+	}
+	if err := p.networkHelper.SetLinkMTU(link, mtu); err != nil {
+		return fmt.Errorf("error while setting the MTU of link %s to %d: %w", link, mtu, err)
+	}
+	p.logger.Info("Set the MTU of link", "link", link, "previous", current, "mtu", mtu)
+	p.metrics.driftCorrections.WithLabelValues("mtu").Inc()
+	return nil
+}
+
+// checkPFMTU returns whether the PFs got the given MTU from the running DHCP server. Only the builtin DHCP server
+// records the MTU its leases were acknowledged with. The DHCPv6 clients get the MTU via the router advertisements,
+// which can't be verified.
+func (p *DPUCNIProvisioner) checkPFMTU(pfMTU int) PFMTUStatus {
+	p.dhcpServerLock.Lock()
+	defer p.dhcpServerLock.Unlock()
+
+	if p.dhcpServerBackend != BuiltinDHCPServer {
+		return PFMTUUnknown
+	}
+	if p.dhcpServer == nil {
+		return PFMTUPending
+	}
+	builtin, ok := p.dhcpServer.process.(*builtinDHCPServer)
+	if !ok {
+		return PFMTUUnknown
+	}
+	leases := map[string]dhcpserver.Lease{}
+	for _, server := range builtin.servers {
+		for _, lease := range server.Leases() {
+			leases[lease.MAC.String()] = lease
+		}
+	}
+
+	status := PFMTUUnknown
+	for _, config := range p.dhcpServer.config {
+		if config.v4 == nil {
+			continue
+		}
+		for _, binding := range config.v4.Bindings {
+			lease, ok := leases[binding.MAC.String()]
+			switch {
+			case !ok:
+				status = PFMTUPending
+			case lease.MTU != pfMTU:
+				return PFMTUMismatch
+			case status == PFMTUUnknown:
+				status = PFMTUVerified
+			}
+		}
+	}
+	return status
+}
+
+// updatePFMTUStatus records whether the PFs got the given MTU via DHCP and reports when it changes
+func (p *DPUCNIProvisioner) updatePFMTUStatus(status PFMTUStatus, pfMTU int) {
+	if status == p.pfMTUStatus {
+		return
+	}
+	p.pfMTUStatus = status
+	switch status {
+	case PFMTUVerified:
+		p.logger.Info("The PFs got the MTU via DHCP", "mtu", pfMTU)
+		p.eventf(corev1.EventTypeNormal, EventReasonPFMTUVerified, "The PFs got MTU %d via DHCP", pfMTU)
+	case PFMTUMismatch:
+		p.logger.Info("A PF holds a lease with another MTU, it gets the MTU when it renews its lease", "mtu", pfMTU)
+		p.eventf(corev1.EventTypeWarning, EventReasonPFMTUMismatch, "A PF holds a DHCP lease with another MTU than %d", pfMTU)
+	}
+}
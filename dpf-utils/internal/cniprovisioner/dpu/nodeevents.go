@@ -0,0 +1,91 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dpucniprovisioner
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+// The reasons of the Events the provisioner emits
+const (
+	// EventReasonConfigurationApplied is emitted when a new generation of the state is applied
+	EventReasonConfigurationApplied = "ConfigurationApplied"
+	// EventReasonConfigurationFailed is emitted when a run of the provisioning flow fails
+	EventReasonConfigurationFailed = "ConfigurationFailed"
+	// EventReasonNetplanApplied is emitted when netplan apply succeeds
+	EventReasonNetplanApplied = "NetplanApplied"
+	// EventReasonNetplanApplyFailed is emitted when netplan apply fails
+	EventReasonNetplanApplyFailed = "NetplanApplyFailed"
+	// EventReasonUnexpectedBROVNAddresses is emitted when br-ovn doesn't have exactly one address of an IP family in
+	// External mode
+	EventReasonUnexpectedBROVNAddresses = "UnexpectedBROVNAddresses"
+	// EventReasonStaleChassisIDRemoved is emitted when the stale chassis ID annotation of the host Node is removed
+	EventReasonStaleChassisIDRemoved = "StaleChassisIDRemoved"
+	// EventReasonOVNConfigurationChanged is emitted when the content of ovn_k8s.conf changes
+	EventReasonOVNConfigurationChanged = "OVNConfigurationChanged"
+	// EventReasonOVNKubeNodeNotificationFailed is emitted when ovnkube-node can't be notified of a change of
+	// ovn_k8s.conf
+	EventReasonOVNKubeNodeNotificationFailed = "OVNKubeNodeNotificationFailed"
+	// EventReasonPFMTUVerified is emitted when every PF got the MTU via DHCP
+	EventReasonPFMTUVerified = "PFMTUVerified"
+	// EventReasonPFMTUMismatch is emitted when a PF holds a DHCP lease with another MTU
+	EventReasonPFMTUMismatch = "PFMTUMismatch"
+	// EventReasonOVSRestartPending is emitted when ovs-vswitchd must be restarted for changes of the OVS profile to take
+	// effect and the provisioner isn't allowed to restart it
+	EventReasonOVSRestartPending = "OVSRestartPending"
+	// EventReasonOVSRestarted is emitted when the provisioner restarts ovs-vswitchd
+	EventReasonOVSRestarted = "OVSRestarted"
+	// EventReasonOVSRestartFailed is emitted when the provisioner fails to restart ovs-vswitchd
+	EventReasonOVSRestartFailed = "OVSRestartFailed"
+)
+
+// SetEventRecorder sets the recorder of the Events emitted against the DPU Node. No Events are emitted when not set.
+func (p *DPUCNIProvisioner) SetEventRecorder(recorder record.EventRecorder) {
+	p.eventRecorder = recorder
+}
+
+// SetHostEventRecorder sets the recorder of the Events emitted against the host Node, which are the same as the ones
+// emitted against the DPU Node. No Events are emitted against the host Node when not set.
+func (p *DPUCNIProvisioner) SetHostEventRecorder(recorder record.EventRecorder) {
+	p.hostEventRecorder = recorder
+}
+
+// eventf emits an Event against the DPU Node and, once the host Node is known, against the host Node. No Events are
+// emitted while planning.
+func (p *DPUCNIProvisioner) eventf(eventType string, reason string, messageFmt string, args ...interface{}) {
+	if p.plan != nil {
+		return
+	}
+	if p.eventRecorder != nil {
+		p.eventRecorder.Eventf(nodeReference(p.dpuHostName), eventType, reason, messageFmt, args...)
+	}
+	if p.hostEventRecorder != nil && p.hostNodeName != "" {
+		p.hostEventRecorder.Eventf(nodeReference(p.hostNodeName), eventType, reason, "DPU "+p.dpuHostName+": "+messageFmt, args...)
+	}
+}
+
+// nodeReference returns a reference to the Node with the given name. Like the kubelet, the name is used as the UID so
+// that the Events show up in kubectl describe node.
+func nodeReference(name string) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		Kind: "Node",
+		Name: name,
+		UID:  k8stypes.UID(name),
+	}
+}
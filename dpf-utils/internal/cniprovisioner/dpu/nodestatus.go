@@ -0,0 +1,216 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dpucniprovisioner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// NodeConditionProvisioned is the condition of the DPU Node that reports whether the last run of the provisioning
+	// flow succeeded
+	NodeConditionProvisioned corev1.NodeConditionType = "OVNDPUProvisioned"
+	// NodeConditionReasonConfigurationApplied is the reason of the condition when the last run succeeded
+	NodeConditionReasonConfigurationApplied = "ConfigurationApplied"
+	// NodeConditionReasonConfigurationFailed is the reason of the condition when the last run failed
+	NodeConditionReasonConfigurationFailed = "ConfigurationFailed"
+	// NodeConditionReasonMTUExceedsUplink is the reason of the condition when the last run failed because the MTU for
+	// OVN doesn't fit in the MTU of an uplink once encapsulated
+	NodeConditionReasonMTUExceedsUplink = "MTUExceedsUplink"
+
+	// nodeAnnotationPrefix is the prefix of the annotations the provisioner publishes on the DPU Node
+	nodeAnnotationPrefix = "dpucniprovisioner.ovn.nvidia.com/"
+	// NodeAnnotationMode is the annotation that holds the mode of the provisioner
+	NodeAnnotationMode = nodeAnnotationPrefix + "mode"
+	// NodeAnnotationVTEPIP is the annotation that holds the VTEP IPs of br-ovn, one per IP family
+	NodeAnnotationVTEPIP = nodeAnnotationPrefix + "vtep-ip"
+	// NodeAnnotationGateway is the annotation that holds the gateways of br-ovn, one per IP family
+	NodeAnnotationGateway = nodeAnnotationPrefix + "gateway"
+	// NodeAnnotationGatewaySource is the annotation that holds the gateway discovery strategies the gateways of br-ovn
+	// were discovered with, one per IP family. It's only set in ExternalIPAM mode.
+	NodeAnnotationGatewaySource = nodeAnnotationPrefix + "gateway-source"
+	// NodeAnnotationMTU is the annotation that holds the MTU configured for OVN
+	NodeAnnotationMTU = nodeAnnotationPrefix + "mtu"
+	// NodeAnnotationMTUSource is the annotation that holds where the MTU configured for OVN comes from
+	NodeAnnotationMTUSource = nodeAnnotationPrefix + "mtu-source"
+	// NodeAnnotationPFMTU is the annotation that holds whether the PFs got the MTU via DHCP. It's only set in
+	// InternalIPAM mode.
+	NodeAnnotationPFMTU = nodeAnnotationPrefix + "pf-mtu"
+	// NodeAnnotationHostNodeName is the annotation that holds the name of the host the DPU belongs to
+	NodeAnnotationHostNodeName = nodeAnnotationPrefix + "host-node-name"
+	// NodeAnnotationOVSRestartPending is the annotation that holds the other_config keys of the OVS profile that wait
+	// for a restart of ovs-vswitchd to take effect. It's removed once ovs-vswitchd is restarted.
+	NodeAnnotationOVSRestartPending = nodeAnnotationPrefix + "ovs-restart-pending"
+	// NodeAnnotationLastReconcileTime is the annotation that holds when the provisioning flow last ran, up to
+	// nodeStatusHeartbeatInterval ago
+	NodeAnnotationLastReconcileTime = nodeAnnotationPrefix + "last-reconcile-time"
+	// NodeAnnotationLastError is the annotation that holds the error of the last run of the provisioning flow. It's
+	// removed once a run succeeds.
+	NodeAnnotationLastError = nodeAnnotationPrefix + "last-error"
+
+	// nodeStatusHeartbeatInterval is how often the status is published when it doesn't change so that the Node doesn't
+	// get updated on every run of the provisioning flow
+	nodeStatusHeartbeatInterval = 5 * time.Minute
+)
+
+// nodeStatus is the status the provisioner publishes on its DPU Node
+type nodeStatus struct {
+	// annotations are the annotations of the status. An empty value removes the annotation.
+	annotations map[string]string
+	provisioned bool
+	reason      string
+	message     string
+}
+
+// publishNodeStatus publishes the resolved configuration and the result of the last run of the provisioning flow on
+// the DPU Node. The resolved configuration is the last applied state, which is still in effect when the run failed.
+// Failing to publish is logged and doesn't fail the provisioning flow.
+func (p *DPUCNIProvisioner) publishNodeStatus(runErr error) {
+	status := p.nodeStatus(runErr)
+	now := p.clock.Now()
+	if reflect.DeepEqual(p.publishedNodeStatus, status) && now.Sub(p.nodeStatusPublishedAt) < nodeStatusHeartbeatInterval {
+		return
+	}
+	if err := p.patchNodeStatus(status, now); err != nil {
+		p.logger.Error(err, "error while publishing the status on the DPU Node")
+		return
+	}
+	p.publishedNodeStatus = status
+	p.nodeStatusPublishedAt = now
+}
+
+// nodeStatus returns the status that corresponds to the last applied state and the given error of the last run
+func (p *DPUCNIProvisioner) nodeStatus(runErr error) *nodeStatus {
+	status := &nodeStatus{
+		annotations: map[string]string{
+			NodeAnnotationMode:          p.mode.String(),
+			NodeAnnotationVTEPIP:        "",
+			NodeAnnotationGateway:       "",
+			NodeAnnotationGatewaySource: "",
+			NodeAnnotationMTU:           "",
+			NodeAnnotationMTUSource:     "",
+			NodeAnnotationPFMTU:         string(p.pfMTUStatus),
+			NodeAnnotationHostNodeName:  "",
+			NodeAnnotationLastError:     "",
+			// The pending restart is reported even when the run failed since the keys were committed
+			NodeAnnotationOVSRestartPending: strings.Join(p.ovsRestartPending.Keys, ","),
+		},
+		provisioned: runErr == nil,
+		reason:      NodeConditionReasonConfigurationApplied,
+	}
+	if runErr != nil {
+		status.reason = NodeConditionReasonConfigurationFailed
+		if mtuErr := (*MTUError)(nil); errors.As(runErr, &mtuErr) {
+			status.reason = NodeConditionReasonMTUExceedsUplink
+		}
+		status.message = runErr.Error()
+		status.annotations[NodeAnnotationLastError] = runErr.Error()
+	}
+
+	state := p.LastAppliedState()
+	if state == nil {
+		return status
+	}
+	vtepIPs := make([]string, 0, len(state.IPFamilies))
+	gateways := make([]string, 0, len(state.IPFamilies))
+	var gatewaySources []string
+	for _, f := range state.IPFamilies {
+		vtepIPs = append(vtepIPs, f.VTEPIP)
+		gateways = append(gateways, f.Gateway)
+		if f.GatewaySource != "" {
+			gatewaySources = append(gatewaySources, string(f.GatewaySource))
+		}
+	}
+	status.annotations[NodeAnnotationVTEPIP] = strings.Join(vtepIPs, ",")
+	status.annotations[NodeAnnotationGateway] = strings.Join(gateways, ",")
+	status.annotations[NodeAnnotationGatewaySource] = strings.Join(gatewaySources, ",")
+	status.annotations[NodeAnnotationHostNodeName] = state.HostName
+	if state.OVNMTU > 0 {
+		status.annotations[NodeAnnotationMTU] = strconv.Itoa(state.OVNMTU)
+		status.annotations[NodeAnnotationMTUSource] = string(state.OVNMTUSource)
+	}
+	return status
+}
+
+// patchNodeStatus patches the annotations and the condition of the DPU Node
+func (p *DPUCNIProvisioner) patchNodeStatus(status *nodeStatus, now time.Time) error {
+	nodeClient := p.dpuClusterKubernetesClient.CoreV1().Nodes()
+	annotations := map[string]*string{}
+	for key, value := range status.annotations {
+		if value == "" {
+			annotations[key] = nil
+			continue
+		}
+		annotations[key] = ptr.To(value)
+	}
+	annotations[NodeAnnotationLastReconcileTime] = ptr.To(now.UTC().Format(time.RFC3339))
+	annotationsPatch, err := json.Marshal(map[string]any{"metadata": map[string]any{"annotations": annotations}})
+	if err != nil {
+		return fmt.Errorf("error while encoding the annotations patch: %w", err)
+	}
+	if _, err := nodeClient.Patch(p.ctx, p.dpuHostName, k8stypes.MergePatchType, annotationsPatch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("error while patching the annotations: %w", err)
+	}
+
+	condition := corev1.NodeCondition{
+		Type:    NodeConditionProvisioned,
+		Status:  corev1.ConditionTrue,
+		Reason:  status.reason,
+		Message: status.message,
+	}
+	if !status.provisioned {
+		condition.Status = corev1.ConditionFalse
+	}
+	return p.patchNodeCondition(condition, now)
+}
+
+// patchNodeCondition patches a condition of the DPU Node. The transition time of the condition is kept unless its
+// status changes. It's safe to call concurrently for different conditions.
+func (p *DPUCNIProvisioner) patchNodeCondition(condition corev1.NodeCondition, now time.Time) error {
+	nodeClient := p.dpuClusterKubernetesClient.CoreV1().Nodes()
+	node, err := nodeClient.Get(p.ctx, p.dpuHostName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error while getting Kubernetes Node: %w", err)
+	}
+
+	condition.LastHeartbeatTime = metav1.NewTime(now)
+	condition.LastTransitionTime = metav1.NewTime(now)
+	for _, c := range node.Status.Conditions {
+		if c.Type == condition.Type && c.Status == condition.Status {
+			condition.LastTransitionTime = c.LastTransitionTime
+		}
+	}
+	conditionPatch, err := json.Marshal(map[string]any{"status": map[string]any{"conditions": []corev1.NodeCondition{condition}}})
+	if err != nil {
+		return fmt.Errorf("error while encoding the condition patch: %w", err)
+	}
+	if _, err := nodeClient.Patch(p.ctx, p.dpuHostName, k8stypes.StrategicMergePatchType, conditionPatch, metav1.PatchOptions{}, "status"); err != nil {
+		return fmt.Errorf("error while patching the %s condition: %w", condition.Type, err)
+	}
+	return nil
+}
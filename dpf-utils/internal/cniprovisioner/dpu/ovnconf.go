@@ -0,0 +1,261 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dpucniprovisioner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// ovnkInputPreviousSuffix is the suffix of the file the previous version of ovn_k8s.conf is kept in
+	ovnkInputPreviousSuffix = ".prev"
+	// ovnKubeNodeNotifyPendingFileName is the name of the file in the state directory that marks that ovn_k8s.conf
+	// changed without ovnkube-node being notified, so that the notification survives a restart of the provisioner
+	ovnKubeNodeNotifyPendingFileName = "ovnkube-node-notify-pending"
+)
+
+// OVNKubeNodeNotifier notifies ovnkube-node that ovn_k8s.conf changed so that it picks up the new content
+type OVNKubeNodeNotifier interface {
+	// Notify notifies ovnkube-node of a change
+	Notify(ctx context.Context) error
+}
+
+// SetOVNKubeNodeNotifier sets how ovnkube-node is notified of changes of ovn_k8s.conf. ovnkube-node is not notified
+// when not set. Call before RunOnce or EnsureConfiguration.
+func (p *DPUCNIProvisioner) SetOVNKubeNodeNotifier(notifier OVNKubeNodeNotifier) {
+	p.ovnKubeNodeNotifier = notifier
+}
+
+// writeOVNKInput writes ovn_k8s.conf when its content differs from the given one. The file is replaced atomically so
+// that ovnkube-node never reads a partially written file, and the previous version is kept next to it. ovnkube-node is
+// notified when a previous version is replaced. Nobody is notified when the file is written for the first time, as
+// ovnkube-node can't have read it yet.
+func (p *DPUCNIProvisioner) writeOVNKInput(path string, content []byte) error {
+	if p.plan != nil {
+		return p.writeFile(path, content, 0644)
+	}
+	p.desireFile(path, content)
+
+	current, err := os.ReadFile(path)
+	exists := err == nil
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error while reading %s: %w", path, err)
+	}
+	if exists && bytes.Equal(current, content) {
+		// A notification of a previous run may have failed
+		return p.notifyOVNKubeNode(path)
+	}
+
+	if exists {
+		previousPath := path + ovnkInputPreviousSuffix
+		if err := writeFileAtomically(previousPath, current, 0644); err != nil {
+			return fmt.Errorf("error while keeping the previous version of %s in %s: %w", path, previousPath, err)
+		}
+		// The notification is recorded before the file is replaced so that it isn't lost when the provisioner stops in
+		// between
+		if err := p.setOVNKubeNodeNotifyPending(true); err != nil {
+			return err
+		}
+	}
+	if err := writeFileAtomically(path, content, 0644); err != nil {
+		return fmt.Errorf("error while writing %s: %w", path, err)
+	}
+	if !exists {
+		p.logger.Info("Wrote OVN Kubernetes configuration", "path", path)
+		return nil
+	}
+
+	p.logger.Info("OVN Kubernetes configuration changed", "path", path)
+	p.eventf(corev1.EventTypeNormal, EventReasonOVNConfigurationChanged, "%s changed", filepath.Base(path))
+	return p.notifyOVNKubeNode(path)
+}
+
+// notifyOVNKubeNode notifies ovnkube-node of a change of the given file that it wasn't notified of yet. The
+// notification is retried on the next run when it fails, including the first run after a restart of the provisioner.
+func (p *DPUCNIProvisioner) notifyOVNKubeNode(path string) error {
+	p.loadOVNKubeNodeNotifyPending()
+	if !p.ovnKubeNodeNotifyPending {
+		return nil
+	}
+	if p.ovnKubeNodeNotifier == nil {
+		return p.setOVNKubeNodeNotifyPending(false)
+	}
+	if err := p.ovnKubeNodeNotifier.Notify(p.ctx); err != nil {
+		p.eventf(corev1.EventTypeWarning, EventReasonOVNKubeNodeNotificationFailed, "Failed to notify ovnkube-node of the change of %s: %v", filepath.Base(path), err)
+		return fmt.Errorf("error while notifying ovnkube-node of the change of %s: %w", path, err)
+	}
+	p.logger.Info("Notified ovnkube-node of the change", "path", path)
+	return p.setOVNKubeNodeNotifyPending(false)
+}
+
+// ovnKubeNodeNotifyPendingPath returns the path to the file that marks a pending notification of ovnkube-node. Empty
+// when the state directory is disabled, in which case the notification is only kept in memory.
+func (p *DPUCNIProvisioner) ovnKubeNodeNotifyPendingPath() string {
+	if p.StateDir == "" {
+		return ""
+	}
+	return filepath.Join(p.FileSystemRoot, p.StateDir, ovnKubeNodeNotifyPendingFileName)
+}
+
+// loadOVNKubeNodeNotifyPending loads whether a notification of ovnkube-node is pending once per process
+func (p *DPUCNIProvisioner) loadOVNKubeNodeNotifyPending() {
+	if p.ovnKubeNodeNotifyPendingLoaded {
+		return
+	}
+	p.ovnKubeNodeNotifyPendingLoaded = true
+	path := p.ovnKubeNodeNotifyPendingPath()
+	if path == "" {
+		return
+	}
+	if _, err := os.Stat(path); err == nil {
+		p.logger.Info("A notification of ovnkube-node is pending since a previous run", "path", path)
+		p.ovnKubeNodeNotifyPending = true
+	}
+}
+
+// setOVNKubeNodeNotifyPending records whether a notification of ovnkube-node is pending. The marker file is removed
+// once it isn't.
+func (p *DPUCNIProvisioner) setOVNKubeNodeNotifyPending(pending bool) error {
+	p.loadOVNKubeNodeNotifyPending()
+	if path := p.ovnKubeNodeNotifyPendingPath(); path != "" {
+		if pending {
+			if err := writeFileAtomically(path, nil, 0644); err != nil {
+				return fmt.Errorf("error while writing %s: %w", path, err)
+			}
+		} else if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error while removing %s: %w", path, err)
+		}
+	}
+	p.ovnKubeNodeNotifyPending = pending
+	return nil
+}
+
+// signalNotifier notifies ovnkube-node by sending a signal to the process whose PID is in a PID file
+type signalNotifier struct {
+	pidFilePath string
+	signal      syscall.Signal
+}
+
+// NewSignalNotifier returns an OVNKubeNodeNotifier that sends the given signal to the process whose PID is in the
+// given file. The process must be visible to the provisioner, e.g. via a shared process namespace.
+func NewSignalNotifier(pidFilePath string, signal syscall.Signal) OVNKubeNodeNotifier {
+	return &signalNotifier{
+		pidFilePath: pidFilePath,
+		signal:      signal,
+	}
+}
+
+// Notify sends the signal to the process
+func (n *signalNotifier) Notify(_ context.Context) error {
+	content, err := os.ReadFile(n.pidFilePath)
+	if err != nil {
+		return fmt.Errorf("error while reading PID file %s: %w", n.pidFilePath, err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil || pid <= 0 {
+		return fmt.Errorf("invalid PID %q in %s", strings.TrimSpace(string(content)), n.pidFilePath)
+	}
+	if err := unix.Kill(pid, n.signal); err != nil {
+		return fmt.Errorf("error while sending signal %d to process %d: %w", n.signal, pid, err)
+	}
+	return nil
+}
+
+// triggerFileNotifier notifies ovnkube-node by updating the modification time of a file it watches
+type triggerFileNotifier struct {
+	path string
+}
+
+// NewTriggerFileNotifier returns an OVNKubeNodeNotifier that creates the given file or updates its modification time
+func NewTriggerFileNotifier(path string) OVNKubeNodeNotifier {
+	return &triggerFileNotifier{
+		path: path,
+	}
+}
+
+// Notify touches the trigger file
+func (n *triggerFileNotifier) Notify(_ context.Context) error {
+	f, err := os.OpenFile(n.path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error while opening trigger file %s: %w", n.path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error while closing trigger file %s: %w", n.path, err)
+	}
+	now := time.Now()
+	if err := os.Chtimes(n.path, now, now); err != nil {
+		return fmt.Errorf("error while touching trigger file %s: %w", n.path, err)
+	}
+	return nil
+}
+
+// podDeleteNotifier notifies ovnkube-node by deleting its Pod so that it's recreated with the new configuration
+type podDeleteNotifier struct {
+	client        kubernetes.Interface
+	namespace     string
+	labelSelector string
+	nodeName      string
+}
+
+// NewPodDeleteNotifier returns an OVNKubeNodeNotifier that deletes the Pods in the given namespace that match the
+// given label selector and run on the given DPU Node
+func NewPodDeleteNotifier(client kubernetes.Interface, namespace string, labelSelector string, nodeName string) OVNKubeNodeNotifier {
+	return &podDeleteNotifier{
+		client:        client,
+		namespace:     namespace,
+		labelSelector: labelSelector,
+		nodeName:      nodeName,
+	}
+}
+
+// Notify deletes the ovnkube-node Pods of the Node. It's not an error when there is none, as a Pod that is yet to be
+// created reads the new configuration anyway.
+func (n *podDeleteNotifier) Notify(ctx context.Context) error {
+	pods, err := n.client.CoreV1().Pods(n.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: n.labelSelector,
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", n.nodeName).String(),
+	})
+	if err != nil {
+		return fmt.Errorf("error while listing the ovnkube-node Pods: %w", err)
+	}
+	var errs []error
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName != n.nodeName {
+			continue
+		}
+		if err := n.client.CoreV1().Pods(n.namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("error while deleting Pod %s/%s: %w", n.namespace, pod.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dpucniprovisioner
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// managedOVNKConfKeys are the keys of ovn_k8s.conf the provisioner manages, per section. The fragment can't set them,
+// even when the provisioner doesn't write them with its current settings, so that what ovnkube reads for them never
+// depends on the fragment. Names are lower case because ovnkube matches section and key names case insensitively.
+var managedOVNKConfKeys = map[string][]string{
+	"gateway":     {"next-hop", "router-subnet"},
+	"kubernetes":  {"ovn-config-namespace"},
+	"ovnkubenode": {"dpu-node-lease-renew-interval", "dpu-node-lease-duration"},
+}
+
+// ovnkConf is the content of ovn_k8s.conf, an INI file whose sections and keys are rendered in the order they are
+// added
+type ovnkConf struct {
+	sections []*ovnkConfSection
+}
+
+// ovnkConfSection is a section of ovn_k8s.conf
+type ovnkConfSection struct {
+	name string
+	keys []ovnkConfKey
+}
+
+// ovnkConfKey is a key of a section of ovn_k8s.conf
+type ovnkConfKey struct {
+	name  string
+	value string
+}
+
+// section returns the section with the given name, which is added when it doesn't exist
+func (c *ovnkConf) section(name string) *ovnkConfSection {
+	for _, s := range c.sections {
+		if strings.EqualFold(s.name, name) {
+			return s
+		}
+	}
+	s := &ovnkConfSection{name: name}
+	c.sections = append(c.sections, s)
+	return s
+}
+
+// set sets the value of the given key, which is added when it doesn't exist
+func (s *ovnkConfSection) set(name string, value string) {
+	for i := range s.keys {
+		if strings.EqualFold(s.keys[i].name, name) {
+			s.keys[i].value = value
+			return
+		}
+	}
+	s.keys = append(s.keys, ovnkConfKey{name: name, value: value})
+}
+
+// has returns whether the section has the given key
+func (s *ovnkConfSection) has(name string) bool {
+	return slices.ContainsFunc(s.keys, func(k ovnkConfKey) bool { return strings.EqualFold(k.name, name) })
+}
+
+// merge adds the sections and keys of the given fragment. It fails when the fragment sets a key the provisioner
+// manages.
+func (c *ovnkConf) merge(fragment *ovnkConf) error {
+	var errs []error
+	for _, fs := range fragment.sections {
+		managed := managedOVNKConfKeys[strings.ToLower(fs.name)]
+		for _, k := range fs.keys {
+			if slices.Contains(managed, strings.ToLower(k.name)) {
+				errs = append(errs, fmt.Errorf("key %s of section [%s] is managed by the provisioner", k.name, fs.name))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	for _, fs := range fragment.sections {
+		s := c.section(fs.name)
+		for _, k := range fs.keys {
+			s.set(k.name, k.value)
+		}
+	}
+	return nil
+}
+
+// render returns the content of the file. Sections are separated by an empty line.
+func (c *ovnkConf) render() []byte {
+	var b bytes.Buffer
+	for i, s := range c.sections {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[%s]\n", s.name)
+		for _, k := range s.keys {
+			fmt.Fprintf(&b, "%s=%s\n", k.name, k.value)
+		}
+	}
+	return b.Bytes()
+}
+
+// parseOVNKConf parses an INI file in the format ovnkube reads. Every key has to have a value and belong to a section,
+// and can't be set more than once in it. Lines starting with # or ; are comments.
+func parseOVNKConf(content []byte) (*ovnkConf, error) {
+	c := &ovnkConf{}
+	var s *ovnkConfSection
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			name, ok := strings.CutSuffix(strings.TrimPrefix(line, "["), "]")
+			name = strings.TrimSpace(name)
+			if !ok || name == "" {
+				return nil, fmt.Errorf("line %d: invalid section header %q", n, line)
+			}
+			s = c.section(name)
+			continue
+		}
+		if s == nil {
+			return nil, fmt.Errorf("line %d: key outside of a section", n)
+		}
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("line %d: expected key=value, got %q", n, line)
+		}
+		if s.has(name) {
+			return nil, fmt.Errorf("line %d: key %s is set more than once in section [%s]", n, name, s.name)
+		}
+		s.set(name, strings.TrimSpace(value))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// readOVNKConfFragment reads and parses the fragment that is merged into ovn_k8s.conf. There is no fragment when none
+// is configured or the file doesn't exist, e.g. because it's an optional ConfigMap that wasn't created.
+func (p *DPUCNIProvisioner) readOVNKConfFragment() (*ovnkConf, error) {
+	if p.OVNKConfFragmentPath == "" {
+		return nil, nil
+	}
+	path := filepath.Join(p.FileSystemRoot, p.OVNKConfFragmentPath)
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while reading %s: %w", path, err)
+	}
+	fragment, err := parseOVNKConf(content)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing %s: %w", path, err)
+	}
+	return fragment, nil
+}
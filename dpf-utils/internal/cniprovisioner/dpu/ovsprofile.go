@@ -0,0 +1,355 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dpucniprovisioner
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/ovsclient"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// docaInitOtherConfigKey is the other_config key of the Open_vSwitch row that enables DOCA in ovs-vswitchd
+	docaInitOtherConfigKey = "doca-init"
+	// ovsRestartPendingFileName is the name of the file in the state directory that records the other_config keys that
+	// changed since ovs-vswitchd was last restarted, so that a pending restart survives a restart of the provisioner
+	ovsRestartPendingFileName = "ovs-restart-pending.json"
+	// ovsRestartCooldownDuration is the minimum time between two restarts of ovs-vswitchd so that a profile that keeps
+	// changing, or a restart that keeps failing, doesn't keep the datapath down
+	ovsRestartCooldownDuration = 5 * time.Minute
+	// vswitchdPIDFilePath is the PID file of ovs-vswitchd
+	vswitchdPIDFilePath = "/var/run/openvswitch/ovs-vswitchd.pid"
+)
+
+// ovsRestartRequiredKeys are the other_config keys of the Open_vSwitch row that ovs-vswitchd only reads when it starts.
+// The other keys, e.g. pmd-cpu-mask or max-idle, are applied by ovs-vswitchd as soon as they change.
+var ovsRestartRequiredKeys = []string{
+	docaInitOtherConfigKey,
+	"dpdk-alloc-mem",
+	"dpdk-extra",
+	"dpdk-hugepage-dir",
+	"dpdk-init",
+	"dpdk-lcore-mask",
+	"dpdk-socket-limit",
+	"dpdk-socket-mem",
+	"hw-offload",
+	"tc-policy",
+	"vhost-sock-dir",
+}
+
+// OVSProfile is the datapath profile of OVS the provisioner applies: the other_config keys of the Open_vSwitch row and
+// the datapath type of bridges
+type OVSProfile struct {
+	// OtherConfig are the other_config keys of the Open_vSwitch row, e.g. hw-offload or pmd-cpu-mask. An empty value
+	// removes the key. doca-init is set with DOCAInit.
+	OtherConfig map[string]string
+	// DOCAInit, when set, enables or disables DOCA in ovs-vswitchd
+	DOCAInit *bool
+	// BridgeDataPathTypes are the datapath types of bridges by name. A bridge that doesn't exist is skipped until it
+	// does, e.g. br-int that ovn-controller creates.
+	BridgeDataPathTypes map[string]ovsclient.BridgeDataPathType
+	// RestartOVS makes the provisioner restart ovs-vswitchd once the changes that require it are applied. Otherwise the
+	// restart is only reported as pending and left to the operator.
+	RestartOVS bool
+	// RestartCommand is the command that restarts ovs-vswitchd. Required when RestartOVS is set as there is no command
+	// that works in every deployment, e.g. host systemd isn't reachable from the container.
+	RestartCommand []string
+}
+
+// ovsRestartPending are the other_config keys that wait for a restart of ovs-vswitchd to take effect
+type ovsRestartPending struct {
+	Keys []string `json:"keys"`
+	// VSwitchdPID is the PID of ovs-vswitchd when the keys changed. The keys took effect once it changes.
+	VSwitchdPID string `json:"vswitchdPID,omitempty"`
+}
+
+// SetOVSProfile sets the datapath profile of OVS the provisioning flow applies. No profile is applied when nil. Call
+// before EnsureConfiguration, use UpdateSettings afterwards.
+func (p *DPUCNIProvisioner) SetOVSProfile(profile *OVSProfile) error {
+	if err := validateOVSProfile(profile); err != nil {
+		return err
+	}
+	p.ovsProfile = profile
+	return nil
+}
+
+// validateOVSProfile validates the given profile
+func validateOVSProfile(profile *OVSProfile) error {
+	if profile == nil {
+		return nil
+	}
+	for key := range profile.OtherConfig {
+		if key == "" {
+			return errors.New("invalid OVS profile: other_config keys must not be empty")
+		}
+		if key == docaInitOtherConfigKey {
+			return fmt.Errorf("invalid OVS profile: other_config key %s is set with DOCAInit", key)
+		}
+	}
+	for bridge, dataPathType := range profile.BridgeDataPathTypes {
+		if bridge == "" {
+			return errors.New("invalid OVS profile: bridge names must not be empty")
+		}
+		if dataPathType != ovsclient.System && dataPathType != ovsclient.NetDev {
+			return fmt.Errorf("invalid OVS profile: unsupported datapath type %q for bridge %s", dataPathType, bridge)
+		}
+	}
+	if profile.RestartOVS && len(profile.RestartCommand) == 0 {
+		return errors.New("invalid OVS profile: the restart command is required when restarting ovs-vswitchd is enabled")
+	}
+	if slices.Contains(profile.RestartCommand, "") {
+		return errors.New("invalid OVS profile: the arguments of the restart command must not be empty")
+	}
+	return nil
+}
+
+// restartEnabled returns whether the provisioner restarts ovs-vswitchd when a change of the profile requires it
+func (o *OVSProfile) restartEnabled() bool {
+	return o != nil && o.RestartOVS
+}
+
+// configureOVSProfile queues the changes of the OVS profile in the given transaction and returns the other_config keys
+// that change and only take effect once ovs-vswitchd is restarted. Only the keys and the datapath types that differ
+// from the current ones are queued.
+func (p *DPUCNIProvisioner) configureOVSProfile(ovsTxn ovsclient.Transaction) ([]string, error) {
+	profile := p.ovsProfile
+	if profile == nil {
+		return nil, nil
+	}
+
+	current, err := p.ovsClient.GetOpenVSwitchOtherConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error while getting the other_config of the Open_vSwitch table: %w", err)
+	}
+	var restartKeys []string
+	for _, key := range sortedKeys(profile.OtherConfig) {
+		value := profile.OtherConfig[key]
+		if value != "" {
+			p.desireOVSOtherConfig(key)
+		}
+		currentValue, ok := current[key]
+		switch {
+		case value == "" && !ok:
+			continue
+		case value == "":
+			p.logger.Info("Removing OVS other_config key", "key", key, "previous", currentValue)
+			ovsTxn.RemoveOpenVSwitchOtherConfig(key)
+		case value == currentValue:
+			continue
+		default:
+			p.logger.Info("Setting OVS other_config key", "key", key, "previous", currentValue, "value", value)
+			ovsTxn.SetOpenVSwitchOtherConfig(key, value)
+		}
+		if slices.Contains(ovsRestartRequiredKeys, key) {
+			restartKeys = append(restartKeys, key)
+		}
+	}
+	if profile.DOCAInit != nil {
+		p.desireOVSOtherConfig(docaInitOtherConfigKey)
+	}
+	if profile.DOCAInit != nil && current[docaInitOtherConfigKey] != strconv.FormatBool(*profile.DOCAInit) {
+		p.logger.Info("Setting OVS other_config key", "key", docaInitOtherConfigKey, "previous", current[docaInitOtherConfigKey], "value", *profile.DOCAInit)
+		ovsTxn.SetDOCAInit(*profile.DOCAInit)
+		restartKeys = append(restartKeys, docaInitOtherConfigKey)
+	}
+
+	for _, bridge := range sortedKeys(profile.BridgeDataPathTypes) {
+		exists, err := p.ovsClient.BridgeExists(bridge)
+		if err != nil {
+			return nil, fmt.Errorf("error while checking if bridge %s exists: %w", bridge, err)
+		}
+		if !exists {
+			p.logger.Info("Skipping the datapath type of a bridge that doesn't exist yet", "bridge", bridge)
+			continue
+		}
+		p.desireBridgeDataPathType(bridge)
+		dataPathType, err := p.ovsClient.GetBridgeDataPathType(bridge)
+		if err != nil {
+			return nil, fmt.Errorf("error while getting the datapath type of bridge %s: %w", bridge, err)
+		}
+		// An empty datapath type is the default one
+		if dataPathType == "" {
+			dataPathType = ovsclient.System
+		}
+		if desired := profile.BridgeDataPathTypes[bridge]; dataPathType != desired {
+			p.logger.Info("Setting the datapath type of bridge", "bridge", bridge, "previous", dataPathType, "datapathType", desired)
+			ovsTxn.SetBridgeDataPathType(bridge, desired)
+		}
+	}
+	return restartKeys, nil
+}
+
+// reconcileOVSRestart records the given other_config keys, which were just committed, as waiting for a restart of
+// ovs-vswitchd and restarts it when the profile allows it. The restart happens once all the changes of the run are
+// committed so that they take effect together, and at most once per ovsRestartCooldownDuration. When the profile
+// doesn't allow it, the restart is reported as pending until ovs-vswitchd is restarted, which is detected by its PID
+// changing.
+func (p *DPUCNIProvisioner) reconcileOVSRestart(changedKeys []string) error {
+	p.loadOVSRestartPending()
+	pid := p.vswitchdPID()
+	current := p.ovsRestartPending
+	if len(current.Keys) > 0 && current.VSwitchdPID != "" && pid != "" && current.VSwitchdPID != pid {
+		p.logger.Info("ovs-vswitchd was restarted, the OVS other_config keys took effect", "keys", current.Keys)
+		current = ovsRestartPending{}
+	}
+
+	pending := ovsRestartPending{Keys: slices.Clone(current.Keys)}
+	for _, key := range changedKeys {
+		if !slices.Contains(pending.Keys, key) {
+			pending.Keys = append(pending.Keys, key)
+		}
+	}
+	slices.Sort(pending.Keys)
+	if len(pending.Keys) > 0 {
+		pending.VSwitchdPID = current.VSwitchdPID
+		if pending.VSwitchdPID == "" {
+			pending.VSwitchdPID = pid
+		}
+	}
+	if !reflect.DeepEqual(pending, p.ovsRestartPending) {
+		if err := p.setOVSRestartPending(pending); err != nil {
+			return err
+		}
+		if len(pending.Keys) > 0 && !slices.Equal(pending.Keys, current.Keys) && !p.ovsProfile.restartEnabled() {
+			p.logger.Info("ovs-vswitchd must be restarted for OVS other_config keys to take effect", "keys", pending.Keys)
+			p.eventf(corev1.EventTypeWarning, EventReasonOVSRestartPending, "ovs-vswitchd must be restarted for OVS other_config keys %s to take effect", strings.Join(pending.Keys, ","))
+		}
+	}
+	if len(pending.Keys) == 0 || !p.ovsProfile.restartEnabled() {
+		return nil
+	}
+
+	if !p.ovsRestartAttemptedAt.IsZero() && p.ovsRestartAttemptedAt.Add(ovsRestartCooldownDuration).After(p.clock.Now()) {
+		p.logger.Info("ovs-vswitchd restart is in cool down period, skipping restart", "keys", pending.Keys)
+		return nil
+	}
+	command := p.ovsProfile.RestartCommand
+	if p.plan != nil {
+		p.plan.add(PlanChange{Kind: PlanChangeCommand, Action: PlanActionRun, Object: strings.Join(command, " ")})
+		return nil
+	}
+
+	p.ovsRestartAttemptedAt = p.clock.Now()
+	p.logger.Info("Restarting ovs-vswitchd for OVS other_config keys to take effect", "keys", pending.Keys)
+	if err := p.restartOVS(command); err != nil {
+		return err
+	}
+	p.eventf(corev1.EventTypeNormal, EventReasonOVSRestarted, "Restarted ovs-vswitchd for OVS other_config keys %s to take effect", strings.Join(pending.Keys, ","))
+	return p.setOVSRestartPending(ovsRestartPending{})
+}
+
+// restartOVS runs the command that restarts ovs-vswitchd
+func (p *DPUCNIProvisioner) restartOVS(command []string) error {
+	cmd := p.exec.Command(command[0], command[1:]...)
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	cmd.SetStdout(&stdout)
+	cmd.SetStderr(&stderr)
+	err := cmd.Run()
+	p.metrics.ovsRestarts.WithLabelValues(resultLabel(err)).Inc()
+	if err != nil {
+		err = fmt.Errorf("error while restarting ovs-vswitchd: stdout='%s' stderr='%s': %w", stdout.String(), stderr.String(), err)
+		p.eventf(corev1.EventTypeWarning, EventReasonOVSRestartFailed, "Restarting ovs-vswitchd failed: %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// vswitchdPID returns the PID of ovs-vswitchd. Empty when it can't be read, e.g. while ovs-vswitchd is down.
+func (p *DPUCNIProvisioner) vswitchdPID() string {
+	content, err := os.ReadFile(filepath.Join(p.FileSystemRoot, vswitchdPIDFilePath))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// ovsRestartPendingPath returns the path to the file that records the keys waiting for a restart of ovs-vswitchd.
+// Empty when the state directory is disabled, in which case they're only kept in memory.
+func (p *DPUCNIProvisioner) ovsRestartPendingPath() string {
+	if p.StateDir == "" {
+		return ""
+	}
+	return filepath.Join(p.FileSystemRoot, p.StateDir, ovsRestartPendingFileName)
+}
+
+// loadOVSRestartPending loads the keys waiting for a restart of ovs-vswitchd once per process. A file that can't be
+// read is ignored.
+func (p *DPUCNIProvisioner) loadOVSRestartPending() {
+	if p.ovsRestartPendingLoaded {
+		return
+	}
+	p.ovsRestartPendingLoaded = true
+	path := p.ovsRestartPendingPath()
+	if path == "" {
+		return
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		p.logger.Error(err, "Ignoring OVS restart pending file that can't be read", "path", path)
+		return
+	}
+	var pending ovsRestartPending
+	if err := json.Unmarshal(content, &pending); err != nil {
+		p.logger.Error(err, "Ignoring OVS restart pending file that can't be parsed", "path", path)
+		return
+	}
+	p.ovsRestartPending = pending
+	p.metrics.ovsRestartPending.Set(boolToFloat(len(pending.Keys) > 0))
+}
+
+// setOVSRestartPending records the keys waiting for a restart of ovs-vswitchd. The file is removed when there are
+// none. Nothing is recorded when planning.
+func (p *DPUCNIProvisioner) setOVSRestartPending(pending ovsRestartPending) error {
+	if p.plan != nil {
+		return nil
+	}
+	if path := p.ovsRestartPendingPath(); path != "" {
+		if len(pending.Keys) == 0 {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("error while removing %s: %w", path, err)
+			}
+		} else {
+			content, err := json.Marshal(pending)
+			if err != nil {
+				return fmt.Errorf("error while encoding the keys waiting for a restart of ovs-vswitchd: %w", err)
+			}
+			if err := writeFileAtomically(path, append(content, '\n'), 0600); err != nil {
+				return fmt.Errorf("error while writing %s: %w", path, err)
+			}
+		}
+	}
+	p.ovsRestartPending = pending
+	p.metrics.ovsRestartPending.Set(boolToFloat(len(pending.Keys) > 0))
+	return nil
+}
@@ -0,0 +1,416 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dpucniprovisioner
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/networkhelper"
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/ovsclient"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// PlanChangeKind is the kind of object a planned change applies to
+type PlanChangeKind string
+
+const (
+	PlanChangeOVS     PlanChangeKind = "ovs"
+	PlanChangeLink    PlanChangeKind = "link"
+	PlanChangeAddress PlanChangeKind = "address"
+	PlanChangeRoute   PlanChangeKind = "route"
+	PlanChangeRule    PlanChangeKind = "rule"
+	PlanChangeFile    PlanChangeKind = "file"
+	PlanChangeCommand PlanChangeKind = "command"
+	PlanChangeNode    PlanChangeKind = "node"
+)
+
+// PlanAction is what a planned change does to its object
+type PlanAction string
+
+const (
+	PlanActionAdd    PlanAction = "add"
+	PlanActionDelete PlanAction = "delete"
+	PlanActionSet    PlanAction = "set"
+	PlanActionRun    PlanAction = "run"
+)
+
+// PlanChange is a change a run of the provisioning flow would make to the system
+type PlanChange struct {
+	Kind   PlanChangeKind `json:"kind"`
+	Action PlanAction     `json:"action"`
+	// Object identifies the object the change applies to, e.g. the path of a file or the key of a route
+	Object string `json:"object"`
+	// Current and Desired are the values of the object before and after the change, when it has one
+	Current string `json:"current,omitempty"`
+	Desired string `json:"desired,omitempty"`
+	// Diff is the unified diff between the current and the desired content of a file
+	Diff string `json:"diff,omitempty"`
+}
+
+// Plan is the report of the changes a run of the provisioning flow would make to the system
+type Plan struct {
+	Mode    Mode         `json:"mode"`
+	Changes []PlanChange `json:"changes"`
+	// Error is the error that stopped the provisioning flow, in which case the changes of the remaining steps are
+	// unknown. It's typically a step that depends on a change being applied first, e.g. br-ovn getting its address via
+	// DHCP once its netplan file is applied.
+	Error string `json:"error,omitempty"`
+}
+
+// add records a change in the plan
+func (p *Plan) add(change PlanChange) {
+	p.Changes = append(p.Changes, change)
+}
+
+// String returns a human readable representation of the plan
+func (p *Plan) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Plan for %s mode: %d change(s)\n", p.Mode, len(p.Changes))
+	writeChanges(&b, p.Changes)
+	if p.Error != "" {
+		fmt.Fprintf(&b, "Planning stopped, the changes of the remaining steps are unknown: %s\n", p.Error)
+	}
+	return b.String()
+}
+
+// writeChanges writes a human readable representation of the changes, one per line followed by the diff, if any
+func writeChanges(b *strings.Builder, changes []PlanChange) {
+	for _, c := range changes {
+		fmt.Fprintf(b, "  %s %s %s", planActionSymbol(c.Action), c.Kind, c.Object)
+		switch {
+		case c.Current != "" && c.Desired != "":
+			fmt.Fprintf(b, ": %q -> %q", c.Current, c.Desired)
+		case c.Desired != "":
+			fmt.Fprintf(b, ": %s", c.Desired)
+		case c.Current != "":
+			fmt.Fprintf(b, ": %s", c.Current)
+		}
+		b.WriteString("\n")
+		for _, line := range strings.SplitAfter(strings.TrimSuffix(c.Diff, "\n"), "\n") {
+			if line != "" {
+				b.WriteString("      " + strings.TrimSuffix(line, "\n") + "\n")
+			}
+		}
+	}
+}
+
+// planActionSymbol returns the symbol the given action is prefixed with in the human readable plan
+func planActionSymbol(action PlanAction) string {
+	switch action {
+	case PlanActionAdd:
+		return "+"
+	case PlanActionDelete:
+		return "-"
+	case PlanActionRun:
+		return "!"
+	default:
+		return "~"
+	}
+}
+
+// Plan computes the changes a run of the provisioning flow would make to the system without making any of them. The
+// live system is read as usual, while every change is recorded in the returned plan instead of being applied. The
+// DHCP server is not started.
+func (p *DPUCNIProvisioner) Plan() *Plan {
+	plan := &Plan{Mode: p.mode, Changes: []PlanChange{}}
+	networkHelper, ovsClient := p.networkHelper, p.ovsClient
+	p.plan = plan
+	p.networkHelper = &planNetworkHelper{NetworkHelper: networkHelper, plan: plan}
+	p.ovsClient = &planOVSClient{OVSClient: ovsClient, plan: plan}
+	defer func() {
+		p.plan = nil
+		p.networkHelper, p.ovsClient = networkHelper, ovsClient
+	}()
+
+	p.applyPendingSettings()
+	if err := p.runConfigurationSteps(); err != nil {
+		plan.Error = err.Error()
+	}
+	return plan
+}
+
+// mkdirAll creates a directory along with any necessary parents. It's a no-op when planning, in which case the
+// directory is reported as part of the file written in it.
+func (p *DPUCNIProvisioner) mkdirAll(path string, perm os.FileMode) error {
+	if p.plan != nil {
+		return nil
+	}
+	return os.MkdirAll(path, perm)
+}
+
+// writeFile writes the content to the file at the given path. When planning, the difference between the current and
+// the given content is recorded instead.
+func (p *DPUCNIProvisioner) writeFile(path string, content []byte, perm os.FileMode) error {
+	p.desireFile(path, content)
+	if p.plan == nil {
+		return os.WriteFile(path, content, perm)
+	}
+
+	current, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error while reading %s: %w", path, err)
+	}
+	if string(current) == string(content) {
+		return nil
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(current)),
+		B:        difflib.SplitLines(string(content)),
+		FromFile: "current",
+		ToFile:   "desired",
+		Context:  3,
+	})
+	if err != nil {
+		return fmt.Errorf("error while computing the diff of %s: %w", path, err)
+	}
+	p.plan.add(PlanChange{Kind: PlanChangeFile, Action: PlanActionSet, Object: path, Diff: diff})
+	return nil
+}
+
+// planNetworkHelper is a NetworkHelper that reads from the live system and records every change in a plan instead of
+// applying it
+type planNetworkHelper struct {
+	networkhelper.NetworkHelper
+	plan *Plan
+}
+
+func (n *planNetworkHelper) SetLinkUp(link string) error {
+	up, err := n.NetworkHelper.LinkAdminUp(link)
+	if err != nil {
+		return err
+	}
+	if !up {
+		n.plan.add(PlanChange{Kind: PlanChangeLink, Action: PlanActionSet, Object: link, Desired: "up"})
+	}
+	return nil
+}
+
+func (n *planNetworkHelper) SetLinkMTU(link string, mtu int) error {
+	current, err := n.NetworkHelper.GetLinkMTU(link)
+	if err != nil {
+		return err
+	}
+	n.plan.add(PlanChange{Kind: PlanChangeLink, Action: PlanActionSet, Object: link + " mtu", Current: strconv.Itoa(current), Desired: strconv.Itoa(mtu)})
+	return nil
+}
+
+func (n *planNetworkHelper) SetLinkDown(link string) error {
+	n.plan.add(PlanChange{Kind: PlanChangeLink, Action: PlanActionSet, Object: link, Desired: "down"})
+	return nil
+}
+
+func (n *planNetworkHelper) RenameLink(link string, newName string) error {
+	n.plan.add(PlanChange{Kind: PlanChangeLink, Action: PlanActionSet, Object: link, Current: link, Desired: newName})
+	return nil
+}
+
+func (n *planNetworkHelper) AddDummyLink(link string) error {
+	n.plan.add(PlanChange{Kind: PlanChangeLink, Action: PlanActionAdd, Object: link, Desired: "dummy"})
+	return nil
+}
+
+func (n *planNetworkHelper) SetLinkIPAddress(link string, ipNet *net.IPNet) error {
+	n.plan.add(PlanChange{Kind: PlanChangeAddress, Action: PlanActionAdd, Object: ipNet.String() + " dev " + link})
+	return nil
+}
+
+func (n *planNetworkHelper) DeleteLinkIPAddress(link string, ipNet *net.IPNet) error {
+	n.plan.add(PlanChange{Kind: PlanChangeAddress, Action: PlanActionDelete, Object: ipNet.String() + " dev " + link})
+	return nil
+}
+
+func (n *planNetworkHelper) DeleteNeighbor(ip net.IP, device string) error {
+	n.plan.add(PlanChange{Kind: PlanChangeAddress, Action: PlanActionDelete, Object: "neighbor " + ip.String() + " dev " + device})
+	return nil
+}
+
+func (n *planNetworkHelper) AddRoute(network *net.IPNet, gateway net.IP, device string, metric *int, table *int) error {
+	change := PlanChange{Kind: PlanChangeRoute, Action: PlanActionAdd, Object: routeKey(network, gateway, device, table)}
+	if metric != nil {
+		change.Desired = "metric " + strconv.Itoa(*metric)
+	}
+	n.plan.add(change)
+	return nil
+}
+
+func (n *planNetworkHelper) DeleteRoute(network *net.IPNet, gateway net.IP, device string) error {
+	return n.DeleteRouteFromTable(network, gateway, device, nil)
+}
+
+func (n *planNetworkHelper) DeleteRouteFromTable(network *net.IPNet, gateway net.IP, device string, table *int) error {
+	n.plan.add(PlanChange{Kind: PlanChangeRoute, Action: PlanActionDelete, Object: routeKey(network, gateway, device, table)})
+	return nil
+}
+
+func (n *planNetworkHelper) AddRule(src *net.IPNet, table int, priority int) error {
+	n.plan.add(PlanChange{Kind: PlanChangeRule, Action: PlanActionAdd, Object: ruleKey(src, table, priority)})
+	return nil
+}
+
+func (n *planNetworkHelper) DeleteRule(src *net.IPNet, table int, priority int) error {
+	n.plan.add(PlanChange{Kind: PlanChangeRule, Action: PlanActionDelete, Object: ruleKey(src, table, priority)})
+	return nil
+}
+
+// planOVSClient is an OVSClient whose transactions record the changes of the Open_vSwitch table in a plan instead of
+// applying them. The provisioning flow only changes OVS via transactions.
+type planOVSClient struct {
+	ovsclient.OVSClient
+	plan *Plan
+}
+
+func (c *planOVSClient) Transaction() ovsclient.Transaction {
+	return &planTransaction{client: c}
+}
+
+// planTransaction is a Transaction that, on Commit, records the external IDs of the Open_vSwitch table whose values
+// differ from the queued ones. Any other queued change is recorded as is.
+type planTransaction struct {
+	client      *planOVSClient
+	externalIDs map[string]string
+	keys        []string
+	removedKeys []string
+	changes     []PlanChange
+}
+
+// setExternalID queues setting an external ID of the Open_vSwitch table
+func (t *planTransaction) setExternalID(key string, value string) {
+	if t.externalIDs == nil {
+		t.externalIDs = map[string]string{}
+	}
+	if _, ok := t.externalIDs[key]; !ok {
+		t.keys = append(t.keys, key)
+	}
+	t.externalIDs[key] = value
+}
+
+// set queues a change to an OVS row other than the one of the Open_vSwitch table
+func (t *planTransaction) set(object string, value string) {
+	t.changes = append(t.changes, PlanChange{Kind: PlanChangeOVS, Action: PlanActionSet, Object: object, Desired: value})
+}
+
+func (t *planTransaction) SetBridgeDataPathType(bridge string, bridgeType ovsclient.BridgeDataPathType) {
+	t.set("Bridge "+bridge+" datapath_type", string(bridgeType))
+}
+
+func (t *planTransaction) SetBridgeMAC(bridge string, mac net.HardwareAddr) {
+	t.set("Bridge "+bridge+" other_config:hwaddr", mac.String())
+}
+
+func (t *planTransaction) SetBridgeUplinkPort(bridge string, port string) {
+	t.set("Bridge "+bridge+" external_ids:bridge-uplink", port)
+}
+
+func (t *planTransaction) SetBridgeHostToServicePort(bridge string, port string) {
+	t.set("Bridge "+bridge+" external_ids:host-to-service-interface", port)
+}
+
+func (t *planTransaction) SetPortExternalID(port string, key string, value string) {
+	t.set("Port "+port+" external_ids:"+key, value)
+}
+
+func (t *planTransaction) SetPortType(port string, portType ovsclient.PortType) {
+	t.set("Interface "+port+" type", string(portType))
+}
+
+func (t *planTransaction) SetPatchPortPeer(port string, peer string) {
+	t.set("Interface "+port+" options:peer", peer)
+}
+
+func (t *planTransaction) SetInterfaceExternalID(iface string, key string, value string) {
+	t.set("Interface "+iface+" external_ids:"+key, value)
+}
+
+func (t *planTransaction) SetOVNEncapIP(ip net.IP) {
+	t.setExternalID("ovn-encap-ip", ip.String())
+}
+
+func (t *planTransaction) SetOVNEncapIPs(ips []net.IP) {
+	values := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		values = append(values, ip.String())
+	}
+	t.setExternalID("ovn-encap-ip", strings.Join(values, ","))
+}
+
+func (t *planTransaction) SetDOCAInit(enable bool) {
+	t.set("Open_vSwitch other_config:doca-init", strconv.FormatBool(enable))
+}
+
+func (t *planTransaction) SetOpenVSwitchOtherConfig(key string, value string) {
+	t.set("Open_vSwitch other_config:"+key, value)
+}
+
+func (t *planTransaction) RemoveOpenVSwitchOtherConfig(key string) {
+	t.changes = append(t.changes, PlanChange{Kind: PlanChangeOVS, Action: PlanActionDelete, Object: "Open_vSwitch other_config:" + key})
+}
+
+func (t *planTransaction) SetKubernetesHostNodeName(name string) {
+	t.setExternalID("host-k8s-nodename", name)
+}
+
+func (t *planTransaction) SetHostName(name string) {
+	t.setExternalID("hostname", name)
+}
+
+func (t *planTransaction) RemoveOVNEncapIP() {
+	t.removedKeys = append(t.removedKeys, "ovn-encap-ip")
+}
+
+func (t *planTransaction) RemoveKubernetesHostNodeName() {
+	t.removedKeys = append(t.removedKeys, "host-k8s-nodename")
+}
+
+func (t *planTransaction) RemoveHostName() {
+	t.removedKeys = append(t.removedKeys, "hostname")
+}
+
+func (t *planTransaction) Commit() error {
+	if len(t.keys) > 0 || len(t.removedKeys) > 0 {
+		current, err := t.client.GetOpenVSwitchExternalIDs()
+		if err != nil {
+			return fmt.Errorf("error while getting the external IDs of the Open_vSwitch table: %w", err)
+		}
+		for _, key := range t.keys {
+			if current[key] == t.externalIDs[key] {
+				continue
+			}
+			t.client.plan.add(PlanChange{
+				Kind:    PlanChangeOVS,
+				Action:  PlanActionSet,
+				Object:  "Open_vSwitch external_ids:" + key,
+				Current: current[key],
+				Desired: t.externalIDs[key],
+			})
+		}
+		for _, key := range t.removedKeys {
+			if value, ok := current[key]; ok {
+				t.client.plan.add(PlanChange{Kind: PlanChangeOVS, Action: PlanActionDelete, Object: "Open_vSwitch external_ids:" + key, Current: value})
+			}
+		}
+	}
+	for _, change := range t.changes {
+		t.client.plan.add(change)
+	}
+	return nil
+}
@@ -26,17 +26,21 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	provisioningv1 "github.com/nvidia/doca-platform/api/provisioning/v1alpha1"
-	"github.com/nvidia/doca-platform/pkg/utils/networkhelper"
 	"github.com/nvidia/ovn-kubernetes-components/internal/constants"
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/logging"
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/networkhelper"
 	"github.com/nvidia/ovn-kubernetes-components/internal/utils/ovsclient"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8stypes "k8s.io/apimachinery/pkg/types"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/clock"
 	kexec "k8s.io/utils/exec"
@@ -61,13 +65,6 @@ const (
 	geneveHeaderSize = 60
 	// maxMTUSize is the maximum MTU size that can be set on a network interface.
 	maxMTUSize = 9216
-	// flannelInterface is the flannel interface that has an IP address in the network where the pods on the local node
-	// will get IPs from.
-	flannelInterface = "cni0"
-	// oobInterface is the out of band interface used by the DPU. This interface is hardcoded to the bridge name used in
-	// the provisioning controller.
-	// TODO: Consider discovering that in the future
-	oobInterface = "br-comm-ch"
 	// sourceRoutingTable is the route table used for source routing
 	sourceRoutingTable = 60
 )
@@ -98,14 +95,33 @@ const (
 )
 
 type DPUCNIProvisioner struct {
-	ctx                        context.Context
-	clock                      clock.Clock
-	ensureConfigurationTicker  clock.Ticker
+	ctx                       context.Context
+	clock                     clock.Clock
+	ensureConfigurationTicker clock.Ticker
+	// eventSources notify the provisioner when state it owns may have drifted
+	eventSources []EventSource
+	// reconcileRequests receives the reason of every reconcile requested by an EventSource
+	reconcileRequests chan string
+	// metrics are the Prometheus collectors of the provisioner
+	metrics *metrics
+	// readiness is the state the readiness of the provisioner is derived from. Guarded by readinessLock.
+	readiness                  readiness
+	readinessLock              sync.Mutex
 	ovsClient                  ovsclient.OVSClient
 	networkHelper              networkhelper.NetworkHelper
 	exec                       kexec.Interface
 	dpuClusterKubernetesClient kubernetes.Interface
 	hostKubernetesClient       client.Client
+	// eventRecorder and hostEventRecorder emit Events against the DPU Node and the host Node respectively
+	eventRecorder     record.EventRecorder
+	hostEventRecorder record.EventRecorder
+	// hostNodeName is the name of the host Node the provisioning flow last found
+	hostNodeName string
+	// baseLogger is the logger of the provisioner. It's used by the goroutines that run next to the provisioning flow.
+	baseLogger klog.Logger
+	// logger is the logger of the ongoing run of the provisioning flow, which adds the reconcile ID, the host Node and
+	// the step to baseLogger. It's only used by the goroutine that runs the provisioning flow.
+	logger klog.Logger
 
 	// FileSystemRoot controls the file system root. It's used for enabling easier testing of the package. Defaults to
 	// empty.
@@ -117,44 +133,106 @@ type DPUCNIProvisioner struct {
 	BootstrapKubeconfigPath string
 	// HostNodeNameFilePath is where the mapped host node name is written. Defaults to hostNodeNameFilePath.
 	HostNodeNameFilePath string
-
-	// vtepIPNet is the IP that should be added to the VTEP interface.
-	vtepIPNet *net.IPNet
-	// gateway is the gateway IP that is configured on the routes related to OVN Kubernetes reaching its peer nodes
-	// when traffic needs to go from one Pod running on Node A to another Pod running on Node B.
-	gateway net.IP
-	// vtepCIDR is the CIDR in which all the VTEP IPs of all the DPUs in the DPU cluster belong to. This CIDR is
-	// configured on the routes related to traffic that needs to go from one Pod running on worker Node A to another Pod
-	// running on worker Node B.
-	vtepCIDR *net.IPNet
-	// hostCIDR is the CIDR of the host machines that is configured on the routes related to OVN Kubernetes reaching
-	// its peer nodes when traffic needs to go from one Pod running on worker Node A to another Pod running on control
-	// plane A (and vice versa).
-	hostCIDR *net.IPNet
-	// pfIP is the IP that should be added to the PF on the host
-	pfIP *net.IPNet
+	// CNIConfDir is the directory the flannel interface is discovered from. Defaults to dpuCNIConfDir.
+	CNIConfDir string
+	// StateDir is the directory the journal of the applied state is kept in. Defaults to defaultStateDir. The journal
+	// is disabled when empty.
+	StateDir string
+	// OVNKConfFragmentPath is an INI file whose sections and keys are merged into ovn_k8s.conf. It's read on every run
+	// of the provisioning flow and can't set the keys the provisioner manages. Disabled when empty.
+	OVNKConfFragmentPath string
+
+	// desiredRoutes and desiredRules are the keys of the routes and rules the ongoing run of the provisioning flow
+	// configures. Every other route and rule owned by the provisioner is deleted at the end of the run.
+	desiredRoutes map[string]bool
+	desiredRules  map[string]bool
+	// desiredState is the state the ongoing run of the provisioning flow applies
+	desiredState *AppliedState
+	// appliedState is the state the last successful run of the provisioning flow applied. Guarded by appliedStateLock.
+	appliedState     *AppliedState
+	appliedStateLock sync.Mutex
+	// appliedStateLoaded is whether the state of the journal was loaded
+	appliedStateLoaded bool
+	// publishedNodeStatus is the status last published on the DPU Node and nodeStatusPublishedAt is when
+	publishedNodeStatus   *nodeStatus
+	nodeStatusPublishedAt time.Time
+
+	// interfaceOverrides are the interfaces that are used instead of the discovered ones
+	interfaceOverrides Interfaces
+	// interfaces are the interfaces selected by the ongoing run of the provisioning flow. Guarded by interfacesLock.
+	interfaces     selectedInterfaces
+	interfacesLock sync.Mutex
+	// uplinkSettings are the uplinks in addition to br-ovn and how the geneve tunnels use them. Guarded by
+	// interfacesLock for writes and for reads outside of the provisioning flow.
+	uplinkSettings Uplinks
+	// encapBridges are the bridges of the uplinks whose VTEP IPs are used as geneve encap IPs
+	encapBridges []string
+
+	// ipFamilies is the addressing per IP family. The first one is the primary IP family, a second one is present in
+	// dual-stack deployments.
+	ipFamilies []*ipFamilyConfig
+	// pendingSettings are the settings given to UpdateSettings that the next run of the provisioning flow applies.
+	// Guarded by pendingSettingsLock.
+	pendingSettings     *pendingSettings
+	pendingSettingsLock sync.Mutex
 	// dpuHostName is the name of the DPU.
 	dpuHostName string
-	// gatewayDiscoveryNetwork is the network from which the DPUCNIProvisioner discovers the gateway that it should be
-	// on relevant underlying systems.
-	gatewayDiscoveryNetwork *net.IPNet
 
-	// dhcpCmd is the struct that holds information about the DHCP Server process
-	dhcpCmd kexec.Cmd
+	// dhcpServer is the supervised DHCP Server process. Guarded by dhcpServerLock.
+	dhcpServer     *dhcpServer
+	dhcpServerLock sync.Mutex
+	// dhcpServerBackend is the implementation of the DHCP server
+	dhcpServerBackend DHCPServerBackend
 	// mode is the mode in which the CNI provisioner is running
 	mode Mode
-	// ovnMTU is the MTU that is configured for OVN
+	// gatewayDiscovery is how the gateway of br-ovn is discovered in ExternalIPAM mode
+	gatewayDiscovery GatewayDiscovery
+	// ovnMTU is the MTU that is configured for OVN. It's derived from the MTU of the uplinks when 0.
 	ovnMTU int
+	// resolvedMTU is the MTU for OVN the provisioning flow last resolved and resolvedMTUSource is where it comes from
+	resolvedMTU       int
+	resolvedMTUSource MTUSource
+	// pfMTUStatus is whether the PFs got the MTU via DHCP
+	pfMTUStatus PFMTUStatus
+	// plan, when set, is the plan the changes of the ongoing run of the provisioning flow are recorded in instead of
+	// being applied
+	plan *Plan
 
 	// writeDPUNodeLeaseToOVNKConf, when true, adds [ovnkubenode] dpu-node-lease-* keys to ovn_k8s.conf.
 	writeDPUNodeLeaseToOVNKConf bool
-	dpuNodeLeaseRenewInterval  int
-	dpuNodeLeaseDuration       int
+	dpuNodeLeaseRenewInterval   int
+	dpuNodeLeaseDuration        int
 
 	// writeOVNKConfigNamespaceToOVNKConf, when true, adds [kubernetes] ovn-config-namespace to ovn_k8s.conf (upstream
 	// Kubernetes.OVNConfigNamespace; DPU leases and other config objects use this namespace).
 	writeOVNKConfigNamespaceToOVNKConf bool
-	ovnConfigNamespace               string
+	ovnConfigNamespace                 string
+
+	// ovnKubeNodeNotifier notifies ovnkube-node of changes of ovn_k8s.conf
+	ovnKubeNodeNotifier OVNKubeNodeNotifier
+	// ovnKubeNodeNotifyPending is whether ovn_k8s.conf changed without ovnkube-node being notified successfully.
+	// ovnKubeNodeNotifyPendingLoaded is whether it was loaded from the state directory.
+	ovnKubeNodeNotifyPending       bool
+	ovnKubeNodeNotifyPendingLoaded bool
+
+	// vtepProbe is how the VTEPs of the other DPUs are probed
+	vtepProbe VTEPProbe
+	// vtepProbeOffset is where the next sample of the VTEPs starts. The fields of the VTEP probe are only used by the
+	// goroutine that probes the VTEPs.
+	vtepProbeOffset int
+	// publishedVTEPConnectivity is the VTEP connectivity condition last published on the DPU Node and
+	// vtepConnectivityPublishedAt is when
+	publishedVTEPConnectivity   *corev1.NodeCondition
+	vtepConnectivityPublishedAt time.Time
+
+	// ovsProfile is the datapath profile of OVS the provisioning flow applies
+	ovsProfile *OVSProfile
+	// ovsRestartPending are the other_config keys that wait for a restart of ovs-vswitchd to take effect
+	ovsRestartPending ovsRestartPending
+	// ovsRestartPendingLoaded is whether the keys of a previous process were loaded
+	ovsRestartPendingLoaded bool
+	// ovsRestartAttemptedAt is when the provisioner last tried to restart ovs-vswitchd
+	ovsRestartAttemptedAt time.Time
 }
 
 // New creates a DPUCNIProvisioner that can configure the system
@@ -167,17 +245,20 @@ func New(ctx context.Context,
 	kubernetesClient kubernetes.Interface,
 	vtepIPNet *net.IPNet,
 	gateway net.IP,
-	vtepCIDR *net.IPNet,
-	hostCIDR *net.IPNet,
+	vtepCIDRs []*net.IPNet,
+	hostCIDRs []*net.IPNet,
 	pfIP *net.IPNet,
 	dpuHostName string,
 	gatewayDiscoveryNetwork *net.IPNet,
 	ovnMTU int,
 ) *DPUCNIProvisioner {
+	logger := klog.LoggerWithValues(klog.FromContext(ctx), logging.KeyDPUNode, dpuHostName)
 	return &DPUCNIProvisioner{
 		ctx:                        ctx,
 		clock:                      clock,
 		ensureConfigurationTicker:  clock.NewTicker(30 * time.Second),
+		reconcileRequests:          make(chan string, 1),
+		metrics:                    newMetrics(),
 		ovsClient:                  ovsClient,
 		networkHelper:              networkHelper,
 		exec:                       exec,
@@ -186,15 +267,15 @@ func New(ctx context.Context,
 		K8sAPIServer:               "",
 		BootstrapKubeconfigPath:    hostBootstrapKubeconfigPath,
 		HostNodeNameFilePath:       hostNodeNameFilePath,
-		vtepIPNet:                  vtepIPNet,
-		gateway:                    gateway,
-		vtepCIDR:                   vtepCIDR,
-		hostCIDR:                   hostCIDR,
-		pfIP:                       pfIP,
+		CNIConfDir:                 dpuCNIConfDir,
+		StateDir:                   defaultStateDir,
+		ipFamilies:                 []*ipFamilyConfig{newIPFamilyConfig(vtepIPNet, gateway, vtepCIDRs, hostCIDRs, pfIP, gatewayDiscoveryNetwork)},
 		dpuHostName:                dpuHostName,
 		mode:                       mode,
-		gatewayDiscoveryNetwork:    gatewayDiscoveryNetwork,
 		ovnMTU:                     ovnMTU,
+		dhcpServerBackend:          DNSMasqDHCPServer,
+		baseLogger:                 logger,
+		logger:                     logger,
 	}
 }
 
@@ -230,12 +311,12 @@ func (p *DPUCNIProvisioner) RunOnce() error {
 	if err := p.configure(); err != nil {
 		return err
 	}
-	klog.Info("Configuration complete.")
+	p.logger.Info("Configuration complete.")
 	if p.mode == InternalIPAM {
 		if err := p.startDHCPServer(); err != nil {
 			return fmt.Errorf("error while starting DHCP server: %w", err)
 		}
-		klog.Info("DHCP Server started.")
+		p.logger.Info("DHCP Server started.")
 	}
 
 	return nil
@@ -244,62 +325,172 @@ func (p *DPUCNIProvisioner) RunOnce() error {
 // Stop stops the provisioner
 func (p *DPUCNIProvisioner) Stop() {
 	if p.mode == InternalIPAM {
-		p.dhcpCmd.Stop()
+		p.stopDHCPServer()
 	}
 
-	klog.Info("Provisioner stopped")
+	p.baseLogger.Info("Provisioner stopped")
 }
 
-// EnsureConfiguration ensures that particular configuration is in place. This is a blocking function.
+// EnsureConfiguration ensures that particular configuration is in place. This is a blocking function. The
+// configuration is reconciled shortly after any of the registered EventSources reports a change, while the periodic
+// ticker acts as a safety net for changes that are not observed.
 func (p *DPUCNIProvisioner) EnsureConfiguration() {
+	for _, source := range p.eventSources {
+		go p.runEventSource(source)
+	}
+
+	var debounce <-chan time.Time
 	for {
 		select {
 		case <-p.ctx.Done():
 			return
 		case <-p.ensureConfigurationTicker.C():
-			if err := p.configure(); err != nil {
-				klog.Errorf("failed to ensure configuration: %s", err.Error())
+			p.reconcile()
+		case reason := <-p.reconcileRequests:
+			if debounce == nil {
+				p.baseLogger.Info("Reconcile requested", "reason", reason)
+				debounce = p.clock.After(reconcileDebounceDuration)
 			}
+		case <-debounce:
+			debounce = nil
+			p.reconcile()
 		}
 	}
 }
 
-// configure runs the provisioning flow once
+// reconcile runs the provisioning flow and logs any error
+func (p *DPUCNIProvisioner) reconcile() {
+	if p.cleanupStarted() {
+		p.baseLogger.Info("Skipping reconciliation, the state of the provisioner is being removed")
+		return
+	}
+	p.removeExpiredCleanupMarker()
+	if err := p.configure(); err != nil {
+		p.logger.Error(err, "failed to ensure configuration")
+		return
+	}
+	if p.mode == InternalIPAM {
+		if err := p.reconcileDHCPServer(); err != nil {
+			p.logger.Error(err, "failed to reconcile DHCP server")
+		}
+	}
+}
+
+// configure runs the provisioning flow once and records its metrics. Every run logs with its own reconcile ID so that
+// it can be traced end to end.
 func (p *DPUCNIProvisioner) configure() error {
-	klog.Info("Configuring Kubernetes host name in OVS")
-	hostName, err := p.findAndSetKubernetesHostNameInOVS()
+	p.logger = klog.LoggerWithValues(p.baseLogger, logging.KeyReconcileID, utilrand.String(10))
+	p.applyPendingSettings()
+	start := p.clock.Now()
+	err := p.runConfigurationSteps()
+	p.metrics.observeReconcile(p.clock.Since(start), err)
+	p.recordConfigurationResult(err)
+	p.publishNodeStatus(err)
 	if err != nil {
+		p.eventf(corev1.EventTypeWarning, EventReasonConfigurationFailed, "Configuration failed: %s", err.Error())
+	}
+	return err
+}
+
+// runConfigurationSteps runs every step of the provisioning flow. All the OVS changes are queued in a single
+// transaction which is committed once every value is known, so that a failure in between can't leave the Open_vSwitch
+// table half updated.
+func (p *DPUCNIProvisioner) runConfigurationSteps() error {
+	ovsTxn := p.ovsClient.Transaction()
+	p.desiredRoutes = map[string]bool{}
+	p.desiredRules = map[string]bool{}
+	p.startDesiredState()
+
+	p.logger.Info("Selecting interfaces", logging.KeyStep, StepInterfaces)
+	if err := p.runStep(StepInterfaces, p.selectInterfaces); err != nil {
+		return fmt.Errorf("error while selecting interfaces: %w", err)
+	}
+
+	p.logger.Info("Configuring Kubernetes host name in OVS", logging.KeyStep, StepHostName)
+	var hostName string
+	if err := p.runStep(StepHostName, func() error {
+		var err error
+		hostName, err = p.findAndSetKubernetesHostNameInOVS(ovsTxn)
+		return err
+	}); err != nil {
 		return fmt.Errorf("error while setting the Kubernetes Host Name in OVS: %w", err)
 	}
-	if err := p.writeHostIdentityBootstrapArtifacts(hostName); err != nil {
+	p.hostNodeName = hostName
+	p.logger = klog.LoggerWithValues(p.logger, logging.KeyHostNode, hostName)
+	if err := p.runStep(StepBootstrapArtifacts, func() error {
+		return p.writeHostIdentityBootstrapArtifacts(hostName)
+	}); err != nil {
 		return fmt.Errorf("error while writing host identity bootstrap artifacts: %w", err)
 	}
-	if err := p.reconcileHostNodeChassisID(hostName); err != nil {
+	if err := p.runStep(StepChassisID, func() error {
+		return p.reconcileHostNodeChassisID(hostName)
+	}); err != nil {
 		return fmt.Errorf("error while reconciling host node chassis annotation: %w", err)
 	}
 
 	if p.mode == ExternalIPAM {
-		klog.Info("Configuring br-ovn")
-		if err := p.configureBROVN(); err != nil {
+		p.logger.Info("Configuring br-ovn", logging.KeyStep, StepBROVN)
+		if err := p.runStep(StepBROVN, p.configureBROVN); err != nil {
 			return fmt.Errorf("error while configuring br-ovn: %w", err)
 		}
 	}
 
-	klog.Info("Configuring system to enable pod to pod on different node connectivity")
-	if err := p.configurePodToPodOnDifferentNodeConnectivity(); err != nil {
+	p.logger.Info("Configuring the MTU", logging.KeyStep, StepMTU)
+	if err := p.runStep(StepMTU, p.configureMTU); err != nil {
+		return fmt.Errorf("error while configuring the MTU: %w", err)
+	}
+
+	p.logger.Info("Configuring system to enable pod to pod on different node connectivity", logging.KeyStep, StepPodToPod)
+	if err := p.runStep(StepPodToPod, func() error {
+		return p.configurePodToPodOnDifferentNodeConnectivity(ovsTxn)
+	}); err != nil {
 		return err
 	}
 
-	klog.Info("Writing OVN Kubernetes expected input files")
-	if err := p.writeFilesForOVN(); err != nil {
+	p.logger.Info("Configuring the OVS profile", logging.KeyStep, StepOVSProfile)
+	var ovsRestartKeys []string
+	if err := p.runStep(StepOVSProfile, func() error {
+		var err error
+		ovsRestartKeys, err = p.configureOVSProfile(ovsTxn)
 		return err
+	}); err != nil {
+		return fmt.Errorf("error while configuring the OVS profile: %w", err)
+	}
+
+	p.logger.Info("Applying OVS configuration", logging.KeyStep, StepOVSConfiguration)
+	if err := p.runStep(StepOVSConfiguration, ovsTxn.Commit); err != nil {
+		return fmt.Errorf("error while applying OVS configuration: %w", err)
+	}
+
+	p.logger.Info("Reconciling the restart of ovs-vswitchd", logging.KeyStep, StepOVSRestart)
+	if err := p.runStep(StepOVSRestart, func() error {
+		return p.reconcileOVSRestart(ovsRestartKeys)
+	}); err != nil {
+		return fmt.Errorf("error while reconciling the restart of ovs-vswitchd: %w", err)
 	}
 
-	klog.Info("Configuring symmetric routing")
-	if err := p.configureSymmetricRouting(); err != nil {
+	p.logger.Info("Writing OVN Kubernetes expected input files", logging.KeyStep, StepOVNFiles)
+	if err := p.runStep(StepOVNFiles, p.writeFilesForOVN); err != nil {
 		return err
 	}
 
+	p.logger.Info("Configuring symmetric routing", logging.KeyStep, StepSymmetricRouting)
+	if err := p.runStep(StepSymmetricRouting, p.configureSymmetricRouting); err != nil {
+		return err
+	}
+
+	p.logger.Info("Removing routes and rules that are no longer needed", logging.KeyStep, StepGarbageCollection)
+	if err := p.runStep(StepGarbageCollection, p.deleteStaleRoutesAndRules); err != nil {
+		return err
+	}
+
+	p.logger.Info("Recording the applied state", logging.KeyStep, StepStateJournal)
+	if err := p.runStep(StepStateJournal, func() error {
+		return p.commitDesiredState(hostName)
+	}); err != nil {
+		return fmt.Errorf("error while recording the applied state: %w", err)
+	}
+
 	return nil
 }
 
@@ -307,7 +498,7 @@ func (p *DPUCNIProvisioner) configure() error {
 // system-id. This allows ovnkube-node to re-register after DPU reprovisioning.
 func (p *DPUCNIProvisioner) reconcileHostNodeChassisID(hostName string) error {
 	if p.hostKubernetesClient == nil {
-		klog.Info("Skipping host-cluster chassis-id reconciliation (no host-cluster client)")
+		p.logger.Info("Skipping host-cluster chassis-id reconciliation (no host-cluster client)")
 		return nil
 	}
 
@@ -327,26 +518,31 @@ func (p *DPUCNIProvisioner) reconcileHostNodeChassisID(hostName string) error {
 	current := strings.TrimSpace(node.Annotations[hostNodeChassisIDAnnotationKey])
 	switch {
 	case current == "":
-		klog.Infof("Host cluster node %s has no %s annotation; no cleanup needed", hostName, hostNodeChassisIDAnnotationKey)
+		p.logger.Info("Host cluster node has no chassis-id annotation; no cleanup needed", "annotation", hostNodeChassisIDAnnotationKey)
 		return nil
 	case current == systemID:
-		klog.Infof("Host cluster node %s already has matching %s=%s", hostName, hostNodeChassisIDAnnotationKey, systemID)
+		p.logger.Info("Host cluster node already has a matching chassis-id annotation", "annotation", hostNodeChassisIDAnnotationKey, "systemID", systemID)
 		return nil
 	}
 
-	klog.Infof("Removing stale %s=%s from host cluster node %s to allow reprovisioned DPU system-id %s to register", hostNodeChassisIDAnnotationKey, current, hostName, systemID)
+	if p.plan != nil {
+		p.plan.add(PlanChange{Kind: PlanChangeNode, Action: PlanActionDelete, Object: hostName + " annotation " + hostNodeChassisIDAnnotationKey, Current: current})
+		return nil
+	}
+	p.logger.Info("Removing stale chassis-id annotation from host cluster node to allow the reprovisioned DPU to register", "annotation", hostNodeChassisIDAnnotationKey, "current", current, "systemID", systemID)
 	base := node.DeepCopy()
 	delete(node.Annotations, hostNodeChassisIDAnnotationKey)
 	if err := p.hostKubernetesClient.Patch(p.ctx, node, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{})); err != nil {
 		return fmt.Errorf("error while removing stale %s annotation from host node %s: %w", hostNodeChassisIDAnnotationKey, hostName, err)
 	}
-	klog.Infof("Removed stale %s=%s from host cluster node %s", hostNodeChassisIDAnnotationKey, current, hostName)
+	p.logger.Info("Removed stale chassis-id annotation from host cluster node", "annotation", hostNodeChassisIDAnnotationKey, "current", current)
+	p.eventf(corev1.EventTypeNormal, EventReasonStaleChassisIDRemoved, "Removed stale %s=%s to allow the reprovisioned DPU with system-id %s to register", hostNodeChassisIDAnnotationKey, current, systemID)
 
 	return nil
 }
 
-// findAndSetKubernetesHostNameInOVS discovers and sets the Kubernetes Host Name in OVS
-func (p *DPUCNIProvisioner) findAndSetKubernetesHostNameInOVS() (string, error) {
+// findAndSetKubernetesHostNameInOVS discovers the Kubernetes Host Name and queues setting it in OVS
+func (p *DPUCNIProvisioner) findAndSetKubernetesHostNameInOVS(ovsTxn ovsclient.Transaction) (string, error) {
 	nodeClient := p.dpuClusterKubernetesClient.CoreV1().Nodes()
 	n, err := nodeClient.Get(p.ctx, p.dpuHostName, metav1.GetOptions{})
 	if err != nil {
@@ -368,12 +564,8 @@ func (p *DPUCNIProvisioner) findAndSetKubernetesHostNameInOVS() (string, error)
 		return "", fmt.Errorf("label %s on node %s cannot be empty", labelKey, p.dpuHostName)
 	}
 
-	if err := p.ovsClient.SetKubernetesHostNodeName(hostName); err != nil {
-		return "", fmt.Errorf("error while setting the Kubernetes Host Name in OVS: %w", err)
-	}
-	if err := p.ovsClient.SetHostName(hostName); err != nil {
-		return "", fmt.Errorf("error while setting the hostname external ID in OVS: %w", err)
-	}
+	ovsTxn.SetKubernetesHostNodeName(hostName)
+	ovsTxn.SetHostName(hostName)
 	return hostName, nil
 }
 
@@ -389,10 +581,10 @@ func (p *DPUCNIProvisioner) writeHostIdentityBootstrapArtifacts(hostName string)
 		hostNodeNamePath = hostNodeNameFilePath
 	}
 	hostNodeNamePath = filepath.Join(p.FileSystemRoot, hostNodeNamePath)
-	if err := os.MkdirAll(filepath.Dir(hostNodeNamePath), 0755); err != nil {
+	if err := p.mkdirAll(filepath.Dir(hostNodeNamePath), 0755); err != nil {
 		return fmt.Errorf("error while creating directory for host node name file %s: %w", hostNodeNamePath, err)
 	}
-	if err := os.WriteFile(hostNodeNamePath, []byte(hostName+"\n"), 0644); err != nil {
+	if err := p.writeFile(hostNodeNamePath, []byte(hostName+"\n"), 0644); err != nil {
 		return fmt.Errorf("error while writing host node name file %s: %w", hostNodeNamePath, err)
 	}
 
@@ -401,7 +593,7 @@ func (p *DPUCNIProvisioner) writeHostIdentityBootstrapArtifacts(hostName string)
 		bootstrapPath = hostBootstrapKubeconfigPath
 	}
 	bootstrapPath = filepath.Join(p.FileSystemRoot, bootstrapPath)
-	if err := os.MkdirAll(filepath.Dir(bootstrapPath), 0700); err != nil {
+	if err := p.mkdirAll(filepath.Dir(bootstrapPath), 0700); err != nil {
 		return fmt.Errorf("error while creating directory for bootstrap kubeconfig %s: %w", bootstrapPath, err)
 	}
 
@@ -428,33 +620,45 @@ users:
     - system:authenticated
 `, strings.TrimSpace(p.K8sAPIServer), hostName)
 
-	if err := os.WriteFile(bootstrapPath, []byte(bootstrapKubeconfig), 0600); err != nil {
+	if err := p.writeFile(bootstrapPath, []byte(bootstrapKubeconfig), 0600); err != nil {
 		return fmt.Errorf("error while writing bootstrap kubeconfig %s: %w", bootstrapPath, err)
 	}
 	return nil
 }
 
-// configurePodToPodOnDifferentNodeConnectivity configures the VTEP interface (br-ovn) and the ovn-encap-ip external ID
-// so that traffic going through the geneve tunnels can function as expected.
-func (p *DPUCNIProvisioner) configurePodToPodOnDifferentNodeConnectivity() error {
+// configurePodToPodOnDifferentNodeConnectivity configures the VTEP interfaces (br-ovn and the additional uplinks) and
+// queues setting the ovn-encap-ip external ID so that traffic going through the geneve tunnels can function as
+// expected.
+func (p *DPUCNIProvisioner) configurePodToPodOnDifferentNodeConnectivity(ovsTxn ovsclient.Transaction) error {
+	uplinks := p.uplinks()
 	if p.mode == InternalIPAM {
-		if err := p.setLinkIPAddressIfNotSet(brOVN, p.vtepIPNet); err != nil {
-			return fmt.Errorf("error while setting VTEP IP: %w", err)
+		for _, c := range p.ipFamilies {
+			if err := p.setLinkIPAddressIfNotSet(brOVN, c.vtepIPNet); err != nil {
+				return fmt.Errorf("error while setting VTEP IP: %w", err)
+			}
 		}
 		if err := p.networkHelper.SetLinkUp(brOVN); err != nil {
 			return fmt.Errorf("error while setting link %s up: %w", brOVN, err)
 		}
 
-		_, vtepNetwork, err := net.ParseCIDR(p.vtepIPNet.String())
-		if err != nil {
-			return fmt.Errorf("error while parsing network from VTEP IP %s: %w", p.vtepIPNet.String(), err)
+		for _, c := range p.ipFamilies {
+			remoteVTEPCIDRs, err := c.remoteVTEPCIDRs()
+			if err != nil {
+				return err
+			}
+
+			// Add routes related to traffic that needs to go from one Pod running on worker Node A to another Pod
+			// running on worker Node B.
+			for _, vtepCIDR := range remoteVTEPCIDRs {
+				if err := p.addRouteIfNotExists(vtepCIDR, c.gateway, brOVN, nil, nil); err != nil {
+					return fmt.Errorf("error while adding route %s %s %s: %w", vtepCIDR, c.gateway.String(), brOVN, err)
+				}
+			}
 		}
 
-		if vtepNetwork.String() != p.vtepCIDR.String() {
-			// Add route related to traffic that needs to go from one Pod running on worker Node A to another Pod running
-			// on worker Node B.
-			if err := p.addRouteIfNotExists(p.vtepCIDR, p.gateway, brOVN, nil, nil); err != nil {
-				return fmt.Errorf("error while adding route %s %s %s: %w", p.vtepCIDR, p.gateway.String(), brOVN, err)
+		for i, u := range uplinks[1:] {
+			if err := p.configureAdditionalUplink(i+1, u); err != nil {
+				return fmt.Errorf("error while configuring uplink %s: %w", u.bridge, err)
 			}
 		}
 	}
@@ -466,13 +670,17 @@ func (p *DPUCNIProvisioner) configurePodToPodOnDifferentNodeConnectivity() error
 	// which gets a DHCP IP in that CIDR. Given that, we need to set the metric of this route to something very high
 	// so that it's the last preferred route in the route table for that CIDR. The reason for that is this OVS bug that
 	// selects the route with the highest prio - see issue 3871067.
-	if err := p.addRouteIfNotExists(p.hostCIDR, p.gateway, brOVN, ptr.To(10000), nil); err != nil {
-		return fmt.Errorf("error while adding route %s %s %s: %w", p.hostCIDR, p.gateway.String(), brOVN, err)
+	for _, c := range p.ipFamilies {
+		for _, hostCIDR := range c.hostCIDRs {
+			if err := p.addRouteIfNotExists(hostCIDR, c.gateway, brOVN, ptr.To(10000), nil); err != nil {
+				return fmt.Errorf("error while adding route %s %s %s: %w", hostCIDR, c.gateway.String(), brOVN, err)
+			}
+		}
 	}
 
-	if err := p.ovsClient.SetOVNEncapIP(p.vtepIPNet.IP); err != nil {
-		return fmt.Errorf("error while setting the OVN Encap IP: %w", err)
-	}
+	// The geneve tunnels are established between the VTEP IPs of a single family across all the DPUs, hence only the
+	// VTEP IPs of the primary family are used.
+	p.setEncapIPs(ovsTxn, uplinks)
 
 	return nil
 }
@@ -484,7 +692,7 @@ func (p *DPUCNIProvisioner) setLinkIPAddressIfNotSet(link string, ipNet *net.IPN
 		return fmt.Errorf("error checking whether IP exists: %w", err)
 	}
 	if hasIP {
-		klog.Infof("Link %s has IP %s, skipping configuration", link, ipNet)
+		p.logger.Info("Link has IP, skipping configuration", logging.KeyLink, link, logging.KeyCIDR, ipNet.String())
 		return nil
 	}
 	if err := p.networkHelper.SetLinkIPAddress(link, ipNet); err != nil {
@@ -493,65 +701,79 @@ func (p *DPUCNIProvisioner) setLinkIPAddressIfNotSet(link string, ipNet *net.IPN
 	return nil
 }
 
-// addRouteIfNotExists adds a route if it doesn't already exist
+// addRouteIfNotExists adds a route if it doesn't already exist. A matching route that isn't tagged with the protocol of
+// the provisioner isn't reported as existing, hence it's adopted by AddRoute.
 func (p *DPUCNIProvisioner) addRouteIfNotExists(network *net.IPNet, gateway net.IP, device string, metric *int, table *int) error {
+	p.desireRoute(network, gateway, device, table)
 	hasRoute, err := p.networkHelper.RouteExists(network, gateway, device, table)
 	if err != nil {
 		return fmt.Errorf("error checking whether route exists: %w", err)
 	}
 	if hasRoute {
-		klog.Infof("Route %s %s %s with metric %v in table %v exists, skipping configuration", network, gateway, device, metric, table)
+		p.logger.Info("Route exists, skipping configuration", logging.KeyCIDR, network.String(), "gateway", gateway, logging.KeyLink, device, "metric", metric, "table", table)
 		return nil
 	}
 	if err := p.networkHelper.AddRoute(network, gateway, device, metric, table); err != nil {
 		return fmt.Errorf("error adding route: %w", err)
 	}
+	p.metrics.driftCorrections.WithLabelValues("route").Inc()
 	return nil
 }
 
-// addRuleIfNotExists adds a rule if it doesn't already exist
+// addRuleIfNotExists adds a rule if it doesn't already exist. A matching rule that isn't tagged with the protocol of
+// the provisioner isn't reported as existing, hence it's adopted by AddRule.
 func (p *DPUCNIProvisioner) addRuleIfNotExists(network *net.IPNet, table int, priority int) error {
+	p.desireRule(network, table, priority)
 	hasRule, err := p.networkHelper.RuleExists(network, table, priority)
 	if err != nil {
 		return fmt.Errorf("error checking whether rule exists: %w", err)
 	}
 	if hasRule {
-		klog.Infof("Rule %v %d %d exists, skipping configuration", network, table, priority)
+		p.logger.Info("Rule exists, skipping configuration", logging.KeyCIDR, network.String(), "table", table, "priority", priority)
 		return nil
 	}
 	if err := p.networkHelper.AddRule(network, table, priority); err != nil {
 		return fmt.Errorf("error adding rule: %w", err)
 	}
+	p.metrics.driftCorrections.WithLabelValues("rule").Inc()
 	return nil
 }
 
-// writeFilesForOVN writes the input files that the ovnkube-controller expects
+// writeFilesForOVN writes the input files that the ovnkube-controller expects. The sections and keys of the fragment
+// are merged into the ones the provisioner manages.
 func (p *DPUCNIProvisioner) writeFilesForOVN() error {
 	configPath := filepath.Join(p.FileSystemRoot, ovnkInputPath)
 
-	// Build the complete content in one operation
-	content := "[Gateway]\n"
-	content += p.writeOVNInputGatewayOptsFile()
-
-	routerSubnetContent, err := p.writeOVNInputRouterSubnetPath()
+	conf := &ovnkConf{}
+	gateway := conf.section("Gateway")
+	gateway.set("next-hop", p.ovnNextHops())
+	routerSubnets, err := p.ovnRouterSubnets()
 	if err != nil {
 		return fmt.Errorf("error while getting the gateway router subnet content: %w", err)
 	}
-	content += routerSubnetContent
+	gateway.set("router-subnet", routerSubnets)
 
 	if p.writeOVNKConfigNamespaceToOVNKConf {
-		content += "\n[kubernetes]\n"
-		content += "ovn-config-namespace=" + p.ovnConfigNamespace + "\n"
+		conf.section("kubernetes").set("ovn-config-namespace", p.ovnConfigNamespace)
 	}
 
 	if p.writeDPUNodeLeaseToOVNKConf {
-		content += "\n[ovnkubenode]\n"
-		content += fmt.Sprintf("dpu-node-lease-renew-interval=%d\n", p.dpuNodeLeaseRenewInterval)
-		content += fmt.Sprintf("dpu-node-lease-duration=%d\n", p.dpuNodeLeaseDuration)
+		ovnkubeNode := conf.section("ovnkubenode")
+		ovnkubeNode.set("dpu-node-lease-renew-interval", strconv.Itoa(p.dpuNodeLeaseRenewInterval))
+		ovnkubeNode.set("dpu-node-lease-duration", strconv.Itoa(p.dpuNodeLeaseDuration))
 	}
 
-	// Write the complete content to the file in one operation
-	err = os.WriteFile(configPath, []byte(content), 0644)
+	fragment, err := p.readOVNKConfFragment()
+	if err != nil {
+		return fmt.Errorf("error while reading the ovn_k8s.conf fragment: %w", err)
+	}
+	if fragment != nil {
+		if err := conf.merge(fragment); err != nil {
+			return fmt.Errorf("error while merging the ovn_k8s.conf fragment: %w", err)
+		}
+	}
+
+	err = p.writeOVNKInput(configPath, conf.render())
 	if err != nil {
 		return fmt.Errorf("error writing to file %s: %w", configPath, err)
 	}
@@ -565,47 +787,64 @@ func (p *DPUCNIProvisioner) configureBROVN() error {
 		return fmt.Errorf("error while br-ovn netplan: %w", err)
 	}
 
-	addrs, err := p.networkHelper.GetLinkIPAddresses(brOVN)
-	if err != nil {
-		return fmt.Errorf("error while getting IP addresses for link %s: %w", brOVN, err)
-	}
+	for _, c := range p.ipFamilies {
+		addrs, err := p.networkHelper.GetLinkIPAddressesByFamily(brOVN, c.family)
+		if err != nil {
+			return fmt.Errorf("error while getting IP addresses for link %s: %w", brOVN, err)
+		}
+
+		if len(addrs) != 1 {
+			if err := p.runNetplanApply(); err != nil {
+				return fmt.Errorf("error running netplan apply: %w", err)
+			}
 
-	if len(addrs) != 1 {
-		if err := p.runNetplanApply(); err != nil {
-			return fmt.Errorf("error running netplan apply: %w", err)
+			p.eventf(corev1.EventTypeWarning, EventReasonUnexpectedBROVNAddresses, "Exactly 1 %s IP is expected in %s, but found %d", c.family, brOVN, len(addrs))
+			return fmt.Errorf("exactly 1 %s IP is expected in %s, but found %d", c.family, brOVN, len(addrs))
 		}
 
-		return fmt.Errorf("exactly 1 IP is expected in %s, but found %d", brOVN, len(addrs))
-	}
+		c.vtepIPNet = addrs[0]
 
-	p.vtepIPNet = addrs[0]
+		strategy := p.gatewayDiscovery.strategy()
+		gateway, err := p.gatewayDiscoverer().discoverGateway(c)
+		if err != nil {
+			return fmt.Errorf("error while discovering the %s gateway via %s: %w", c.family, strategy, err)
+		}
 
-	gateway, err := p.networkHelper.GetGateway(p.gatewayDiscoveryNetwork)
-	if err != nil {
-		return fmt.Errorf("error while parsing gateway from gateway discovery network %s: %w", p.gatewayDiscoveryNetwork.String(), err)
+		if !gateway.Equal(c.gateway) || strategy != c.gatewaySource {
+			p.logger.Info("Discovered gateway", "family", c.family, "gateway", gateway, "source", strategy)
+		}
+		c.gateway = gateway
+		c.gatewaySource = strategy
 	}
-
-	p.gateway = gateway
 	return nil
 }
 
-// writeOVNInputGatewayOptsFile returns the gateway options content
-// that ovnkube-controller reads from.
-func (p *DPUCNIProvisioner) writeOVNInputGatewayOptsFile() string {
-	return "next-hop=" + p.gateway.String() + "\n"
+// ovnNextHops returns the gateway next hops that ovnkube-controller reads from. Dual-stack deployments get a comma
+// separated next hop per IP family.
+func (p *DPUCNIProvisioner) ovnNextHops() string {
+	nextHops := make([]string, 0, len(p.ipFamilies))
+	for _, c := range p.ipFamilies {
+		nextHops = append(nextHops, c.gateway.String())
+	}
+	return strings.Join(nextHops, ",")
 }
 
-// writeOVNInputRouterSubnetPath returns the Gateway Router Subnet content
-// that kubeovn-controller reads.
-func (p *DPUCNIProvisioner) writeOVNInputRouterSubnetPath() (string, error) {
-	_, vtepNetwork, err := net.ParseCIDR(p.vtepIPNet.String())
-	if err != nil {
-		return "", fmt.Errorf("error while parsing network from VTEP IP %s: %w", p.vtepIPNet.String(), err)
+// ovnRouterSubnets returns the Gateway Router Subnet that kubeovn-controller reads. Dual-stack deployments get a comma
+// separated subnet per IP family.
+func (p *DPUCNIProvisioner) ovnRouterSubnets() (string, error) {
+	routerSubnets := make([]string, 0, len(p.ipFamilies))
+	for _, c := range p.ipFamilies {
+		vtepNetwork, err := c.vtepNetwork()
+		if err != nil {
+			return "", err
+		}
+		routerSubnets = append(routerSubnets, vtepNetwork.String())
 	}
-	return "router-subnet=" + vtepNetwork.String() + "\n", nil
+	return strings.Join(routerSubnets, ","), nil
 }
 
-// writeNetplanFileForBROVN writes a netplan file for br-ovn to request dhcp
+// writeNetplanFileForBROVN writes a netplan file for br-ovn to request an address via DHCP for every IP family. IPv6
+// addresses are requested via DHCPv6, with the router advertisements being accepted to learn the on-link prefix.
 func (p *DPUCNIProvisioner) writeNetplanFileForBROVN() error {
 	configPath := filepath.Join(p.FileSystemRoot, brOVNNetplanConfigPath)
 	content := fmt.Sprintf(`
@@ -614,12 +853,23 @@ network:
   version: 2
   bridges:
     %s:
-      dhcp4: yes
+`, brOVN)
+	if p.ipFamily(networkhelper.IPv4) != nil {
+		content += `      dhcp4: yes
       dhcp4-overrides:
         use-dns: no
-      openvswitch: {}
-`, brOVN)
-	if err := os.WriteFile(configPath, []byte(content), 0600); err != nil {
+`
+	}
+	if p.ipFamily(networkhelper.IPv6) != nil {
+		content += `      dhcp6: yes
+      dhcp6-overrides:
+        use-dns: no
+      accept-ra: yes
+`
+	}
+	content += `      openvswitch: {}
+`
+	if err := p.writeFile(configPath, []byte(content), 0600); err != nil {
 		return fmt.Errorf("error while writing file %s: %w", configPath, err)
 	}
 
@@ -644,17 +894,17 @@ func (p *DPUCNIProvisioner) runNetplanApply() error {
 
 	lastSuccessfulRunTimestamp := time.Unix(int64(lastSuccessfulRunTimestampRawInt), 0)
 	if lastSuccessfulRunTimestamp.Add(netplanApplyCooldownDuration).After(p.clock.Now()) {
-		klog.Info("netplan apply is in cool down period, skipping apply")
+		p.logger.Info("netplan apply is in cool down period, skipping apply")
 		return nil
 	}
 
-	cmd := p.exec.Command("netplan", "apply")
-	var stdout bytes.Buffer
-	var stderr bytes.Buffer
-	cmd.SetStdout(&stdout)
-	cmd.SetStderr(&stderr)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("error running netplan: stdout='%s' stderr='%s': %w", stdout.String(), stderr.String(), err)
+	if p.plan != nil {
+		p.plan.add(PlanChange{Kind: PlanChangeCommand, Action: PlanActionRun, Object: "netplan apply"})
+		return nil
+	}
+
+	if err := p.netplanApply(); err != nil {
+		return err
 	}
 
 	if err = os.WriteFile(applyDonePath, []byte(strconv.Itoa(int(p.clock.Now().Unix()))), 0644); err != nil {
@@ -664,54 +914,21 @@ func (p *DPUCNIProvisioner) runNetplanApply() error {
 	return nil
 }
 
-// startDHCPServer starts a DHCP Server to enable the PF on the host to get an IP.
-func (p *DPUCNIProvisioner) startDHCPServer() error {
-	if p.dhcpCmd != nil {
-		klog.Warning("DHCP Server already running")
-		return nil
-	}
-
-	_, vtepNetwork, err := net.ParseCIDR(p.vtepIPNet.String())
-	if err != nil {
-		return fmt.Errorf("error while parsing network from VTEP IP %s: %w", p.vtepIPNet.String(), err)
-	}
-
-	mac, err := p.networkHelper.GetHostPFMACAddressDPU("0")
+// netplanApply runs netplan apply
+func (p *DPUCNIProvisioner) netplanApply() error {
+	cmd := p.exec.Command("netplan", "apply")
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	cmd.SetStdout(&stdout)
+	cmd.SetStderr(&stderr)
+	err := cmd.Run()
+	p.metrics.netplanApplies.WithLabelValues(resultLabel(err)).Inc()
 	if err != nil {
-		return fmt.Errorf("error while parsing MAC address of the PF on the host: %w", err)
-	}
-
-	// Add the geneve header size to the MTU.
-	pfMTU := p.ovnMTU + geneveHeaderSize
-
-	if pfMTU == geneveHeaderSize || pfMTU > maxMTUSize {
-		return errors.New("invalid PF MTU: it must be greater than 60 and less than or equal to 9216")
-	}
-
-	args := []string{
-		"--keep-in-foreground",
-		"--port=0",         // Disable DNS Server
-		"--log-facility=-", // Log to stderr
-		fmt.Sprintf("--interface=%s", brOVN),
-		"--dhcp-option=option:router",
-		fmt.Sprintf("--dhcp-option=option:mtu,%d", pfMTU),
-		fmt.Sprintf("--dhcp-range=%s,static", vtepNetwork.IP.String()),
-		fmt.Sprintf("--dhcp-host=%s,%s", mac, p.pfIP.IP.String()),
-	}
-
-	if vtepNetwork.String() != p.vtepCIDR.String() {
-		args = append(args, fmt.Sprintf("--dhcp-option=option:classless-static-route,%s,%s", p.vtepCIDR.String(), p.gateway.String()))
-	}
-
-	cmd := p.exec.Command("dnsmasq", args...)
-
-	cmd.SetStdout(os.Stdout)
-	cmd.SetStderr(os.Stderr)
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("error while starting the DHCP server: %w", err)
+		err = fmt.Errorf("error running netplan: stdout='%s' stderr='%s': %w", stdout.String(), stderr.String(), err)
+		p.eventf(corev1.EventTypeWarning, EventReasonNetplanApplyFailed, "netplan apply failed: %s", err.Error())
+		return err
 	}
-
-	p.dhcpCmd = cmd
+	p.eventf(corev1.EventTypeNormal, EventReasonNetplanApplied, "netplan apply succeeded")
 	return nil
 }
 
@@ -720,14 +937,33 @@ func (p *DPUCNIProvisioner) startDHCPServer() error {
 // * When source address is a primary CNI address of a Pod on the DPUCluster, traffic should always go back via the OOB
 // This feature is essential so that DPUService ConfigPorts feature is working as expected.
 func (p *DPUCNIProvisioner) configureSymmetricRouting() error {
+	for i, c := range p.ipFamilies {
+		if err := p.configureSymmetricRoutingForIPFamily(c, i == 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configureSymmetricRoutingForIPFamily configures source routing for a single IP family. The DPU cluster network is
+// not necessarily dual-stack, hence the flannel interface is required to have an address only in the primary IP
+// family. Source routing of any other IP family is skipped when it doesn't have one.
+func (p *DPUCNIProvisioner) configureSymmetricRoutingForIPFamily(c *ipFamilyConfig, required bool) error {
+	flannelInterface, oobInterface := p.interfaces.Flannel, p.interfaces.OOB
+
 	// When source address is a Pod on the DPUCluster, traffic should always go back via the OOB
-	flannelInterfaceIPs, err := p.networkHelper.GetLinkIPAddresses(flannelInterface)
+	flannelInterfaceIPs, err := p.networkHelper.GetLinkIPAddressesByFamily(flannelInterface, c.family)
 	if err != nil {
 		return fmt.Errorf("error while getting IP addresses for link %s: %w", flannelInterface, err)
 	}
 
+	if !required && len(flannelInterfaceIPs) == 0 {
+		p.logger.Info("Flannel interface has no address of the IP family, skipping its source routing", logging.KeyLink, flannelInterface, "family", c.family.String())
+		return nil
+	}
+
 	if len(flannelInterfaceIPs) != 1 {
-		return fmt.Errorf("flannel interface %s is expected to have a single address", flannelInterface)
+		return fmt.Errorf("flannel interface %s is expected to have a single %s address", flannelInterface, c.family)
 	}
 
 	_, flannelNetwork, err := net.ParseCIDR(flannelInterfaceIPs[0].String())
@@ -740,19 +976,19 @@ func (p *DPUCNIProvisioner) configureSymmetricRouting() error {
 		return fmt.Errorf("error while adding rule: %w", err)
 	}
 
-	// When source address is an IP belonging to the DPU (OOB), traffic should always go back via the OOB
-	oobInterfaceIPs, err := p.networkHelper.GetLinkIPAddresses(oobInterface)
+	oobInterfaceIPs, err := p.networkHelper.GetLinkIPAddressesByFamily(oobInterface, c.family)
 	if err != nil {
 		return fmt.Errorf("error while getting IP addresses for link %s: %w", oobInterface, err)
 	}
 
+	// When source address is an IP belonging to the DPU (OOB), traffic should always go back via the OOB
 	if len(oobInterfaceIPs) != 1 {
-		return fmt.Errorf("oob interface %s is expected to have a single address", oobInterface)
+		return fmt.Errorf("oob interface %s is expected to have a single %s address", oobInterface, c.family)
 	}
 
 	oobInterfaceIP := &net.IPNet{
 		IP:   oobInterfaceIPs[0].IP,
-		Mask: net.CIDRMask(32, 32),
+		Mask: c.family.HostMask(),
 	}
 
 	// ip rule a prio 32000 from 10.0.110.70/32 lookup 60
@@ -760,20 +996,18 @@ func (p *DPUCNIProvisioner) configureSymmetricRouting() error {
 		return fmt.Errorf("error while adding rule: %w", err)
 	}
 
-	_, defaultRouteNetwork, err := net.ParseCIDR("0.0.0.0/0")
-	if err != nil {
-		return fmt.Errorf("error while parsing 0.0.0.0/0 as network: %w", err)
-	}
-
+	defaultRouteNetwork := c.family.DefaultRouteNetwork()
 	defaultGateway, err := p.networkHelper.GetGateway(defaultRouteNetwork)
 	if err != nil {
 		return fmt.Errorf("error while parsing gateway %s: %w", defaultRouteNetwork.String(), err)
 	}
 
-	// Add route referenced by the above rules
+	// Add routes referenced by the above rules
 	// ip route a table 60 10.0.120.0/22 via 10.0.110.254 dev br-comm-ch
-	if err := p.addRouteIfNotExists(p.vtepCIDR, defaultGateway, oobInterface, nil, ptr.To(sourceRoutingTable)); err != nil {
-		return fmt.Errorf("error while adding rule: %w", err)
+	for _, vtepCIDR := range c.vtepCIDRs {
+		if err := p.addRouteIfNotExists(vtepCIDR, defaultGateway, oobInterface, nil, ptr.To(sourceRoutingTable)); err != nil {
+			return fmt.Errorf("error while adding rule: %w", err)
+		}
 	}
 
 	return nil
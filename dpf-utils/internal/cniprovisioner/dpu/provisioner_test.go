@@ -19,24 +19,40 @@ package dpucniprovisioner_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	networkhelperMock "github.com/nvidia/doca-platform/pkg/utils/networkhelper/mock"
 	dpucniprovisioner "github.com/nvidia/ovn-kubernetes-components/internal/cniprovisioner/dpu"
+	nethelper "github.com/nvidia/ovn-kubernetes-components/internal/utils/networkhelper"
+	networkhelperMock "github.com/nvidia/ovn-kubernetes-components/internal/utils/networkhelper/mock"
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/ovsclient"
 	ovsclientMock "github.com/nvidia/ovn-kubernetes-components/internal/utils/ovsclient/mock"
 
+	"github.com/go-logr/logr/funcr"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
 	"go.uber.org/mock/gomock"
+	"golang.org/x/sys/unix"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	testclient "k8s.io/client-go/kubernetes/fake"
 	k8sscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
 	clock "k8s.io/utils/clock/testing"
 	kexec "k8s.io/utils/exec"
 	kexecTesting "k8s.io/utils/exec/testing"
@@ -57,6 +73,8 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 		It("should configure the system fully when different subnets per DPU", func() {
 			testCtrl := gomock.NewController(GinkgoT())
 			ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+			ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+			ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
 			networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
 			fakeExec := &kexecTesting.FakeExec{}
 			vtepIPNet, err := netlink.ParseIPNet("192.168.1.1/24")
@@ -87,7 +105,7 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 			}
 			kubernetesClient := testclient.NewClientset()
 			hostKubernetesClient := fake.NewClientBuilder().WithScheme(k8sscheme.Scheme).WithObjects(newHostKubernetesClient("host1")).Build()
-			provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.InternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, vtepIPNet, gateway, vtepCIDR, hostCIDR, pfIPNet, fakeNode.Name, nil, 8940)
+			provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.InternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, vtepIPNet, gateway, []*net.IPNet{vtepCIDR}, []*net.IPNet{hostCIDR}, pfIPNet, fakeNode.Name, nil, 8940)
 			provisioner.SetHostKubernetesClient(hostKubernetesClient)
 
 			// Prepare Filesystem
@@ -131,13 +149,13 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 			networkhelper.EXPECT().AddRoute(hostCIDR, gateway, "br-ovn", ptr.To[int](10000), nil)
 			networkhelper.EXPECT().GetHostPFMACAddressDPU("0").Return(mac, nil)
 
-			networkhelper.EXPECT().GetLinkIPAddresses("cni0").Return([]*net.IPNet{flannelIP}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{flannelIP}, nil)
 			_, flannelIPNet, err := net.ParseCIDR(flannelIP.String())
 			Expect(err).ToNot(HaveOccurred())
 			networkhelper.EXPECT().RuleExists(flannelIPNet, 60, 31000).Return(false, nil)
 			networkhelper.EXPECT().AddRule(flannelIPNet, 60, 31000).Return(nil)
 
-			networkhelper.EXPECT().GetLinkIPAddresses("br-comm-ch").Return([]*net.IPNet{oobIPNet}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{oobIPNet}, nil)
 			networkhelper.EXPECT().RuleExists(oobIPNetWith32Mask, 60, 32000).Return(false, nil)
 			networkhelper.EXPECT().AddRule(oobIPNetWith32Mask, 60, 32000).Return(nil)
 
@@ -145,10 +163,11 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 			networkhelper.EXPECT().RouteExists(vtepCIDR, defaultGateway, "br-comm-ch", ptr.To(60)).Return(false, nil)
 			networkhelper.EXPECT().AddRoute(vtepCIDR, defaultGateway, "br-comm-ch", nil, ptr.To(60)).Return(nil)
 
-			ovsClient.EXPECT().SetOVNEncapIP(net.ParseIP("192.168.1.1"))
+			ovsTxn.EXPECT().SetOVNEncapIP(net.ParseIP("192.168.1.1"))
 			ovsClient.EXPECT().GetSystemID().Return("test-system-id", nil)
-			ovsClient.EXPECT().SetKubernetesHostNodeName("host1")
-			ovsClient.EXPECT().SetHostName("host1")
+			ovsTxn.EXPECT().SetKubernetesHostNodeName("host1")
+			ovsTxn.EXPECT().SetHostName("host1")
+			ovsTxn.EXPECT().Commit()
 
 			fakeNode.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Node"))
 			fakeNode.SetManagedFields(nil)
@@ -160,6 +179,10 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 			})
 			Expect(err).NotTo(HaveOccurred())
 
+			networkhelper.EXPECT().ListOwnedRoutes()
+			networkhelper.EXPECT().ListOwnedRules()
+			networkhelper.EXPECT().GetLinkMTU("br-ovn").Return(9216, nil)
+			expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.InternalIPAM)
 			err = provisioner.RunOnce()
 			Expect(err).ToNot(HaveOccurred())
 
@@ -172,6 +195,8 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 		It("should configure the system fully when same subnet across DPUs", func() {
 			testCtrl := gomock.NewController(GinkgoT())
 			ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+			ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+			ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
 			networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
 			fakeExec := &kexecTesting.FakeExec{}
 			vtepIPNet, err := netlink.ParseIPNet("192.168.1.1/24")
@@ -202,7 +227,7 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 			}
 			kubernetesClient := testclient.NewClientset()
 			hostKubernetesClient := fake.NewClientBuilder().WithScheme(k8sscheme.Scheme).WithObjects(newHostKubernetesClient("host1")).Build()
-			provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.InternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, vtepIPNet, gateway, vtepCIDR, hostCIDR, pfIPNet, fakeNode.Name, nil, 1440)
+			provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.InternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, vtepIPNet, gateway, []*net.IPNet{vtepCIDR}, []*net.IPNet{hostCIDR}, pfIPNet, fakeNode.Name, nil, 1440)
 			provisioner.SetHostKubernetesClient(hostKubernetesClient)
 
 			// Prepare Filesystem
@@ -245,13 +270,13 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 			networkhelper.EXPECT().AddRoute(hostCIDR, gateway, "br-ovn", ptr.To[int](10000), nil)
 			networkhelper.EXPECT().GetHostPFMACAddressDPU("0").Return(mac, nil)
 
-			networkhelper.EXPECT().GetLinkIPAddresses("cni0").Return([]*net.IPNet{flannelIP}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{flannelIP}, nil)
 			_, flannelIPNet, err := net.ParseCIDR(flannelIP.String())
 			Expect(err).ToNot(HaveOccurred())
 			networkhelper.EXPECT().RuleExists(flannelIPNet, 60, 31000).Return(false, nil)
 			networkhelper.EXPECT().AddRule(flannelIPNet, 60, 31000).Return(nil)
 
-			networkhelper.EXPECT().GetLinkIPAddresses("br-comm-ch").Return([]*net.IPNet{oobIPNet}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{oobIPNet}, nil)
 			networkhelper.EXPECT().RuleExists(oobIPNetWith32Mask, 60, 32000).Return(false, nil)
 			networkhelper.EXPECT().AddRule(oobIPNetWith32Mask, 60, 32000).Return(nil)
 
@@ -259,10 +284,11 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 			networkhelper.EXPECT().RouteExists(vtepCIDR, defaultGateway, "br-comm-ch", ptr.To(60)).Return(false, nil)
 			networkhelper.EXPECT().AddRoute(vtepCIDR, defaultGateway, "br-comm-ch", nil, ptr.To(60)).Return(nil)
 
-			ovsClient.EXPECT().SetOVNEncapIP(net.ParseIP("192.168.1.1"))
+			ovsTxn.EXPECT().SetOVNEncapIP(net.ParseIP("192.168.1.1"))
 			ovsClient.EXPECT().GetSystemID().Return("test-system-id", nil)
-			ovsClient.EXPECT().SetKubernetesHostNodeName("host1")
-			ovsClient.EXPECT().SetHostName("host1")
+			ovsTxn.EXPECT().SetKubernetesHostNodeName("host1")
+			ovsTxn.EXPECT().SetHostName("host1")
+			ovsTxn.EXPECT().Commit()
 
 			fakeNode.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Node"))
 			fakeNode.SetManagedFields(nil)
@@ -274,6 +300,10 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 			})
 			Expect(err).NotTo(HaveOccurred())
 
+			networkhelper.EXPECT().ListOwnedRoutes()
+			networkhelper.EXPECT().ListOwnedRules()
+			networkhelper.EXPECT().GetLinkMTU("br-ovn").Return(9216, nil)
+			expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.InternalIPAM)
 			err = provisioner.RunOnce()
 			Expect(err).ToNot(HaveOccurred())
 
@@ -286,6 +316,8 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 		It("should remove a stale host node chassis annotation when it differs from the local OVS system-id", func(ctx context.Context) {
 			testCtrl := gomock.NewController(GinkgoT())
 			ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+			ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+			ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
 			networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
 			fakeExec := &kexecTesting.FakeExec{}
 			vtepIPNet, err := netlink.ParseIPNet("192.168.1.1/24")
@@ -315,7 +347,7 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 			}
 			kubernetesClient := testclient.NewClientset(fakeNode)
 			hostKubernetesClient := fake.NewClientBuilder().WithScheme(k8sscheme.Scheme).WithObjects(hostNode).Build()
-			provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.InternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, vtepIPNet, gateway, vtepCIDR, hostCIDR, pfIPNet, fakeNode.Name, nil, 1500)
+			provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.InternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, vtepIPNet, gateway, []*net.IPNet{vtepCIDR}, []*net.IPNet{hostCIDR}, pfIPNet, fakeNode.Name, nil, 1500)
 			provisioner.SetHostKubernetesClient(hostKubernetesClient)
 
 			tmpDir, err := os.MkdirTemp("", "dpucniprovisioner")
@@ -334,14 +366,15 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 
 			dummyIP, err := netlink.ParseIPNet("10.244.6.30/24")
 			Expect(err).ToNot(HaveOccurred())
-			networkhelper.EXPECT().GetLinkIPAddresses("cni0").Return([]*net.IPNet{dummyIP}, nil)
-			networkhelper.EXPECT().GetLinkIPAddresses("br-comm-ch").Return([]*net.IPNet{dummyIP}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{dummyIP}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{dummyIP}, nil)
 
 			networkHelperMockAll(networkhelper)
-			ovsClient.EXPECT().SetKubernetesHostNodeName("host1")
-			ovsClient.EXPECT().SetHostName("host1")
+			ovsTxn.EXPECT().SetKubernetesHostNodeName("host1")
+			ovsTxn.EXPECT().SetHostName("host1")
+			ovsTxn.EXPECT().Commit()
 			ovsClient.EXPECT().GetSystemID().Return("new-system-id", nil)
-			ovsClient.EXPECT().SetOVNEncapIP(gomock.Any()).AnyTimes()
+			ovsTxn.EXPECT().SetOVNEncapIP(gomock.Any()).AnyTimes()
 
 			err = provisioner.RunOnce()
 			Expect(err).ToNot(HaveOccurred())
@@ -355,6 +388,8 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 		It("should keep the host node chassis annotation when it already matches the local OVS system-id", func(ctx context.Context) {
 			testCtrl := gomock.NewController(GinkgoT())
 			ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+			ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+			ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
 			networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
 			fakeExec := &kexecTesting.FakeExec{}
 			vtepIPNet, err := netlink.ParseIPNet("192.168.1.1/24")
@@ -384,7 +419,7 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 			}
 			kubernetesClient := testclient.NewClientset(fakeNode)
 			hostKubernetesClient := fake.NewClientBuilder().WithScheme(k8sscheme.Scheme).WithObjects(hostNode).Build()
-			provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.InternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, vtepIPNet, gateway, vtepCIDR, hostCIDR, pfIPNet, fakeNode.Name, nil, 1500)
+			provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.InternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, vtepIPNet, gateway, []*net.IPNet{vtepCIDR}, []*net.IPNet{hostCIDR}, pfIPNet, fakeNode.Name, nil, 1500)
 			provisioner.SetHostKubernetesClient(hostKubernetesClient)
 
 			tmpDir, err := os.MkdirTemp("", "dpucniprovisioner")
@@ -403,14 +438,15 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 
 			dummyIP, err := netlink.ParseIPNet("10.244.6.30/24")
 			Expect(err).ToNot(HaveOccurred())
-			networkhelper.EXPECT().GetLinkIPAddresses("cni0").Return([]*net.IPNet{dummyIP}, nil)
-			networkhelper.EXPECT().GetLinkIPAddresses("br-comm-ch").Return([]*net.IPNet{dummyIP}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{dummyIP}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{dummyIP}, nil)
 
 			networkHelperMockAll(networkhelper)
-			ovsClient.EXPECT().SetKubernetesHostNodeName("host1")
-			ovsClient.EXPECT().SetHostName("host1")
+			ovsTxn.EXPECT().SetKubernetesHostNodeName("host1")
+			ovsTxn.EXPECT().SetHostName("host1")
+			ovsTxn.EXPECT().Commit()
 			ovsClient.EXPECT().GetSystemID().Return("current-system-id", nil)
-			ovsClient.EXPECT().SetOVNEncapIP(gomock.Any()).AnyTimes()
+			ovsTxn.EXPECT().SetOVNEncapIP(gomock.Any()).AnyTimes()
 
 			err = provisioner.RunOnce()
 			Expect(err).ToNot(HaveOccurred())
@@ -424,6 +460,8 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 		It("should keep the host node chassis annotation absent when it is not set", func(ctx context.Context) {
 			testCtrl := gomock.NewController(GinkgoT())
 			ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+			ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+			ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
 			networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
 			fakeExec := &kexecTesting.FakeExec{}
 			vtepIPNet, err := netlink.ParseIPNet("192.168.1.1/24")
@@ -450,7 +488,7 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 			}
 			kubernetesClient := testclient.NewClientset(fakeNode)
 			hostKubernetesClient := fake.NewClientBuilder().WithScheme(k8sscheme.Scheme).WithObjects(hostNode).Build()
-			provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.InternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, vtepIPNet, gateway, vtepCIDR, hostCIDR, pfIPNet, fakeNode.Name, nil, 1500)
+			provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.InternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, vtepIPNet, gateway, []*net.IPNet{vtepCIDR}, []*net.IPNet{hostCIDR}, pfIPNet, fakeNode.Name, nil, 1500)
 			provisioner.SetHostKubernetesClient(hostKubernetesClient)
 
 			tmpDir, err := os.MkdirTemp("", "dpucniprovisioner")
@@ -469,14 +507,15 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 
 			dummyIP, err := netlink.ParseIPNet("10.244.6.30/24")
 			Expect(err).ToNot(HaveOccurred())
-			networkhelper.EXPECT().GetLinkIPAddresses("cni0").Return([]*net.IPNet{dummyIP}, nil)
-			networkhelper.EXPECT().GetLinkIPAddresses("br-comm-ch").Return([]*net.IPNet{dummyIP}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{dummyIP}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{dummyIP}, nil)
 
 			networkHelperMockAll(networkhelper)
-			ovsClient.EXPECT().SetKubernetesHostNodeName("host1")
-			ovsClient.EXPECT().SetHostName("host1")
+			ovsTxn.EXPECT().SetKubernetesHostNodeName("host1")
+			ovsTxn.EXPECT().SetHostName("host1")
+			ovsTxn.EXPECT().Commit()
 			ovsClient.EXPECT().GetSystemID().Return("new-system-id", nil)
-			ovsClient.EXPECT().SetOVNEncapIP(gomock.Any()).AnyTimes()
+			ovsTxn.EXPECT().SetOVNEncapIP(gomock.Any()).AnyTimes()
 
 			err = provisioner.RunOnce()
 			Expect(err).ToNot(HaveOccurred())
@@ -490,6 +529,8 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 		It("should not error out on subsequent runs when network calls and OVS calls are fully mocked", func(ctx context.Context) {
 			testCtrl := gomock.NewController(GinkgoT())
 			ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+			ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+			ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
 			networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
 			fakeExec := &kexecTesting.FakeExec{}
 			vtepIPNet, err := netlink.ParseIPNet("192.168.1.1/24")
@@ -511,7 +552,7 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 			}
 			kubernetesClient := testclient.NewClientset(fakeNode)
 			hostKubernetesClient := fake.NewClientBuilder().WithScheme(k8sscheme.Scheme).WithObjects(newHostKubernetesClient("host1")).Build()
-			provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.InternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, vtepIPNet, gateway, vtepCIDR, hostCIDR, pfIPNet, fakeNode.Name, nil, 1500)
+			provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.InternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, vtepIPNet, gateway, []*net.IPNet{vtepCIDR}, []*net.IPNet{hostCIDR}, pfIPNet, fakeNode.Name, nil, 1500)
 			provisioner.SetHostKubernetesClient(hostKubernetesClient)
 
 			// Prepare Filesystem
@@ -533,13 +574,13 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 			// mock them with gomock.Any()
 			dummyIP, err := netlink.ParseIPNet("10.244.6.30/24")
 			Expect(err).ToNot(HaveOccurred())
-			networkhelper.EXPECT().GetLinkIPAddresses("cni0").Return([]*net.IPNet{dummyIP}, nil)
-			networkhelper.EXPECT().GetLinkIPAddresses("br-comm-ch").Return([]*net.IPNet{dummyIP}, nil)
-			networkhelper.EXPECT().GetLinkIPAddresses("cni0").Return([]*net.IPNet{dummyIP}, nil)
-			networkhelper.EXPECT().GetLinkIPAddresses("br-comm-ch").Return([]*net.IPNet{dummyIP}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{dummyIP}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{dummyIP}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{dummyIP}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{dummyIP}, nil)
 
 			networkHelperMockAll(networkhelper)
-			ovsClientMockAll(ovsClient)
+			ovsClientMockAll(ovsClient, ovsTxn)
 
 			err = provisioner.RunOnce()
 			Expect(err).ToNot(HaveOccurred())
@@ -550,6 +591,8 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 		It("should not error out when network and ovs clients are mocked like in the real world", func(ctx context.Context) {
 			testCtrl := gomock.NewController(GinkgoT())
 			ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+			ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+			ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
 			networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
 			fakeExec := &kexecTesting.FakeExec{}
 			vtepIPNet, err := netlink.ParseIPNet("192.168.1.1/24")
@@ -580,7 +623,7 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 			}
 			kubernetesClient := testclient.NewClientset(fakeNode)
 			hostKubernetesClient := fake.NewClientBuilder().WithScheme(k8sscheme.Scheme).WithObjects(newHostKubernetesClient("host1")).Build()
-			provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.InternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, vtepIPNet, gateway, vtepCIDR, hostCIDR, pfIPNet, fakeNode.Name, nil, 1500)
+			provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.InternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, vtepIPNet, gateway, []*net.IPNet{vtepCIDR}, []*net.IPNet{hostCIDR}, pfIPNet, fakeNode.Name, nil, 1500)
 			provisioner.SetHostKubernetesClient(hostKubernetesClient)
 
 			// Prepare Filesystem
@@ -610,13 +653,13 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 			mac, _ := net.ParseMAC("00:00:00:00:00:01")
 			networkhelper.EXPECT().GetHostPFMACAddressDPU("0").Return(mac, nil)
 
-			networkhelper.EXPECT().GetLinkIPAddresses("cni0").Return([]*net.IPNet{flannelIP}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{flannelIP}, nil)
 			_, flannelIPNet, err := net.ParseCIDR(flannelIP.String())
 			Expect(err).ToNot(HaveOccurred())
 			networkhelper.EXPECT().RuleExists(flannelIPNet, 60, 31000).Return(false, nil)
 			networkhelper.EXPECT().AddRule(flannelIPNet, 60, 31000).Return(nil)
 
-			networkhelper.EXPECT().GetLinkIPAddresses("br-comm-ch").Return([]*net.IPNet{oobIPNet}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{oobIPNet}, nil)
 			networkhelper.EXPECT().RuleExists(oobIPNetWith32Mask, 60, 32000).Return(false, nil)
 			networkhelper.EXPECT().AddRule(oobIPNetWith32Mask, 60, 32000).Return(nil)
 
@@ -624,10 +667,15 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 			networkhelper.EXPECT().RouteExists(vtepCIDR, defaultGateway, "br-comm-ch", ptr.To(60)).Return(false, nil)
 			networkhelper.EXPECT().AddRoute(vtepCIDR, defaultGateway, "br-comm-ch", nil, ptr.To(60)).Return(nil)
 
-			ovsClient.EXPECT().SetOVNEncapIP(net.ParseIP("192.168.1.1"))
-			ovsClient.EXPECT().SetKubernetesHostNodeName("host1")
-			ovsClient.EXPECT().SetHostName("host1")
+			ovsTxn.EXPECT().SetOVNEncapIP(net.ParseIP("192.168.1.1"))
+			ovsTxn.EXPECT().SetKubernetesHostNodeName("host1")
+			ovsTxn.EXPECT().SetHostName("host1")
+			ovsTxn.EXPECT().Commit()
 
+			networkhelper.EXPECT().ListOwnedRoutes()
+			networkhelper.EXPECT().ListOwnedRules()
+			networkhelper.EXPECT().GetLinkMTU("br-ovn").Return(9216, nil)
+			expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.InternalIPAM)
 			err = provisioner.RunOnce()
 			Expect(err).ToNot(HaveOccurred())
 
@@ -636,26 +684,35 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 			networkhelper.EXPECT().SetLinkUp("br-ovn")
 			networkhelper.EXPECT().RouteExists(vtepCIDR, gateway, "br-ovn", nil).Return(true, nil)
 			networkhelper.EXPECT().RouteExists(hostCIDR, gateway, "br-ovn", nil).Return(true, nil)
+			// The DHCP server arguments are rendered again to find out whether it needs to be restarted
+			networkhelper.EXPECT().GetHostPFMACAddressDPU("0").Return(mac, nil)
 
-			networkhelper.EXPECT().GetLinkIPAddresses("cni0").Return([]*net.IPNet{flannelIP}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{flannelIP}, nil)
 			networkhelper.EXPECT().RuleExists(flannelIPNet, 60, 31000).Return(true, nil)
 
-			networkhelper.EXPECT().GetLinkIPAddresses("br-comm-ch").Return([]*net.IPNet{oobIPNet}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{oobIPNet}, nil)
 			networkhelper.EXPECT().RuleExists(oobIPNetWith32Mask, 60, 32000).Return(true, nil)
 
 			networkhelper.EXPECT().GetGateway(defaultRouteNetwork).Return(defaultGateway, nil)
 			networkhelper.EXPECT().RouteExists(vtepCIDR, defaultGateway, "br-comm-ch", ptr.To(60)).Return(true, nil)
 
-			ovsClient.EXPECT().SetOVNEncapIP(net.ParseIP("192.168.1.1"))
-			ovsClient.EXPECT().SetKubernetesHostNodeName("host1")
-			ovsClient.EXPECT().SetHostName("host1")
+			ovsTxn.EXPECT().SetOVNEncapIP(net.ParseIP("192.168.1.1"))
+			ovsTxn.EXPECT().SetKubernetesHostNodeName("host1")
+			ovsTxn.EXPECT().SetHostName("host1")
+			ovsTxn.EXPECT().Commit()
 
+			networkhelper.EXPECT().ListOwnedRoutes()
+			networkhelper.EXPECT().ListOwnedRules()
+			networkhelper.EXPECT().GetLinkMTU("br-ovn").Return(9216, nil)
+			expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.InternalIPAM)
 			err = provisioner.RunOnce()
 			Expect(err).ToNot(HaveOccurred())
 		})
 		It("should not start another dnsmasq if dnsmasq already running", func(ctx context.Context) {
 			testCtrl := gomock.NewController(GinkgoT())
 			ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+			ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+			ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
 			networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
 			fakeExec := &kexecTesting.FakeExec{}
 			vtepIPNet, err := netlink.ParseIPNet("192.168.1.1/24")
@@ -677,7 +734,7 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 			}
 			kubernetesClient := testclient.NewClientset(fakeNode)
 			hostKubernetesClient := fake.NewClientBuilder().WithScheme(k8sscheme.Scheme).WithObjects(newHostKubernetesClient("host1")).Build()
-			provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.InternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, vtepIPNet, gateway, vtepCIDR, hostCIDR, pfIPNet, fakeNode.Name, nil, 1500)
+			provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.InternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, vtepIPNet, gateway, []*net.IPNet{vtepCIDR}, []*net.IPNet{hostCIDR}, pfIPNet, fakeNode.Name, nil, 1500)
 			provisioner.SetHostKubernetesClient(hostKubernetesClient)
 
 			// Prepare Filesystem
@@ -699,13 +756,13 @@ var _ = Describe("DPU CNI Provisioner in Internal mode", func() {
 			// mock them with gomock.Any()
 			dummyIP, err := netlink.ParseIPNet("10.244.6.30/24")
 			Expect(err).ToNot(HaveOccurred())
-			networkhelper.EXPECT().GetLinkIPAddresses("cni0").Return([]*net.IPNet{dummyIP}, nil)
-			networkhelper.EXPECT().GetLinkIPAddresses("br-comm-ch").Return([]*net.IPNet{dummyIP}, nil)
-			networkhelper.EXPECT().GetLinkIPAddresses("cni0").Return([]*net.IPNet{dummyIP}, nil)
-			networkhelper.EXPECT().GetLinkIPAddresses("br-comm-ch").Return([]*net.IPNet{dummyIP}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{dummyIP}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{dummyIP}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{dummyIP}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{dummyIP}, nil)
 
 			networkHelperMockAll(networkhelper)
-			ovsClientMockAll(ovsClient)
+			ovsClientMockAll(ovsClient, ovsTxn)
 
 			err = provisioner.RunOnce()
 			Expect(err).ToNot(HaveOccurred())
@@ -723,6 +780,8 @@ var _ = Describe("DPU CNI Provisioner in External mode", func() {
 		It("should configure the system fully when same subnet across DPUs", func() {
 			testCtrl := gomock.NewController(GinkgoT())
 			ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+			ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+			ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
 			networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
 			fakeExec := &kexecTesting.FakeExec{}
 			_, hostCIDR, err := net.ParseCIDR("10.0.100.1/24")
@@ -750,7 +809,7 @@ var _ = Describe("DPU CNI Provisioner in External mode", func() {
 			}
 			kubernetesClient := testclient.NewClientset(fakeNode)
 			hostKubernetesClient := fake.NewClientBuilder().WithScheme(k8sscheme.Scheme).WithObjects(newHostKubernetesClient("host1")).Build()
-			provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.ExternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, nil, nil, vtepCIDR, hostCIDR, nil, fakeNode.Name, gatewayDiscoveryNetwork, 0)
+			provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.ExternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, nil, nil, []*net.IPNet{vtepCIDR}, []*net.IPNet{hostCIDR}, nil, fakeNode.Name, gatewayDiscoveryNetwork, 0)
 			provisioner.SetHostKubernetesClient(hostKubernetesClient)
 
 			// Prepare Filesystem
@@ -774,11 +833,12 @@ var _ = Describe("DPU CNI Provisioner in External mode", func() {
 			}))
 
 			ovsClient.EXPECT().GetSystemID().Return("test-system-id", nil)
-			ovsClient.EXPECT().SetKubernetesHostNodeName("host1")
-			ovsClient.EXPECT().SetHostName("host1")
+			ovsTxn.EXPECT().SetKubernetesHostNodeName("host1")
+			ovsTxn.EXPECT().SetHostName("host1")
+			ovsTxn.EXPECT().Commit()
 			brOVNAddress, err := netlink.ParseIPNet("192.168.0.3/23")
 			Expect(err).ToNot(HaveOccurred())
-			networkhelper.EXPECT().GetLinkIPAddresses("br-ovn").Return([]*net.IPNet{brOVNAddress}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-ovn", nethelper.IPv4).Return([]*net.IPNet{brOVNAddress}, nil)
 			_, fakeNetwork, err := net.ParseCIDR("169.254.99.100/32")
 			Expect(err).ToNot(HaveOccurred())
 			gateway := net.ParseIP("192.168.1.254")
@@ -786,13 +846,13 @@ var _ = Describe("DPU CNI Provisioner in External mode", func() {
 			networkhelper.EXPECT().RouteExists(hostCIDR, gateway, "br-ovn", nil)
 			networkhelper.EXPECT().AddRoute(hostCIDR, gateway, "br-ovn", ptr.To(10000), nil)
 
-			networkhelper.EXPECT().GetLinkIPAddresses("cni0").Return([]*net.IPNet{flannelIP}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{flannelIP}, nil)
 			_, flannelIPNet, err := net.ParseCIDR(flannelIP.String())
 			Expect(err).ToNot(HaveOccurred())
 			networkhelper.EXPECT().RuleExists(flannelIPNet, 60, 31000).Return(false, nil)
 			networkhelper.EXPECT().AddRule(flannelIPNet, 60, 31000).Return(nil)
 
-			networkhelper.EXPECT().GetLinkIPAddresses("br-comm-ch").Return([]*net.IPNet{oobIPNet}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{oobIPNet}, nil)
 			networkhelper.EXPECT().RuleExists(oobIPNetWith32Mask, 60, 32000).Return(false, nil)
 			networkhelper.EXPECT().AddRule(oobIPNetWith32Mask, 60, 32000).Return(nil)
 
@@ -800,8 +860,12 @@ var _ = Describe("DPU CNI Provisioner in External mode", func() {
 			networkhelper.EXPECT().RouteExists(vtepCIDR, defaultGateway, "br-comm-ch", ptr.To(60)).Return(false, nil)
 			networkhelper.EXPECT().AddRoute(vtepCIDR, defaultGateway, "br-comm-ch", nil, ptr.To(60)).Return(nil)
 
-			ovsClient.EXPECT().SetOVNEncapIP(brOVNAddress.IP)
+			ovsTxn.EXPECT().SetOVNEncapIP(brOVNAddress.IP)
 
+			networkhelper.EXPECT().ListOwnedRoutes()
+			networkhelper.EXPECT().ListOwnedRules()
+			networkhelper.EXPECT().GetLinkMTU("br-ovn").Return(9216, nil)
+			expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.ExternalIPAM)
 			err = provisioner.RunOnce()
 			Expect(err).ToNot(HaveOccurred())
 
@@ -829,6 +893,8 @@ network:
 		It("should not error out when network and ovs clients are mocked like in the real world", func(ctx context.Context) {
 			testCtrl := gomock.NewController(GinkgoT())
 			ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+			ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+			ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
 			networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
 			fakeExec := &kexecTesting.FakeExec{}
 			_, hostCIDR, err := net.ParseCIDR("10.0.100.1/24")
@@ -856,7 +922,7 @@ network:
 			}
 			kubernetesClient := testclient.NewClientset(fakeNode)
 			hostKubernetesClient := fake.NewClientBuilder().WithScheme(k8sscheme.Scheme).WithObjects(newHostKubernetesClient("host1")).Build()
-			provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.ExternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, nil, nil, vtepCIDR, hostCIDR, nil, fakeNode.Name, gatewayDiscoveryNetwork, 0)
+			provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.ExternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, nil, nil, []*net.IPNet{vtepCIDR}, []*net.IPNet{hostCIDR}, nil, fakeNode.Name, gatewayDiscoveryNetwork, 0)
 			provisioner.SetHostKubernetesClient(hostKubernetesClient)
 
 			// Prepare Filesystem
@@ -885,27 +951,29 @@ network:
 			gateway := net.ParseIP("192.168.1.254")
 			By("Checking the first run")
 			ovsClient.EXPECT().GetSystemID().Return("test-system-id", nil).AnyTimes()
-			ovsClient.EXPECT().SetKubernetesHostNodeName("host1")
-			ovsClient.EXPECT().SetHostName("host1")
-			networkhelper.EXPECT().GetLinkIPAddresses("br-ovn").Return([]*net.IPNet{}, nil)
+			ovsTxn.EXPECT().SetKubernetesHostNodeName("host1")
+			ovsTxn.EXPECT().SetHostName("host1")
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-ovn", nethelper.IPv4).Return([]*net.IPNet{}, nil)
 
+			expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.ExternalIPAM)
 			err = provisioner.RunOnce()
 			Expect(err).To(HaveOccurred())
 
 			By("Checking the second run")
-			ovsClient.EXPECT().SetKubernetesHostNodeName("host1")
-			ovsClient.EXPECT().SetHostName("host1")
-			networkhelper.EXPECT().GetLinkIPAddresses("br-ovn").Return([]*net.IPNet{brOVNAddress}, nil)
+			ovsTxn.EXPECT().SetKubernetesHostNodeName("host1")
+			ovsTxn.EXPECT().SetHostName("host1")
+			ovsTxn.EXPECT().Commit()
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-ovn", nethelper.IPv4).Return([]*net.IPNet{brOVNAddress}, nil)
 			networkhelper.EXPECT().GetGateway(fakeNetwork).Return(gateway, nil)
 			networkhelper.EXPECT().RouteExists(hostCIDR, gateway, "br-ovn", nil).Return(true, nil)
 
-			networkhelper.EXPECT().GetLinkIPAddresses("cni0").Return([]*net.IPNet{flannelIP}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{flannelIP}, nil)
 			_, flannelIPNet, err := net.ParseCIDR(flannelIP.String())
 			Expect(err).ToNot(HaveOccurred())
 			networkhelper.EXPECT().RuleExists(flannelIPNet, 60, 31000).Return(false, nil)
 			networkhelper.EXPECT().AddRule(flannelIPNet, 60, 31000).Return(nil)
 
-			networkhelper.EXPECT().GetLinkIPAddresses("br-comm-ch").Return([]*net.IPNet{oobIPNet}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{oobIPNet}, nil)
 			networkhelper.EXPECT().RuleExists(oobIPNetWith32Mask, 60, 32000).Return(false, nil)
 			networkhelper.EXPECT().AddRule(oobIPNetWith32Mask, 60, 32000).Return(nil)
 
@@ -913,30 +981,39 @@ network:
 			networkhelper.EXPECT().RouteExists(vtepCIDR, defaultGateway, "br-comm-ch", ptr.To(60)).Return(false, nil)
 			networkhelper.EXPECT().AddRoute(vtepCIDR, defaultGateway, "br-comm-ch", nil, ptr.To(60)).Return(nil)
 
-			ovsClient.EXPECT().SetOVNEncapIP(brOVNAddress.IP)
+			ovsTxn.EXPECT().SetOVNEncapIP(brOVNAddress.IP)
 
+			networkhelper.EXPECT().ListOwnedRoutes()
+			networkhelper.EXPECT().ListOwnedRules()
+			networkhelper.EXPECT().GetLinkMTU("br-ovn").Return(9216, nil)
+			expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.ExternalIPAM)
 			err = provisioner.RunOnce()
 			Expect(err).ToNot(HaveOccurred())
 
 			By("Checking the third run")
-			ovsClient.EXPECT().SetKubernetesHostNodeName("host1")
-			ovsClient.EXPECT().SetHostName("host1")
-			networkhelper.EXPECT().GetLinkIPAddresses("br-ovn").Return([]*net.IPNet{brOVNAddress}, nil)
+			ovsTxn.EXPECT().SetKubernetesHostNodeName("host1")
+			ovsTxn.EXPECT().SetHostName("host1")
+			ovsTxn.EXPECT().Commit()
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-ovn", nethelper.IPv4).Return([]*net.IPNet{brOVNAddress}, nil)
 			networkhelper.EXPECT().GetGateway(fakeNetwork).Return(gateway, nil)
 			networkhelper.EXPECT().RouteExists(hostCIDR, gateway, "br-ovn", nil).Return(true, nil)
 
-			networkhelper.EXPECT().GetLinkIPAddresses("cni0").Return([]*net.IPNet{flannelIP}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{flannelIP}, nil)
 			Expect(err).ToNot(HaveOccurred())
 			networkhelper.EXPECT().RuleExists(flannelIPNet, 60, 31000).Return(true, nil)
 
-			networkhelper.EXPECT().GetLinkIPAddresses("br-comm-ch").Return([]*net.IPNet{oobIPNet}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{oobIPNet}, nil)
 			networkhelper.EXPECT().RuleExists(oobIPNetWith32Mask, 60, 32000).Return(true, nil)
 
 			networkhelper.EXPECT().GetGateway(defaultRouteNetwork).Return(defaultGateway, nil)
 			networkhelper.EXPECT().RouteExists(vtepCIDR, defaultGateway, "br-comm-ch", ptr.To(60)).Return(true, nil)
 
-			ovsClient.EXPECT().SetOVNEncapIP(brOVNAddress.IP)
+			ovsTxn.EXPECT().SetOVNEncapIP(brOVNAddress.IP)
 
+			networkhelper.EXPECT().ListOwnedRoutes()
+			networkhelper.EXPECT().ListOwnedRules()
+			networkhelper.EXPECT().GetLinkMTU("br-ovn").Return(9216, nil)
+			expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.ExternalIPAM)
 			err = provisioner.RunOnce()
 			Expect(err).ToNot(HaveOccurred())
 
@@ -946,6 +1023,8 @@ network:
 		It("should not run netplan apply when in cooldown period and when network and ovs clients are mocked like in the real world", func(ctx context.Context) {
 			testCtrl := gomock.NewController(GinkgoT())
 			ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+			ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+			ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
 			networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
 			fakeExec := &kexecTesting.FakeExec{}
 			_, hostCIDR, err := net.ParseCIDR("10.0.100.1/24")
@@ -974,7 +1053,7 @@ network:
 			kubernetesClient := testclient.NewClientset(fakeNode)
 			fakeClock := clock.NewFakeClock(time.Now())
 			hostKubernetesClient := fake.NewClientBuilder().WithScheme(k8sscheme.Scheme).WithObjects(newHostKubernetesClient("host1")).Build()
-			provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.ExternalIPAM, fakeClock, ovsClient, networkhelper, fakeExec, kubernetesClient, nil, nil, vtepCIDR, hostCIDR, nil, fakeNode.Name, gatewayDiscoveryNetwork, 0)
+			provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.ExternalIPAM, fakeClock, ovsClient, networkhelper, fakeExec, kubernetesClient, nil, nil, []*net.IPNet{vtepCIDR}, []*net.IPNet{hostCIDR}, nil, fakeNode.Name, gatewayDiscoveryNetwork, 0)
 			provisioner.SetHostKubernetesClient(hostKubernetesClient)
 
 			// Prepare Filesystem
@@ -1005,49 +1084,53 @@ network:
 
 			By("Checking the first run")
 			ovsClient.EXPECT().GetSystemID().Return("test-system-id", nil).AnyTimes()
-			ovsClient.EXPECT().SetKubernetesHostNodeName("host1")
-			ovsClient.EXPECT().SetHostName("host1")
-			networkhelper.EXPECT().GetLinkIPAddresses("br-ovn").Return([]*net.IPNet{}, nil)
+			ovsTxn.EXPECT().SetKubernetesHostNodeName("host1")
+			ovsTxn.EXPECT().SetHostName("host1")
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-ovn", nethelper.IPv4).Return([]*net.IPNet{}, nil)
 
+			expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.ExternalIPAM)
 			err = provisioner.RunOnce()
 			Expect(err).To(HaveOccurred())
 
 			fakeClock.Step(60 * time.Second)
 
 			By("Checking the second run")
-			ovsClient.EXPECT().SetKubernetesHostNodeName("host1")
-			ovsClient.EXPECT().SetHostName("host1")
-			networkhelper.EXPECT().GetLinkIPAddresses("br-ovn").Return([]*net.IPNet{}, nil)
+			ovsTxn.EXPECT().SetKubernetesHostNodeName("host1")
+			ovsTxn.EXPECT().SetHostName("host1")
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-ovn", nethelper.IPv4).Return([]*net.IPNet{}, nil)
 
+			expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.ExternalIPAM)
 			err = provisioner.RunOnce()
 			Expect(err).To(HaveOccurred())
 
 			fakeClock.Step(60 * time.Second)
 
 			By("Checking the third run")
-			ovsClient.EXPECT().SetKubernetesHostNodeName("host1")
-			ovsClient.EXPECT().SetHostName("host1")
-			networkhelper.EXPECT().GetLinkIPAddresses("br-ovn").Return([]*net.IPNet{}, nil)
+			ovsTxn.EXPECT().SetKubernetesHostNodeName("host1")
+			ovsTxn.EXPECT().SetHostName("host1")
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-ovn", nethelper.IPv4).Return([]*net.IPNet{}, nil)
 
+			expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.ExternalIPAM)
 			err = provisioner.RunOnce()
 			Expect(err).To(HaveOccurred())
 
 			fakeClock.Step(60 * time.Second)
 
 			By("Checking the fourth run")
-			ovsClient.EXPECT().SetKubernetesHostNodeName("host1")
-			ovsClient.EXPECT().SetHostName("host1")
-			networkhelper.EXPECT().GetLinkIPAddresses("br-ovn").Return([]*net.IPNet{brOVNAddress}, nil)
+			ovsTxn.EXPECT().SetKubernetesHostNodeName("host1")
+			ovsTxn.EXPECT().SetHostName("host1")
+			ovsTxn.EXPECT().Commit()
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-ovn", nethelper.IPv4).Return([]*net.IPNet{brOVNAddress}, nil)
 			networkhelper.EXPECT().GetGateway(fakeNetwork).Return(gateway, nil)
 			networkhelper.EXPECT().RouteExists(hostCIDR, gateway, "br-ovn", nil).Return(true, nil)
 
-			networkhelper.EXPECT().GetLinkIPAddresses("cni0").Return([]*net.IPNet{flannelIP}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{flannelIP}, nil)
 			_, flannelIPNet, err := net.ParseCIDR(flannelIP.String())
 			Expect(err).ToNot(HaveOccurred())
 			networkhelper.EXPECT().RuleExists(flannelIPNet, 60, 31000).Return(false, nil)
 			networkhelper.EXPECT().AddRule(flannelIPNet, 60, 31000).Return(nil)
 
-			networkhelper.EXPECT().GetLinkIPAddresses("br-comm-ch").Return([]*net.IPNet{oobIPNet}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{oobIPNet}, nil)
 			networkhelper.EXPECT().RuleExists(oobIPNetWith32Mask, 60, 32000).Return(false, nil)
 			networkhelper.EXPECT().AddRule(oobIPNetWith32Mask, 60, 32000).Return(nil)
 
@@ -1055,8 +1138,12 @@ network:
 			networkhelper.EXPECT().RouteExists(vtepCIDR, defaultGateway, "br-comm-ch", ptr.To(60)).Return(false, nil)
 			networkhelper.EXPECT().AddRoute(vtepCIDR, defaultGateway, "br-comm-ch", nil, ptr.To(60)).Return(nil)
 
-			ovsClient.EXPECT().SetOVNEncapIP(brOVNAddress.IP)
+			ovsTxn.EXPECT().SetOVNEncapIP(brOVNAddress.IP)
 
+			networkhelper.EXPECT().ListOwnedRoutes()
+			networkhelper.EXPECT().ListOwnedRules()
+			networkhelper.EXPECT().GetLinkMTU("br-ovn").Return(9216, nil)
+			expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.ExternalIPAM)
 			err = provisioner.RunOnce()
 			Expect(err).ToNot(HaveOccurred())
 
@@ -1066,24 +1153,2742 @@ network:
 	})
 })
 
+var _ = Describe("DPU CNI Provisioner event driven reconciliation", func() {
+	It("should reconcile shortly after an event source reports a change", func() {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		vtepIPNet, err := netlink.ParseIPNet("192.168.1.1/24")
+		Expect(err).ToNot(HaveOccurred())
+		gateway := net.ParseIP("192.168.1.10")
+		vtepCIDR, err := netlink.ParseIPNet("192.168.1.0/23")
+		Expect(err).ToNot(HaveOccurred())
+		hostCIDR, err := netlink.ParseIPNet("10.0.100.1/24")
+		Expect(err).ToNot(HaveOccurred())
+		pfIPNet, err := netlink.ParseIPNet("192.168.1.2/24")
+		Expect(err).ToNot(HaveOccurred())
+		fakeNode := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "dpu1",
+				Labels: map[string]string{
+					"provisioning.dpu.nvidia.com/dpunode-name": "host1",
+				},
+			},
+		}
+		kubernetesClient := testclient.NewClientset(fakeNode)
+		fakeClock := clock.NewFakeClock(time.Now())
+		start := fakeClock.Now()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		provisioner := dpucniprovisioner.New(ctx, dpucniprovisioner.InternalIPAM, fakeClock, ovsClient, networkhelper, &kexecTesting.FakeExec{}, kubernetesClient, vtepIPNet, gateway, []*net.IPNet{vtepCIDR}, []*net.IPNet{hostCIDR}, pfIPNet, fakeNode.Name, nil, 1500)
+
+		tmpDir, err := os.MkdirTemp("", "dpucniprovisioner")
+		defer func() {
+			err := os.RemoveAll(tmpDir)
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		Expect(err).NotTo(HaveOccurred())
+		provisioner.FileSystemRoot = tmpDir
+		Expect(os.MkdirAll(filepath.Join(tmpDir, "/etc/openvswitch"), 0755)).To(Succeed())
+
+		dummyIP, err := netlink.ParseIPNet("10.244.6.30/24")
+		Expect(err).ToNot(HaveOccurred())
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{dummyIP}, nil).AnyTimes()
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{dummyIP}, nil).AnyTimes()
+		// Registered before the catch-all expectations so that it takes precedence
+		var commits atomic.Int32
+		ovsTxn.EXPECT().Commit().DoAndReturn(func() error {
+			commits.Add(1)
+			return nil
+		}).AnyTimes()
+		networkHelperMockAll(networkhelper)
+		ovsClientMockAll(ovsClient, ovsTxn)
+
+		source := &fakeEventSource{events: make(chan string)}
+		provisioner.AddEventSource(source)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			provisioner.EnsureConfiguration()
+		}()
+
+		By("Reporting a burst of changes")
+		source.events <- "route changed"
+		source.events <- "address changed"
+
+		By("Checking that a single reconcile happens after the debounce period")
+		Eventually(func() int32 {
+			fakeClock.Step(100 * time.Millisecond)
+			return commits.Load()
+		}).Should(Equal(int32(1)))
+		Expect(fakeClock.Since(start)).To(BeNumerically("<", 30*time.Second))
+		Consistently(commits.Load).Should(Equal(int32(1)))
+
+		cancel()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("should report changes to the host name labels of the DPU Node", func(ctx context.Context) {
+		fakeNode := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "dpu1",
+				Labels: map[string]string{
+					"provisioning.dpu.nvidia.com/dpunode-name": "host1",
+				},
+			},
+		}
+		kubernetesClient := testclient.NewClientset(fakeNode)
+		source := dpucniprovisioner.NewNodeEventSource(kubernetesClient, fakeNode.Name)
+
+		reasons := make(chan string, 10)
+		sourceCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			defer GinkgoRecover()
+			Expect(source.Run(sourceCtx, func(reason string) { reasons <- reason })).To(Succeed())
+		}()
+
+		By("Changing the host name label until the informer picks it up")
+		i := 0
+		Eventually(func() int {
+			i++
+			node, err := kubernetesClient.CoreV1().Nodes().Get(ctx, fakeNode.Name, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			node.Labels["provisioning.dpu.nvidia.com/dpunode-name"] = fmt.Sprintf("host%d", i)
+			_, err = kubernetesClient.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			return len(reasons)
+		}).Should(BeNumerically(">", 0))
+
+		By("Changing an unrelated label")
+		// Drain the notifications caused by the previous updates
+		Eventually(func() int {
+			drained := 0
+			for len(reasons) > 0 {
+				<-reasons
+				drained++
+			}
+			return drained
+		}).Should(BeZero())
+		node, err := kubernetesClient.CoreV1().Nodes().Get(ctx, fakeNode.Name, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		node.Labels["unrelated"] = "value"
+		_, err = kubernetesClient.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Consistently(reasons).ShouldNot(Receive())
+	})
+
+	It("should report changes to the IPv4 and IPv6 routes and rules of the source routing table", func(ctx context.Context) {
+		if os.Geteuid() != 0 {
+			Skip("creating network namespaces requires root")
+		}
+		source := dpucniprovisioner.NewNetlinkEventSource(func() []string { return nil })
+
+		// The source subscribes from a thread that is moved into a new network namespace. The thread is never unlocked
+		// so that it's discarded once the source stops.
+		reasons := make(chan string, 64)
+		namespaces := make(chan netns.NsHandle, 1)
+		sourceCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			runtime.LockOSThread()
+			ns, err := netns.New()
+			if err != nil {
+				close(namespaces)
+				return
+			}
+			namespaces <- ns
+			Expect(source.Run(sourceCtx, func(reason string) {
+				select {
+				case reasons <- reason:
+				default:
+				}
+			})).To(Succeed())
+		}()
+		DeferCleanup(func() {
+			cancel()
+			Eventually(done).Should(BeClosed())
+		})
+		ns, ok := <-namespaces
+		if !ok {
+			Skip("creating network namespaces is not permitted")
+		}
+		DeferCleanup(ns.Close)
+		handle, err := netlink.NewHandleAt(ns)
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(handle.Close)
+
+		Expect(handle.LinkAdd(&netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "dual-stack"}, PeerName: "dual-stack-peer"})).To(Succeed())
+		link, err := handle.LinkByName("dual-stack")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(handle.LinkSetUp(link)).To(Succeed())
+		for _, addr := range []string{"192.168.1.1/24", "fd00:1::1/64"} {
+			ipNet, err := netlink.ParseIPNet(addr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handle.AddrAdd(link, &netlink.Addr{IPNet: ipNet, Flags: unix.IFA_F_NODAD})).To(Succeed())
+		}
+		// drain returns the reasons reported so far
+		drain := func() []string {
+			var drained []string
+			for {
+				select {
+				case reason := <-reasons:
+					drained = append(drained, reason)
+				default:
+					return drained
+				}
+			}
+		}
+
+		By("Changing an IPv4 route of the source routing table until the source is subscribed")
+		ipv4Route := &netlink.Route{Dst: mustParseIPNet("10.0.0.0/16"), LinkIndex: link.Attrs().Index, Table: 60}
+		Eventually(func() []string {
+			Expect(handle.RouteAdd(ipv4Route)).To(Succeed())
+			Expect(handle.RouteDel(ipv4Route)).To(Succeed())
+			return drain()
+		}).Should(ContainElement(ContainSubstring("Dst: 10.0.0.0/16")))
+
+		By("Adding and deleting an IPv6 rule")
+		drain()
+		ipv6Rule := netlink.NewRule()
+		ipv6Rule.Family = netlink.FAMILY_V6
+		ipv6Rule.Src = mustParseIPNet("fd00:2::/64")
+		ipv6Rule.Table = 60
+		ipv6Rule.Priority = 31000
+		Expect(handle.RuleAdd(ipv6Rule)).To(Succeed())
+		Eventually(drain).Should(ContainElement("rules changed"))
+		Expect(handle.RuleDel(ipv6Rule)).To(Succeed())
+		Eventually(drain).Should(ContainElement("rules changed"))
+
+		By("Adding an IPv6 route to the source routing table")
+		drain()
+		Expect(handle.RouteAdd(&netlink.Route{Dst: mustParseIPNet("fd00:3::/64"), LinkIndex: link.Attrs().Index, Table: 60})).To(Succeed())
+		Eventually(drain).Should(ContainElement(ContainSubstring("Dst: fd00:3::/64")))
+	})
+})
+
+var _ = Describe("DPU CNI Provisioner metrics", func() {
+	It("should record the failed step, the reconcile result and the drift corrections", func(ctx context.Context) {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		vtepIPNet, err := netlink.ParseIPNet("192.168.1.1/24")
+		Expect(err).ToNot(HaveOccurred())
+		gateway := net.ParseIP("192.168.1.10")
+		vtepCIDR, err := netlink.ParseIPNet("192.168.1.0/23")
+		Expect(err).ToNot(HaveOccurred())
+		hostCIDR, err := netlink.ParseIPNet("10.0.100.1/24")
+		Expect(err).ToNot(HaveOccurred())
+		pfIPNet, err := netlink.ParseIPNet("192.168.1.2/24")
+		Expect(err).ToNot(HaveOccurred())
+		fakeNode := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "dpu1",
+				Labels: map[string]string{
+					"provisioning.dpu.nvidia.com/dpunode-name": "host1",
+				},
+			},
+		}
+		kubernetesClient := testclient.NewClientset(fakeNode)
+		fakeClock := clock.NewFakeClock(time.Now())
+		provisioner := dpucniprovisioner.New(ctx, dpucniprovisioner.InternalIPAM, fakeClock, ovsClient, networkhelper, &kexecTesting.FakeExec{}, kubernetesClient, vtepIPNet, gateway, []*net.IPNet{vtepCIDR}, []*net.IPNet{hostCIDR}, pfIPNet, fakeNode.Name, nil, 1500)
+
+		registry := prometheus.NewRegistry()
+		Expect(provisioner.RegisterMetrics(registry)).To(Succeed())
+
+		tmpDir, err := os.MkdirTemp("", "dpucniprovisioner")
+		defer func() {
+			err := os.RemoveAll(tmpDir)
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		Expect(err).NotTo(HaveOccurred())
+		provisioner.FileSystemRoot = tmpDir
+		Expect(os.MkdirAll(filepath.Join(tmpDir, "/etc/openvswitch"), 0755)).To(Succeed())
+
+		// The routes are reported missing and the cni0 interface has no address, so that symmetric routing fails
+		networkHelperMockAll(networkhelper)
+		ovsClientMockAll(ovsClient, ovsTxn)
+
+		Expect(provisioner.RunOnce()).ToNot(Succeed())
+
+		Expect(testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP dpucniprovisioner_reconciles_total Number of runs of the provisioning flow by result.
+# TYPE dpucniprovisioner_reconciles_total counter
+dpucniprovisioner_reconciles_total{result="failure"} 1
+# HELP dpucniprovisioner_step_failures_total Number of failures of each step of the provisioning flow.
+# TYPE dpucniprovisioner_step_failures_total counter
+dpucniprovisioner_step_failures_total{step="symmetric_routing"} 1
+# HELP dpucniprovisioner_drift_corrections_total Number of routes and rules that were found missing and had to be added again.
+# TYPE dpucniprovisioner_drift_corrections_total counter
+dpucniprovisioner_drift_corrections_total{kind="route"} 2
+`), "dpucniprovisioner_reconciles_total", "dpucniprovisioner_step_failures_total", "dpucniprovisioner_drift_corrections_total")).To(Succeed())
+
+		By("Checking that every step that ran has its duration recorded")
+		Expect(testutil.CollectAndCount(registry, "dpucniprovisioner_step_duration_seconds")).To(Equal(11))
+	})
+})
+
+var _ = Describe("DPU CNI Provisioner readiness", func() {
+	It("should report not ready after consecutive failures and when the DHCP server exits", func(ctx context.Context) {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		vtepIPNet, err := netlink.ParseIPNet("192.168.1.1/24")
+		Expect(err).ToNot(HaveOccurred())
+		gateway := net.ParseIP("192.168.1.10")
+		vtepCIDR, err := netlink.ParseIPNet("192.168.1.0/23")
+		Expect(err).ToNot(HaveOccurred())
+		hostCIDR, err := netlink.ParseIPNet("10.0.100.1/24")
+		Expect(err).ToNot(HaveOccurred())
+		pfIPNet, err := netlink.ParseIPNet("192.168.1.2/24")
+		Expect(err).ToNot(HaveOccurred())
+		fakeNode := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "dpu1",
+				Labels: map[string]string{
+					"provisioning.dpu.nvidia.com/dpunode-name": "host1",
+				},
+			},
+		}
+		kubernetesClient := testclient.NewClientset(fakeNode)
+		provisioner := dpucniprovisioner.New(ctx, dpucniprovisioner.InternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, vtepIPNet, gateway, []*net.IPNet{vtepCIDR}, []*net.IPNet{hostCIDR}, pfIPNet, fakeNode.Name, nil, 1500)
+
+		tmpDir, err := os.MkdirTemp("", "dpucniprovisioner")
+		defer func() {
+			err := os.RemoveAll(tmpDir)
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		Expect(err).NotTo(HaveOccurred())
+		provisioner.FileSystemRoot = tmpDir
+		Expect(os.MkdirAll(filepath.Join(tmpDir, "/etc/openvswitch"), 0755)).To(Succeed())
+
+		dhcpServer := &blockingCmd{FakeCmd: &kexecTesting.FakeCmd{}, exit: make(chan error)}
+		fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+			return dhcpServer
+		}))
+
+		// cni0 has no address during the first runs so that symmetric routing fails
+		dummyIP, err := netlink.ParseIPNet("10.244.6.30/24")
+		Expect(err).ToNot(HaveOccurred())
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return(nil, nil).Times(3)
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{dummyIP}, nil)
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{dummyIP}, nil)
+		networkHelperMockAll(networkhelper)
+		ovsClientMockAll(ovsClient, ovsTxn)
+
+		By("Failing fewer times than the threshold")
+		Expect(provisioner.RunOnce()).ToNot(Succeed())
+		Expect(provisioner.RunOnce()).ToNot(Succeed())
+		Expect(provisioner.CheckReadiness()).To(Succeed())
+
+		By("Failing as many times as the threshold")
+		Expect(provisioner.RunOnce()).ToNot(Succeed())
+		Expect(provisioner.CheckReadiness()).To(MatchError(ContainSubstring("configuration failed 3 times in a row")))
+
+		By("Succeeding once")
+		Expect(provisioner.RunOnce()).To(Succeed())
+		Expect(provisioner.CheckReadiness()).To(Succeed())
+
+		By("Having the DHCP server exit")
+		dhcpServer.exit <- errors.New("signal: killed")
+		Eventually(provisioner.CheckReadiness).Should(MatchError(ContainSubstring("DHCP server exited: signal: killed")))
+	})
+})
+
+var _ = Describe("DPU CNI Provisioner DHCP server supervision", func() {
+	It("should restart the DHCP server when it exits and when its arguments change", func(ctx context.Context) {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		vtepIPNet, err := netlink.ParseIPNet("192.168.1.1/24")
+		Expect(err).ToNot(HaveOccurred())
+		gateway := net.ParseIP("192.168.1.10")
+		vtepCIDR, err := netlink.ParseIPNet("192.168.1.0/23")
+		Expect(err).ToNot(HaveOccurred())
+		hostCIDR, err := netlink.ParseIPNet("10.0.100.1/24")
+		Expect(err).ToNot(HaveOccurred())
+		pfIPNet, err := netlink.ParseIPNet("192.168.1.2/24")
+		Expect(err).ToNot(HaveOccurred())
+		fakeNode := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "dpu1",
+				Labels: map[string]string{
+					"provisioning.dpu.nvidia.com/dpunode-name": "host1",
+				},
+			},
+		}
+		kubernetesClient := testclient.NewClientset(fakeNode)
+		fakeClock := clock.NewFakeClock(time.Now())
+		provisionerCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		provisioner := dpucniprovisioner.New(provisionerCtx, dpucniprovisioner.InternalIPAM, fakeClock, ovsClient, networkhelper, fakeExec, kubernetesClient, vtepIPNet, gateway, []*net.IPNet{vtepCIDR}, []*net.IPNet{hostCIDR}, pfIPNet, fakeNode.Name, nil, 1500)
+		registry := prometheus.NewRegistry()
+		Expect(provisioner.RegisterMetrics(registry)).To(Succeed())
+
+		tmpDir, err := os.MkdirTemp("", "dpucniprovisioner")
+		defer func() {
+			err := os.RemoveAll(tmpDir)
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		Expect(err).NotTo(HaveOccurred())
+		provisioner.FileSystemRoot = tmpDir
+		Expect(os.MkdirAll(filepath.Join(tmpDir, "/etc/openvswitch"), 0755)).To(Succeed())
+
+		dhcpServers := make(chan *blockingCmd, 3)
+		for range 3 {
+			fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+				Expect(cmd).To(Equal("dnsmasq"))
+				c := &blockingCmd{FakeCmd: &kexecTesting.FakeCmd{Argv: args}, exit: make(chan error, 1)}
+				dhcpServers <- c
+				return c
+			}))
+		}
+
+		var pfMAC atomic.Value
+		mac, err := net.ParseMAC("00:00:00:00:00:01")
+		Expect(err).ToNot(HaveOccurred())
+		pfMAC.Store(mac)
+		networkhelper.EXPECT().GetHostPFMACAddressDPU("0").DoAndReturn(func(string) (net.HardwareAddr, error) {
+			return pfMAC.Load().(net.HardwareAddr), nil
+		}).AnyTimes()
+		dummyIP, err := netlink.ParseIPNet("10.244.6.30/24")
+		Expect(err).ToNot(HaveOccurred())
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{dummyIP}, nil).AnyTimes()
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{dummyIP}, nil).AnyTimes()
+		networkHelperMockAll(networkhelper)
+		ovsClientMockAll(ovsClient, ovsTxn)
+
+		Expect(provisioner.RunOnce()).To(Succeed())
+		var first *blockingCmd
+		Expect(dhcpServers).To(Receive(&first))
+
+		By("Having the DHCP server exit")
+		first.exit <- errors.New("signal: killed")
+		Eventually(provisioner.CheckReadiness).Should(MatchError(ContainSubstring("DHCP server exited: signal: killed")))
+
+		By("Checking that it's restarted after the backoff")
+		var second *blockingCmd
+		Eventually(func(g Gomega) {
+			fakeClock.Step(time.Second)
+			g.Expect(dhcpServers).To(Receive(&second))
+		}).Should(Succeed())
+		Expect(second.Argv).To(Equal(first.Argv))
+		Eventually(provisioner.CheckReadiness).Should(Succeed())
+
+		By("Changing the MAC address of the PF")
+		newMAC, err := net.ParseMAC("00:00:00:00:00:02")
+		Expect(err).ToNot(HaveOccurred())
+		pfMAC.Store(newMAC)
+		Expect(provisioner.RunOnce()).To(Succeed())
+		var third *blockingCmd
+		Expect(dhcpServers).To(Receive(&third))
+		Expect(third.Argv).To(ContainElement("--dhcp-host=00:00:00:00:00:02,192.168.1.2"))
+
+		By("Checking that replacing the DHCP server is not counted as an unexpected exit")
+		Consistently(func() error {
+			fakeClock.Step(time.Second)
+			return provisioner.CheckReadiness()
+		}).Should(Succeed())
+		Expect(testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP dpucniprovisioner_dhcp_server_exits_total Number of unexpected exits of the DHCP server process by reason.
+# TYPE dpucniprovisioner_dhcp_server_exits_total counter
+dpucniprovisioner_dhcp_server_exits_total{reason="error"} 1
+# HELP dpucniprovisioner_dhcp_server_restarts_total Number of times the DHCP server process had to be restarted.
+# TYPE dpucniprovisioner_dhcp_server_restarts_total counter
+dpucniprovisioner_dhcp_server_restarts_total 1
+`), "dpucniprovisioner_dhcp_server_exits_total", "dpucniprovisioner_dhcp_server_restarts_total")).To(Succeed())
+	})
+})
+
+var _ = Describe("DPU CNI Provisioner in dual-stack clusters", func() {
+	fakeNode := newFakeDPUNode()
+
+	It("should configure both IP families in Internal mode", func() {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		kubernetesClient := testclient.NewClientset(fakeNode.DeepCopy())
+
+		vtepIPNet := mustParseIPNet("192.168.1.1/24")
+		gateway := net.ParseIP("192.168.1.10")
+		vtepCIDR := mustParseIPNet("192.168.1.0/23")
+		hostCIDR := mustParseIPNet("10.0.100.1/24")
+		pfIPNet := mustParseIPNet("192.168.1.2/24")
+		vtepIPNet6 := mustParseIPNet("fd00:1::1/64")
+		gateway6 := net.ParseIP("fd00:1::a")
+		vtepCIDR6 := mustParseCIDR("fd00::/48")
+		hostCIDR6 := mustParseCIDR("fd00:100::/64")
+		pfIPNet6 := mustParseIPNet("fd00:1::2/64")
+
+		provisioner := newInternalIPAMProvisioner(context.Background(), clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, 8940)
+		Expect(provisioner.AddIPFamily(vtepIPNet, gateway, []*net.IPNet{vtepCIDR}, []*net.IPNet{hostCIDR}, pfIPNet, nil)).ToNot(Succeed())
+		Expect(provisioner.AddIPFamily(vtepIPNet6, gateway, []*net.IPNet{vtepCIDR6}, []*net.IPNet{hostCIDR6}, pfIPNet6, nil)).ToNot(Succeed())
+		Expect(provisioner.AddIPFamily(vtepIPNet6, gateway6, []*net.IPNet{vtepCIDR6}, []*net.IPNet{hostCIDR6}, pfIPNet6, nil)).To(Succeed())
+
+		tmpDir := provisioner.FileSystemRoot
+		ovnInputDirPath := filepath.Join(tmpDir, "/etc/openvswitch")
+		Expect(os.MkdirAll(ovnInputDirPath, 0755)).To(Succeed())
+
+		mac, _ := net.ParseMAC("00:00:00:00:00:01")
+		fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+			Expect(cmd).To(Equal("dnsmasq"))
+			Expect(args).To(Equal([]string{
+				"--keep-in-foreground",
+				"--port=0",
+				"--log-facility=-",
+				"--interface=br-ovn",
+				"--dhcp-option=option:router",
+				"--dhcp-option=option:mtu,9000",
+				"--dhcp-range=192.168.1.0,static",
+				"--dhcp-range=fd00:1::,static,64",
+				"--enable-ra",
+				"--ra-param=br-ovn,mtu:9000,60,0",
+				"--dhcp-host=00:00:00:00:00:01,192.168.1.2,[fd00:1::2]",
+				"--dhcp-option=option:classless-static-route,192.168.1.0/23,192.168.1.10",
+			}))
+			return kexec.New().Command("echo")
+		}))
+
+		networkhelper.EXPECT().LinkIPAddressExists("br-ovn", vtepIPNet)
+		networkhelper.EXPECT().SetLinkIPAddress("br-ovn", vtepIPNet)
+		networkhelper.EXPECT().LinkIPAddressExists("br-ovn", vtepIPNet6)
+		networkhelper.EXPECT().SetLinkIPAddress("br-ovn", vtepIPNet6)
+		networkhelper.EXPECT().SetLinkUp("br-ovn")
+		networkhelper.EXPECT().RouteExists(vtepCIDR, gateway, "br-ovn", nil)
+		networkhelper.EXPECT().AddRoute(vtepCIDR, gateway, "br-ovn", nil, nil)
+		networkhelper.EXPECT().RouteExists(vtepCIDR6, gateway6, "br-ovn", nil)
+		networkhelper.EXPECT().AddRoute(vtepCIDR6, gateway6, "br-ovn", nil, nil)
+		networkhelper.EXPECT().RouteExists(hostCIDR, gateway, "br-ovn", nil)
+		networkhelper.EXPECT().AddRoute(hostCIDR, gateway, "br-ovn", ptr.To(10000), nil)
+		networkhelper.EXPECT().RouteExists(hostCIDR6, gateway6, "br-ovn", nil)
+		networkhelper.EXPECT().AddRoute(hostCIDR6, gateway6, "br-ovn", ptr.To(10000), nil)
+		networkhelper.EXPECT().GetHostPFMACAddressDPU("0").Return(mac, nil)
+
+		By("Configuring source routing for both IP families")
+		flannelIP := mustParseIPNet("10.244.6.30/24")
+		flannelIP6 := mustParseIPNet("fd10:244:6::1e/64")
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{flannelIP}, nil)
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv6).Return([]*net.IPNet{flannelIP6}, nil)
+		networkhelper.EXPECT().RuleExists(mustParseCIDR("10.244.6.0/24"), 60, 31000).Return(false, nil)
+		networkhelper.EXPECT().AddRule(mustParseCIDR("10.244.6.0/24"), 60, 31000).Return(nil)
+		networkhelper.EXPECT().RuleExists(mustParseCIDR("fd10:244:6::/64"), 60, 31000).Return(false, nil)
+		networkhelper.EXPECT().AddRule(mustParseCIDR("fd10:244:6::/64"), 60, 31000).Return(nil)
+
+		oobIP := mustParseIPNet("10.0.100.100/24")
+		oobIP6 := mustParseIPNet("fd00:100::64/64")
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{oobIP}, nil)
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv6).Return([]*net.IPNet{oobIP6}, nil)
+		networkhelper.EXPECT().RuleExists(mustParseIPNet("10.0.100.100/32"), 60, 32000).Return(false, nil)
+		networkhelper.EXPECT().AddRule(mustParseIPNet("10.0.100.100/32"), 60, 32000).Return(nil)
+		networkhelper.EXPECT().RuleExists(mustParseIPNet("fd00:100::64/128"), 60, 32000).Return(false, nil)
+		networkhelper.EXPECT().AddRule(mustParseIPNet("fd00:100::64/128"), 60, 32000).Return(nil)
+
+		defaultGateway := net.ParseIP("10.0.100.254")
+		defaultGateway6 := net.ParseIP("fd00:100::fe")
+		networkhelper.EXPECT().GetGateway(mustParseCIDR("0.0.0.0/0")).Return(defaultGateway, nil)
+		networkhelper.EXPECT().GetGateway(mustParseCIDR("::/0")).Return(defaultGateway6, nil)
+		networkhelper.EXPECT().RouteExists(vtepCIDR, defaultGateway, "br-comm-ch", ptr.To(60)).Return(false, nil)
+		networkhelper.EXPECT().AddRoute(vtepCIDR, defaultGateway, "br-comm-ch", nil, ptr.To(60)).Return(nil)
+		networkhelper.EXPECT().RouteExists(vtepCIDR6, defaultGateway6, "br-comm-ch", ptr.To(60)).Return(false, nil)
+		networkhelper.EXPECT().AddRoute(vtepCIDR6, defaultGateway6, "br-comm-ch", nil, ptr.To(60)).Return(nil)
+
+		By("Using the VTEP IP of the primary IP family for the geneve tunnels")
+		ovsTxn.EXPECT().SetOVNEncapIP(net.ParseIP("192.168.1.1"))
+		ovsTxn.EXPECT().SetKubernetesHostNodeName("host1")
+		ovsTxn.EXPECT().SetHostName("host1")
+		ovsTxn.EXPECT().Commit()
+
+		networkhelper.EXPECT().ListOwnedRoutes()
+		networkhelper.EXPECT().ListOwnedRules()
+		networkhelper.EXPECT().GetLinkMTU("br-ovn").Return(9216, nil)
+		expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.InternalIPAM)
+		Expect(provisioner.RunOnce()).To(Succeed())
+
+		ovnInput, err := os.ReadFile(filepath.Join(ovnInputDirPath, "ovn_k8s.conf"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(ovnInput)).To(Equal("[Gateway]\nnext-hop=192.168.1.10,fd00:1::a\nrouter-subnet=192.168.1.0/24,fd00:1::/64\n"))
+		Expect(fakeExec.CommandCalls).To(Equal(1))
+	})
+
+	It("should request an address per IP family for br-ovn in External mode", func() {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		kubernetesClient := testclient.NewClientset(fakeNode.DeepCopy())
+
+		// IPv6 is the primary IP family
+		vtepCIDR6 := mustParseCIDR("fd00::/48")
+		hostCIDR6 := mustParseCIDR("fd00:100::/64")
+		gatewayDiscoveryNetwork6 := mustParseCIDR("fd00:ffff::/128")
+		vtepCIDR := mustParseCIDR("192.168.0.0/23")
+		hostCIDR := mustParseCIDR("10.0.100.0/24")
+		gatewayDiscoveryNetwork := mustParseCIDR("169.254.99.100/32")
+		provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.ExternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, nil, nil, []*net.IPNet{vtepCIDR6}, []*net.IPNet{hostCIDR6}, nil, fakeNode.Name, gatewayDiscoveryNetwork6, 0)
+		Expect(provisioner.AddIPFamily(nil, nil, []*net.IPNet{vtepCIDR}, []*net.IPNet{hostCIDR}, nil, gatewayDiscoveryNetwork)).To(Succeed())
+
+		tmpDir, err := os.MkdirTemp("", "dpucniprovisioner")
+		Expect(err).NotTo(HaveOccurred())
+		defer func() {
+			Expect(os.RemoveAll(tmpDir)).To(Succeed())
+		}()
+		provisioner.FileSystemRoot = tmpDir
+		netplanDirPath := filepath.Join(tmpDir, "/etc/netplan")
+		Expect(os.MkdirAll(netplanDirPath, 0755)).To(Succeed())
+		ovnInputDirPath := filepath.Join(tmpDir, "/etc/openvswitch")
+		Expect(os.MkdirAll(ovnInputDirPath, 0755)).To(Succeed())
+
+		fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+			Expect(cmd).To(Equal("netplan"))
+			Expect(args).To(Equal([]string{"apply"}))
+			return kexec.New().Command("echo")
+		}))
+
+		ovsTxn.EXPECT().SetKubernetesHostNodeName("host1").Times(2)
+		ovsTxn.EXPECT().SetHostName("host1").Times(2)
+
+		By("Running netplan apply while br-ovn has no IPv4 address yet")
+		brOVNAddress6 := mustParseIPNet("fd00:0:1::3/64")
+		gateway6 := net.ParseIP("fd00:0:1::fe")
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-ovn", nethelper.IPv6).Return([]*net.IPNet{brOVNAddress6}, nil).Times(2)
+		networkhelper.EXPECT().GetGateway(gatewayDiscoveryNetwork6).Return(gateway6, nil).Times(2)
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-ovn", nethelper.IPv4).Return(nil, nil)
+		expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv6, dpucniprovisioner.ExternalIPAM)
+		err = provisioner.RunOnce()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("exactly 1 IPv4 IP is expected in br-ovn, but found 0"))
+		Expect(fakeExec.CommandCalls).To(Equal(1))
+
+		netplanFileContent, err := os.ReadFile(filepath.Join(netplanDirPath, "80-br-ovn.yaml"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(netplanFileContent)).To(Equal(`
+network:
+  renderer: networkd
+  version: 2
+  bridges:
+    br-ovn:
+      dhcp4: yes
+      dhcp4-overrides:
+        use-dns: no
+      dhcp6: yes
+      dhcp6-overrides:
+        use-dns: no
+      accept-ra: yes
+      openvswitch: {}
+`))
+
+		By("Configuring both IP families once br-ovn has both addresses")
+		brOVNAddress := mustParseIPNet("192.168.0.3/23")
+		gateway := net.ParseIP("192.168.1.254")
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-ovn", nethelper.IPv4).Return([]*net.IPNet{brOVNAddress}, nil)
+		networkhelper.EXPECT().GetGateway(gatewayDiscoveryNetwork).Return(gateway, nil)
+		networkhelper.EXPECT().RouteExists(hostCIDR6, gateway6, "br-ovn", nil).Return(true, nil)
+		networkhelper.EXPECT().RouteExists(hostCIDR, gateway, "br-ovn", nil).Return(true, nil)
+		ovsTxn.EXPECT().SetOVNEncapIP(brOVNAddress6.IP)
+		ovsTxn.EXPECT().Commit()
+
+		By("Skipping source routing of the secondary IP family when the DPU cluster network is single-stack")
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv6).Return([]*net.IPNet{mustParseIPNet("fd10:244:6::1e/64")}, nil)
+		networkhelper.EXPECT().RuleExists(gomock.Any(), 60, gomock.Any()).Return(true, nil).Times(2)
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv6).Return([]*net.IPNet{mustParseIPNet("fd00:100::64/64")}, nil)
+		networkhelper.EXPECT().GetGateway(mustParseCIDR("::/0")).Return(net.ParseIP("fd00:100::fe"), nil)
+		networkhelper.EXPECT().RouteExists(vtepCIDR6, net.ParseIP("fd00:100::fe"), "br-comm-ch", ptr.To(60)).Return(true, nil)
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return(nil, nil)
+
+		networkhelper.EXPECT().ListOwnedRoutes()
+		networkhelper.EXPECT().ListOwnedRules()
+		networkhelper.EXPECT().GetLinkMTU("br-ovn").Return(9216, nil)
+		expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv6, dpucniprovisioner.ExternalIPAM)
+		Expect(provisioner.RunOnce()).To(Succeed())
+
+		ovnInput, err := os.ReadFile(filepath.Join(ovnInputDirPath, "ovn_k8s.conf"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(ovnInput)).To(Equal("[Gateway]\nnext-hop=fd00:0:1::fe,192.168.1.254\nrouter-subnet=fd00:0:1::/64,192.168.0.0/23\n"))
+	})
+})
+
+var _ = Describe("DPU CNI Provisioner in multi-rack fabrics", func() {
+	It("should add a route per VTEP CIDR and host CIDR", func() {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		vtepIPNet, err := netlink.ParseIPNet("192.168.1.1/24")
+		Expect(err).ToNot(HaveOccurred())
+		gateway := net.ParseIP("192.168.1.10")
+		_, localVTEPCIDR, err := net.ParseCIDR("192.168.1.0/24")
+		Expect(err).ToNot(HaveOccurred())
+		_, rack2VTEPCIDR, err := net.ParseCIDR("192.168.2.0/24")
+		Expect(err).ToNot(HaveOccurred())
+		_, rack3VTEPCIDR, err := net.ParseCIDR("192.168.3.0/24")
+		Expect(err).ToNot(HaveOccurred())
+		_, hostCIDR1, err := net.ParseCIDR("10.0.100.0/24")
+		Expect(err).ToNot(HaveOccurred())
+		_, hostCIDR2, err := net.ParseCIDR("10.0.101.0/24")
+		Expect(err).ToNot(HaveOccurred())
+		pfIPNet, err := netlink.ParseIPNet("192.168.1.2/24")
+		Expect(err).ToNot(HaveOccurred())
+		oobIPNet, err := netlink.ParseIPNet("10.0.100.100/24")
+		Expect(err).ToNot(HaveOccurred())
+		oobIPNetWith32Mask, err := netlink.ParseIPNet("10.0.100.100/32")
+		Expect(err).ToNot(HaveOccurred())
+		flannelIP, err := netlink.ParseIPNet("10.244.6.30/24")
+		Expect(err).ToNot(HaveOccurred())
+		_, defaultRouteNetwork, err := net.ParseCIDR("0.0.0.0/0")
+		Expect(err).ToNot(HaveOccurred())
+		defaultGateway := net.ParseIP("10.0.100.254")
+		fakeNode := newFakeDPUNode()
+		kubernetesClient := testclient.NewClientset(fakeNode)
+		vtepCIDRs := []*net.IPNet{localVTEPCIDR, rack2VTEPCIDR, rack3VTEPCIDR}
+		provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.InternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, vtepIPNet, gateway, vtepCIDRs, []*net.IPNet{hostCIDR1, hostCIDR2}, pfIPNet, fakeNode.Name, nil, 1500)
+
+		// Prepare Filesystem
+		tmpDir, err := os.MkdirTemp("", "dpucniprovisioner")
+		defer func() {
+			err := os.RemoveAll(tmpDir)
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		Expect(err).NotTo(HaveOccurred())
+		provisioner.FileSystemRoot = tmpDir
+		ovnInputDirPath := filepath.Join(tmpDir, "/etc/openvswitch")
+		Expect(os.MkdirAll(ovnInputDirPath, 0755)).To(Succeed())
+
+		mac, _ := net.ParseMAC("00:00:00:00:00:01")
+		fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+			Expect(cmd).To(Equal("dnsmasq"))
+			Expect(args).To(Equal([]string{
+				"--keep-in-foreground",
+				"--port=0",
+				"--log-facility=-",
+				"--interface=br-ovn",
+				"--dhcp-option=option:router",
+				"--dhcp-option=option:mtu,1560",
+				"--dhcp-range=192.168.1.0,static",
+				"--dhcp-host=00:00:00:00:00:01,192.168.1.2",
+				"--dhcp-option=option:classless-static-route,192.168.2.0/24,192.168.1.10,192.168.3.0/24,192.168.1.10",
+			}))
+
+			return kexec.New().Command("echo")
+		}))
+
+		networkhelper.EXPECT().LinkIPAddressExists("br-ovn", vtepIPNet)
+		networkhelper.EXPECT().SetLinkIPAddress("br-ovn", vtepIPNet)
+		networkhelper.EXPECT().SetLinkUp("br-ovn")
+		networkhelper.EXPECT().RouteExists(rack2VTEPCIDR, gateway, "br-ovn", nil)
+		networkhelper.EXPECT().AddRoute(rack2VTEPCIDR, gateway, "br-ovn", nil, nil)
+		networkhelper.EXPECT().RouteExists(rack3VTEPCIDR, gateway, "br-ovn", nil)
+		networkhelper.EXPECT().AddRoute(rack3VTEPCIDR, gateway, "br-ovn", nil, nil)
+		networkhelper.EXPECT().RouteExists(hostCIDR1, gateway, "br-ovn", nil)
+		networkhelper.EXPECT().AddRoute(hostCIDR1, gateway, "br-ovn", ptr.To[int](10000), nil)
+		networkhelper.EXPECT().RouteExists(hostCIDR2, gateway, "br-ovn", nil)
+		networkhelper.EXPECT().AddRoute(hostCIDR2, gateway, "br-ovn", ptr.To[int](10000), nil)
+		networkhelper.EXPECT().GetHostPFMACAddressDPU("0").Return(mac, nil)
+
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{flannelIP}, nil)
+		_, flannelIPNet, err := net.ParseCIDR(flannelIP.String())
+		Expect(err).ToNot(HaveOccurred())
+		networkhelper.EXPECT().RuleExists(flannelIPNet, 60, 31000).Return(true, nil)
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{oobIPNet}, nil)
+		networkhelper.EXPECT().RuleExists(oobIPNetWith32Mask, 60, 32000).Return(true, nil)
+		networkhelper.EXPECT().GetGateway(defaultRouteNetwork).Return(defaultGateway, nil)
+		for _, vtepCIDR := range vtepCIDRs {
+			networkhelper.EXPECT().RouteExists(vtepCIDR, defaultGateway, "br-comm-ch", ptr.To(60)).Return(false, nil)
+			networkhelper.EXPECT().AddRoute(vtepCIDR, defaultGateway, "br-comm-ch", nil, ptr.To(60)).Return(nil)
+		}
+
+		ovsTxn.EXPECT().SetOVNEncapIP(net.ParseIP("192.168.1.1"))
+		ovsTxn.EXPECT().SetKubernetesHostNodeName("host1")
+		ovsTxn.EXPECT().SetHostName("host1")
+		ovsTxn.EXPECT().Commit()
+
+		networkhelper.EXPECT().ListOwnedRoutes()
+		networkhelper.EXPECT().ListOwnedRules()
+		networkhelper.EXPECT().GetLinkMTU("br-ovn").Return(9216, nil)
+		expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.InternalIPAM)
+		Expect(provisioner.RunOnce()).To(Succeed())
+		Expect(fakeExec.CommandCalls).To(Equal(1))
+	})
+})
+
+var _ = Describe("DPU CNI Provisioner garbage collection", func() {
+	It("should delete the routes and rules it owns that are no longer needed", func() {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		_, hostCIDR, err := net.ParseCIDR("10.0.100.0/24")
+		Expect(err).ToNot(HaveOccurred())
+		_, gatewayDiscoveryNetwork, err := net.ParseCIDR("169.254.99.100/32")
+		Expect(err).ToNot(HaveOccurred())
+		_, localVTEPCIDR, err := net.ParseCIDR("192.168.0.0/23")
+		Expect(err).ToNot(HaveOccurred())
+		_, remoteVTEPCIDR, err := net.ParseCIDR("192.168.2.0/23")
+		Expect(err).ToNot(HaveOccurred())
+		oobIPNet, err := netlink.ParseIPNet("10.0.100.100/24")
+		Expect(err).ToNot(HaveOccurred())
+		oobIPNetWith32Mask, err := netlink.ParseIPNet("10.0.100.100/32")
+		Expect(err).ToNot(HaveOccurred())
+		flannelIP, err := netlink.ParseIPNet("10.244.6.30/24")
+		Expect(err).ToNot(HaveOccurred())
+		_, flannelIPNet, err := net.ParseCIDR(flannelIP.String())
+		Expect(err).ToNot(HaveOccurred())
+		_, defaultRouteNetwork, err := net.ParseCIDR("0.0.0.0/0")
+		Expect(err).ToNot(HaveOccurred())
+		defaultGateway := net.ParseIP("10.0.100.254")
+		fakeNode := newFakeDPUNode()
+		kubernetesClient := testclient.NewClientset(fakeNode)
+		provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.ExternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, nil, nil, []*net.IPNet{localVTEPCIDR, remoteVTEPCIDR}, []*net.IPNet{hostCIDR}, nil, fakeNode.Name, gatewayDiscoveryNetwork, 0)
+
+		// Prepare Filesystem
+		tmpDir, err := os.MkdirTemp("", "dpucniprovisioner")
+		defer func() {
+			err := os.RemoveAll(tmpDir)
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		Expect(err).NotTo(HaveOccurred())
+		provisioner.FileSystemRoot = tmpDir
+		Expect(os.MkdirAll(filepath.Join(tmpDir, "/etc/netplan"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(tmpDir, "/etc/openvswitch"), 0755)).To(Succeed())
+
+		brOVNAddress, err := netlink.ParseIPNet("192.168.0.3/23")
+		Expect(err).ToNot(HaveOccurred())
+		ovsTxn.EXPECT().SetKubernetesHostNodeName("host1")
+		ovsTxn.EXPECT().SetHostName("host1")
+		ovsTxn.EXPECT().SetOVNEncapIP(brOVNAddress.IP)
+		ovsTxn.EXPECT().Commit()
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-ovn", nethelper.IPv4).Return([]*net.IPNet{brOVNAddress}, nil)
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{flannelIP}, nil)
+		networkhelper.EXPECT().RuleExists(flannelIPNet, 60, 31000).Return(true, nil)
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{oobIPNet}, nil)
+		networkhelper.EXPECT().RuleExists(oobIPNetWith32Mask, 60, 32000).Return(true, nil)
+		networkhelper.EXPECT().GetGateway(defaultRouteNetwork).Return(defaultGateway, nil)
+		networkhelper.EXPECT().RouteExists(localVTEPCIDR, defaultGateway, "br-comm-ch", ptr.To(60)).Return(true, nil)
+		networkhelper.EXPECT().RouteExists(remoteVTEPCIDR, defaultGateway, "br-comm-ch", ptr.To(60)).Return(true, nil)
+
+		gateway := net.ParseIP("192.168.1.253")
+		networkhelper.EXPECT().GetGateway(gatewayDiscoveryNetwork).Return(gateway, nil)
+		networkhelper.EXPECT().RouteExists(hostCIDR, gateway, "br-ovn", nil).Return(false, nil)
+		networkhelper.EXPECT().AddRoute(hostCIDR, gateway, "br-ovn", ptr.To(10000), nil).Return(nil)
+
+		// The routes via the previous gateway and of the VTEP CIDR dropped from the configuration before the provisioner
+		// restarted, as well as the rule of the previous flannel subnet are stale
+		previousGateway := net.ParseIP("192.168.1.254")
+		_, previousFlannelIPNet, err := net.ParseCIDR("10.244.5.0/24")
+		Expect(err).ToNot(HaveOccurred())
+		_, droppedVTEPCIDR, err := net.ParseCIDR("192.168.4.0/23")
+		Expect(err).ToNot(HaveOccurred())
+		networkhelper.EXPECT().ListOwnedRoutes().Return([]nethelper.Route{
+			{Network: hostCIDR, Gateway: previousGateway, Device: "br-ovn", Metric: 10000, Table: 254},
+			{Network: hostCIDR, Gateway: gateway, Device: "br-ovn", Metric: 10000, Table: 254},
+			{Network: localVTEPCIDR, Gateway: defaultGateway, Device: "br-comm-ch", Table: 60},
+			{Network: remoteVTEPCIDR, Gateway: defaultGateway, Device: "br-comm-ch", Table: 60},
+			{Network: droppedVTEPCIDR, Gateway: defaultGateway, Device: "br-comm-ch", Table: 60},
+		}, nil)
+		networkhelper.EXPECT().ListOwnedRules().Return([]nethelper.Rule{
+			{Src: previousFlannelIPNet, Table: 60, Priority: 31000},
+			{Src: flannelIPNet, Table: 60, Priority: 31000},
+			{Src: oobIPNetWith32Mask, Table: 60, Priority: 32000},
+		}, nil)
+		networkhelper.EXPECT().DeleteRouteFromTable(hostCIDR, previousGateway, "br-ovn", ptr.To(254)).Return(nil)
+		networkhelper.EXPECT().DeleteRouteFromTable(droppedVTEPCIDR, defaultGateway, "br-comm-ch", ptr.To(60)).Return(nil)
+		networkhelper.EXPECT().DeleteRule(previousFlannelIPNet, 60, 31000).Return(nil)
+		networkhelper.EXPECT().GetLinkMTU("br-ovn").Return(9216, nil)
+		expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.ExternalIPAM)
+		Expect(provisioner.RunOnce()).To(Succeed())
+	})
+})
+
+var _ = Describe("DPU CNI Provisioner settings update", func() {
+	It("should apply updated settings on the next run", func() {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		_, hostCIDR, err := net.ParseCIDR("10.0.100.0/24")
+		Expect(err).ToNot(HaveOccurred())
+		_, newHostCIDR, err := net.ParseCIDR("10.0.200.0/24")
+		Expect(err).ToNot(HaveOccurred())
+		_, gatewayDiscoveryNetwork, err := net.ParseCIDR("169.254.99.100/32")
+		Expect(err).ToNot(HaveOccurred())
+		_, vtepCIDR, err := net.ParseCIDR("192.168.0.0/23")
+		Expect(err).ToNot(HaveOccurred())
+		oobIPNet, err := netlink.ParseIPNet("10.0.100.100/24")
+		Expect(err).ToNot(HaveOccurred())
+		oobIPNetWith32Mask, err := netlink.ParseIPNet("10.0.100.100/32")
+		Expect(err).ToNot(HaveOccurred())
+		flannelIP, err := netlink.ParseIPNet("10.244.6.30/24")
+		Expect(err).ToNot(HaveOccurred())
+		_, flannelIPNet, err := net.ParseCIDR(flannelIP.String())
+		Expect(err).ToNot(HaveOccurred())
+		_, defaultRouteNetwork, err := net.ParseCIDR("0.0.0.0/0")
+		Expect(err).ToNot(HaveOccurred())
+		defaultGateway := net.ParseIP("10.0.100.254")
+		fakeNode := newFakeDPUNode()
+		kubernetesClient := testclient.NewClientset(fakeNode)
+		provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.ExternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, nil, nil, []*net.IPNet{vtepCIDR}, []*net.IPNet{hostCIDR}, nil, fakeNode.Name, gatewayDiscoveryNetwork, 0)
+
+		// Prepare Filesystem
+		tmpDir, err := os.MkdirTemp("", "dpucniprovisioner")
+		defer func() {
+			err := os.RemoveAll(tmpDir)
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		Expect(err).NotTo(HaveOccurred())
+		provisioner.FileSystemRoot = tmpDir
+		Expect(os.MkdirAll(filepath.Join(tmpDir, "/etc/netplan"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(tmpDir, "/etc/openvswitch"), 0755)).To(Succeed())
+
+		brOVNAddress, err := netlink.ParseIPNet("192.168.0.3/23")
+		Expect(err).ToNot(HaveOccurred())
+		gateway := net.ParseIP("192.168.1.254")
+		ovsTxn.EXPECT().SetKubernetesHostNodeName("host1").Times(2)
+		ovsTxn.EXPECT().SetHostName("host1").Times(2)
+		ovsTxn.EXPECT().SetOVNEncapIP(brOVNAddress.IP).Times(2)
+		ovsTxn.EXPECT().Commit().Times(2)
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-ovn", nethelper.IPv4).Return([]*net.IPNet{brOVNAddress}, nil).Times(2)
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{flannelIP}, nil).Times(2)
+		networkhelper.EXPECT().RuleExists(flannelIPNet, 60, 31000).Return(true, nil).Times(2)
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{oobIPNet}, nil).Times(2)
+		networkhelper.EXPECT().RuleExists(oobIPNetWith32Mask, 60, 32000).Return(true, nil).Times(2)
+		networkhelper.EXPECT().GetGateway(defaultRouteNetwork).Return(defaultGateway, nil).Times(2)
+		networkhelper.EXPECT().RouteExists(vtepCIDR, defaultGateway, "br-comm-ch", ptr.To(60)).Return(true, nil).Times(2)
+		networkhelper.EXPECT().GetGateway(gatewayDiscoveryNetwork).Return(gateway, nil).Times(2)
+
+		networkhelper.EXPECT().RouteExists(hostCIDR, gateway, "br-ovn", nil).Return(true, nil)
+		networkhelper.EXPECT().ListOwnedRoutes()
+		networkhelper.EXPECT().ListOwnedRules()
+		networkhelper.EXPECT().GetLinkMTU("br-ovn").Return(9216, nil)
+		expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.ExternalIPAM)
+		Expect(provisioner.RunOnce()).To(Succeed())
+
+		// Invalid settings are rejected and the current ones are kept
+		Expect(provisioner.UpdateSettings(dpucniprovisioner.Settings{})).ToNot(Succeed())
+		Expect(provisioner.UpdateSettings(dpucniprovisioner.Settings{
+			IPFamilies: []dpucniprovisioner.IPFamilySettings{
+				{VTEPCIDRs: []*net.IPNet{vtepCIDR}, HostCIDRs: []*net.IPNet{newHostCIDR}, GatewayDiscoveryNetwork: gatewayDiscoveryNetwork},
+			},
+		})).To(Succeed())
+
+		// The route to the host CIDR that was replaced is removed
+		networkhelper.EXPECT().RouteExists(newHostCIDR, gateway, "br-ovn", nil).Return(false, nil)
+		networkhelper.EXPECT().AddRoute(newHostCIDR, gateway, "br-ovn", ptr.To(10000), nil).Return(nil)
+		networkhelper.EXPECT().ListOwnedRoutes().Return([]nethelper.Route{
+			{Network: hostCIDR, Gateway: gateway, Device: "br-ovn", Metric: 10000, Table: 254},
+			{Network: newHostCIDR, Gateway: gateway, Device: "br-ovn", Metric: 10000, Table: 254},
+			{Network: vtepCIDR, Gateway: defaultGateway, Device: "br-comm-ch", Table: 60},
+		}, nil)
+		networkhelper.EXPECT().ListOwnedRules()
+		networkhelper.EXPECT().DeleteRouteFromTable(hostCIDR, gateway, "br-ovn", ptr.To(254)).Return(nil)
+		networkhelper.EXPECT().GetLinkMTU("br-ovn").Return(9216, nil)
+		expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.ExternalIPAM)
+		Expect(provisioner.RunOnce()).To(Succeed())
+	})
+})
+
+var _ = Describe("DPU CNI Provisioner interface selection", func() {
+	var (
+		vtepCIDR  = mustParseIPNet("192.168.1.0/23")
+		flannelIP = mustParseIPNet("10.244.6.30/24")
+		oobIP     = mustParseIPNet("10.0.110.70/24")
+	)
+
+	newProvisioner := func(networkhelper *networkhelperMock.MockNetworkHelper, ovsClient *ovsclientMock.MockOVSClient) *dpucniprovisioner.DPUCNIProvisioner {
+		fakeExec := &kexecTesting.FakeExec{}
+		fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+			return kexec.New().Command("echo")
+		}))
+		return newInternalIPAMProvisioner(context.Background(), clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, testclient.NewClientset(newFakeDPUNode()), 1500)
+	}
+
+	It("should discover the OOB bridge, the flannel interface and the PF", func() {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		provisioner := newProvisioner(networkhelper, ovsClient)
+		registry := prometheus.NewRegistry()
+		Expect(provisioner.RegisterMetrics(registry)).To(Succeed())
+
+		cniConfDir := filepath.Join(provisioner.FileSystemRoot, "/etc/cni/net.d")
+		Expect(os.MkdirAll(cniConfDir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(cniConfDir, "05-loopback.conf"), []byte(`{"cniVersion": "0.3.1", "name": "lo", "type": "loopback"}`), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(cniConfDir, "10-flannel.conflist"), []byte(`{
+  "name": "cbr0",
+  "cniVersion": "0.3.1",
+  "plugins": [
+    {"type": "flannel", "delegate": {"bridge": "pods0", "hairpinMode": true, "isDefaultGateway": true}},
+    {"type": "portmap", "capabilities": {"portMappings": true}}
+  ]
+}`), 0644)).To(Succeed())
+
+		// br-ovn is never the OOB bridge, even if it has the preferred default route
+		networkhelper.EXPECT().GetDefaultRouteDevices(nethelper.IPv4).Return([]string{"br-ovn", "oob0"}, nil)
+		// PF 0 is not connected to OVS, hence PF 1 is the one of the host
+		networkhelper.EXPECT().GetPFRepresentorDPU("0").Return("pf0hpf", nil)
+		ovsClient.EXPECT().InterfaceToBridge("pf0hpf").Return("", errors.New("no interface named pf0hpf"))
+		networkhelper.EXPECT().GetPFRepresentorDPU("1").Return("pf1hpf", nil)
+		ovsClient.EXPECT().InterfaceToBridge("pf1hpf").Return("br-sfc", nil)
+
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("pods0", nethelper.IPv4).Return([]*net.IPNet{flannelIP}, nil)
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("oob0", nethelper.IPv4).Return([]*net.IPNet{oobIP}, nil)
+		networkhelper.EXPECT().RouteExists(vtepCIDR, gomock.Any(), "oob0", ptr.To(60)).Return(true, nil)
+		mac, err := net.ParseMAC("00:00:00:00:00:01")
+		Expect(err).ToNot(HaveOccurred())
+		networkhelper.EXPECT().GetHostPFMACAddressDPU("1").Return(mac, nil)
+		networkHelperMockAll(networkhelper)
+		ovsClientMockAll(ovsClient, ovsTxn)
+
+		Expect(provisioner.RunOnce()).To(Succeed())
+		Expect(provisioner.ManagedLinks()).To(Equal([]string{"br-ovn", "oob0", "pods0"}))
+		Expect(testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP dpucniprovisioner_selected_interface_info Interfaces the provisioner works with by role and how they were selected.
+# TYPE dpucniprovisioner_selected_interface_info gauge
+dpucniprovisioner_selected_interface_info{name="1",role="pf",source="discovered"} 1
+dpucniprovisioner_selected_interface_info{name="oob0",role="oob",source="discovered"} 1
+dpucniprovisioner_selected_interface_info{name="pods0",role="flannel",source="discovered"} 1
+`), "dpucniprovisioner_selected_interface_info")).To(Succeed())
+	})
+
+	It("should use the overrides instead of discovering the interfaces", func() {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		provisioner := newProvisioner(networkhelper, ovsClient)
+		Expect(provisioner.SetInterfaceOverrides(dpucniprovisioner.Interfaces{PFIndex: "2"})).ToNot(Succeed())
+		Expect(provisioner.SetInterfaceOverrides(dpucniprovisioner.Interfaces{OOB: "oob1", Flannel: "pods1", PFIndex: "1"})).To(Succeed())
+
+		networkhelper.EXPECT().GetDefaultRouteDevices(gomock.Any()).Times(0)
+		networkhelper.EXPECT().GetPFRepresentorDPU(gomock.Any()).Times(0)
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("pods1", nethelper.IPv4).Return([]*net.IPNet{flannelIP}, nil)
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("oob1", nethelper.IPv4).Return([]*net.IPNet{oobIP}, nil)
+		mac, err := net.ParseMAC("00:00:00:00:00:01")
+		Expect(err).ToNot(HaveOccurred())
+		networkhelper.EXPECT().GetHostPFMACAddressDPU("1").Return(mac, nil)
+		networkHelperMockAll(networkhelper)
+		ovsClientMockAll(ovsClient, ovsTxn)
+
+		Expect(provisioner.RunOnce()).To(Succeed())
+		Expect(provisioner.ManagedLinks()).To(Equal([]string{"br-ovn", "oob1", "pods1"}))
+	})
+})
+
+var _ = Describe("DPU CNI Provisioner with multiple uplinks", func() {
+	fakeNode := newFakeDPUNode()
+	vtepIPNet := mustParseIPNet("192.168.1.1/24")
+	gateway := net.ParseIP("192.168.1.10")
+	vtepCIDR := mustParseCIDR("192.168.0.0/22")
+	hostCIDR := mustParseCIDR("10.0.100.0/24")
+	pfIPNet := mustParseIPNet("192.168.1.2/24")
+	uplinkVTEPIPNet := mustParseIPNet("192.168.2.1/24")
+	uplinkGateway := net.ParseIP("192.168.2.10")
+	uplinkPFIPNet := mustParseIPNet("192.168.2.2/24")
+	additionalUplink := dpucniprovisioner.Uplink{
+		Bridge:  "br-ovn-p1",
+		Port:    "p1",
+		PFIndex: "1",
+		IPFamilies: []dpucniprovisioner.UplinkIPFamily{
+			{VTEPIPNet: uplinkVTEPIPNet, Gateway: uplinkGateway, PFIP: uplinkPFIPNet},
+		},
+	}
+
+	newProvisioner := func(mode dpucniprovisioner.Mode, ovsClient *ovsclientMock.MockOVSClient, networkhelper *networkhelperMock.MockNetworkHelper, fakeExec *kexecTesting.FakeExec) *dpucniprovisioner.DPUCNIProvisioner {
+		provisioner := dpucniprovisioner.New(context.Background(), mode, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, testclient.NewClientset(fakeNode.DeepCopy()), vtepIPNet, gateway, []*net.IPNet{vtepCIDR}, []*net.IPNet{hostCIDR}, pfIPNet, fakeNode.Name, nil, 1500)
+		tmpDir, err := os.MkdirTemp("", "dpucniprovisioner")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(func() {
+			Expect(os.RemoveAll(tmpDir)).To(Succeed())
+		})
+		provisioner.FileSystemRoot = tmpDir
+		Expect(os.MkdirAll(filepath.Join(tmpDir, "/etc/openvswitch"), 0755)).To(Succeed())
+		return provisioner
+	}
+	expectSourceRoutingAddresses := func(networkhelper *networkhelperMock.MockNetworkHelper) {
+		dummyIP := mustParseIPNet("10.244.6.30/24")
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{dummyIP}, nil).AnyTimes()
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{dummyIP}, nil).AnyTimes()
+	}
+
+	It("should reject invalid uplinks", func() {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		provisioner := newProvisioner(dpucniprovisioner.InternalIPAM, ovsClient, networkhelper, &kexecTesting.FakeExec{})
+
+		withUplink := func(mutate func(u *dpucniprovisioner.Uplink)) dpucniprovisioner.Uplinks {
+			u := additionalUplink
+			u.IPFamilies = slices.Clone(u.IPFamilies)
+			mutate(&u)
+			return dpucniprovisioner.Uplinks{Additional: []dpucniprovisioner.Uplink{u}}
+		}
+		Expect(provisioner.SetUplinks(dpucniprovisioner.Uplinks{EncapMode: "round-robin"})).ToNot(Succeed())
+		Expect(provisioner.SetUplinks(withUplink(func(u *dpucniprovisioner.Uplink) { u.Bridge = "br-ovn" }))).ToNot(Succeed())
+		Expect(provisioner.SetUplinks(withUplink(func(u *dpucniprovisioner.Uplink) { u.PFIndex = "" }))).ToNot(Succeed())
+		Expect(provisioner.SetUplinks(withUplink(func(u *dpucniprovisioner.Uplink) { u.IPFamilies = nil }))).ToNot(Succeed())
+		Expect(provisioner.SetUplinks(withUplink(func(u *dpucniprovisioner.Uplink) { u.IPFamilies[0].Gateway = nil }))).ToNot(Succeed())
+		Expect(provisioner.SetUplinks(withUplink(func(u *dpucniprovisioner.Uplink) {}))).To(Succeed())
+
+		externalProvisioner := newProvisioner(dpucniprovisioner.ExternalIPAM, ovsClient, networkhelper, &kexecTesting.FakeExec{})
+		Expect(externalProvisioner.SetUplinks(withUplink(func(u *dpucniprovisioner.Uplink) {}))).ToNot(Succeed())
+		Expect(externalProvisioner.SetUplinks(dpucniprovisioner.Uplinks{PrimaryPort: "p0"})).To(Succeed())
+	})
+
+	It("should configure every uplink and serve the PF of each of them", func() {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		provisioner := newProvisioner(dpucniprovisioner.InternalIPAM, ovsClient, networkhelper, fakeExec)
+		Expect(provisioner.SetUplinks(dpucniprovisioner.Uplinks{Additional: []dpucniprovisioner.Uplink{additionalUplink}})).To(Succeed())
+
+		fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+			Expect(cmd).To(Equal("dnsmasq"))
+			Expect(args).To(Equal([]string{
+				"--keep-in-foreground",
+				"--port=0",
+				"--log-facility=-",
+				"--interface=br-ovn",
+				"--interface=br-ovn-p1",
+				"--dhcp-option=tag:br-ovn,option:router",
+				"--dhcp-option=tag:br-ovn,option:mtu,1560",
+				"--dhcp-range=set:br-ovn,192.168.1.0,static",
+				"--dhcp-option=tag:br-ovn-p1,option:router",
+				"--dhcp-option=tag:br-ovn-p1,option:mtu,1560",
+				"--dhcp-range=set:br-ovn-p1,192.168.2.0,static",
+				"--dhcp-host=00:00:00:00:00:01,192.168.1.2",
+				"--dhcp-host=00:00:00:00:00:02,192.168.2.2",
+				"--dhcp-option=tag:br-ovn,option:classless-static-route,192.168.0.0/22,192.168.1.10",
+				"--dhcp-option=tag:br-ovn-p1,option:classless-static-route,192.168.0.0/22,192.168.2.10",
+			}))
+			return kexec.New().Command("echo")
+		}))
+
+		mac0, _ := net.ParseMAC("00:00:00:00:00:01")
+		mac1, _ := net.ParseMAC("00:00:00:00:00:02")
+		networkhelper.EXPECT().GetHostPFMACAddressDPU("0").Return(mac0, nil)
+		networkhelper.EXPECT().GetHostPFMACAddressDPU("1").Return(mac1, nil)
+
+		By("Source routing the traffic of the VTEP IP of the additional uplink via its bridge")
+		networkhelper.EXPECT().LinkIPAddressExists("br-ovn-p1", uplinkVTEPIPNet).Return(false, nil)
+		networkhelper.EXPECT().SetLinkIPAddress("br-ovn-p1", uplinkVTEPIPNet)
+		networkhelper.EXPECT().SetLinkUp("br-ovn-p1")
+		networkhelper.EXPECT().RuleExists(mustParseIPNet("192.168.2.1/32"), 71, 30000).Return(false, nil)
+		networkhelper.EXPECT().AddRule(mustParseIPNet("192.168.2.1/32"), 71, 30000)
+		networkhelper.EXPECT().RouteExists(vtepCIDR, uplinkGateway, "br-ovn-p1", ptr.To(71)).Return(false, nil)
+		networkhelper.EXPECT().AddRoute(vtepCIDR, uplinkGateway, "br-ovn-p1", nil, ptr.To(71))
+		networkhelper.EXPECT().RouteExists(hostCIDR, uplinkGateway, "br-ovn-p1", ptr.To(71)).Return(false, nil)
+		networkhelper.EXPECT().AddRoute(hostCIDR, uplinkGateway, "br-ovn-p1", ptr.To(10000), ptr.To(71))
+
+		By("Using the VTEP IP of br-ovn as it has no port to watch")
+		networkhelper.EXPECT().LinkOperUp("p1").Return(true, nil)
+		ovsTxn.EXPECT().SetOVNEncapIP(net.ParseIP("192.168.1.1"))
+
+		expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.InternalIPAM)
+		expectSourceRoutingAddresses(networkhelper)
+		networkHelperMockAll(networkhelper)
+		ovsClientMockAll(ovsClient, ovsTxn)
+		Expect(provisioner.RunOnce()).To(Succeed())
+		Expect(fakeExec.CommandCalls).To(Equal(1))
+		Expect(provisioner.ManagedLinks()).To(Equal([]string{"br-ovn", "br-comm-ch", "cni0", "br-ovn-p1", "p1"}))
+	})
+
+	It("should fail over the encap IP in active-backup mode", func() {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+			return kexec.New().Command("echo")
+		}))
+		provisioner := newProvisioner(dpucniprovisioner.InternalIPAM, ovsClient, networkhelper, fakeExec)
+		registry := prometheus.NewRegistry()
+		Expect(provisioner.RegisterMetrics(registry)).To(Succeed())
+		Expect(provisioner.SetUplinks(dpucniprovisioner.Uplinks{
+			PrimaryPort: "p0",
+			Additional:  []dpucniprovisioner.Uplink{additionalUplink},
+			EncapMode:   dpucniprovisioner.ActiveBackupEncap,
+		})).To(Succeed())
+
+		gomock.InOrder(
+			networkhelper.EXPECT().LinkOperUp("p0").Return(true, nil),
+			networkhelper.EXPECT().LinkOperUp("p0").Return(false, nil),
+		)
+		networkhelper.EXPECT().LinkOperUp("p1").Return(true, nil).Times(2)
+		ovsTxn.EXPECT().SetOVNEncapIP(net.ParseIP("192.168.1.1"))
+		ovsTxn.EXPECT().SetOVNEncapIP(net.ParseIP("192.168.2.1"))
+		ovsTxn.EXPECT().SetKubernetesHostNodeName("host1").AnyTimes()
+		ovsTxn.EXPECT().SetHostName("host1").AnyTimes()
+		ovsTxn.EXPECT().Commit().AnyTimes()
+		for range 2 {
+			expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.InternalIPAM)
+		}
+		expectSourceRoutingAddresses(networkhelper)
+		networkHelperMockAll(networkhelper)
+
+		By("Using the VTEP IP of br-ovn while both ports are up")
+		Expect(provisioner.RunOnce()).To(Succeed())
+
+		By("Using the VTEP IP of the additional uplink once the port of br-ovn goes down")
+		Expect(provisioner.RunOnce()).To(Succeed())
+		Expect(testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP dpucniprovisioner_uplink_encap_active Whether the VTEP IP of each uplink is used as geneve encap IP (1) or not (0).
+# TYPE dpucniprovisioner_uplink_encap_active gauge
+dpucniprovisioner_uplink_encap_active{bridge="br-ovn"} 0
+dpucniprovisioner_uplink_encap_active{bridge="br-ovn-p1"} 1
+`), "dpucniprovisioner_uplink_encap_active")).To(Succeed())
+	})
+
+	It("should use the VTEP IPs of all the healthy uplinks in ECMP mode", func() {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+			return kexec.New().Command("echo")
+		}))
+		provisioner := newProvisioner(dpucniprovisioner.InternalIPAM, ovsClient, networkhelper, fakeExec)
+		Expect(provisioner.SetUplinks(dpucniprovisioner.Uplinks{
+			PrimaryPort: "p0",
+			Additional:  []dpucniprovisioner.Uplink{additionalUplink},
+			EncapMode:   dpucniprovisioner.ECMPEncap,
+		})).To(Succeed())
+
+		gomock.InOrder(
+			networkhelper.EXPECT().LinkOperUp("p0").Return(true, nil),
+			networkhelper.EXPECT().LinkOperUp("p0").Return(false, nil),
+			networkhelper.EXPECT().LinkOperUp("p0").Return(false, nil),
+		)
+		gomock.InOrder(
+			networkhelper.EXPECT().LinkOperUp("p1").Return(true, nil),
+			networkhelper.EXPECT().LinkOperUp("p1").Return(true, nil),
+			networkhelper.EXPECT().LinkOperUp("p1").Return(false, errors.New("link not found")),
+		)
+		bothEncapIPs := []net.IP{net.ParseIP("192.168.1.1"), net.ParseIP("192.168.2.1")}
+		ovsTxn.EXPECT().SetOVNEncapIPs(bothEncapIPs).Times(2)
+		ovsTxn.EXPECT().SetOVNEncapIP(net.ParseIP("192.168.2.1"))
+		ovsTxn.EXPECT().SetKubernetesHostNodeName("host1").AnyTimes()
+		ovsTxn.EXPECT().SetHostName("host1").AnyTimes()
+		ovsTxn.EXPECT().Commit().AnyTimes()
+		for range 3 {
+			expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.InternalIPAM)
+		}
+		expectSourceRoutingAddresses(networkhelper)
+		networkHelperMockAll(networkhelper)
+
+		By("Using both VTEP IPs while both ports are up")
+		Expect(provisioner.RunOnce()).To(Succeed())
+
+		By("Using only the VTEP IP of the additional uplink once the port of br-ovn goes down")
+		Expect(provisioner.RunOnce()).To(Succeed())
+
+		By("Falling back to both VTEP IPs when none of the uplinks is healthy")
+		Expect(provisioner.RunOnce()).To(Succeed())
+	})
+})
+
+var _ = Describe("DPU CNI Provisioner plan", func() {
+	fakeNode := newFakeDPUNode()
+	// summarize returns the action, kind and object of every change of the plan
+	summarize := func(plan *dpucniprovisioner.Plan) []string {
+		summary := make([]string, 0, len(plan.Changes))
+		for _, c := range plan.Changes {
+			summary = append(summary, fmt.Sprintf("%s %s %s", c.Action, c.Kind, c.Object))
+		}
+		return summary
+	}
+
+	It("should report the changes without applying them in Internal mode", func() {
+		testCtrl := gomock.NewController(GinkgoT())
+		// No expectation is set for the calls that change the system, hence any of them fails the test
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		vtepIPNet := mustParseIPNet("192.168.1.1/24")
+		gateway := net.ParseIP("192.168.1.10")
+		vtepCIDR := mustParseIPNet("192.168.1.0/23")
+		hostCIDR := mustParseIPNet("10.0.100.1/24")
+		provisioner := newInternalIPAMProvisioner(context.Background(), clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, testclient.NewClientset(fakeNode), 1500)
+
+		tmpDir := provisioner.FileSystemRoot
+		provisioner.K8sAPIServer = "https://10.0.100.1:6443"
+		provisioner.BootstrapKubeconfigPath = "/host-kubernetes/kubelet.conf"
+		provisioner.HostNodeNameFilePath = "/var/run/ovn-kubernetes/host-node-name"
+		ovnInputPath := filepath.Join(tmpDir, "/etc/openvswitch/ovn_k8s.conf")
+		Expect(os.MkdirAll(filepath.Dir(ovnInputPath), 0755)).To(Succeed())
+		oldOVNInput := "[Gateway]\nnext-hop=192.168.1.254\nrouter-subnet=192.168.1.0/24\n"
+		Expect(os.WriteFile(ovnInputPath, []byte(oldOVNInput), 0644)).To(Succeed())
+
+		networkhelper.EXPECT().GetLinkMTU("br-ovn").Return(9216, nil)
+		expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.InternalIPAM)
+		networkhelper.EXPECT().LinkIPAddressExists("br-ovn", vtepIPNet).Return(false, nil)
+		networkhelper.EXPECT().LinkAdminUp("br-ovn").Return(false, nil)
+		networkhelper.EXPECT().RouteExists(vtepCIDR, gateway, "br-ovn", nil).Return(false, nil)
+		networkhelper.EXPECT().RouteExists(hostCIDR, gateway, "br-ovn", nil).Return(true, nil)
+		ovsClient.EXPECT().GetOpenVSwitchExternalIDs().Return(map[string]string{
+			"hostname":          "host1",
+			"host-k8s-nodename": "host1",
+			"ovn-encap-ip":      "192.168.1.5",
+		}, nil)
+
+		flannelIP := mustParseIPNet("10.244.6.30/24")
+		oobIP := mustParseIPNet("10.0.100.100/24")
+		defaultGateway := net.ParseIP("10.0.100.254")
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{flannelIP}, nil)
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{oobIP}, nil)
+		networkhelper.EXPECT().RuleExists(gomock.Any(), 60, gomock.Any()).Return(true, nil).Times(2)
+		networkhelper.EXPECT().GetGateway(gomock.Any()).Return(defaultGateway, nil)
+		networkhelper.EXPECT().RouteExists(vtepCIDR, defaultGateway, "br-comm-ch", ptr.To(60)).Return(true, nil)
+
+		staleNetwork := mustParseIPNet("10.0.200.0/24")
+		networkhelper.EXPECT().ListOwnedRoutes().Return([]nethelper.Route{
+			{Network: vtepCIDR, Gateway: gateway, Device: "br-ovn", Table: 254},
+			{Network: staleNetwork, Gateway: gateway, Device: "br-ovn", Table: 254},
+		}, nil)
+		networkhelper.EXPECT().ListOwnedRules()
+
+		plan := provisioner.Plan()
+		Expect(plan.Error).To(BeEmpty())
+		Expect(summarize(plan)).To(Equal([]string{
+			"set file " + filepath.Join(tmpDir, "/var/run/ovn-kubernetes/host-node-name"),
+			"set file " + filepath.Join(tmpDir, "/host-kubernetes/kubelet.conf"),
+			"add address 192.168.1.1/24 dev br-ovn",
+			"set link br-ovn",
+			"add route 192.168.1.0/23 via 192.168.1.10 dev br-ovn table 254",
+			"set ovs Open_vSwitch external_ids:ovn-encap-ip",
+			"set file " + ovnInputPath,
+			"delete route 10.0.200.0/24 via 192.168.1.10 dev br-ovn table 254",
+		}))
+		Expect(plan.Changes[5].Current).To(Equal("192.168.1.5"))
+		Expect(plan.Changes[5].Desired).To(Equal("192.168.1.1"))
+		Expect(plan.Changes[6].Diff).To(ContainSubstring("-next-hop=192.168.1.254\n+next-hop=192.168.1.10\n"))
+		Expect(plan.String()).To(ContainSubstring(`~ ovs Open_vSwitch external_ids:ovn-encap-ip: "192.168.1.5" -> "192.168.1.1"`))
+
+		By("Checking that nothing was written")
+		ovnInput, err := os.ReadFile(ovnInputPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(ovnInput)).To(Equal(oldOVNInput))
+		Expect(filepath.Join(tmpDir, "/host-kubernetes")).ToNot(BeADirectory())
+		Expect(filepath.Join(tmpDir, "/var/run")).ToNot(BeADirectory())
+		Expect(fakeExec.CommandCalls).To(Equal(0))
+	})
+
+	It("should report the netplan apply br-ovn is waiting for in External mode", func() {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		vtepCIDR := mustParseIPNet("192.168.1.0/23")
+		hostCIDR := mustParseIPNet("10.0.100.1/24")
+		gatewayDiscoveryNetwork := mustParseIPNet("169.254.99.100/32")
+		provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.ExternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, testclient.NewClientset(fakeNode.DeepCopy()), nil, nil, []*net.IPNet{vtepCIDR}, []*net.IPNet{hostCIDR}, nil, fakeNode.Name, gatewayDiscoveryNetwork, 0)
+
+		tmpDir, err := os.MkdirTemp("", "dpucniprovisioner")
+		Expect(err).NotTo(HaveOccurred())
+		defer func() {
+			Expect(os.RemoveAll(tmpDir)).To(Succeed())
+		}()
+		provisioner.FileSystemRoot = tmpDir
+		netplanPath := filepath.Join(tmpDir, "/etc/netplan/80-br-ovn.yaml")
+
+		expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.ExternalIPAM)
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-ovn", nethelper.IPv4).Return(nil, nil)
+
+		plan := provisioner.Plan()
+		Expect(plan.Error).To(ContainSubstring("exactly 1 IPv4 IP is expected in br-ovn, but found 0"))
+		Expect(summarize(plan)).To(Equal([]string{
+			"set file " + netplanPath,
+			"run command netplan apply",
+		}))
+		Expect(plan.Changes[0].Diff).To(ContainSubstring("+      dhcp4: yes\n"))
+		Expect(plan.String()).To(HaveSuffix("Planning stopped, the changes of the remaining steps are unknown: " + plan.Error + "\n"))
+		Expect(netplanPath).ToNot(BeAnExistingFile())
+		Expect(fakeExec.CommandCalls).To(Equal(0))
+	})
+})
+
+var _ = Describe("DPU CNI Provisioner cleanup", func() {
+	// summarize returns the action, kind and object of every removed object of the report
+	summarize := func(report *dpucniprovisioner.CleanupReport) []string {
+		summary := make([]string, 0, len(report.Removed))
+		for _, c := range report.Removed {
+			summary = append(summary, fmt.Sprintf("%s %s %s", c.Action, c.Kind, c.Object))
+		}
+		return summary
+	}
+	// writeFiles writes the given files under the given root
+	writeFiles := func(root string, paths ...string) {
+		for _, path := range paths {
+			Expect(os.MkdirAll(filepath.Dir(filepath.Join(root, path)), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(root, path), []byte("content"), 0644)).To(Succeed())
+		}
+	}
+
+	It("should remove the state it configured in Internal mode and pause reconciling", func() {
+		testCtrl := gomock.NewController(GinkgoT())
+		// No expectation is set for the calls that aren't expected to be made, hence any of them fails the test
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		vtepIPNet := mustParseIPNet("192.168.1.1/24")
+		gateway := net.ParseIP("192.168.1.10")
+		vtepCIDR := mustParseIPNet("192.168.1.0/23")
+		fakeClock := clock.NewFakeClock(time.Now())
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		provisioner := newInternalIPAMProvisioner(ctx, fakeClock, ovsClient, networkhelper, fakeExec, testclient.NewClientset(), 1500)
+
+		tmpDir := provisioner.FileSystemRoot
+		writeFiles(tmpDir, "/etc/openvswitch/ovn_k8s.conf", "/host-kubernetes/kubelet.conf", "/var/run/ovn-kubernetes/host-node-name")
+		// Files that are not written by the provisioner are left alone
+		writeFiles(tmpDir, "/etc/openvswitch/conf.db", "/etc/netplan/50-cloud-init.yaml")
+
+		ovsClient.EXPECT().GetOpenVSwitchExternalIDs().Return(map[string]string{
+			"hostname":          "host1",
+			"host-k8s-nodename": "host1",
+			"ovn-encap-ip":      "192.168.1.1",
+			"system-id":         "test-system-id",
+		}, nil)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn)
+		ovsTxn.EXPECT().RemoveOVNEncapIP()
+		ovsTxn.EXPECT().RemoveKubernetesHostNodeName()
+		ovsTxn.EXPECT().RemoveHostName()
+		ovsTxn.EXPECT().Commit()
+
+		flannelIPNet := mustParseIPNet("10.244.6.0/24")
+		networkhelper.EXPECT().ListOwnedRoutes().Return([]nethelper.Route{
+			{Network: vtepCIDR, Gateway: gateway, Device: "br-ovn", Table: 254},
+		}, nil)
+		networkhelper.EXPECT().DeleteRouteFromTable(vtepCIDR, gateway, "br-ovn", ptr.To(254))
+		networkhelper.EXPECT().ListOwnedRules().Return([]nethelper.Rule{
+			{Src: flannelIPNet, Table: 60, Priority: 31000},
+		}, nil)
+		networkhelper.EXPECT().DeleteRule(flannelIPNet, 60, 31000)
+		networkhelper.EXPECT().LinkIPAddressExists("br-ovn", vtepIPNet).Return(true, nil)
+		networkhelper.EXPECT().DeleteLinkIPAddress("br-ovn", vtepIPNet)
+
+		report, err := provisioner.Cleanup()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(summarize(report)).To(Equal([]string{
+			"delete ovs Open_vSwitch external_ids:ovn-encap-ip",
+			"delete ovs Open_vSwitch external_ids:host-k8s-nodename",
+			"delete ovs Open_vSwitch external_ids:hostname",
+			"delete route 192.168.1.0/23 via 192.168.1.10 dev br-ovn table 254",
+			"delete rule from 10.244.6.0/24 lookup 60 priority 31000",
+			"delete file " + filepath.Join(tmpDir, "/etc/openvswitch/ovn_k8s.conf"),
+			"delete file " + filepath.Join(tmpDir, "/host-kubernetes/kubelet.conf"),
+			"delete file " + filepath.Join(tmpDir, "/var/run/ovn-kubernetes/host-node-name"),
+			"delete address 192.168.1.1/24 dev br-ovn",
+		}))
+		Expect(report.Removed[0].Current).To(Equal("192.168.1.1"))
+		Expect(report.String()).To(HavePrefix("Cleanup in internal-ipam mode: 9 object(s) removed\n"))
+
+		By("Checking that only the files of the provisioner were removed")
+		Expect(filepath.Join(tmpDir, "/etc/openvswitch/ovn_k8s.conf")).ToNot(BeAnExistingFile())
+		Expect(filepath.Join(tmpDir, "/etc/openvswitch/conf.db")).To(BeAnExistingFile())
+		Expect(filepath.Join(tmpDir, "/etc/netplan/50-cloud-init.yaml")).To(BeAnExistingFile())
+		Expect(fakeExec.CommandCalls).To(Equal(0))
+
+		By("Checking that the provisioner doesn't reconcile anymore")
+		// Any call the provisioning flow makes fails the test as no expectation is set for it
+		source := &fakeEventSource{events: make(chan string)}
+		provisioner.AddEventSource(source)
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			provisioner.EnsureConfiguration()
+		}()
+		source.events <- "link br-ovn changed"
+		for range 10 {
+			fakeClock.Step(30 * time.Second)
+			time.Sleep(10 * time.Millisecond)
+		}
+		cancel()
+		Eventually(done).Should(BeClosed())
+		Expect(provisioner.CheckReadiness()).To(MatchError(ContainSubstring("reconciliation is paused")))
+
+		By("Checking that the pause expires")
+		fakeClock.Step(10 * time.Minute)
+		Expect(provisioner.CheckReadiness()).To(Succeed())
+	})
+
+	It("should remove the netplan configuration of br-ovn and its addresses in External mode", func() {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		vtepCIDR := mustParseIPNet("192.168.1.0/23")
+		hostCIDR := mustParseIPNet("10.0.100.1/24")
+		gatewayDiscoveryNetwork := mustParseIPNet("169.254.99.100/32")
+		provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.ExternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, testclient.NewClientset(), nil, nil, []*net.IPNet{vtepCIDR}, []*net.IPNet{hostCIDR}, nil, "dpu1", gatewayDiscoveryNetwork, 0)
+
+		tmpDir, err := os.MkdirTemp("", "dpucniprovisioner")
+		Expect(err).NotTo(HaveOccurred())
+		defer func() {
+			Expect(os.RemoveAll(tmpDir)).To(Succeed())
+		}()
+		provisioner.FileSystemRoot = tmpDir
+		netplanPath := filepath.Join(tmpDir, "/etc/netplan/80-br-ovn.yaml")
+		netplanDonePath := filepath.Join(tmpDir, "/etc/netplan/.dpucniprovisioner.done")
+		writeFiles(tmpDir, "/etc/netplan/80-br-ovn.yaml", "/etc/netplan/.dpucniprovisioner.done")
+
+		fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+			Expect(cmd).To(Equal("netplan"))
+			Expect(args).To(Equal([]string{"apply"}))
+			// netplan is applied once br-ovn isn't configured anymore
+			Expect(netplanPath).ToNot(BeAnExistingFile())
+			return kexec.New().Command("echo")
+		}))
+
+		// The OVS external IDs are already removed, hence none is removed and the report stays empty
+		ovsClient.EXPECT().GetOpenVSwitchExternalIDs().Return(map[string]string{"system-id": "test-system-id"}, nil)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn)
+		ovsTxn.EXPECT().Commit()
+		networkhelper.EXPECT().ListOwnedRoutes().Return(nil, errors.New("netlink error"))
+		networkhelper.EXPECT().ListOwnedRules()
+		brOVNAddress := mustParseIPNet("192.168.1.3/23")
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-ovn", nethelper.IPv4).Return([]*net.IPNet{brOVNAddress}, nil)
+		networkhelper.EXPECT().DeleteLinkIPAddress("br-ovn", brOVNAddress)
+
+		report, err := provisioner.Cleanup()
+		By("Checking that the cleanup carries on when an object can't be removed")
+		Expect(err).To(MatchError(ContainSubstring("error while listing owned routes: netlink error")))
+		Expect(report.Errors).To(HaveLen(1))
+		Expect(summarize(report)).To(Equal([]string{
+			"delete file " + netplanPath,
+			"delete file " + netplanDonePath,
+			"delete address 192.168.1.3/23 dev br-ovn",
+		}))
+		Expect(netplanDonePath).ToNot(BeAnExistingFile())
+		Expect(fakeExec.CommandCalls).To(Equal(1))
+	})
+
+	It("should remove what the journal records as set by the OVS profile and the pending restart of ovs-vswitchd", func() {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		vtepIPNet := mustParseIPNet("192.168.1.1/24")
+		provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.InternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, &kexecTesting.FakeExec{}, testclient.NewClientset(), vtepIPNet, net.ParseIP("192.168.1.10"), []*net.IPNet{mustParseIPNet("192.168.1.0/23")}, []*net.IPNet{mustParseIPNet("10.0.100.1/24")}, mustParseIPNet("192.168.1.2/24"), "dpu1", nil, 1500)
+
+		tmpDir, err := os.MkdirTemp("", "dpucniprovisioner")
+		Expect(err).NotTo(HaveOccurred())
+		defer func() {
+			Expect(os.RemoveAll(tmpDir)).To(Succeed())
+		}()
+		provisioner.FileSystemRoot = tmpDir
+		journalPath := filepath.Join(tmpDir, "/var/lib/dpucniprovisioner/state.json")
+		pendingPath := filepath.Join(tmpDir, "/var/lib/dpucniprovisioner/ovs-restart-pending.json")
+		writeFiles(tmpDir, "/var/lib/dpucniprovisioner/ovs-restart-pending.json")
+		Expect(os.WriteFile(journalPath, []byte(`{"version": 1, "generation": 1, "ovsOtherConfig": ["doca-init", "hw-offload", "pmd-cpu-mask"], "bridgeDataPathTypes": ["br-int", "br-ovn"]}`), 0600)).To(Succeed())
+
+		ovsClient.EXPECT().GetOpenVSwitchExternalIDs().Return(map[string]string{"system-id": "test-system-id"}, nil)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn)
+		// pmd-cpu-mask was removed in the meantime and br-int was deleted
+		ovsClient.EXPECT().GetOpenVSwitchOtherConfig().Return(map[string]string{"doca-init": "true", "hw-offload": "true", "max-idle": "30000"}, nil)
+		ovsTxn.EXPECT().RemoveOpenVSwitchOtherConfig("doca-init")
+		ovsTxn.EXPECT().RemoveOpenVSwitchOtherConfig("hw-offload")
+		ovsClient.EXPECT().BridgeExists("br-int").Return(false, nil)
+		ovsClient.EXPECT().BridgeExists("br-ovn").Return(true, nil)
+		ovsClient.EXPECT().GetBridgeDataPathType("br-ovn").Return(ovsclient.NetDev, nil)
+		ovsTxn.EXPECT().SetBridgeDataPathType("br-ovn", ovsclient.System)
+		ovsTxn.EXPECT().Commit()
+		networkhelper.EXPECT().ListOwnedRoutes()
+		networkhelper.EXPECT().ListOwnedRules()
+		networkhelper.EXPECT().LinkIPAddressExists("br-ovn", vtepIPNet).Return(false, nil)
+
+		report, err := provisioner.Cleanup()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(summarize(report)).To(Equal([]string{
+			"delete ovs Open_vSwitch other_config:doca-init",
+			"delete ovs Open_vSwitch other_config:hw-offload",
+			"delete ovs Bridge br-ovn datapath_type",
+			"delete file " + journalPath,
+			"delete file " + pendingPath,
+		}))
+		Expect(report.Removed[2].Current).To(Equal("netdev"))
+		Expect(pendingPath).ToNot(BeAnExistingFile())
+	})
+})
+
+var _ = Describe("DPU CNI Provisioner state journal", func() {
+	fakeNode := newFakeDPUNode()
+
+	// newProvisioner creates a provisioner whose network and OVS calls are all mocked and that runs the provisioning
+	// flow the given number of times
+	newProvisioner := func(tmpDir string, runs int) *dpucniprovisioner.DPUCNIProvisioner {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		for range runs {
+			fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+				return kexec.New().Command("echo")
+			}))
+			expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.InternalIPAM)
+		}
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{mustParseIPNet("10.244.6.30/24")}, nil).AnyTimes()
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{mustParseIPNet("10.0.100.100/24")}, nil).AnyTimes()
+		networkHelperMockAll(networkhelper)
+		ovsClientMockAll(ovsClient, ovsTxn)
+
+		provisioner := newInternalIPAMProvisioner(context.Background(), clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, testclient.NewClientset(fakeNode), 1500)
+		provisioner.FileSystemRoot = tmpDir
+		return provisioner
+	}
+
+	It("should record the applied state and clean up the files of the previous generation after a restart", func() {
+		tmpDir, err := os.MkdirTemp("", "dpucniprovisioner")
+		Expect(err).NotTo(HaveOccurred())
+		defer func() {
+			Expect(os.RemoveAll(tmpDir)).To(Succeed())
+		}()
+		Expect(os.MkdirAll(filepath.Join(tmpDir, "/etc/openvswitch"), 0755)).To(Succeed())
+		journalPath := filepath.Join(tmpDir, "/var/lib/dpucniprovisioner/state.json")
+		bootstrapPath := filepath.Join(tmpDir, "/host-kubernetes/kubelet.conf")
+		hostNodeNamePath := filepath.Join(tmpDir, "/var/run/ovn-kubernetes/host-node-name")
+		ovnInputPath := filepath.Join(tmpDir, "/etc/openvswitch/ovn_k8s.conf")
+
+		By("Recording the first generation")
+		provisioner := newProvisioner(tmpDir, 2)
+		provisioner.K8sAPIServer = "https://10.0.100.1:6443"
+		Expect(provisioner.LastAppliedState()).To(BeNil())
+		Expect(provisioner.RunOnce()).To(Succeed())
+		state := provisioner.LastAppliedState()
+		Expect(state).ToNot(BeNil())
+		Expect(state.Generation).To(Equal(int64(1)))
+		Expect(state.HostName).To(Equal("host1"))
+		Expect(state.Inputs.IPFamilies).To(Equal([]dpucniprovisioner.IPFamilyInputs{{
+			VTEPIP:    "192.168.1.1/24",
+			Gateway:   "192.168.1.10",
+			VTEPCIDRs: []string{"192.168.1.0/23"},
+			HostCIDRs: []string{"10.0.100.1/24"},
+			PFIP:      "192.168.1.2/24",
+		}}))
+		Expect(state.IPFamilies).To(Equal([]dpucniprovisioner.AppliedIPFamily{{Family: "IPv4", VTEPIP: "192.168.1.1/24", Gateway: "192.168.1.10"}}))
+		Expect(state.Routes).To(ContainElement("192.168.1.0/23 via 192.168.1.10 dev br-ovn table 254"))
+		Expect(state.Rules).To(ContainElement("from 10.244.6.0/24 lookup 60 priority 31000"))
+		var paths []string
+		for _, f := range state.Files {
+			paths = append(paths, f.Path)
+		}
+		Expect(paths).To(Equal([]string{hostNodeNamePath, bootstrapPath, ovnInputPath}))
+		Expect(journalPath).To(BeAnExistingFile())
+
+		By("Keeping the generation when nothing changed")
+		Expect(provisioner.RunOnce()).To(Succeed())
+		Expect(provisioner.LastAppliedState().Generation).To(Equal(int64(1)))
+
+		By("Restarting without the host cluster bootstrap after the host node name file got changed by someone else")
+		Expect(os.WriteFile(hostNodeNamePath, []byte("host2\n"), 0644)).To(Succeed())
+		provisioner = newProvisioner(tmpDir, 1)
+		Expect(provisioner.RunOnce()).To(Succeed())
+		state = provisioner.LastAppliedState()
+		Expect(state.Generation).To(Equal(int64(2)))
+		Expect(state.Files).To(HaveLen(1))
+		Expect(bootstrapPath).ToNot(BeAnExistingFile())
+		Expect(hostNodeNamePath).To(BeAnExistingFile())
+		Expect(ovnInputPath).To(BeAnExistingFile())
+
+		By("Checking the journal")
+		content, err := os.ReadFile(journalPath)
+		Expect(err).ToNot(HaveOccurred())
+		journal := &dpucniprovisioner.AppliedState{}
+		Expect(json.Unmarshal(content, journal)).To(Succeed())
+		Expect(journal).To(Equal(state))
+	})
+})
+
+var _ = Describe("DPU CNI Provisioner node status", func() {
+	// provisionedCondition returns the condition the provisioner publishes on the given Node
+	provisionedCondition := func(node *corev1.Node) *corev1.NodeCondition {
+		for _, c := range node.Status.Conditions {
+			if c.Type == dpucniprovisioner.NodeConditionProvisioned {
+				return &c
+			}
+		}
+		return nil
+	}
+	// patches returns the number of patches of the Node so far
+	patches := func(kubernetesClient *testclient.Clientset) int {
+		count := 0
+		for _, action := range kubernetesClient.Actions() {
+			if action.GetVerb() == "patch" && action.GetResource().Resource == "nodes" {
+				count++
+			}
+		}
+		return count
+	}
+
+	It("should publish the resolved configuration and the result of every run on the DPU Node", func(ctx context.Context) {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		for range 3 {
+			fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+				return kexec.New().Command("echo")
+			}))
+		}
+		for range 4 {
+			expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.InternalIPAM)
+		}
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{mustParseIPNet("10.244.6.30/24")}, nil).AnyTimes()
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{mustParseIPNet("10.0.100.100/24")}, nil).AnyTimes()
+		networkHelperMockAll(networkhelper)
+		ovsClientMockAll(ovsClient, ovsTxn)
+
+		fakeNode := newFakeDPUNode()
+		kubernetesClient := testclient.NewClientset(fakeNode)
+		fakeClock := clock.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+		provisioner := newInternalIPAMProvisioner(context.Background(), fakeClock, ovsClient, networkhelper, fakeExec, kubernetesClient, 1500)
+		nodeClient := kubernetesClient.CoreV1().Nodes()
+
+		By("Publishing the resolved configuration after a successful run")
+		Expect(provisioner.RunOnce()).To(Succeed())
+		node, err := nodeClient.Get(ctx, fakeNode.Name, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(node.Annotations).To(Equal(map[string]string{
+			dpucniprovisioner.NodeAnnotationMode:              "internal-ipam",
+			dpucniprovisioner.NodeAnnotationVTEPIP:            "192.168.1.1/24",
+			dpucniprovisioner.NodeAnnotationGateway:           "192.168.1.10",
+			dpucniprovisioner.NodeAnnotationMTU:               "1500",
+			dpucniprovisioner.NodeAnnotationMTUSource:         "configured",
+			dpucniprovisioner.NodeAnnotationPFMTU:             "unknown",
+			dpucniprovisioner.NodeAnnotationHostNodeName:      "host1",
+			dpucniprovisioner.NodeAnnotationLastReconcileTime: "2026-01-01T00:00:00Z",
+		}))
+		condition := provisionedCondition(node)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Status).To(Equal(corev1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(dpucniprovisioner.NodeConditionReasonConfigurationApplied))
+
+		By("Not updating the Node when nothing changed")
+		patchesSoFar := patches(kubernetesClient)
+		fakeClock.Step(time.Minute)
+		Expect(provisioner.RunOnce()).To(Succeed())
+		Expect(patches(kubernetesClient)).To(Equal(patchesSoFar))
+
+		By("Publishing the error of a failed run along with the configuration still in effect")
+		node.Labels = nil
+		_, err = nodeClient.Update(ctx, node, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		fakeClock.Step(time.Minute)
+		Expect(provisioner.RunOnce()).ToNot(Succeed())
+		node, err = nodeClient.Get(ctx, fakeNode.Name, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(node.Annotations).To(HaveKeyWithValue(dpucniprovisioner.NodeAnnotationVTEPIP, "192.168.1.1/24"))
+		Expect(node.Annotations).To(HaveKeyWithValue(dpucniprovisioner.NodeAnnotationLastError, ContainSubstring("neither label")))
+		Expect(node.Annotations).To(HaveKeyWithValue(dpucniprovisioner.NodeAnnotationLastReconcileTime, "2026-01-01T00:02:00Z"))
+		condition = provisionedCondition(node)
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(dpucniprovisioner.NodeConditionReasonConfigurationFailed))
+		Expect(condition.Message).To(ContainSubstring("neither label"))
+		Expect(condition.LastTransitionTime.Time).To(BeTemporally("==", fakeClock.Now()))
+
+		By("Clearing the error once a run succeeds")
+		node.Labels = fakeNode.Labels
+		_, err = nodeClient.Update(ctx, node, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		fakeClock.Step(time.Minute)
+		Expect(provisioner.RunOnce()).To(Succeed())
+		node, err = nodeClient.Get(ctx, fakeNode.Name, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(node.Annotations).ToNot(HaveKey(dpucniprovisioner.NodeAnnotationLastError))
+		Expect(node.Status.Conditions).To(HaveLen(1))
+		Expect(provisionedCondition(node).Status).To(Equal(corev1.ConditionTrue))
+	})
+})
+
+var _ = Describe("DPU CNI Provisioner MTU", func() {
+	fakeNode := newFakeDPUNode()
+	// newProvisioner returns a provisioner in Internal mode whose br-ovn has p0 as its port
+	newProvisioner := func(networkhelper *networkhelperMock.MockNetworkHelper, ovsClient *ovsclientMock.MockOVSClient, fakeExec *kexecTesting.FakeExec, kubernetesClient *testclient.Clientset, ovnMTU int) *dpucniprovisioner.DPUCNIProvisioner {
+		provisioner := newInternalIPAMProvisioner(context.Background(), clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, ovnMTU)
+		Expect(provisioner.SetUplinks(dpucniprovisioner.Uplinks{PrimaryPort: "p0"})).To(Succeed())
+		return provisioner
+	}
+
+	It("should derive the MTU from the port of br-ovn and set it on br-ovn", func(ctx context.Context) {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+			Expect(cmd).To(Equal("dnsmasq"))
+			Expect(args).To(ContainElement("--dhcp-option=option:mtu,1500"))
+			return kexec.New().Command("echo")
+		}))
+		kubernetesClient := testclient.NewClientset(fakeNode.DeepCopy())
+		provisioner := newProvisioner(networkhelper, ovsClient, fakeExec, kubernetesClient, 0)
+
+		networkhelper.EXPECT().GetLinkMTU("p0").Return(1500, nil).Times(2)
+		gomock.InOrder(
+			networkhelper.EXPECT().GetLinkMTU("br-ovn").Return(9216, nil),
+			networkhelper.EXPECT().SetLinkMTU("br-ovn", 1500),
+			networkhelper.EXPECT().GetLinkMTU("br-ovn").Return(1500, nil),
+		)
+		networkhelper.EXPECT().LinkOperUp("p0").Return(true, nil).AnyTimes()
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{mustParseIPNet("10.244.6.30/24")}, nil).AnyTimes()
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{mustParseIPNet("10.0.100.100/24")}, nil).AnyTimes()
+		for range 2 {
+			expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.InternalIPAM)
+		}
+		networkHelperMockAll(networkhelper)
+		ovsClientMockAll(ovsClient, ovsTxn)
+
+		By("Setting the MTU of p0 on br-ovn and serving it to the PF")
+		Expect(provisioner.RunOnce()).To(Succeed())
+		node, err := kubernetesClient.CoreV1().Nodes().Get(ctx, fakeNode.Name, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(node.Annotations).To(HaveKeyWithValue(dpucniprovisioner.NodeAnnotationMTU, "1440"))
+		Expect(node.Annotations).To(HaveKeyWithValue(dpucniprovisioner.NodeAnnotationMTUSource, "derived"))
+		Expect(node.Annotations).To(HaveKeyWithValue(dpucniprovisioner.NodeAnnotationPFMTU, "unknown"))
+
+		By("Not setting the MTU of br-ovn again once it's set")
+		Expect(provisioner.RunOnce()).To(Succeed())
+	})
+
+	It("should fail with a dedicated condition reason when the MTU doesn't fit in the uplink", func(ctx context.Context) {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		kubernetesClient := testclient.NewClientset(fakeNode.DeepCopy())
+		provisioner := newProvisioner(networkhelper, ovsClient, fakeExec, kubernetesClient, 1500)
+
+		// The MTU of br-ovn is never set, hence any call to SetLinkMTU fails the test
+		networkhelper.EXPECT().GetLinkMTU("p0").Return(1500, nil)
+		networkhelper.EXPECT().LinkOperUp("p0").Return(true, nil).AnyTimes()
+		expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.InternalIPAM)
+		networkhelper.EXPECT().AddRoute(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		networkhelper.EXPECT().GetHostPFMACAddressDPU(gomock.Any()).AnyTimes()
+		networkhelper.EXPECT().LinkIPAddressExists(gomock.Any(), gomock.Any()).AnyTimes()
+		networkhelper.EXPECT().RouteExists(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		networkhelper.EXPECT().SetLinkIPAddress(gomock.Any(), gomock.Any()).AnyTimes()
+		networkhelper.EXPECT().SetLinkUp(gomock.Any()).AnyTimes()
+		ovsClientMockAll(ovsClient, ovsTxn)
+
+		err := provisioner.RunOnce()
+		var mtuErr *dpucniprovisioner.MTUError
+		Expect(errors.As(err, &mtuErr)).To(BeTrue())
+		Expect(*mtuErr).To(Equal(dpucniprovisioner.MTUError{MTU: 1500, Link: "p0", LinkMTU: 1500}))
+		Expect(fakeExec.CommandCalls).To(Equal(0))
+
+		node, err := kubernetesClient.CoreV1().Nodes().Get(ctx, fakeNode.Name, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(node.Status.Conditions).To(ContainElement(And(
+			HaveField("Type", dpucniprovisioner.NodeConditionProvisioned),
+			HaveField("Status", corev1.ConditionFalse),
+			HaveField("Reason", dpucniprovisioner.NodeConditionReasonMTUExceedsUplink),
+			HaveField("Message", ContainSubstring("exceeds the MTU 1500 of p0")),
+		)))
+	})
+})
+
+var _ = Describe("DPU CNI Provisioner VTEP probe", func() {
+	vtepConnectivityCondition := func(ctx context.Context, kubernetesClient *testclient.Clientset) *corev1.NodeCondition {
+		node, err := kubernetesClient.CoreV1().Nodes().Get(ctx, "dpu1", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		for _, c := range node.Status.Conditions {
+			if c.Type == dpucniprovisioner.NodeConditionVTEPConnectivity {
+				return &c
+			}
+		}
+		return nil
+	}
+
+	It("should reject invalid settings", func() {
+		provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.InternalIPAM, clock.NewFakeClock(time.Now()), nil, nil, nil, nil, nil, nil, nil, nil, nil, "dpu1", nil, 1500)
+		Expect(provisioner.SetVTEPProbe(dpucniprovisioner.VTEPProbe{})).To(Succeed())
+		Expect(provisioner.SetVTEPProbe(dpucniprovisioner.VTEPProbe{Interval: time.Minute, Timeout: time.Minute, SampleSize: 1})).ToNot(Succeed())
+		Expect(provisioner.SetVTEPProbe(dpucniprovisioner.VTEPProbe{Interval: time.Minute, Timeout: time.Second})).ToNot(Succeed())
+		Expect(provisioner.SetVTEPProbe(dpucniprovisioner.VTEPProbe{Interval: time.Minute, Timeout: time.Second, SampleSize: 1})).To(Succeed())
+	})
+
+	It("should probe a rotating sample of the VTEPs of the other DPUs and publish the results", func(ctx context.Context) {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+			return kexec.New().Command("echo")
+		}))
+		expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.InternalIPAM)
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{mustParseIPNet("10.244.6.30/24")}, nil).AnyTimes()
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{mustParseIPNet("10.0.100.100/24")}, nil).AnyTimes()
+		networkHelperMockAll(networkhelper)
+		ovsClientMockAll(ovsClient, ovsTxn)
+
+		dpuNode := func(name string, annotations map[string]string) *corev1.Node {
+			return &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        name,
+					Annotations: annotations,
+					Labels: map[string]string{
+						"provisioning.dpu.nvidia.com/dpunode-name": "host1",
+					},
+				},
+			}
+		}
+		kubernetesClient := testclient.NewClientset(
+			dpuNode("dpu1", nil),
+			dpuNode("dpu2", map[string]string{dpucniprovisioner.NodeAnnotationVTEPIP: "192.168.0.5/24"}),
+			dpuNode("dpu3", map[string]string{"k8s.ovn.org/node-encap-ips": `["192.168.0.6"]`}),
+			// Neither a VTEP of the IP family of the DPU nor a VTEP at all
+			dpuNode("dpu4", map[string]string{dpucniprovisioner.NodeAnnotationVTEPIP: "fd00::5/64"}),
+			dpuNode("dpu5", nil),
+		)
+		provisioner := dpucniprovisioner.New(context.Background(), dpucniprovisioner.InternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, mustParseIPNet("192.168.1.1/24"), net.ParseIP("192.168.1.10"), []*net.IPNet{mustParseIPNet("192.168.0.0/16")}, []*net.IPNet{mustParseIPNet("10.0.100.1/24")}, mustParseIPNet("192.168.1.2/24"), "dpu1", nil, 1500)
+		tmpDir, err := os.MkdirTemp("", "dpucniprovisioner")
+		Expect(err).NotTo(HaveOccurred())
+		defer func() {
+			Expect(os.RemoveAll(tmpDir)).To(Succeed())
+		}()
+		provisioner.FileSystemRoot = tmpDir
+		Expect(os.MkdirAll(filepath.Join(tmpDir, "/etc/openvswitch"), 0755)).To(Succeed())
+		registry := prometheus.NewRegistry()
+		Expect(provisioner.RegisterMetrics(registry)).To(Succeed())
+		Expect(provisioner.SetVTEPProbe(dpucniprovisioner.VTEPProbe{Interval: time.Minute, Timeout: time.Second, SampleSize: 1})).To(Succeed())
+
+		By("Not probing before the state is applied")
+		Expect(provisioner.ProbeVTEPsOnce()).To(Succeed())
+		Expect(vtepConnectivityCondition(ctx, kubernetesClient)).To(BeNil())
+		Expect(provisioner.RunOnce()).To(Succeed())
+
+		By("Probing the first VTEP with a small packet and then with one of the size of the geneve traffic")
+		gomock.InOrder(
+			networkhelper.EXPECT().Ping(net.ParseIP("192.168.0.5"), 64, time.Second).Return(true, nil),
+			networkhelper.EXPECT().Ping(net.ParseIP("192.168.0.5"), 1560, time.Second).Return(true, nil),
+		)
+		Expect(provisioner.ProbeVTEPsOnce()).To(Succeed())
+		condition := vtepConnectivityCondition(ctx, kubernetesClient)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Status).To(Equal(corev1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(dpucniprovisioner.NodeConditionReasonPeersReachable))
+		node, err := kubernetesClient.CoreV1().Nodes().Get(ctx, "dpu1", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(node.Status.Conditions).To(ContainElement(HaveField("Type", dpucniprovisioner.NodeConditionProvisioned)))
+
+		By("Probing the next VTEP, published by ovnkube, whose path doesn't carry the geneve traffic")
+		gomock.InOrder(
+			networkhelper.EXPECT().Ping(net.ParseIP("192.168.0.6"), 64, time.Second).Return(true, nil),
+			networkhelper.EXPECT().Ping(net.ParseIP("192.168.0.6"), 1560, time.Second).Return(false, nil),
+		)
+		Expect(provisioner.ProbeVTEPsOnce()).To(Succeed())
+		condition = vtepConnectivityCondition(ctx, kubernetesClient)
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(dpucniprovisioner.NodeConditionReasonPathMTUExceeded))
+		Expect(condition.Message).To(Equal("not reachable with 1560 byte packets: dpu3 (192.168.0.6)"))
+		Expect(testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP dpucniprovisioner_vtep_peer_reachable Whether the VTEP of another DPU answered the last probe (1) or not (0).
+# TYPE dpucniprovisioner_vtep_peer_reachable gauge
+dpucniprovisioner_vtep_peer_reachable{address="192.168.0.6",peer="dpu3"} 1
+# HELP dpucniprovisioner_vtep_peer_path_mtu_ok Whether the VTEP of another DPU answered the last probe of the size of the geneve traffic sent with the DF bit set (1) or not (0).
+# TYPE dpucniprovisioner_vtep_peer_path_mtu_ok gauge
+dpucniprovisioner_vtep_peer_path_mtu_ok{address="192.168.0.6",peer="dpu3"} 0
+`), "dpucniprovisioner_vtep_peer_reachable", "dpucniprovisioner_vtep_peer_path_mtu_ok")).To(Succeed())
+
+		By("Not probing the path MTU of a VTEP that is unreachable")
+		networkhelper.EXPECT().Ping(net.ParseIP("192.168.0.5"), 64, time.Second).Return(false, nil)
+		Expect(provisioner.ProbeVTEPsOnce()).To(Succeed())
+		condition = vtepConnectivityCondition(ctx, kubernetesClient)
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(dpucniprovisioner.NodeConditionReasonPeersUnreachable))
+		Expect(condition.Message).To(Equal("unreachable: dpu2 (192.168.0.5)"))
+	})
+})
+
+var _ = Describe("DPU CNI Provisioner OVS profile", func() {
+	fakeNode := newFakeDPUNode()
+	// newProvisioner returns a provisioner in Internal mode that applies the given profile and runs the provisioning
+	// flow the given number of times. ovs-vswitchd runs with PID 100.
+	newProvisioner := func(fakeClock *clock.FakeClock, ovsClient *ovsclientMock.MockOVSClient, ovsTxn *ovsclientMock.MockTransaction, networkhelper *networkhelperMock.MockNetworkHelper, fakeExec *kexecTesting.FakeExec, kubernetesClient *testclient.Clientset, profile *dpucniprovisioner.OVSProfile, runs int) (*dpucniprovisioner.DPUCNIProvisioner, string) {
+		for range runs {
+			expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.InternalIPAM)
+		}
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{mustParseIPNet("10.244.6.30/24")}, nil).AnyTimes()
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{mustParseIPNet("10.0.100.100/24")}, nil).AnyTimes()
+		networkHelperMockAll(networkhelper)
+		ovsClientMockAll(ovsClient, ovsTxn)
+
+		provisioner := newInternalIPAMProvisioner(context.Background(), fakeClock, ovsClient, networkhelper, fakeExec, kubernetesClient, 1500)
+		tmpDir := provisioner.FileSystemRoot
+		Expect(os.MkdirAll(filepath.Join(tmpDir, "/var/run/openvswitch"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(tmpDir, "/var/run/openvswitch/ovs-vswitchd.pid"), []byte("100\n"), 0644)).To(Succeed())
+		Expect(provisioner.SetOVSProfile(profile)).To(Succeed())
+		return provisioner, tmpDir
+	}
+
+	It("should apply the profile and report the pending restart until ovs-vswitchd is restarted", func(ctx context.Context) {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+			Expect(cmd).To(Equal("dnsmasq"))
+			return kexec.New().Command("echo")
+		}))
+
+		// Only the keys and the datapath types that differ are changed, and only once
+		gomock.InOrder(
+			ovsClient.EXPECT().GetOpenVSwitchOtherConfig().Return(map[string]string{"hw-offload": "false", "max-idle": "10000", "pmd-cpu-mask": "0x6"}, nil),
+			ovsClient.EXPECT().GetOpenVSwitchOtherConfig().Return(map[string]string{"hw-offload": "true", "max-idle": "30000"}, nil).Times(2),
+		)
+		ovsTxn.EXPECT().SetOpenVSwitchOtherConfig("hw-offload", "true")
+		ovsTxn.EXPECT().SetOpenVSwitchOtherConfig("max-idle", "30000")
+		ovsTxn.EXPECT().RemoveOpenVSwitchOtherConfig("pmd-cpu-mask")
+		ovsClient.EXPECT().BridgeExists("br-int").Return(false, nil).Times(3)
+		ovsClient.EXPECT().BridgeExists("br-ovn").Return(true, nil).Times(3)
+		gomock.InOrder(
+			ovsClient.EXPECT().GetBridgeDataPathType("br-ovn").Return(ovsclient.BridgeDataPathType(""), nil),
+			ovsClient.EXPECT().GetBridgeDataPathType("br-ovn").Return(ovsclient.NetDev, nil).Times(2),
+		)
+		ovsTxn.EXPECT().SetBridgeDataPathType("br-ovn", ovsclient.NetDev)
+
+		kubernetesClient := testclient.NewClientset(fakeNode.DeepCopy())
+		provisioner, tmpDir := newProvisioner(clock.NewFakeClock(time.Now()), ovsClient, ovsTxn, networkhelper, fakeExec, kubernetesClient, &dpucniprovisioner.OVSProfile{
+			OtherConfig:         map[string]string{"hw-offload": "true", "max-idle": "30000", "pmd-cpu-mask": ""},
+			BridgeDataPathTypes: map[string]ovsclient.BridgeDataPathType{"br-int": ovsclient.NetDev, "br-ovn": ovsclient.NetDev},
+		}, 3)
+		recorder := record.NewFakeRecorder(100)
+		provisioner.SetEventRecorder(recorder)
+		pendingPath := filepath.Join(tmpDir, "/var/lib/dpucniprovisioner/ovs-restart-pending.json")
+
+		By("Applying the profile and reporting the keys that wait for a restart of ovs-vswitchd")
+		Expect(provisioner.RunOnce()).To(Succeed())
+		node, err := kubernetesClient.CoreV1().Nodes().Get(ctx, fakeNode.Name, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(node.Annotations).To(HaveKeyWithValue(dpucniprovisioner.NodeAnnotationOVSRestartPending, "hw-offload"))
+		Expect(drainEvents(recorder)).To(ContainElement("Warning OVSRestartPending ovs-vswitchd must be restarted for OVS other_config keys hw-offload to take effect"))
+		Expect(pendingPath).To(BeARegularFile())
+		// The journal records what the profile sets so that a cleanup removes it, but not the keys it removes
+		Expect(provisioner.LastAppliedState().OVSOtherConfig).To(Equal([]string{"hw-offload", "max-idle"}))
+		Expect(provisioner.LastAppliedState().BridgeDataPathTypes).To(Equal([]string{"br-ovn"}))
+
+		By("Not reporting the pending restart again while ovs-vswitchd isn't restarted")
+		Expect(provisioner.RunOnce()).To(Succeed())
+		Expect(drainEvents(recorder)).ToNot(ContainElement(HavePrefix("Warning OVSRestartPending")))
+		node, err = kubernetesClient.CoreV1().Nodes().Get(ctx, fakeNode.Name, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(node.Annotations).To(HaveKeyWithValue(dpucniprovisioner.NodeAnnotationOVSRestartPending, "hw-offload"))
+
+		By("Clearing the pending restart once ovs-vswitchd is restarted out of band")
+		Expect(os.WriteFile(filepath.Join(tmpDir, "/var/run/openvswitch/ovs-vswitchd.pid"), []byte("200\n"), 0644)).To(Succeed())
+		Expect(provisioner.RunOnce()).To(Succeed())
+		node, err = kubernetesClient.CoreV1().Nodes().Get(ctx, fakeNode.Name, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(node.Annotations).ToNot(HaveKey(dpucniprovisioner.NodeAnnotationOVSRestartPending))
+		Expect(pendingPath).ToNot(BeAnExistingFile())
+	})
+
+	It("should require the restart command when the profile allows restarting ovs-vswitchd", func() {
+		provisioner := newInternalIPAMProvisioner(context.Background(), clock.NewFakeClock(time.Now()), nil, nil, &kexecTesting.FakeExec{}, testclient.NewClientset(), 1500)
+		Expect(provisioner.SetOVSProfile(&dpucniprovisioner.OVSProfile{DOCAInit: ptr.To(true), RestartOVS: true})).To(MatchError(ContainSubstring("the restart command is required")))
+		Expect(provisioner.SetOVSProfile(&dpucniprovisioner.OVSProfile{DOCAInit: ptr.To(true)})).To(Succeed())
+	})
+
+	It("should restart ovs-vswitchd at most once per cool down period when the profile allows it", func(ctx context.Context) {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		fakeExec.CommandScript = append(fakeExec.CommandScript,
+			kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+				Expect(append([]string{cmd}, args...)).To(Equal([]string{"ovs-ctl", "restart"}))
+				return kexec.New().Command("false")
+			}),
+			kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+				Expect(cmd).To(Equal("dnsmasq"))
+				return &blockingCmd{FakeCmd: &kexecTesting.FakeCmd{}, exit: make(chan error)}
+			}),
+			kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+				Expect(append([]string{cmd}, args...)).To(Equal([]string{"ovs-ctl", "restart"}))
+				return kexec.New().Command("echo")
+			}),
+		)
+
+		gomock.InOrder(
+			ovsClient.EXPECT().GetOpenVSwitchOtherConfig().Return(map[string]string{}, nil),
+			ovsClient.EXPECT().GetOpenVSwitchOtherConfig().Return(map[string]string{"doca-init": "true"}, nil).Times(2),
+		)
+		ovsTxn.EXPECT().SetDOCAInit(true)
+
+		fakeClock := clock.NewFakeClock(time.Now())
+		kubernetesClient := testclient.NewClientset(fakeNode.DeepCopy())
+		provisioner, _ := newProvisioner(fakeClock, ovsClient, ovsTxn, networkhelper, fakeExec, kubernetesClient, &dpucniprovisioner.OVSProfile{
+			DOCAInit:       ptr.To(true),
+			RestartOVS:     true,
+			RestartCommand: []string{"ovs-ctl", "restart"},
+		}, 3)
+		recorder := record.NewFakeRecorder(100)
+		provisioner.SetEventRecorder(recorder)
+
+		By("Failing the run when the restart fails and keeping the restart pending")
+		Expect(provisioner.RunOnce()).To(MatchError(ContainSubstring("error while restarting ovs-vswitchd")))
+		Expect(drainEvents(recorder)).To(ContainElement(HavePrefix("Warning OVSRestartFailed")))
+		node, err := kubernetesClient.CoreV1().Nodes().Get(ctx, fakeNode.Name, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(node.Annotations).To(HaveKeyWithValue(dpucniprovisioner.NodeAnnotationOVSRestartPending, "doca-init"))
+
+		By("Not restarting ovs-vswitchd again during the cool down period")
+		Expect(provisioner.RunOnce()).To(Succeed())
+		Expect(fakeExec.CommandCalls).To(Equal(2))
+
+		By("Restarting ovs-vswitchd once the cool down period is over")
+		fakeClock.Step(6 * time.Minute)
+		Expect(provisioner.RunOnce()).To(Succeed())
+		Expect(fakeExec.CommandCalls).To(Equal(3))
+		Expect(drainEvents(recorder)).To(ContainElement("Normal OVSRestarted Restarted ovs-vswitchd for OVS other_config keys doca-init to take effect"))
+		node, err = kubernetesClient.CoreV1().Nodes().Get(ctx, fakeNode.Name, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(node.Annotations).ToNot(HaveKey(dpucniprovisioner.NodeAnnotationOVSRestartPending))
+	})
+})
+
+var _ = Describe("DPU CNI Provisioner gateway discovery", func() {
+	gatewayDiscoveryNetwork := mustParseIPNet("169.254.99.100/32")
+
+	var (
+		networkhelper    *networkhelperMock.MockNetworkHelper
+		kubernetesClient *testclient.Clientset
+		provisioner      *dpucniprovisioner.DPUCNIProvisioner
+		tmpDir           string
+		// expectRun expects the calls of a run of the provisioning flow that don't depend on the strategy
+		expectRun func()
+	)
+	BeforeEach(func() {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper = networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+			return kexec.New().Command("echo")
+		}))
+		fakeNode := newFakeDPUNode()
+		kubernetesClient = testclient.NewClientset(fakeNode)
+		provisioner = dpucniprovisioner.New(context.Background(), dpucniprovisioner.ExternalIPAM, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, nil, nil, []*net.IPNet{mustParseIPNet("192.168.0.0/23")}, []*net.IPNet{mustParseIPNet("10.0.100.0/24")}, nil, fakeNode.Name, gatewayDiscoveryNetwork, 0)
+		var err error
+		tmpDir, err = os.MkdirTemp("", "dpucniprovisioner")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, tmpDir)
+		provisioner.FileSystemRoot = tmpDir
+		Expect(os.MkdirAll(filepath.Join(tmpDir, "/etc/netplan"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(tmpDir, "/etc/openvswitch"), 0755)).To(Succeed())
+
+		expectRun = func() {
+			expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.ExternalIPAM)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-ovn", nethelper.IPv4).Return([]*net.IPNet{mustParseIPNet("192.168.0.3/23")}, nil)
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{mustParseIPNet("10.244.6.30/24")}, nil).AnyTimes()
+			networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{mustParseIPNet("10.0.100.100/24")}, nil).AnyTimes()
+			ovsClientMockAll(ovsClient, ovsTxn)
+		}
+	})
+	// expectGateway runs the provisioning flow and expects it to configure the given gateway and to report the given
+	// source
+	expectGateway := func(ctx context.Context, gateway string, source dpucniprovisioner.GatewayDiscoveryStrategy) {
+		GinkgoHelper()
+		Expect(provisioner.RunOnce()).To(Succeed())
+		ovnInput, err := os.ReadFile(filepath.Join(tmpDir, "/etc/openvswitch/ovn_k8s.conf"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(ovnInput)).To(ContainSubstring("next-hop=" + gateway + "\n"))
+		Expect(provisioner.LastAppliedState().IPFamilies).To(ConsistOf(dpucniprovisioner.AppliedIPFamily{
+			Family:        "IPv4",
+			VTEPIP:        "192.168.0.3/23",
+			Gateway:       gateway,
+			GatewaySource: source,
+		}))
+		node, err := kubernetesClient.CoreV1().Nodes().Get(ctx, "dpu1", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(node.Annotations).To(HaveKeyWithValue(dpucniprovisioner.NodeAnnotationGateway, gateway))
+		Expect(node.Annotations).To(HaveKeyWithValue(dpucniprovisioner.NodeAnnotationGatewaySource, string(source)))
+	}
+
+	It("should discover the gateway from the route to the gateway discovery network by default", func(ctx context.Context) {
+		expectRun()
+		networkhelper.EXPECT().GetGateway(gatewayDiscoveryNetwork).Return(net.ParseIP("192.168.1.254"), nil)
+		networkHelperMockAll(networkhelper)
+		expectGateway(ctx, "192.168.1.254", dpucniprovisioner.RouteGatewayDiscovery)
+	})
+
+	It("should discover the gateway from the router option of the DHCP lease of br-ovn", func(ctx context.Context) {
+		expectRun()
+		Expect(provisioner.SetGatewayDiscovery(dpucniprovisioner.GatewayDiscovery{Strategy: dpucniprovisioner.DHCPLeaseGatewayDiscovery})).To(Succeed())
+		leasesDir := filepath.Join(tmpDir, "/run/systemd/netif/leases")
+		Expect(os.MkdirAll(leasesDir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(leasesDir, "7"), []byte("# This is private data. Do not parse.\nADDRESS=192.168.0.3\nROUTER=192.168.1.253 192.168.1.252\n"), 0644)).To(Succeed())
+		networkhelper.EXPECT().GetLinkIndex("br-ovn").Return(7, nil)
+		networkHelperMockAll(networkhelper)
+		expectGateway(ctx, "192.168.1.253", dpucniprovisioner.DHCPLeaseGatewayDiscovery)
+	})
+
+	It("should use the static gateway", func(ctx context.Context) {
+		expectRun()
+		Expect(provisioner.SetGatewayDiscovery(dpucniprovisioner.GatewayDiscovery{
+			Strategy: dpucniprovisioner.StaticGatewayDiscovery,
+			Gateways: []net.IP{net.ParseIP("192.168.1.250")},
+		})).To(Succeed())
+		networkHelperMockAll(networkhelper)
+		expectGateway(ctx, "192.168.1.250", dpucniprovisioner.StaticGatewayDiscovery)
+	})
+
+	It("should use the first gateway candidate that answers the probe", func(ctx context.Context) {
+		expectRun()
+		Expect(provisioner.SetGatewayDiscovery(dpucniprovisioner.GatewayDiscovery{
+			Strategy: dpucniprovisioner.ProbeGatewayDiscovery,
+			Gateways: []net.IP{net.ParseIP("192.168.1.250"), net.ParseIP("192.168.1.251"), net.ParseIP("192.168.1.252")},
+		})).To(Succeed())
+		gomock.InOrder(
+			networkhelper.EXPECT().ProbeNeighbor("br-ovn", net.ParseIP("192.168.1.250"), time.Second).Return(false, nil),
+			networkhelper.EXPECT().ProbeNeighbor("br-ovn", net.ParseIP("192.168.1.251"), time.Second).Return(true, nil),
+		)
+		networkHelperMockAll(networkhelper)
+		expectGateway(ctx, "192.168.1.251", dpucniprovisioner.ProbeGatewayDiscovery)
+	})
+
+	It("should fail when none of the gateway candidates answers the probe", func() {
+		expectRun()
+		Expect(provisioner.SetGatewayDiscovery(dpucniprovisioner.GatewayDiscovery{
+			Strategy: dpucniprovisioner.ProbeGatewayDiscovery,
+			Gateways: []net.IP{net.ParseIP("192.168.1.250")},
+		})).To(Succeed())
+		networkhelper.EXPECT().ProbeNeighbor("br-ovn", net.ParseIP("192.168.1.250"), time.Second).Return(false, nil)
+		networkHelperMockAll(networkhelper)
+		err := provisioner.RunOnce()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("none of the IPv4 gateway candidates answered"))
+	})
+
+	It("should reject gateway discovery settings that don't match the IP families", func() {
+		Expect(provisioner.SetGatewayDiscovery(dpucniprovisioner.GatewayDiscovery{Strategy: "arp"})).ToNot(Succeed())
+		Expect(provisioner.SetGatewayDiscovery(dpucniprovisioner.GatewayDiscovery{Strategy: dpucniprovisioner.StaticGatewayDiscovery})).ToNot(Succeed())
+		Expect(provisioner.SetGatewayDiscovery(dpucniprovisioner.GatewayDiscovery{
+			Strategy: dpucniprovisioner.StaticGatewayDiscovery,
+			Gateways: []net.IP{net.ParseIP("fd00:1::fe")},
+		})).ToNot(Succeed())
+		Expect(provisioner.SetGatewayDiscovery(dpucniprovisioner.GatewayDiscovery{
+			Strategy: dpucniprovisioner.RouteGatewayDiscovery,
+			Gateways: []net.IP{net.ParseIP("192.168.1.250")},
+		})).ToNot(Succeed())
+	})
+})
+
+// fakeOVNKubeNodeNotifier is an OVNKubeNodeNotifier that fails as many times as requested
+type fakeOVNKubeNodeNotifier struct {
+	calls    int
+	failures int
+}
+
+func (n *fakeOVNKubeNodeNotifier) Notify(_ context.Context) error {
+	n.calls++
+	if n.calls <= n.failures {
+		return errors.New("ovnkube-node is unreachable")
+	}
+	return nil
+}
+
+var _ = Describe("DPU CNI Provisioner ovn_k8s.conf", func() {
+	It("should replace ovn_k8s.conf only when its content changes and notify ovnkube-node", func() {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		for range 5 {
+			fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+				return kexec.New().Command("echo")
+			}))
+			expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.InternalIPAM)
+		}
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{mustParseIPNet("10.244.6.30/24")}, nil).AnyTimes()
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{mustParseIPNet("10.0.100.100/24")}, nil).AnyTimes()
+		networkHelperMockAll(networkhelper)
+		ovsClientMockAll(ovsClient, ovsTxn)
+
+		fakeNode := newFakeDPUNode()
+		provisioner := newInternalIPAMProvisioner(context.Background(), clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, testclient.NewClientset(fakeNode), 1500)
+		notifier := &fakeOVNKubeNodeNotifier{failures: 1}
+		provisioner.SetOVNKubeNodeNotifier(notifier)
+		tmpDir := provisioner.FileSystemRoot
+		ovnInputDirPath := filepath.Join(tmpDir, "/etc/openvswitch")
+		Expect(os.MkdirAll(ovnInputDirPath, 0755)).To(Succeed())
+		ovnInputPath := filepath.Join(ovnInputDirPath, "ovn_k8s.conf")
+		originalContent := "[Gateway]\nnext-hop=192.168.1.10\nrouter-subnet=192.168.1.0/24\n"
+
+		By("Not notifying ovnkube-node when the file is written for the first time")
+		Expect(provisioner.RunOnce()).To(Succeed())
+		Expect(os.ReadFile(ovnInputPath)).To(BeEquivalentTo(originalContent))
+		Expect(ovnInputPath + ".prev").ToNot(BeAnExistingFile())
+		Expect(notifier.calls).To(BeZero())
+
+		By("Leaving the file alone when its content doesn't change")
+		before, err := os.Stat(ovnInputPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(provisioner.RunOnce()).To(Succeed())
+		after, err := os.Stat(ovnInputPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.SameFile(before, after)).To(BeTrue())
+		Expect(notifier.calls).To(BeZero())
+
+		By("Keeping the previous version and failing the run when ovnkube-node can't be notified of a change")
+		provisioner.SetOVNConfigNamespaceForOVNConf("ovn-kubernetes")
+		err = provisioner.RunOnce()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("ovnkube-node is unreachable"))
+		Expect(os.ReadFile(ovnInputPath)).To(BeEquivalentTo(originalContent + "\n[kubernetes]\novn-config-namespace=ovn-kubernetes\n"))
+		Expect(os.ReadFile(ovnInputPath + ".prev")).To(BeEquivalentTo(originalContent))
+		Expect(notifier.calls).To(Equal(1))
+
+		By("Retrying the notification on the next run, even after a restart of the provisioner")
+		notifyPendingPath := filepath.Join(tmpDir, "/var/lib/dpucniprovisioner/ovnkube-node-notify-pending")
+		Expect(notifyPendingPath).To(BeARegularFile())
+		provisioner = newInternalIPAMProvisioner(context.Background(), clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, testclient.NewClientset(fakeNode), 1500)
+		provisioner.SetOVNKubeNodeNotifier(notifier)
+		provisioner.SetOVNConfigNamespaceForOVNConf("ovn-kubernetes")
+		provisioner.FileSystemRoot = tmpDir
+		Expect(provisioner.RunOnce()).To(Succeed())
+		Expect(notifier.calls).To(Equal(2))
+		Expect(notifyPendingPath).ToNot(BeAnExistingFile())
+		Expect(provisioner.RunOnce()).To(Succeed())
+		Expect(notifier.calls).To(Equal(2))
+		entries, err := os.ReadDir(ovnInputDirPath)
+		Expect(err).ToNot(HaveOccurred())
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		Expect(names).To(ConsistOf("ovn_k8s.conf", "ovn_k8s.conf.prev"))
+	})
+
+	It("should merge the sections and keys of the fragment into ovn_k8s.conf", func() {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		for range 4 {
+			fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+				return kexec.New().Command("echo")
+			}))
+			expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.InternalIPAM)
+		}
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{mustParseIPNet("10.244.6.30/24")}, nil).AnyTimes()
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{mustParseIPNet("10.0.100.100/24")}, nil).AnyTimes()
+		networkHelperMockAll(networkhelper)
+		ovsClientMockAll(ovsClient, ovsTxn)
+
+		fakeNode := newFakeDPUNode()
+		provisioner := newInternalIPAMProvisioner(context.Background(), clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, testclient.NewClientset(fakeNode), 1500)
+		provisioner.SetOVNConfigNamespaceForOVNConf("ovn-kubernetes")
+		tmpDir := provisioner.FileSystemRoot
+		provisioner.OVNKConfFragmentPath = "/etc/dpucniprovisioner/ovnk-conf/ovn_k8s.conf"
+		fragmentPath := filepath.Join(tmpDir, provisioner.OVNKConfFragmentPath)
+		Expect(os.MkdirAll(filepath.Dir(fragmentPath), 0755)).To(Succeed())
+		ovnInputDirPath := filepath.Join(tmpDir, "/etc/openvswitch")
+		Expect(os.MkdirAll(ovnInputDirPath, 0755)).To(Succeed())
+		ovnInputPath := filepath.Join(ovnInputDirPath, "ovn_k8s.conf")
+		managedContent := "[Gateway]\nnext-hop=192.168.1.10\nrouter-subnet=192.168.1.0/24\n\n[kubernetes]\novn-config-namespace=ovn-kubernetes\n"
+
+		By("Writing the managed keys only when the fragment doesn't exist")
+		Expect(provisioner.RunOnce()).To(Succeed())
+		Expect(os.ReadFile(ovnInputPath)).To(BeEquivalentTo(managedContent))
+
+		By("Merging the fragment into the managed sections and appending its other sections")
+		Expect(os.WriteFile(fragmentPath, []byte("# Added by the cluster admin\n[gateway]\nmode = shared\n\n[default]\nmtu=1400\n; Encapsulation\nencap-port=6081\n[Kubernetes]\nno-hostsubnet-nodes=node-role.kubernetes.io/control-plane\n"), 0644)).To(Succeed())
+		Expect(provisioner.RunOnce()).To(Succeed())
+		mergedContent := "[Gateway]\nnext-hop=192.168.1.10\nrouter-subnet=192.168.1.0/24\nmode=shared\n\n[kubernetes]\novn-config-namespace=ovn-kubernetes\nno-hostsubnet-nodes=node-role.kubernetes.io/control-plane\n\n[default]\nmtu=1400\nencap-port=6081\n"
+		Expect(os.ReadFile(ovnInputPath)).To(BeEquivalentTo(mergedContent))
+
+		By("Rejecting a fragment that sets a managed key")
+		Expect(os.WriteFile(fragmentPath, []byte("[Gateway]\nNext-Hop=10.0.0.1\n[ovnkubenode]\ndpu-node-lease-duration=5\n"), 0644)).To(Succeed())
+		err := provisioner.RunOnce()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("key Next-Hop of section [Gateway] is managed by the provisioner"))
+		Expect(err.Error()).To(ContainSubstring("key dpu-node-lease-duration of section [ovnkubenode] is managed by the provisioner"))
+		Expect(os.ReadFile(ovnInputPath)).To(BeEquivalentTo(mergedContent))
+
+		By("Rejecting a fragment that can't be parsed")
+		Expect(os.WriteFile(fragmentPath, []byte("mtu=1400\n"), 0644)).To(Succeed())
+		err = provisioner.RunOnce()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("line 1: key outside of a section"))
+		Expect(os.ReadFile(ovnInputPath)).To(BeEquivalentTo(mergedContent))
+	})
+
+	It("should send the signal to the process of the PID file", func(ctx context.Context) {
+		pidFilePath := filepath.Join(GinkgoT().TempDir(), "ovnkube-node.pid")
+		notifier := dpucniprovisioner.NewSignalNotifier(pidFilePath, 0)
+		Expect(notifier.Notify(ctx)).ToNot(Succeed())
+
+		Expect(os.WriteFile(pidFilePath, []byte("not-a-pid\n"), 0644)).To(Succeed())
+		Expect(notifier.Notify(ctx)).ToNot(Succeed())
+
+		// Signal 0 only checks that the process exists
+		Expect(os.WriteFile(pidFilePath, []byte(fmt.Sprintf("%d\n", os.Getpid())), 0644)).To(Succeed())
+		Expect(notifier.Notify(ctx)).To(Succeed())
+	})
+
+	It("should touch the trigger file", func(ctx context.Context) {
+		triggerFilePath := filepath.Join(GinkgoT().TempDir(), "trigger")
+		notifier := dpucniprovisioner.NewTriggerFileNotifier(triggerFilePath)
+		Expect(notifier.Notify(ctx)).To(Succeed())
+		Expect(triggerFilePath).To(BeAnExistingFile())
+
+		past := time.Now().Add(-time.Hour)
+		Expect(os.Chtimes(triggerFilePath, past, past)).To(Succeed())
+		Expect(notifier.Notify(ctx)).To(Succeed())
+		info, err := os.Stat(triggerFilePath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.ModTime()).To(BeTemporally(">", past))
+	})
+
+	It("should delete the ovnkube-node Pods of the DPU Node", func(ctx context.Context) {
+		pod := func(name string, nodeName string, component string) *corev1.Pod {
+			return &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: "ovn-kubernetes",
+					Labels:    map[string]string{"app.kubernetes.io/component": component},
+				},
+				Spec: corev1.PodSpec{NodeName: nodeName},
+			}
+		}
+		kubernetesClient := testclient.NewClientset(
+			pod("ovnkube-node-dpu1", "dpu1", "ovnkube-node"),
+			pod("ovnkube-node-dpu2", "dpu2", "ovnkube-node"),
+			pod("ovnkube-control-plane-dpu1", "dpu1", "ovnkube-control-plane"),
+		)
+		notifier := dpucniprovisioner.NewPodDeleteNotifier(kubernetesClient, "ovn-kubernetes", "app.kubernetes.io/component=ovnkube-node", "dpu1")
+		Expect(notifier.Notify(ctx)).To(Succeed())
+
+		pods, err := kubernetesClient.CoreV1().Pods("ovn-kubernetes").List(ctx, metav1.ListOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		var names []string
+		for _, p := range pods.Items {
+			names = append(names, p.Name)
+		}
+		Expect(names).To(ConsistOf("ovnkube-node-dpu2", "ovnkube-control-plane-dpu1"))
+
+		By("Not failing when there is no Pod left to delete")
+		Expect(notifier.Notify(ctx)).To(Succeed())
+	})
+})
+
+var _ = Describe("DPU CNI Provisioner events", func() {
+	It("should emit Events against the DPU Node and the host Node", func(ctx context.Context) {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		for range 3 {
+			fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+				return kexec.New().Command("echo")
+			}))
+		}
+		for range 3 {
+			expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.InternalIPAM)
+		}
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{mustParseIPNet("10.244.6.30/24")}, nil).AnyTimes()
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{mustParseIPNet("10.0.100.100/24")}, nil).AnyTimes()
+		networkHelperMockAll(networkhelper)
+		ovsClientMockAll(ovsClient, ovsTxn)
+
+		fakeNode := newFakeDPUNode()
+		kubernetesClient := testclient.NewClientset(fakeNode)
+		provisioner := newInternalIPAMProvisioner(context.Background(), clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, 1500)
+		tmpDir := provisioner.FileSystemRoot
+		provisioner.StateDir = filepath.Join(tmpDir, "state")
+		dpuRecorder := record.NewFakeRecorder(100)
+		hostRecorder := record.NewFakeRecorder(100)
+		provisioner.SetEventRecorder(dpuRecorder)
+		provisioner.SetHostEventRecorder(hostRecorder)
+
+		By("Emitting an Event once a new generation of the state is applied")
+		Expect(provisioner.RunOnce()).To(Succeed())
+		Expect(drainEvents(dpuRecorder)).To(ContainElement("Normal ConfigurationApplied Applied generation 1 of the state"))
+		Expect(drainEvents(hostRecorder)).To(ContainElement("Normal ConfigurationApplied DPU dpu1: Applied generation 1 of the state"))
+
+		By("Not emitting an Event when the state doesn't change")
+		Expect(provisioner.RunOnce()).To(Succeed())
+		Expect(drainEvents(dpuRecorder)).ToNot(ContainElement(HavePrefix("Normal ConfigurationApplied")))
+
+		By("Emitting a Warning Event when a run fails")
+		node, err := kubernetesClient.CoreV1().Nodes().Get(ctx, fakeNode.Name, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		node.Labels = nil
+		_, err = kubernetesClient.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(provisioner.RunOnce()).ToNot(Succeed())
+		Expect(drainEvents(dpuRecorder)).To(ContainElement(And(HavePrefix("Warning ConfigurationFailed"), ContainSubstring("neither label"))))
+		Expect(drainEvents(hostRecorder)).To(ContainElement(HavePrefix("Warning ConfigurationFailed DPU dpu1: ")))
+	})
+})
+
+var _ = Describe("DPU CNI Provisioner logging", func() {
+	It("should log every run with its own reconcile ID along with the nodes and the step", func() {
+		testCtrl := gomock.NewController(GinkgoT())
+		ovsClient := ovsclientMock.NewMockOVSClient(testCtrl)
+		ovsTxn := ovsclientMock.NewMockTransaction(testCtrl)
+		ovsClient.EXPECT().Transaction().Return(ovsTxn).AnyTimes()
+		networkhelper := networkhelperMock.NewMockNetworkHelper(testCtrl)
+		fakeExec := &kexecTesting.FakeExec{}
+		// The DHCP server keeps running so that its supervisor doesn't log outside of the reconciliations
+		fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+			return &blockingCmd{FakeCmd: &kexecTesting.FakeCmd{}, exit: make(chan error, 1)}
+		}))
+		for range 2 {
+			expectInterfacesDiscovered(networkhelper, ovsClient, nethelper.IPv4, dpucniprovisioner.InternalIPAM)
+		}
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("cni0", nethelper.IPv4).Return([]*net.IPNet{mustParseIPNet("10.244.6.30/24")}, nil).AnyTimes()
+		networkhelper.EXPECT().GetLinkIPAddressesByFamily("br-comm-ch", nethelper.IPv4).Return([]*net.IPNet{mustParseIPNet("10.0.100.100/24")}, nil).AnyTimes()
+		networkHelperMockAll(networkhelper)
+		ovsClientMockAll(ovsClient, ovsTxn)
+
+		var entriesLock sync.Mutex
+		var entries []map[string]any
+		logger := funcr.NewJSON(func(obj string) {
+			entry := map[string]any{}
+			Expect(json.Unmarshal([]byte(obj), &entry)).To(Succeed())
+			entriesLock.Lock()
+			defer entriesLock.Unlock()
+			entries = append(entries, entry)
+		}, funcr.Options{})
+		loggedEntries := func() []map[string]any {
+			entriesLock.Lock()
+			defer entriesLock.Unlock()
+			return slices.Clone(entries)
+		}
+		ctx := klog.NewContext(context.Background(), logger)
+
+		fakeNode := newFakeDPUNode()
+		kubernetesClient := testclient.NewClientset(fakeNode)
+		provisioner := newInternalIPAMProvisioner(ctx, clock.NewFakeClock(time.Now()), ovsClient, networkhelper, fakeExec, kubernetesClient, 1500)
+		tmpDir := provisioner.FileSystemRoot
+		provisioner.StateDir = filepath.Join(tmpDir, "state")
+
+		Expect(provisioner.RunOnce()).To(Succeed())
+		firstRun := len(loggedEntries())
+		Expect(provisioner.RunOnce()).To(Succeed())
+		logged := loggedEntries()
+		Expect(logged).ToNot(BeEmpty())
+		provisioner.Stop()
+
+		reconcileIDs := map[any]bool{}
+		for i, entry := range logged {
+			Expect(entry).To(HaveKeyWithValue("dpuNode", "dpu1"))
+			Expect(entry).To(HaveKeyWithValue("reconcileID", Not(BeEmpty())))
+			run := logged[0]
+			if i >= firstRun {
+				run = logged[firstRun]
+			}
+			Expect(entry["reconcileID"]).To(Equal(run["reconcileID"]), "entry %d", i)
+			reconcileIDs[entry["reconcileID"]] = true
+		}
+		Expect(reconcileIDs).To(HaveLen(2))
+
+		entryWithMessage := func(msg string) map[string]any {
+			for _, entry := range logged {
+				if entry["msg"] == msg {
+					return entry
+				}
+			}
+			return nil
+		}
+		Expect(entryWithMessage("Selected interfaces")).To(And(
+			HaveKeyWithValue("step", "interfaces"),
+			HaveKeyWithValue("oob", "br-comm-ch"),
+			Not(HaveKey("hostNode")),
+		))
+		Expect(entryWithMessage("Configuring symmetric routing")).To(And(
+			HaveKeyWithValue("step", "symmetric_routing"),
+			HaveKeyWithValue("hostNode", "host1"),
+		))
+		Expect(entryWithMessage("Using the VTEP IPs of the uplinks as geneve encap IPs")).To(
+			HaveKeyWithValue("step", "pod_to_pod"),
+		)
+		Expect(entryWithMessage("Configuration complete.")).To(And(
+			HaveKeyWithValue("hostNode", "host1"),
+			Not(HaveKey("step")),
+		))
+	})
+})
+
+// blockingCmd is a fake command whose Wait blocks until an exit error is sent to its channel
+type blockingCmd struct {
+	*kexecTesting.FakeCmd
+	exit chan error
+}
+
+func (c *blockingCmd) Wait() error {
+	return <-c.exit
+}
+
+func (c *blockingCmd) Stop() {
+	select {
+	case c.exit <- errors.New("signal: terminated"):
+	default:
+	}
+}
+
+// drainEvents returns the events recorded so far
+func drainEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+// fakeEventSource is an EventSource that reports every event sent to its channel
+type fakeEventSource struct {
+	events chan string
+}
+
+func (s *fakeEventSource) Run(ctx context.Context, notify func(reason string)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case reason := <-s.events:
+			notify(reason)
+		}
+	}
+}
+
 // networkHelperMockAll mocks all networkhelper functions. Useful for tests where we don't test the network calls
 func networkHelperMockAll(networkHelper *networkhelperMock.MockNetworkHelper) {
 	networkHelper.EXPECT().AddRoute(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 	networkHelper.EXPECT().AddRule(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 	networkHelper.EXPECT().GetGateway(gomock.Any()).AnyTimes()
-	networkHelper.EXPECT().GetLinkIPAddresses(gomock.Any()).AnyTimes()
+	networkHelper.EXPECT().GetLinkIPAddressesByFamily(gomock.Any(), gomock.Any()).AnyTimes()
 	networkHelper.EXPECT().GetHostPFMACAddressDPU(gomock.Any()).AnyTimes()
 	networkHelper.EXPECT().LinkIPAddressExists(gomock.Any(), gomock.Any()).AnyTimes()
 	networkHelper.EXPECT().RouteExists(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 	networkHelper.EXPECT().RuleExists(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 	networkHelper.EXPECT().SetLinkIPAddress(gomock.Any(), gomock.Any()).AnyTimes()
 	networkHelper.EXPECT().SetLinkUp(gomock.Any()).AnyTimes()
+	networkHelper.EXPECT().ListOwnedRoutes().AnyTimes()
+	networkHelper.EXPECT().ListOwnedRules().AnyTimes()
+	networkHelper.EXPECT().GetDefaultRouteDevices(gomock.Any()).AnyTimes()
+	networkHelper.EXPECT().GetPFRepresentorDPU(gomock.Any()).AnyTimes()
+	networkHelper.EXPECT().GetLinkMTU(gomock.Any()).Return(9216, nil).AnyTimes()
+	networkHelper.EXPECT().SetLinkMTU(gomock.Any(), gomock.Any()).AnyTimes()
+}
+
+// expectInterfacesDiscovered expects a run of the provisioning flow to discover br-comm-ch as the OOB interface and,
+// in Internal mode, PF 0 as the PF of the host
+func expectInterfacesDiscovered(networkHelper *networkhelperMock.MockNetworkHelper, ovsClient *ovsclientMock.MockOVSClient, family nethelper.Family, mode dpucniprovisioner.Mode) {
+	networkHelper.EXPECT().GetDefaultRouteDevices(family).Return([]string{"br-comm-ch"}, nil)
+	if mode == dpucniprovisioner.InternalIPAM {
+		networkHelper.EXPECT().GetPFRepresentorDPU("0").Return("pf0hpf", nil)
+		ovsClient.EXPECT().InterfaceToBridge("pf0hpf").Return("br-sfc", nil)
+	}
 }
 
 // ovsClientMockAll mocks all ovsclient functions. Useful for tests where we don't test the ovsclient calls
-func ovsClientMockAll(ovsClient *ovsclientMock.MockOVSClient) {
+func ovsClientMockAll(ovsClient *ovsclientMock.MockOVSClient, ovsTxn *ovsclientMock.MockTransaction) {
 	ovsClient.EXPECT().GetSystemID().Return("test-system-id", nil).AnyTimes()
-	ovsClient.EXPECT().SetKubernetesHostNodeName(gomock.Any()).AnyTimes()
-	ovsClient.EXPECT().SetHostName(gomock.Any()).AnyTimes()
-	ovsClient.EXPECT().SetOVNEncapIP(gomock.Any()).AnyTimes()
+	ovsTxn.EXPECT().SetKubernetesHostNodeName(gomock.Any()).AnyTimes()
+	ovsTxn.EXPECT().SetHostName(gomock.Any()).AnyTimes()
+	ovsTxn.EXPECT().SetOVNEncapIP(gomock.Any()).AnyTimes()
+	ovsTxn.EXPECT().Commit().AnyTimes()
+}
+
+// mustParseIPNet parses the given address in CIDR notation, keeping the address of the host
+func mustParseIPNet(s string) *net.IPNet {
+	ipNet, err := netlink.ParseIPNet(s)
+	Expect(err).ToNot(HaveOccurred())
+	return ipNet
+}
+
+// mustParseCIDR parses the given network in CIDR notation
+func mustParseCIDR(s string) *net.IPNet {
+	_, ipNet, err := net.ParseCIDR(s)
+	Expect(err).ToNot(HaveOccurred())
+	return ipNet
+}
+
+// newFakeDPUNode returns the DPU Node most specs use, which is labeled with the name of its host
+func newFakeDPUNode() *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "dpu1",
+			Labels: map[string]string{
+				"provisioning.dpu.nvidia.com/dpunode-name": "host1",
+			},
+		},
+	}
+}
+
+// newInternalIPAMProvisioner returns a provisioner in Internal mode for the Node of newFakeDPUNode with the addressing
+// most specs use. Its file system root is a temporary directory that contains /etc/openvswitch and that is removed once
+// the spec ends.
+func newInternalIPAMProvisioner(ctx context.Context, fakeClock *clock.FakeClock, ovsClient ovsclient.OVSClient, networkHelper nethelper.NetworkHelper, fakeExec kexec.Interface, kubernetesClient *testclient.Clientset, ovnMTU int) *dpucniprovisioner.DPUCNIProvisioner {
+	provisioner := dpucniprovisioner.New(ctx, dpucniprovisioner.InternalIPAM, fakeClock, ovsClient, networkHelper, fakeExec, kubernetesClient, mustParseIPNet("192.168.1.1/24"), net.ParseIP("192.168.1.10"), []*net.IPNet{mustParseIPNet("192.168.1.0/23")}, []*net.IPNet{mustParseIPNet("10.0.100.1/24")}, mustParseIPNet("192.168.1.2/24"), "dpu1", nil, ovnMTU)
+	tmpDir, err := os.MkdirTemp("", "dpucniprovisioner")
+	Expect(err).NotTo(HaveOccurred())
+	DeferCleanup(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+	provisioner.FileSystemRoot = tmpDir
+	Expect(os.MkdirAll(filepath.Join(tmpDir, "/etc/openvswitch"), 0755)).To(Succeed())
+	return provisioner
 }
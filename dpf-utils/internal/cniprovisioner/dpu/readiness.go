@@ -0,0 +1,90 @@
+/*
+Copyright 2024 NVIDIA.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dpucniprovisioner
+
+import (
+	"errors"
+	"fmt"
+)
+
+// readinessFailureThreshold is the number of consecutive failed runs of the provisioning flow after which the
+// provisioner reports not ready.
+const readinessFailureThreshold = 3
+
+// readiness tracks the state the readiness of the provisioner is derived from
+type readiness struct {
+	// consecutiveFailures is the number of runs of the provisioning flow that failed since the last successful one
+	consecutiveFailures int
+	// lastError is the error of the last failed run of the provisioning flow
+	lastError error
+	// dhcpServerExited is set when the DHCP server process has exited and has not been restarted yet
+	dhcpServerExited bool
+	// dhcpServerExitError is the error the DHCP server process exited with
+	dhcpServerExitError error
+}
+
+// CheckReadiness returns an error when the provisioner is not ready, that is when the provisioning flow failed
+// readinessFailureThreshold times in a row, when the DHCP server has exited or when reconciliation is paused after a
+// cleanup.
+func (p *DPUCNIProvisioner) CheckReadiness() error {
+	p.readinessLock.Lock()
+	defer p.readinessLock.Unlock()
+
+	var errs []error
+	if p.readiness.consecutiveFailures >= readinessFailureThreshold {
+		errs = append(errs, fmt.Errorf("configuration failed %d times in a row: %w", p.readiness.consecutiveFailures, p.readiness.lastError))
+	}
+	if p.readiness.dhcpServerExited {
+		errs = append(errs, fmt.Errorf("DHCP server exited: %s", describeExit(p.readiness.dhcpServerExitError)))
+	}
+	if p.cleanupStarted() {
+		errs = append(errs, errors.New("reconciliation is paused as the state of the provisioner was removed by a cleanup"))
+	}
+	return errors.Join(errs...)
+}
+
+// recordConfigurationResult updates the readiness with the result of a run of the provisioning flow
+func (p *DPUCNIProvisioner) recordConfigurationResult(err error) {
+	p.readinessLock.Lock()
+	defer p.readinessLock.Unlock()
+
+	if err != nil {
+		p.readiness.consecutiveFailures++
+		p.readiness.lastError = err
+		return
+	}
+	p.readiness.consecutiveFailures = 0
+	p.readiness.lastError = nil
+}
+
+// recordDHCPServerStart updates the readiness with the start of the DHCP server process
+func (p *DPUCNIProvisioner) recordDHCPServerStart() {
+	p.readinessLock.Lock()
+	defer p.readinessLock.Unlock()
+
+	p.readiness.dhcpServerExited = false
+	p.readiness.dhcpServerExitError = nil
+}
+
+// recordDHCPServerExit updates the readiness with the exit of the DHCP server process
+func (p *DPUCNIProvisioner) recordDHCPServerExit(err error) {
+	p.readinessLock.Lock()
+	defer p.readinessLock.Unlock()
+
+	p.readiness.dhcpServerExited = true
+	p.readiness.dhcpServerExitError = err
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dpucniprovisioner
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// IPFamilySettings is the addressing of a single IP family. The fields follow the same rules as the respective inputs
+// of New.
+type IPFamilySettings struct {
+	VTEPIPNet               *net.IPNet
+	Gateway                 net.IP
+	VTEPCIDRs               []*net.IPNet
+	HostCIDRs               []*net.IPNet
+	PFIP                    *net.IPNet
+	GatewayDiscoveryNetwork *net.IPNet
+}
+
+// DPUNodeLease are the ovnkube-node DPU lease intervals in seconds
+type DPUNodeLease struct {
+	RenewInterval int
+	Duration      int
+}
+
+// Settings are the inputs of the provisioner that can be changed while it's running
+type Settings struct {
+	// IPFamilies is the addressing per IP family, the first one being the primary IP family
+	IPFamilies []IPFamilySettings
+	// OVNMTU is the MTU that is configured for OVN. It's derived from the MTU of the uplinks when 0.
+	OVNMTU int
+	// DPUNodeLease, when set, is written to ovn_k8s.conf
+	DPUNodeLease *DPUNodeLease
+	// OVNConfigNamespace, when not empty, is written to ovn_k8s.conf
+	OVNConfigNamespace string
+	// Interfaces are the interfaces that are used instead of the discovered ones
+	Interfaces Interfaces
+	// Uplinks are the uplinks in addition to br-ovn and how the geneve tunnels use them
+	Uplinks Uplinks
+	// GatewayDiscovery is how the gateway of br-ovn is discovered in ExternalIPAM mode
+	GatewayDiscovery GatewayDiscovery
+	// OVSProfile, when set, is the datapath profile of OVS
+	OVSProfile *OVSProfile
+}
+
+// UpdateSettings validates the given settings and has them applied at the start of the next run of the provisioning
+// flow. The settings that were not applied yet are replaced. It's safe to call while EnsureConfiguration is running,
+// the caller is expected to request a reconcile afterwards, e.g. via an EventSource.
+func (p *DPUCNIProvisioner) UpdateSettings(settings Settings) error {
+	if len(settings.IPFamilies) == 0 {
+		return errors.New("error while updating settings: at least 1 IP family is expected")
+	}
+	var ipFamilies []*ipFamilyConfig
+	for _, f := range settings.IPFamilies {
+		var err error
+		ipFamilies, err = appendIPFamily(ipFamilies, newIPFamilyConfig(f.VTEPIPNet, f.Gateway, f.VTEPCIDRs, f.HostCIDRs, f.PFIP, f.GatewayDiscoveryNetwork))
+		if err != nil {
+			return fmt.Errorf("error while updating settings: %w", err)
+		}
+	}
+	if err := validateInterfaces(settings.Interfaces); err != nil {
+		return fmt.Errorf("error while updating settings: %w", err)
+	}
+	if err := validateUplinks(p.mode, settings.Uplinks, ipFamilies); err != nil {
+		return fmt.Errorf("error while updating settings: %w", err)
+	}
+	if err := validateGatewayDiscovery(settings.GatewayDiscovery, ipFamilies); err != nil {
+		return fmt.Errorf("error while updating settings: %w", err)
+	}
+	if err := validateOVSProfile(settings.OVSProfile); err != nil {
+		return fmt.Errorf("error while updating settings: %w", err)
+	}
+	if lease := settings.DPUNodeLease; lease != nil && (lease.RenewInterval <= 0 || lease.Duration <= lease.RenewInterval) {
+		return fmt.Errorf("error while updating settings: invalid DPU node lease renew interval %d and duration %d", lease.RenewInterval, lease.Duration)
+	}
+
+	p.pendingSettingsLock.Lock()
+	defer p.pendingSettingsLock.Unlock()
+	p.pendingSettings = &pendingSettings{Settings: settings, ipFamilies: ipFamilies}
+	return nil
+}
+
+// pendingSettings are validated Settings that are not applied yet
+type pendingSettings struct {
+	Settings
+	ipFamilies []*ipFamilyConfig
+}
+
+// applyPendingSettings applies the settings given to UpdateSettings, if any. It must be called from the goroutine that
+// runs the provisioning flow.
+func (p *DPUCNIProvisioner) applyPendingSettings() {
+	p.pendingSettingsLock.Lock()
+	pending := p.pendingSettings
+	p.pendingSettings = nil
+	p.pendingSettingsLock.Unlock()
+	if pending == nil {
+		return
+	}
+
+	p.logger.Info("Applying updated settings")
+	p.ipFamilies = pending.ipFamilies
+	p.ovnMTU = pending.OVNMTU
+	if pending.DPUNodeLease != nil {
+		p.SetDPUNodeLeaseForOVNConf(pending.DPUNodeLease.RenewInterval, pending.DPUNodeLease.Duration)
+	} else {
+		p.writeDPUNodeLeaseToOVNKConf = false
+		p.dpuNodeLeaseRenewInterval = 0
+		p.dpuNodeLeaseDuration = 0
+	}
+	p.SetOVNConfigNamespaceForOVNConf(pending.OVNConfigNamespace)
+	p.interfaceOverrides = pending.Interfaces
+	p.gatewayDiscovery = pending.GatewayDiscovery
+	p.ovsProfile = pending.OVSProfile
+	p.interfacesLock.Lock()
+	p.uplinkSettings = pending.Uplinks
+	p.interfacesLock.Unlock()
+}
@@ -0,0 +1,334 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dpucniprovisioner
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/logging"
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/networkhelper"
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/ovsclient"
+
+	"k8s.io/utils/ptr"
+)
+
+// EncapMode is how the VTEP IPs of the uplinks are used as geneve encap IPs
+type EncapMode string
+
+const (
+	// ActiveBackupEncap uses the VTEP IP of the first healthy uplink only, the other uplinks take over when it fails
+	ActiveBackupEncap EncapMode = "active-backup"
+	// ECMPEncap uses the VTEP IPs of all the healthy uplinks so that the tunnels are spread across them
+	ECMPEncap EncapMode = "ecmp"
+)
+
+const (
+	// uplinkRoutingTableBase is the base of the route tables used for source routing the traffic of the additional
+	// uplinks. The additional uplink at index i of the uplinks uses table uplinkRoutingTableBase + i.
+	uplinkRoutingTableBase = 70
+	// uplinkRulePriority is the priority of the rules that send the traffic of the VTEP IP of an additional uplink to
+	// its route table. It's lower than the priorities of the symmetric routing rules so that it's evaluated first.
+	uplinkRulePriority = 30000
+)
+
+// Uplink is an uplink of the DPU in addition to br-ovn, e.g. the second port of a dual port DPU. It's only supported in
+// InternalIPAM mode.
+type Uplink struct {
+	// Bridge is the OVS bridge whose internal port acts as the VTEP of the uplink
+	Bridge string
+	// Port is the physical port of the uplink whose carrier decides whether the uplink is healthy. The uplink is always
+	// considered healthy when empty.
+	Port string
+	// PFIndex is the index of the PF ("0" or "1") the host uses to reach the DPU through the uplink
+	PFIndex string
+	// IPFamilies is the addressing of the uplink, one per IP family of the provisioner
+	IPFamilies []UplinkIPFamily
+}
+
+// UplinkIPFamily is the addressing of an uplink for a single IP family. The VTEP and host CIDRs are the ones of the IP
+// family of the provisioner.
+type UplinkIPFamily struct {
+	// VTEPIPNet is the IP that should be added to the bridge of the uplink
+	VTEPIPNet *net.IPNet
+	// Gateway is the gateway the remote VTEPs and hosts are reached through from the uplink
+	Gateway net.IP
+	// PFIP is the IP the PF of the uplink gets from the DHCP server
+	PFIP *net.IPNet
+}
+
+// Uplinks are the uplinks of the DPU in addition to br-ovn and how the geneve tunnels use them
+type Uplinks struct {
+	// PrimaryPort is the physical port of br-ovn whose carrier decides whether br-ovn is healthy. br-ovn is always
+	// considered healthy when empty.
+	PrimaryPort string
+	// Additional are the uplinks in addition to br-ovn, in order of preference
+	Additional []Uplink
+	// EncapMode is how the VTEP IPs of the uplinks are used as geneve encap IPs. Defaults to ActiveBackupEncap.
+	EncapMode EncapMode
+}
+
+// uplinkConfig is an uplink the provisioning flow configures
+type uplinkConfig struct {
+	// bridge is the OVS bridge whose internal port acts as the VTEP of the uplink
+	bridge string
+	// port is the physical port whose carrier decides whether the uplink is healthy. Empty when not monitored.
+	port string
+	// pfIndex is the index of the PF of the host that is served via the uplink
+	pfIndex string
+	// ipFamilies is the addressing of the uplink in the same order as the IP families of the provisioner
+	ipFamilies []*ipFamilyConfig
+}
+
+// primaryIPFamily returns the addressing of the uplink the geneve tunnels use
+func (u *uplinkConfig) primaryIPFamily() *ipFamilyConfig {
+	return u.ipFamilies[0]
+}
+
+// validateUplinks validates the given uplinks against the mode and the IP families of the provisioner
+func validateUplinks(mode Mode, uplinks Uplinks, ipFamilies []*ipFamilyConfig) error {
+	switch uplinks.EncapMode {
+	case "", ActiveBackupEncap, ECMPEncap:
+	default:
+		return fmt.Errorf("unknown encap mode %q", uplinks.EncapMode)
+	}
+	if len(uplinks.Additional) > 0 && mode != InternalIPAM {
+		return fmt.Errorf("additional uplinks are only supported in %s mode", InternalIPAM)
+	}
+
+	bridges := []string{brOVN}
+	var pfIndexes []string
+	for _, u := range uplinks.Additional {
+		if u.Bridge == "" {
+			return errors.New("the bridge of an uplink can't be empty")
+		}
+		if slices.Contains(bridges, u.Bridge) {
+			return fmt.Errorf("bridge %s is used by more than one uplink", u.Bridge)
+		}
+		bridges = append(bridges, u.Bridge)
+		if err := validateInterfaces(Interfaces{PFIndex: u.PFIndex}); err != nil {
+			return fmt.Errorf("invalid uplink %s: %w", u.Bridge, err)
+		}
+		if u.PFIndex == "" {
+			return fmt.Errorf("invalid uplink %s: the PF index can't be empty", u.Bridge)
+		}
+		if slices.Contains(pfIndexes, u.PFIndex) {
+			return fmt.Errorf("PF %s is used by more than one uplink", u.PFIndex)
+		}
+		pfIndexes = append(pfIndexes, u.PFIndex)
+		if _, err := newUplinkIPFamilies(u, ipFamilies); err != nil {
+			return fmt.Errorf("invalid uplink %s: %w", u.Bridge, err)
+		}
+	}
+	return nil
+}
+
+// newUplinkIPFamilies returns the addressing of the given uplink in the order of the given IP families of the
+// provisioner, whose VTEP and host CIDRs it shares
+func newUplinkIPFamilies(u Uplink, ipFamilies []*ipFamilyConfig) ([]*ipFamilyConfig, error) {
+	byFamily := map[networkhelper.Family]*ipFamilyConfig{}
+	for _, f := range u.IPFamilies {
+		if f.VTEPIPNet == nil || f.Gateway == nil || f.PFIP == nil {
+			return nil, errors.New("the VTEP IP, gateway and PF IP of every IP family are required")
+		}
+		c := newIPFamilyConfig(f.VTEPIPNet, f.Gateway, nil, nil, f.PFIP, nil)
+		if err := c.validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := byFamily[c.family]; ok {
+			return nil, fmt.Errorf("%s is given more than once", c.family)
+		}
+		byFamily[c.family] = c
+	}
+	if len(byFamily) != len(ipFamilies) {
+		return nil, fmt.Errorf("%d IP families are expected, got %d", len(ipFamilies), len(byFamily))
+	}
+
+	configs := make([]*ipFamilyConfig, 0, len(ipFamilies))
+	for _, shared := range ipFamilies {
+		c, ok := byFamily[shared.family]
+		if !ok {
+			return nil, fmt.Errorf("no %s addressing is given", shared.family)
+		}
+		c.vtepCIDRs = shared.vtepCIDRs
+		c.hostCIDRs = shared.hostCIDRs
+		configs = append(configs, c)
+	}
+	return configs, nil
+}
+
+// SetUplinks sets the uplinks of the DPU in addition to br-ovn and how the geneve tunnels use them. Call before RunOnce
+// or EnsureConfiguration.
+func (p *DPUCNIProvisioner) SetUplinks(uplinks Uplinks) error {
+	if err := validateUplinks(p.mode, uplinks, p.ipFamilies); err != nil {
+		return fmt.Errorf("error while setting uplinks: %w", err)
+	}
+	p.interfacesLock.Lock()
+	defer p.interfacesLock.Unlock()
+	p.uplinkSettings = uplinks
+	return nil
+}
+
+// uplinks returns the uplinks the ongoing run of the provisioning flow configures, br-ovn being the first one
+func (p *DPUCNIProvisioner) uplinks() []*uplinkConfig {
+	uplinks := []*uplinkConfig{{
+		bridge:     brOVN,
+		port:       p.uplinkSettings.PrimaryPort,
+		pfIndex:    p.interfaces.PFIndex,
+		ipFamilies: p.ipFamilies,
+	}}
+	for _, u := range p.uplinkSettings.Additional {
+		// The uplinks are validated against the IP families whenever either of them changes
+		ipFamilies, _ := newUplinkIPFamilies(u, p.ipFamilies)
+		uplinks = append(uplinks, &uplinkConfig{
+			bridge:     u.Bridge,
+			port:       u.Port,
+			pfIndex:    u.PFIndex,
+			ipFamilies: ipFamilies,
+		})
+	}
+	return uplinks
+}
+
+// additionalUplinkPFIndexes returns the indexes of the PFs that are served via the additional uplinks
+func (p *DPUCNIProvisioner) additionalUplinkPFIndexes() []string {
+	pfIndexes := make([]string, 0, len(p.uplinkSettings.Additional))
+	for _, u := range p.uplinkSettings.Additional {
+		pfIndexes = append(pfIndexes, u.PFIndex)
+	}
+	return pfIndexes
+}
+
+// configureAdditionalUplink configures the VTEP of an additional uplink and source routes the traffic of its VTEP IPs
+// via its bridge, so that the geneve traffic leaves through the uplink that owns its encap IP. The routes of br-ovn in
+// the main table are left untouched.
+func (p *DPUCNIProvisioner) configureAdditionalUplink(index int, u *uplinkConfig) error {
+	for _, c := range u.ipFamilies {
+		if err := p.setLinkIPAddressIfNotSet(u.bridge, c.vtepIPNet); err != nil {
+			return fmt.Errorf("error while setting VTEP IP: %w", err)
+		}
+	}
+	if err := p.networkHelper.SetLinkUp(u.bridge); err != nil {
+		return fmt.Errorf("error while setting link %s up: %w", u.bridge, err)
+	}
+
+	table := uplinkRoutingTableBase + index
+	for _, c := range u.ipFamilies {
+		vtepIP := &net.IPNet{IP: c.vtepIPNet.IP, Mask: c.family.HostMask()}
+		if err := p.addRuleIfNotExists(vtepIP, table, uplinkRulePriority); err != nil {
+			return fmt.Errorf("error while adding rule: %w", err)
+		}
+
+		remoteVTEPCIDRs, err := c.remoteVTEPCIDRs()
+		if err != nil {
+			return err
+		}
+		for _, vtepCIDR := range remoteVTEPCIDRs {
+			if err := p.addRouteIfNotExists(vtepCIDR, c.gateway, u.bridge, nil, ptr.To(table)); err != nil {
+				return fmt.Errorf("error while adding route %s %s %s: %w", vtepCIDR, c.gateway.String(), u.bridge, err)
+			}
+		}
+		// Same metric as the one of br-ovn, see configurePodToPodOnDifferentNodeConnectivity
+		for _, hostCIDR := range c.hostCIDRs {
+			if err := p.addRouteIfNotExists(hostCIDR, c.gateway, u.bridge, ptr.To(10000), ptr.To(table)); err != nil {
+				return fmt.Errorf("error while adding route %s %s %s: %w", hostCIDR, c.gateway.String(), u.bridge, err)
+			}
+		}
+	}
+	return nil
+}
+
+// setEncapIPs queues setting the ovn-encap-ip external ID to the VTEP IPs of the primary IP family of the uplinks the
+// encap mode selects. A single uplink is used regardless of its health.
+func (p *DPUCNIProvisioner) setEncapIPs(ovsTxn ovsclient.Transaction, uplinks []*uplinkConfig) {
+	selected := uplinks
+	if len(uplinks) > 1 {
+		selected = p.selectEncapUplinks(uplinks)
+	}
+
+	encapIPs := make([]net.IP, 0, len(selected))
+	bridges := make([]string, 0, len(selected))
+	for _, u := range selected {
+		encapIPs = append(encapIPs, u.primaryIPFamily().vtepIPNet.IP)
+		bridges = append(bridges, u.bridge)
+	}
+	if len(encapIPs) == 1 {
+		ovsTxn.SetOVNEncapIP(encapIPs[0])
+	} else {
+		ovsTxn.SetOVNEncapIPs(encapIPs)
+	}
+
+	if !slices.Equal(p.encapBridges, bridges) {
+		p.logger.Info("Using the VTEP IPs of the uplinks as geneve encap IPs", "uplinks", bridges)
+		p.encapBridges = bridges
+		p.metrics.observeEncapUplinks(uplinks, bridges)
+	}
+}
+
+// selectEncapUplinks returns the uplinks whose VTEP IPs are used as geneve encap IPs according to the encap mode. All
+// the uplinks are considered when none of them is healthy, as there is nothing better to fall back to.
+func (p *DPUCNIProvisioner) selectEncapUplinks(uplinks []*uplinkConfig) []*uplinkConfig {
+	var healthy []*uplinkConfig
+	for _, u := range uplinks {
+		if p.isUplinkHealthy(u) {
+			healthy = append(healthy, u)
+		}
+	}
+	if len(healthy) == 0 {
+		p.logger.Info("None of the uplinks is healthy, considering all of them")
+		healthy = uplinks
+	}
+	if p.uplinkSettings.EncapMode == ECMPEncap {
+		return healthy
+	}
+	return healthy[:1]
+}
+
+// isUplinkHealthy returns whether the port of the uplink is operationally up. Uplinks without a port are always
+// healthy.
+func (p *DPUCNIProvisioner) isUplinkHealthy(u *uplinkConfig) bool {
+	if u.port == "" {
+		return true
+	}
+	up, err := p.networkHelper.LinkOperUp(u.port)
+	if err != nil {
+		p.logger.Info("Considering uplink unhealthy: error while getting the state of its port", "uplink", u.bridge, logging.KeyLink, u.port, "err", err)
+		return false
+	}
+	if !up {
+		p.logger.V(2).Info("Uplink is unhealthy: its port is down", "uplink", u.bridge, logging.KeyLink, u.port)
+	}
+	return up
+}
+
+// uplinkLinks returns the bridges and ports of all the uplinks but br-ovn, which is always managed. Must be called
+// with interfacesLock held.
+func (p *DPUCNIProvisioner) uplinkLinks() []string {
+	var links []string
+	if p.uplinkSettings.PrimaryPort != "" {
+		links = append(links, p.uplinkSettings.PrimaryPort)
+	}
+	for _, u := range p.uplinkSettings.Additional {
+		links = append(links, u.Bridge)
+		if u.Port != "" {
+			links = append(links, u.Port)
+		}
+	}
+	return links
+}
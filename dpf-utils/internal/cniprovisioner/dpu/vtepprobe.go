@@ -0,0 +1,311 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dpucniprovisioner
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/networkhelper"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// NodeConditionVTEPConnectivity is the condition of the DPU Node that reports whether the VTEPs of the other DPUs
+	// that were last probed are reachable with packets of the size of the geneve traffic
+	NodeConditionVTEPConnectivity corev1.NodeConditionType = "OVNDPUVTEPConnectivity"
+	// NodeConditionReasonPeersReachable is the reason of the condition when every probed VTEP is reachable
+	NodeConditionReasonPeersReachable = "PeersReachable"
+	// NodeConditionReasonPeersUnreachable is the reason of the condition when a probed VTEP doesn't answer
+	NodeConditionReasonPeersUnreachable = "PeersUnreachable"
+	// NodeConditionReasonPathMTUExceeded is the reason of the condition when every probed VTEP answers, but not all of
+	// them with packets of the size of the geneve traffic
+	NodeConditionReasonPathMTUExceeded = "PathMTUExceeded"
+	// NodeConditionReasonNoPeers is the reason of the condition when no other DPU publishes its VTEP IP
+	NodeConditionReasonNoPeers = "NoPeers"
+
+	// ovnEncapIPsAnnotationKey is the Node annotation ovnkube publishes the geneve encap IPs of the Node in. It's used
+	// for the DPUs that don't publish NodeAnnotationVTEPIP.
+	ovnEncapIPsAnnotationKey = "k8s.ovn.org/node-encap-ips"
+	// vtepReachabilityProbeSize is the size of the packets that check whether a VTEP is reachable at all
+	vtepReachabilityProbeSize = 64
+)
+
+// VTEPProbe is how the VTEPs of the other DPUs are probed
+type VTEPProbe struct {
+	// Interval is how often a sample of the VTEPs is probed. Probing is disabled when 0.
+	Interval time.Duration
+	// Timeout is how long a VTEP is given to answer a probe
+	Timeout time.Duration
+	// SampleSize is how many VTEPs are probed every interval. The sample rotates over all the VTEPs.
+	SampleSize int
+}
+
+// vtepPeer is the VTEP IP of another DPU
+type vtepPeer struct {
+	node string
+	ip   net.IP
+}
+
+// vtepProbeResult is the result of the probe of a VTEP
+type vtepProbeResult struct {
+	peer      vtepPeer
+	reachable bool
+	// pathMTU is whether the VTEP answers packets of size encapMTU sent with the DF bit set. Always false when the
+	// VTEP isn't reachable.
+	pathMTU bool
+}
+
+// SetVTEPProbe sets how the VTEPs of the other DPUs are probed. Call before ProbeVTEPs.
+func (p *DPUCNIProvisioner) SetVTEPProbe(probe VTEPProbe) error {
+	if probe.Interval < 0 {
+		return fmt.Errorf("invalid VTEP probe interval %s: must not be negative", probe.Interval)
+	}
+	if probe.Interval > 0 {
+		if probe.Timeout <= 0 || probe.Timeout >= probe.Interval {
+			return fmt.Errorf("invalid VTEP probe timeout %s: must be greater than 0 and less than the interval", probe.Timeout)
+		}
+		if probe.SampleSize <= 0 {
+			return fmt.Errorf("invalid VTEP probe sample size %d: must be greater than 0", probe.SampleSize)
+		}
+	}
+	p.vtepProbe = probe
+	return nil
+}
+
+// ProbeVTEPs probes a sample of the VTEPs of the other DPUs every interval until the context of the provisioner is
+// done. This is a blocking function that returns right away when probing is disabled.
+func (p *DPUCNIProvisioner) ProbeVTEPs() {
+	if p.vtepProbe.Interval == 0 {
+		return
+	}
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-p.clock.After(p.vtepProbe.Interval):
+			if err := p.ProbeVTEPsOnce(); err != nil {
+				p.baseLogger.Error(err, "failed to probe the VTEPs of the other DPUs")
+			}
+		}
+	}
+}
+
+// ProbeVTEPsOnce probes the next sample of the VTEPs of the other DPUs and publishes the results as metrics and as the
+// NodeConditionVTEPConnectivity condition of the DPU Node. Every VTEP is first probed with a small packet to check that
+// it's reachable and then with a packet of the size of the geneve traffic, i.e. the MTU for OVN plus the geneve
+// overhead, with the DF bit set to check that the path carries it without fragmentation.
+func (p *DPUCNIProvisioner) ProbeVTEPsOnce() error {
+	state := p.LastAppliedState()
+	if state == nil {
+		p.baseLogger.V(2).Info("Skipping the VTEP probe, no state is applied yet")
+		return nil
+	}
+	var families []networkhelper.Family
+	for _, f := range state.IPFamilies {
+		ip, _, err := net.ParseCIDR(f.VTEPIP)
+		if err != nil {
+			return fmt.Errorf("error while parsing the applied VTEP IP %s: %w", f.VTEPIP, err)
+		}
+		families = append(families, networkhelper.FamilyOf(ip))
+	}
+
+	peers, err := p.discoverVTEPPeers(families)
+	if err != nil {
+		return err
+	}
+	sample := p.sampleVTEPPeers(peers)
+
+	encapMTU := 0
+	if state.OVNMTU > 0 {
+		encapMTU = state.OVNMTU + geneveHeaderSize
+	}
+	results := make([]vtepProbeResult, 0, len(sample))
+	for _, peer := range sample {
+		result, err := p.probeVTEP(peer, encapMTU)
+		if err != nil {
+			return err
+		}
+		results = append(results, result)
+	}
+	p.metrics.observeVTEPProbe(results, encapMTU > 0)
+	p.publishVTEPConnectivity(vtepConnectivityCondition(results, encapMTU))
+	return nil
+}
+
+// discoverVTEPPeers returns the VTEP IPs of the given families that the other DPU Nodes publish, sorted by Node and IP
+func (p *DPUCNIProvisioner) discoverVTEPPeers(families []networkhelper.Family) ([]vtepPeer, error) {
+	nodes, err := p.dpuClusterKubernetesClient.CoreV1().Nodes().List(p.ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while listing the DPU Nodes: %w", err)
+	}
+	var peers []vtepPeer
+	for _, node := range nodes.Items {
+		if node.Name == p.dpuHostName {
+			continue
+		}
+		ips, err := nodeVTEPIPs(&node)
+		if err != nil {
+			p.baseLogger.V(2).Info("Ignoring the VTEP IPs of Node", "node", node.Name, "err", err)
+			continue
+		}
+		for _, ip := range ips {
+			if slices.Contains(families, networkhelper.FamilyOf(ip)) {
+				peers = append(peers, vtepPeer{node: node.Name, ip: ip})
+			}
+		}
+	}
+	slices.SortFunc(peers, func(a, b vtepPeer) int {
+		if c := strings.Compare(a.node, b.node); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ip.String(), b.ip.String())
+	})
+	return peers, nil
+}
+
+// nodeVTEPIPs returns the VTEP IPs a Node publishes, the ones the provisioner of the DPU published or else the geneve
+// encap IPs published by ovnkube
+func nodeVTEPIPs(node *corev1.Node) ([]net.IP, error) {
+	var ips []net.IP
+	if value := node.Annotations[NodeAnnotationVTEPIP]; value != "" {
+		for _, cidr := range strings.Split(value, ",") {
+			ip, _, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("error while parsing annotation %s: %w", NodeAnnotationVTEPIP, err)
+			}
+			ips = append(ips, ip)
+		}
+		return ips, nil
+	}
+	if value := node.Annotations[ovnEncapIPsAnnotationKey]; value != "" {
+		var encapIPs []string
+		if err := json.Unmarshal([]byte(value), &encapIPs); err != nil {
+			return nil, fmt.Errorf("error while parsing annotation %s: %w", ovnEncapIPsAnnotationKey, err)
+		}
+		for _, encapIP := range encapIPs {
+			ip := net.ParseIP(encapIP)
+			if ip == nil {
+				return nil, fmt.Errorf("error while parsing annotation %s: invalid IP %q", ovnEncapIPsAnnotationKey, encapIP)
+			}
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
+
+// sampleVTEPPeers returns the next sample of the given VTEPs. Consecutive samples rotate over the VTEPs so that all of
+// them get probed over time.
+func (p *DPUCNIProvisioner) sampleVTEPPeers(peers []vtepPeer) []vtepPeer {
+	if len(peers) <= p.vtepProbe.SampleSize {
+		return peers
+	}
+	sample := make([]vtepPeer, 0, p.vtepProbe.SampleSize)
+	for i := range p.vtepProbe.SampleSize {
+		sample = append(sample, peers[(p.vtepProbeOffset+i)%len(peers)])
+	}
+	p.vtepProbeOffset = (p.vtepProbeOffset + p.vtepProbe.SampleSize) % len(peers)
+	return sample
+}
+
+// probeVTEP probes a VTEP. The path MTU isn't probed when encapMTU is 0.
+func (p *DPUCNIProvisioner) probeVTEP(peer vtepPeer, encapMTU int) (vtepProbeResult, error) {
+	result := vtepProbeResult{peer: peer}
+	reachable, err := p.networkHelper.Ping(peer.ip, vtepReachabilityProbeSize, p.vtepProbe.Timeout)
+	if err != nil {
+		return result, fmt.Errorf("error while probing the VTEP %s of Node %s: %w", peer.ip, peer.node, err)
+	}
+	result.reachable = reachable
+	if reachable && encapMTU > 0 {
+		result.pathMTU, err = p.networkHelper.Ping(peer.ip, encapMTU, p.vtepProbe.Timeout)
+		if err != nil {
+			return result, fmt.Errorf("error while probing the path MTU to the VTEP %s of Node %s: %w", peer.ip, peer.node, err)
+		}
+	}
+	p.baseLogger.V(2).Info("Probed VTEP", "node", peer.node, "ip", peer.ip, "reachable", result.reachable, "pathMTU", result.pathMTU)
+	return result, nil
+}
+
+// vtepConnectivityCondition returns the NodeConditionVTEPConnectivity condition that corresponds to the given results.
+// The status is unknown when no VTEP was probed.
+func vtepConnectivityCondition(results []vtepProbeResult, encapMTU int) corev1.NodeCondition {
+	condition := corev1.NodeCondition{Type: NodeConditionVTEPConnectivity}
+	if len(results) == 0 {
+		condition.Status = corev1.ConditionUnknown
+		condition.Reason = NodeConditionReasonNoPeers
+		condition.Message = "No other DPU publishes its VTEP IP"
+		return condition
+	}
+
+	var unreachable, pathMTUExceeded []string
+	for _, r := range results {
+		peer := fmt.Sprintf("%s (%s)", r.peer.node, r.peer.ip)
+		switch {
+		case !r.reachable:
+			unreachable = append(unreachable, peer)
+		case encapMTU > 0 && !r.pathMTU:
+			pathMTUExceeded = append(pathMTUExceeded, peer)
+		}
+	}
+	var messages []string
+	if len(unreachable) > 0 {
+		messages = append(messages, "unreachable: "+strings.Join(unreachable, ", "))
+	}
+	if len(pathMTUExceeded) > 0 {
+		messages = append(messages, fmt.Sprintf("not reachable with %d byte packets: %s", encapMTU, strings.Join(pathMTUExceeded, ", ")))
+	}
+
+	switch {
+	case len(unreachable) > 0:
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = NodeConditionReasonPeersUnreachable
+	case len(pathMTUExceeded) > 0:
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = NodeConditionReasonPathMTUExceeded
+	default:
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = NodeConditionReasonPeersReachable
+		messages = append(messages, fmt.Sprintf("%d probed VTEPs are reachable", len(results)))
+	}
+	condition.Message = strings.Join(messages, "; ")
+	return condition
+}
+
+// publishVTEPConnectivity patches the given condition on the DPU Node when it changed or when it was last published
+// more than nodeStatusHeartbeatInterval ago. Failing to publish is logged.
+func (p *DPUCNIProvisioner) publishVTEPConnectivity(condition corev1.NodeCondition) {
+	now := p.clock.Now()
+	if reflect.DeepEqual(p.publishedVTEPConnectivity, &condition) && now.Sub(p.vtepConnectivityPublishedAt) < nodeStatusHeartbeatInterval {
+		return
+	}
+	if p.publishedVTEPConnectivity == nil || p.publishedVTEPConnectivity.Reason != condition.Reason {
+		p.baseLogger.Info("VTEP connectivity changed", "status", condition.Status, "reason", condition.Reason, "message", condition.Message)
+	}
+	if err := p.patchNodeCondition(condition, now); err != nil {
+		p.baseLogger.Error(err, "error while publishing the VTEP connectivity on the DPU Node")
+		return
+	}
+	p.publishedVTEPConnectivity = &condition
+	p.vtepConnectivityPublishedAt = now
+}
@@ -0,0 +1,265 @@
+/*
+Copyright 2024 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dhcpserver
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+)
+
+// This file implements the subset of the DHCPv4 wire format (RFC 2131, RFC 2132 and RFC 3442) the server needs.
+
+const (
+	// serverPort is the UDP port DHCP servers listen on
+	serverPort = 67
+	// clientPort is the UDP port DHCP clients listen on
+	clientPort = 68
+
+	opRequest = 1
+	opReply   = 2
+
+	hardwareTypeEthernet = 1
+
+	// flagBroadcast is the flag a client sets when it can't receive unicast replies before it's configured
+	flagBroadcast = 0x8000
+
+	// fixedHeaderLength is the length of the message up to and including the magic cookie
+	fixedHeaderLength = 240
+	// minMessageLength is the minimum length of a BOOTP message. Shorter replies are padded for old clients.
+	minMessageLength = 300
+)
+
+// magicCookie marks the beginning of the options
+var magicCookie = [4]byte{99, 130, 83, 99}
+
+// messageType is the value of the DHCP Message Type option
+type messageType byte
+
+const (
+	messageTypeDiscover messageType = 1
+	messageTypeOffer    messageType = 2
+	messageTypeRequest  messageType = 3
+	messageTypeDecline  messageType = 4
+	messageTypeAck      messageType = 5
+	messageTypeNak      messageType = 6
+	messageTypeRelease  messageType = 7
+	messageTypeInform   messageType = 8
+)
+
+// String returns the string representation of the message type
+func (t messageType) String() string {
+	switch t {
+	case messageTypeDiscover:
+		return "DHCPDISCOVER"
+	case messageTypeOffer:
+		return "DHCPOFFER"
+	case messageTypeRequest:
+		return "DHCPREQUEST"
+	case messageTypeDecline:
+		return "DHCPDECLINE"
+	case messageTypeAck:
+		return "DHCPACK"
+	case messageTypeNak:
+		return "DHCPNAK"
+	case messageTypeRelease:
+		return "DHCPRELEASE"
+	case messageTypeInform:
+		return "DHCPINFORM"
+	default:
+		return fmt.Sprintf("DHCP(%d)", byte(t))
+	}
+}
+
+// optionCode is the code of a DHCP option
+type optionCode byte
+
+const (
+	optionPad                  optionCode = 0
+	optionSubnetMask           optionCode = 1
+	optionRouter               optionCode = 3
+	optionInterfaceMTU         optionCode = 26
+	optionRequestedIPAddress   optionCode = 50
+	optionIPAddressLeaseTime   optionCode = 51
+	optionMessageType          optionCode = 53
+	optionServerIdentifier     optionCode = 54
+	optionMessage              optionCode = 56
+	optionRenewalTimeValue     optionCode = 58
+	optionRebindingTimeValue   optionCode = 59
+	optionClasslessStaticRoute optionCode = 121
+	optionEnd                  optionCode = 255
+)
+
+// message is a DHCPv4 message
+type message struct {
+	op                    byte
+	hardwareType          byte
+	hardwareAddressLength byte
+	hops                  byte
+	transactionID         uint32
+	secs                  uint16
+	flags                 uint16
+	clientIP              net.IP
+	yourIP                net.IP
+	serverIP              net.IP
+	gatewayIP             net.IP
+	clientHardwareAddress net.HardwareAddr
+	options               map[optionCode][]byte
+}
+
+// parseMessage parses a DHCPv4 message from its wire format
+func parseMessage(b []byte) (*message, error) {
+	if len(b) < fixedHeaderLength {
+		return nil, fmt.Errorf("message is %d bytes long, expected at least %d", len(b), fixedHeaderLength)
+	}
+	if [4]byte(b[236:240]) != magicCookie {
+		return nil, errors.New("message doesn't contain the DHCP magic cookie")
+	}
+
+	m := &message{
+		op:                    b[0],
+		hardwareType:          b[1],
+		hardwareAddressLength: b[2],
+		hops:                  b[3],
+		transactionID:         binary.BigEndian.Uint32(b[4:8]),
+		secs:                  binary.BigEndian.Uint16(b[8:10]),
+		flags:                 binary.BigEndian.Uint16(b[10:12]),
+		clientIP:              net.IP(slices.Clone(b[12:16])),
+		yourIP:                net.IP(slices.Clone(b[16:20])),
+		serverIP:              net.IP(slices.Clone(b[20:24])),
+		gatewayIP:             net.IP(slices.Clone(b[24:28])),
+		options:               map[optionCode][]byte{},
+	}
+	if m.hardwareAddressLength > 16 {
+		return nil, fmt.Errorf("invalid hardware address length %d", m.hardwareAddressLength)
+	}
+	m.clientHardwareAddress = net.HardwareAddr(slices.Clone(b[28 : 28+int(m.hardwareAddressLength)]))
+
+	options := b[fixedHeaderLength:]
+	for len(options) > 0 {
+		code := optionCode(options[0])
+		if code == optionEnd {
+			break
+		}
+		if code == optionPad {
+			options = options[1:]
+			continue
+		}
+		if len(options) < 2 || len(options) < 2+int(options[1]) {
+			return nil, fmt.Errorf("option %d is truncated", code)
+		}
+		length := int(options[1])
+		// Options that appear multiple times are concatenated as per RFC 3396
+		m.options[code] = append(m.options[code], options[2:2+length]...)
+		options = options[2+length:]
+	}
+
+	return m, nil
+}
+
+// marshal returns the wire format of the message. The message type option is written first and the rest of the
+// options follow in ascending order of their code.
+func (m *message) marshal() ([]byte, error) {
+	b := make([]byte, fixedHeaderLength, minMessageLength)
+	b[0] = m.op
+	b[1] = m.hardwareType
+	b[2] = m.hardwareAddressLength
+	b[3] = m.hops
+	binary.BigEndian.PutUint32(b[4:8], m.transactionID)
+	binary.BigEndian.PutUint16(b[8:10], m.secs)
+	binary.BigEndian.PutUint16(b[10:12], m.flags)
+	copy(b[12:16], m.clientIP.To4())
+	copy(b[16:20], m.yourIP.To4())
+	copy(b[20:24], m.serverIP.To4())
+	copy(b[24:28], m.gatewayIP.To4())
+	copy(b[28:44], m.clientHardwareAddress)
+	copy(b[236:240], magicCookie[:])
+
+	codes := make([]optionCode, 0, len(m.options))
+	for code := range m.options {
+		codes = append(codes, code)
+	}
+	slices.SortFunc(codes, func(a, b optionCode) int {
+		switch {
+		case a == b:
+			return 0
+		case a == optionMessageType:
+			return -1
+		case b == optionMessageType:
+			return 1
+		default:
+			return int(a) - int(b)
+		}
+	})
+	for _, code := range codes {
+		value := m.options[code]
+		if len(value) > 255 {
+			return nil, fmt.Errorf("option %d is %d bytes long, expected at most 255", code, len(value))
+		}
+		b = append(b, byte(code), byte(len(value)))
+		b = append(b, value...)
+	}
+	b = append(b, byte(optionEnd))
+
+	for len(b) < minMessageLength {
+		b = append(b, byte(optionPad))
+	}
+	return b, nil
+}
+
+// messageType returns the type of the message or 0 if the message has no valid type
+func (m *message) messageType() messageType {
+	value := m.options[optionMessageType]
+	if len(value) != 1 {
+		return 0
+	}
+	return messageType(value[0])
+}
+
+// ipOption returns the value of an option that contains a single IPv4 address or nil if the option is not set
+func (m *message) ipOption(code optionCode) net.IP {
+	value := m.options[code]
+	if len(value) != net.IPv4len {
+		return nil
+	}
+	return net.IP(value)
+}
+
+// uint32Option encodes a 32 bit integer option value
+func uint32Option(v uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, v)
+}
+
+// uint16Option encodes a 16 bit integer option value
+func uint16Option(v uint16) []byte {
+	return binary.BigEndian.AppendUint16(nil, v)
+}
+
+// classlessStaticRouteOption encodes routes as described in RFC 3442. Only the significant octets of each destination
+// are written.
+func classlessStaticRouteOption(routes []Route) []byte {
+	var b []byte
+	for _, route := range routes {
+		ones, _ := route.Destination.Mask.Size()
+		b = append(b, byte(ones))
+		b = append(b, route.Destination.IP.To4()[:(ones+7)/8]...)
+		b = append(b, route.Gateway.To4()...)
+	}
+	return b
+}
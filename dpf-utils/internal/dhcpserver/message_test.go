@@ -0,0 +1,104 @@
+/*
+Copyright 2024 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dhcpserver
+
+import (
+	"net"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	g := NewWithT(t)
+	mac, err := net.ParseMAC("00:00:00:00:00:01")
+	g.Expect(err).ToNot(HaveOccurred())
+	m := &message{
+		op:                    opReply,
+		hardwareType:          hardwareTypeEthernet,
+		hardwareAddressLength: 6,
+		transactionID:         0xdeadbeef,
+		flags:                 flagBroadcast,
+		clientIP:              net.IPv4zero.To4(),
+		yourIP:                net.ParseIP("192.168.1.2").To4(),
+		serverIP:              net.IPv4zero.To4(),
+		gatewayIP:             net.IPv4zero.To4(),
+		clientHardwareAddress: mac,
+		options: map[optionCode][]byte{
+			optionSubnetMask:  {255, 255, 255, 0},
+			optionMessageType: {byte(messageTypeAck)},
+		},
+	}
+
+	b, err := m.marshal()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(b).To(HaveLen(minMessageLength))
+	// The message type is the first option
+	g.Expect(b[fixedHeaderLength : fixedHeaderLength+3]).To(Equal([]byte{byte(optionMessageType), 1, byte(messageTypeAck)}))
+
+	parsed, err := parseMessage(b)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(parsed).To(Equal(m))
+	g.Expect(parsed.messageType()).To(Equal(messageTypeAck))
+}
+
+func TestParseMessage(t *testing.T) {
+	valid, err := (&message{options: map[optionCode][]byte{optionMessageType: {byte(messageTypeDiscover)}}}).marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	noCookie := append([]byte{}, valid...)
+	noCookie[236] = 0
+	truncated := append(append([]byte{}, valid[:fixedHeaderLength]...), byte(optionRequestedIPAddress), 4, 192, 168)
+
+	tests := []struct {
+		name        string
+		input       []byte
+		expectedErr string
+	}{
+		{name: "too short", input: valid[:100], expectedErr: "expected at least 240"},
+		{name: "no magic cookie", input: noCookie, expectedErr: "magic cookie"},
+		{name: "truncated option", input: truncated, expectedErr: "option 50 is truncated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			_, err := parseMessage(tt.input)
+			g.Expect(err).To(MatchError(ContainSubstring(tt.expectedErr)))
+		})
+	}
+}
+
+func TestClasslessStaticRouteOption(t *testing.T) {
+	g := NewWithT(t)
+	_, tenNet, err := net.ParseCIDR("10.0.0.0/8")
+	g.Expect(err).ToNot(HaveOccurred())
+	_, vtepCIDR, err := net.ParseCIDR("192.168.0.0/23")
+	g.Expect(err).ToNot(HaveOccurred())
+	_, defaultRoute, err := net.ParseCIDR("0.0.0.0/0")
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(classlessStaticRouteOption([]Route{
+		{Destination: tenNet, Gateway: net.ParseIP("10.1.1.1")},
+		{Destination: vtepCIDR, Gateway: net.ParseIP("192.168.1.10")},
+		{Destination: defaultRoute, Gateway: net.ParseIP("192.168.1.254")},
+	})).To(Equal([]byte{
+		8, 10, 10, 1, 1, 1,
+		23, 192, 168, 0, 192, 168, 1, 10,
+		0, 192, 168, 1, 254,
+	}))
+}
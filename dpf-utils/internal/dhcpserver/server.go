@@ -0,0 +1,331 @@
+/*
+Copyright 2024 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dhcpserver implements a minimal DHCPv4 server that hands out static MAC to IP bindings together with the
+// handful of options the DPU needs to configure the PF on the host.
+package dhcpserver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/logging"
+
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+// defaultLeaseDuration is the lease duration used when the Config doesn't specify one. It matches the dnsmasq default.
+const defaultLeaseDuration = time.Hour
+
+// Route is a classless static route sent to the clients via option 121
+type Route struct {
+	// Destination is the network the route is for
+	Destination *net.IPNet
+	// Gateway is the next hop of the route
+	Gateway net.IP
+}
+
+// Binding is a static MAC to IP binding
+type Binding struct {
+	// MAC is the hardware address of the client
+	MAC net.HardwareAddr
+	// IP is the address the client gets
+	IP net.IP
+}
+
+// Config is the configuration of the Server
+type Config struct {
+	// ServerIP is the address of the server on the interface it serves. It's sent as the server identifier.
+	ServerIP net.IP
+	// SubnetMask is the subnet mask sent to the clients
+	SubnetMask net.IPMask
+	// Router is the default gateway sent to the clients. No router is sent when nil.
+	Router net.IP
+	// MTU is the interface MTU sent to the clients. No MTU is sent when 0.
+	MTU int
+	// Routes are the classless static routes sent to the clients
+	Routes []Route
+	// Bindings are the clients the server answers to. Requests from any other client are ignored.
+	Bindings []Binding
+	// LeaseDuration is the duration of the leases. Defaults to one hour.
+	LeaseDuration time.Duration
+}
+
+// validate returns an error if the Config can't be served
+func (c *Config) validate() error {
+	if c.ServerIP.To4() == nil {
+		return fmt.Errorf("server IP %v is not an IPv4 address", c.ServerIP)
+	}
+	if ones, bits := c.SubnetMask.Size(); bits != 8*net.IPv4len || ones == 0 {
+		return fmt.Errorf("subnet mask %v is not a valid IPv4 mask", c.SubnetMask)
+	}
+	if c.Router != nil && c.Router.To4() == nil {
+		return fmt.Errorf("router %v is not an IPv4 address", c.Router)
+	}
+	if c.MTU < 0 || c.MTU > 0xffff {
+		return fmt.Errorf("invalid MTU %d", c.MTU)
+	}
+	for _, route := range c.Routes {
+		if route.Destination == nil || route.Destination.IP.To4() == nil || route.Gateway.To4() == nil {
+			return fmt.Errorf("route %v via %v is not an IPv4 route", route.Destination, route.Gateway)
+		}
+	}
+	for _, binding := range c.Bindings {
+		if len(binding.MAC) != 6 || binding.IP.To4() == nil {
+			return fmt.Errorf("binding %v to %v is not an Ethernet to IPv4 binding", binding.MAC, binding.IP)
+		}
+	}
+	if c.LeaseDuration < 0 {
+		return fmt.Errorf("invalid lease duration %s", c.LeaseDuration)
+	}
+	return nil
+}
+
+// Lease is an address leased to a client
+type Lease struct {
+	// MAC is the hardware address of the client
+	MAC net.HardwareAddr
+	// IP is the leased address
+	IP net.IP
+	// MTU is the interface MTU the client was acknowledged with, 0 when none was sent
+	MTU int
+	// Expiry is when the lease expires
+	Expiry time.Time
+}
+
+// Server is a DHCPv4 server that serves static bindings
+type Server struct {
+	config Config
+	clock  clock.PassiveClock
+
+	lock   sync.Mutex
+	leases map[string]Lease
+}
+
+// New creates a Server for the given Config
+func New(config Config, clock clock.PassiveClock) (*Server, error) {
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid DHCP server configuration: %w", err)
+	}
+	if config.LeaseDuration == 0 {
+		config.LeaseDuration = defaultLeaseDuration
+	}
+	return &Server{
+		config: config,
+		clock:  clock,
+		leases: map[string]Lease{},
+	}, nil
+}
+
+// Leases returns the leases that haven't expired, sorted by IP
+func (s *Server) Leases() []Lease {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.clock.Now()
+	leases := make([]Lease, 0, len(s.leases))
+	for mac, lease := range s.leases {
+		if !lease.Expiry.After(now) {
+			delete(s.leases, mac)
+			continue
+		}
+		leases = append(leases, lease)
+	}
+	slices.SortFunc(leases, func(a, b Lease) int {
+		return bytes.Compare(a.IP.To4(), b.IP.To4())
+	})
+	return leases
+}
+
+// Serve answers the requests received on the given connection until the context is cancelled. The connection is
+// closed when Serve returns. Returns nil when the context is cancelled.
+func (s *Server) Serve(ctx context.Context, conn net.PacketConn) error {
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer func() {
+		if stop() {
+			conn.Close()
+		}
+	}()
+
+	logger := klog.FromContext(ctx)
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("error while reading DHCP request: %w", err)
+		}
+
+		req, err := parseMessage(buf[:n])
+		if err != nil {
+			logger.V(2).Info("Ignoring invalid DHCP message", "address", addr.String(), "err", err)
+			continue
+		}
+		reply := s.handle(logger, req)
+		if reply == nil {
+			continue
+		}
+		if err := s.send(conn, reply); err != nil {
+			logger.Error(err, "Error while sending DHCP reply", "messageType", reply.messageType().String(), logging.KeyMAC, reply.clientHardwareAddress.String())
+		}
+	}
+}
+
+// send sends the reply to the address RFC 2131 section 4.1 prescribes. Replies to clients that don't have an address
+// yet are broadcast since unicasting them would require injecting an ARP entry.
+func (s *Server) send(conn net.PacketConn, reply *message) error {
+	b, err := reply.marshal()
+	if err != nil {
+		return err
+	}
+
+	addr := &net.UDPAddr{IP: net.IPv4bcast, Port: clientPort}
+	switch {
+	case !reply.gatewayIP.Equal(net.IPv4zero):
+		addr = &net.UDPAddr{IP: reply.gatewayIP, Port: serverPort}
+	case reply.messageType() != messageTypeNak && !reply.clientIP.Equal(net.IPv4zero):
+		addr = &net.UDPAddr{IP: reply.clientIP, Port: clientPort}
+	}
+
+	_, err = conn.WriteTo(b, addr)
+	return err
+}
+
+// handle returns the reply to the given request or nil if the request must not be answered
+func (s *Server) handle(logger klog.Logger, req *message) *message {
+	if req.op != opRequest || req.hardwareType != hardwareTypeEthernet || len(req.clientHardwareAddress) != 6 {
+		return nil
+	}
+
+	mac := req.clientHardwareAddress
+	ip := s.bindingFor(mac)
+	if ip == nil {
+		logger.V(2).Info("Ignoring DHCP message from a client that has no binding", "messageType", req.messageType().String(), logging.KeyMAC, mac.String())
+		return nil
+	}
+
+	switch req.messageType() {
+	case messageTypeDiscover:
+		logger.Info("Offering the bound address on DHCPDISCOVER", logging.KeyMAC, mac.String(), logging.KeyIP, ip.String())
+		return s.reply(req, messageTypeOffer, ip)
+	case messageTypeRequest:
+		if serverID := req.ipOption(optionServerIdentifier); serverID != nil && !serverID.Equal(s.config.ServerIP) {
+			// The client selected another server
+			return nil
+		}
+		requested := req.ipOption(optionRequestedIPAddress)
+		if requested == nil {
+			requested = req.clientIP
+		}
+		if !requested.Equal(ip) {
+			logger.Info("Refusing DHCPREQUEST for an address that isn't bound to the client", logging.KeyMAC, mac.String(), "requested", requested.String(), logging.KeyIP, ip.String())
+			return s.nak(req, fmt.Sprintf("address %s is not bound to %s", requested, mac))
+		}
+		s.lock.Lock()
+		s.leases[mac.String()] = Lease{MAC: mac, IP: ip, MTU: s.config.MTU, Expiry: s.clock.Now().Add(s.config.LeaseDuration)}
+		s.lock.Unlock()
+		logger.Info("Leased the bound address on DHCPREQUEST", logging.KeyMAC, mac.String(), logging.KeyIP, ip.String())
+		return s.reply(req, messageTypeAck, ip)
+	case messageTypeInform:
+		return s.reply(req, messageTypeAck, nil)
+	case messageTypeDecline:
+		logger.Info("Releasing the lease on DHCPDECLINE, the address may be in use by another host", logging.KeyMAC, mac.String(), logging.KeyIP, ip.String())
+		s.releaseLease(mac)
+	case messageTypeRelease:
+		logger.Info("Releasing the lease on DHCPRELEASE", logging.KeyMAC, mac.String(), logging.KeyIP, ip.String())
+		s.releaseLease(mac)
+	}
+	return nil
+}
+
+// bindingFor returns the IP bound to the given MAC or nil if there is no binding
+func (s *Server) bindingFor(mac net.HardwareAddr) net.IP {
+	for _, binding := range s.config.Bindings {
+		if bytes.Equal(binding.MAC, mac) {
+			return binding.IP.To4()
+		}
+	}
+	return nil
+}
+
+// releaseLease removes the lease of the given client
+func (s *Server) releaseLease(mac net.HardwareAddr) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.leases, mac.String())
+}
+
+// newReply returns a reply of the given type to the given request with the fields common to all the replies set
+func (s *Server) newReply(req *message, t messageType) *message {
+	return &message{
+		op:                    opReply,
+		hardwareType:          req.hardwareType,
+		hardwareAddressLength: req.hardwareAddressLength,
+		transactionID:         req.transactionID,
+		flags:                 req.flags,
+		clientIP:              net.IPv4zero,
+		yourIP:                net.IPv4zero,
+		serverIP:              net.IPv4zero,
+		gatewayIP:             req.gatewayIP,
+		clientHardwareAddress: req.clientHardwareAddress,
+		options: map[optionCode][]byte{
+			optionMessageType:      {byte(t)},
+			optionServerIdentifier: s.config.ServerIP.To4(),
+		},
+	}
+}
+
+// reply returns a reply of the given type that carries the configuration. No address is offered when ip is nil.
+func (s *Server) reply(req *message, t messageType, ip net.IP) *message {
+	reply := s.newReply(req, t)
+	reply.clientIP = req.clientIP
+	if ip != nil {
+		leaseSeconds := uint32(s.config.LeaseDuration / time.Second)
+		reply.yourIP = ip
+		reply.options[optionIPAddressLeaseTime] = uint32Option(leaseSeconds)
+		reply.options[optionRenewalTimeValue] = uint32Option(leaseSeconds / 2)
+		reply.options[optionRebindingTimeValue] = uint32Option(leaseSeconds / 8 * 7)
+	}
+	reply.options[optionSubnetMask] = []byte(s.config.SubnetMask)
+	if s.config.Router != nil {
+		reply.options[optionRouter] = s.config.Router.To4()
+	}
+	if s.config.MTU > 0 {
+		reply.options[optionInterfaceMTU] = uint16Option(uint16(s.config.MTU))
+	}
+	if len(s.config.Routes) > 0 {
+		reply.options[optionClasslessStaticRoute] = classlessStaticRouteOption(s.config.Routes)
+	}
+	return reply
+}
+
+// nak returns a DHCPNAK to the given request
+func (s *Server) nak(req *message, reason string) *message {
+	reply := s.newReply(req, messageTypeNak)
+	reply.flags |= flagBroadcast
+	reply.options[optionMessage] = []byte(reason)
+	return reply
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2024 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dhcpserver
+
+import (
+	"context"
+	"net"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"k8s.io/utils/clock"
+)
+
+const (
+	testServerLink = "dhcp-server"
+	testClientLink = "dhcp-client"
+)
+
+// setupVethPair creates a veth pair whose ends live in two new network namespaces. The server end gets the given
+// address, the client end has no address like the PF of a host that waits for its lease. Returns the sockets of the
+// server and the client.
+func setupVethPair(t *testing.T, serverIP *net.IPNet, clientMAC net.HardwareAddr) (net.PacketConn, net.PacketConn) {
+	if os.Geteuid() != 0 {
+		t.Skip("creating network namespaces requires root")
+	}
+	g := NewWithT(t)
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	origin, err := netns.Get()
+	g.Expect(err).ToNot(HaveOccurred())
+	defer func() {
+		g.Expect(netns.Set(origin)).To(Succeed())
+		origin.Close()
+	}()
+
+	clientNS, err := netns.New()
+	if err != nil {
+		t.Skipf("creating network namespaces is not permitted: %s", err.Error())
+	}
+	t.Cleanup(func() { clientNS.Close() })
+	serverNS, err := netns.New()
+	g.Expect(err).ToNot(HaveOccurred())
+	t.Cleanup(func() { serverNS.Close() })
+
+	// Now in the server namespace
+	veth := &netlink.Veth{
+		LinkAttrs:        netlink.LinkAttrs{Name: testServerLink},
+		PeerName:         testClientLink,
+		PeerHardwareAddr: clientMAC,
+		PeerNamespace:    netlink.NsFd(clientNS),
+	}
+	g.Expect(netlink.LinkAdd(veth)).To(Succeed())
+	serverLink, err := netlink.LinkByName(testServerLink)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(netlink.AddrAdd(serverLink, &netlink.Addr{IPNet: serverIP})).To(Succeed())
+	g.Expect(netlink.LinkSetUp(serverLink)).To(Succeed())
+	serverConn, err := Listen(testServerLink)
+	g.Expect(err).ToNot(HaveOccurred())
+	t.Cleanup(func() { serverConn.Close() })
+
+	g.Expect(netns.Set(clientNS)).To(Succeed())
+	clientLink, err := netlink.LinkByName(testClientLink)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(netlink.LinkSetUp(clientLink)).To(Succeed())
+	clientConn, err := listen(testClientLink, clientPort)
+	g.Expect(err).ToNot(HaveOccurred())
+	t.Cleanup(func() { clientConn.Close() })
+
+	return serverConn, clientConn
+}
+
+// exchange broadcasts the request from the client and returns the reply it receives
+func exchange(g Gomega, conn net.PacketConn, req *message) *message {
+	b, err := req.marshal()
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = conn.WriteTo(b, &net.UDPAddr{IP: net.IPv4bcast, Port: serverPort})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(conn.SetReadDeadline(time.Now().Add(5 * time.Second))).To(Succeed())
+	buf := make([]byte, 1500)
+	n, _, err := conn.ReadFrom(buf)
+	g.Expect(err).ToNot(HaveOccurred())
+	reply, err := parseMessage(buf[:n])
+	g.Expect(err).ToNot(HaveOccurred())
+	return reply
+}
+
+func TestServeOverVethPair(t *testing.T) {
+	g := NewWithT(t)
+	config, mac := newTestConfig(t)
+	serverConn, clientConn := setupVethPair(t, &net.IPNet{IP: config.ServerIP, Mask: config.SubnetMask}, mac)
+
+	s, err := New(config, clock.RealClock{})
+	g.Expect(err).ToNot(HaveOccurred())
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(ctx, serverConn)
+	}()
+
+	offer := exchange(g, clientConn, newTestRequest(mac, messageTypeDiscover, nil))
+	g.Expect(offer.messageType()).To(Equal(messageTypeOffer))
+	g.Expect(offer.yourIP.Equal(net.ParseIP("192.168.1.2"))).To(BeTrue())
+	g.Expect(offer.options[optionInterfaceMTU]).To(Equal([]byte{0x06, 0x18}))
+
+	ack := exchange(g, clientConn, newTestRequest(mac, messageTypeRequest, map[optionCode][]byte{
+		optionRequestedIPAddress: offer.yourIP.To4(),
+		optionServerIdentifier:   offer.ipOption(optionServerIdentifier),
+	}))
+	g.Expect(ack.messageType()).To(Equal(messageTypeAck))
+	g.Expect(ack.options[optionClasslessStaticRoute]).To(Equal([]byte{23, 192, 168, 0, 192, 168, 1, 10}))
+	g.Expect(s.Leases()).To(HaveLen(1))
+
+	cancel()
+	g.Eventually(served).Should(Receive(BeNil()))
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2024 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dhcpserver
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/klog/v2"
+	clock "k8s.io/utils/clock/testing"
+)
+
+// newTestConfig returns a Config that binds the returned MAC to 192.168.1.2
+func newTestConfig(t *testing.T) (Config, net.HardwareAddr) {
+	mac, err := net.ParseMAC("00:00:00:00:00:01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, vtepCIDR, err := net.ParseCIDR("192.168.0.0/23")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Config{
+		ServerIP:   net.ParseIP("192.168.1.1"),
+		SubnetMask: net.CIDRMask(24, 32),
+		MTU:        1560,
+		Routes:     []Route{{Destination: vtepCIDR, Gateway: net.ParseIP("192.168.1.10")}},
+		Bindings:   []Binding{{MAC: mac, IP: net.ParseIP("192.168.1.2")}},
+	}, mac
+}
+
+// newTestRequest returns a request of the given type from the given client
+func newTestRequest(mac net.HardwareAddr, t messageType, options map[optionCode][]byte) *message {
+	m := &message{
+		op:                    opRequest,
+		hardwareType:          hardwareTypeEthernet,
+		hardwareAddressLength: 6,
+		transactionID:         42,
+		clientIP:              net.IPv4zero,
+		yourIP:                net.IPv4zero,
+		serverIP:              net.IPv4zero,
+		gatewayIP:             net.IPv4zero,
+		clientHardwareAddress: mac,
+		options:               map[optionCode][]byte{optionMessageType: {byte(t)}},
+	}
+	for code, value := range options {
+		m.options[code] = value
+	}
+	return m
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	config, _ := newTestConfig(t)
+	config.ServerIP = net.ParseIP("fd00::1")
+	_, err := New(config, clock.NewFakeClock(time.Now()))
+	NewWithT(t).Expect(err).To(MatchError(ContainSubstring("is not an IPv4 address")))
+}
+
+func TestServerHandle(t *testing.T) {
+	config, mac := newTestConfig(t)
+	unknownMAC, err := net.ParseMAC("00:00:00:00:00:02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	configuration := map[optionCode][]byte{
+		optionServerIdentifier:     {192, 168, 1, 1},
+		optionSubnetMask:           {255, 255, 255, 0},
+		optionInterfaceMTU:         {0x06, 0x18},
+		optionClasslessStaticRoute: {23, 192, 168, 0, 192, 168, 1, 10},
+		optionIPAddressLeaseTime:   {0, 0, 0x0e, 0x10},
+		optionRenewalTimeValue:     {0, 0, 0x07, 0x08},
+		optionRebindingTimeValue:   {0, 0, 0x0c, 0x4e},
+	}
+	withType := func(t messageType, options map[optionCode][]byte) map[optionCode][]byte {
+		m := map[optionCode][]byte{optionMessageType: {byte(t)}}
+		for code, value := range options {
+			m[code] = value
+		}
+		return m
+	}
+
+	tests := []struct {
+		name            string
+		request         *message
+		expectedType    messageType
+		expectedYourIP  net.IP
+		expectedOptions map[optionCode][]byte
+		expectedLeases  int
+	}{
+		{
+			name:            "discover from bound client",
+			request:         newTestRequest(mac, messageTypeDiscover, nil),
+			expectedType:    messageTypeOffer,
+			expectedYourIP:  net.ParseIP("192.168.1.2"),
+			expectedOptions: withType(messageTypeOffer, configuration),
+		},
+		{
+			name:    "discover from unknown client",
+			request: newTestRequest(unknownMAC, messageTypeDiscover, nil),
+		},
+		{
+			name:            "request for the bound address",
+			request:         newTestRequest(mac, messageTypeRequest, map[optionCode][]byte{optionRequestedIPAddress: {192, 168, 1, 2}, optionServerIdentifier: {192, 168, 1, 1}}),
+			expectedType:    messageTypeAck,
+			expectedYourIP:  net.ParseIP("192.168.1.2"),
+			expectedOptions: withType(messageTypeAck, configuration),
+			expectedLeases:  1,
+		},
+		{
+			name:           "request for another address",
+			request:        newTestRequest(mac, messageTypeRequest, map[optionCode][]byte{optionRequestedIPAddress: {192, 168, 1, 3}}),
+			expectedType:   messageTypeNak,
+			expectedYourIP: net.IPv4zero,
+			expectedOptions: map[optionCode][]byte{
+				optionMessageType:      {byte(messageTypeNak)},
+				optionServerIdentifier: {192, 168, 1, 1},
+				optionMessage:          []byte("address 192.168.1.3 is not bound to 00:00:00:00:00:01"),
+			},
+		},
+		{
+			name:    "request for another server",
+			request: newTestRequest(mac, messageTypeRequest, map[optionCode][]byte{optionRequestedIPAddress: {192, 168, 1, 2}, optionServerIdentifier: {192, 168, 1, 5}}),
+		},
+		{
+			name:    "release",
+			request: newTestRequest(mac, messageTypeRelease, nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			s, err := New(config, clock.NewFakeClock(time.Now()))
+			g.Expect(err).ToNot(HaveOccurred())
+
+			reply := s.handle(klog.Background(), tt.request)
+			if tt.expectedType == 0 {
+				g.Expect(reply).To(BeNil())
+				return
+			}
+			g.Expect(reply).ToNot(BeNil())
+			g.Expect(reply.op).To(Equal(byte(opReply)))
+			g.Expect(reply.transactionID).To(Equal(tt.request.transactionID))
+			g.Expect(reply.clientHardwareAddress).To(Equal(tt.request.clientHardwareAddress))
+			g.Expect(reply.messageType()).To(Equal(tt.expectedType))
+			g.Expect(reply.yourIP.Equal(tt.expectedYourIP)).To(BeTrue(), "yiaddr is %s", reply.yourIP)
+			g.Expect(reply.options).To(Equal(tt.expectedOptions))
+			g.Expect(s.Leases()).To(HaveLen(tt.expectedLeases))
+		})
+	}
+}
+
+func TestServerLeases(t *testing.T) {
+	g := NewWithT(t)
+	config, mac := newTestConfig(t)
+	fakeClock := clock.NewFakeClock(time.Now())
+	s, err := New(config, fakeClock)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(s.handle(klog.Background(), newTestRequest(mac, messageTypeRequest, map[optionCode][]byte{optionRequestedIPAddress: {192, 168, 1, 2}}))).ToNot(BeNil())
+	g.Expect(s.Leases()).To(ConsistOf(Lease{MAC: mac, IP: net.ParseIP("192.168.1.2").To4(), MTU: 1560, Expiry: fakeClock.Now().Add(defaultLeaseDuration)}))
+
+	// Releasing the lease
+	g.Expect(s.handle(klog.Background(), newTestRequest(mac, messageTypeRelease, nil))).To(BeNil())
+	g.Expect(s.Leases()).To(BeEmpty())
+
+	// Letting the lease expire
+	g.Expect(s.handle(klog.Background(), newTestRequest(mac, messageTypeRequest, map[optionCode][]byte{optionRequestedIPAddress: {192, 168, 1, 2}}))).ToNot(BeNil())
+	fakeClock.Step(defaultLeaseDuration)
+	g.Expect(s.Leases()).To(BeEmpty())
+}
@@ -29,10 +29,24 @@ const (
 // ReportReady declares that the application is ready. It writes a file on the host which can be used in a readiness
 // probe.
 func ReportReady() error {
-	err := os.WriteFile(readyzFilePath, []byte("ready"), 0644)
+	return writeReadyFile(readyzFilePath)
+}
+
+// writeReadyFile writes the ready file at the given path
+func writeReadyFile(path string) error {
+	err := os.WriteFile(path, []byte("ready"), 0644)
 	if err != nil {
 		return fmt.Errorf("error while writing ready file: %w", err)
 	}
 
 	return nil
 }
+
+// removeReadyFile removes the ready file at the given path if it exists
+func removeReadyFile(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error while removing ready file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2024 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package readyz
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// serverShutdownTimeout is how long the health probe server is given to shut down gracefully
+const serverShutdownTimeout = 5 * time.Second
+
+// Checker checks a particular aspect of the application. It returns an error describing the problem when the check
+// doesn't pass.
+type Checker func() error
+
+// Ping is a Checker that always passes. It can be used as a health check that only verifies that the HTTP server
+// responds.
+func Ping() error {
+	return nil
+}
+
+// namedChecker is a Checker registered under a name
+type namedChecker struct {
+	name  string
+	check Checker
+}
+
+// Registry holds the health and readiness checks of an application and serves them over HTTP
+type Registry struct {
+	lock          sync.RWMutex
+	healthzChecks []namedChecker
+	readyzChecks  []namedChecker
+
+	// FilePath is the file that SyncReadyFile keeps in sync with the readiness of the application. Defaults to the
+	// file written by ReportReady.
+	FilePath string
+}
+
+// NewRegistry creates an empty Registry. An empty Registry reports healthy and ready.
+func NewRegistry() *Registry {
+	return &Registry{
+		FilePath: readyzFilePath,
+	}
+}
+
+// AddHealthzCheck registers a check that is run on every /healthz request
+func (r *Registry) AddHealthzCheck(name string, check Checker) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	checks, err := addChecker(r.healthzChecks, name, check)
+	if err != nil {
+		return err
+	}
+	r.healthzChecks = checks
+	return nil
+}
+
+// AddReadyzCheck registers a check that is run on every /readyz request and by SyncReadyFile
+func (r *Registry) AddReadyzCheck(name string, check Checker) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	checks, err := addChecker(r.readyzChecks, name, check)
+	if err != nil {
+		return err
+	}
+	r.readyzChecks = checks
+	return nil
+}
+
+// addChecker returns the given checks with the new check appended, ensuring that names are unique
+func addChecker(checks []namedChecker, name string, check Checker) ([]namedChecker, error) {
+	for _, c := range checks {
+		if c.name == name {
+			return nil, fmt.Errorf("check %s is already registered", name)
+		}
+	}
+	return append(checks, namedChecker{name: name, check: check}), nil
+}
+
+// Healthz runs all the health checks and returns an error that joins the errors of all the failed ones
+func (r *Registry) Healthz() error {
+	_, err := r.run(r.healthChecksSnapshot())
+	return err
+}
+
+// Readyz runs all the readiness checks and returns an error that joins the errors of all the failed ones
+func (r *Registry) Readyz() error {
+	_, err := r.run(r.readyChecksSnapshot())
+	return err
+}
+
+// HealthzHandler returns an HTTP handler that serves the result of the health checks
+func (r *Registry) HealthzHandler() http.Handler {
+	return r.handler("healthz", r.healthChecksSnapshot)
+}
+
+// ReadyzHandler returns an HTTP handler that serves the result of the readiness checks
+func (r *Registry) ReadyzHandler() http.Handler {
+	return r.handler("readyz", r.readyChecksSnapshot)
+}
+
+// Handler returns an HTTP handler that serves both the /healthz and the /readyz endpoints
+func (r *Registry) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", r.HealthzHandler())
+	mux.Handle("/readyz", r.ReadyzHandler())
+	return mux
+}
+
+// Serve serves the /healthz and /readyz endpoints on the given address until the context is cancelled. It returns
+// once the address is bound, the server itself runs in the background.
+func (r *Registry) Serve(ctx context.Context, bindAddress string) error {
+	listener, err := net.Listen("tcp", bindAddress)
+	if err != nil {
+		return fmt.Errorf("error while listening on health probe address %s: %w", bindAddress, err)
+	}
+
+	server := &http.Server{
+		Handler:           r.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	logger := klog.FromContext(ctx)
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error(err, "Health probe server stopped", "address", bindAddress)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Error(err, "Error while shutting down health probe server", "address", bindAddress)
+		}
+	}()
+
+	logger.Info("Serving health probes", "address", bindAddress)
+	return nil
+}
+
+// SyncReadyFile keeps the ready file in sync with the result of the readiness checks so that file based readiness
+// probes keep working. The file is written when the checks pass and removed otherwise. This is a blocking function.
+func (r *Registry) SyncReadyFile(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		r.syncReadyFile(klog.FromContext(ctx))
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncReadyFile writes or removes the ready file according to the result of the readiness checks
+func (r *Registry) syncReadyFile(logger klog.Logger) {
+	if err := r.Readyz(); err != nil {
+		if err := removeReadyFile(r.FilePath); err != nil {
+			logger.Error(err, "Error while reporting not ready", "path", r.FilePath)
+		}
+		return
+	}
+	if err := writeReadyFile(r.FilePath); err != nil {
+		logger.Error(err, "Error while reporting ready", "path", r.FilePath)
+	}
+}
+
+// handler returns an HTTP handler that runs the checks returned by the given function and reports a per check
+// result. It responds with 500 when any of the checks fails.
+func (r *Registry) handler(endpoint string, checks func() []namedChecker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		output, err := r.run(checks())
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if err != nil {
+			klog.V(2).InfoS("Check failed", "endpoint", endpoint, "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(&output, "%s check failed\n", endpoint)
+		} else {
+			fmt.Fprintf(&output, "%s check passed\n", endpoint)
+		}
+		_, _ = output.WriteTo(w)
+	})
+}
+
+// run runs the given checks and returns a human readable report together with the joined errors of the failed ones
+func (r *Registry) run(checks []namedChecker) (bytes.Buffer, error) {
+	var output bytes.Buffer
+	var errs []error
+	for _, c := range checks {
+		if err := c.check(); err != nil {
+			fmt.Fprintf(&output, "[-]%s failed: %s\n", c.name, err.Error())
+			errs = append(errs, fmt.Errorf("check %s failed: %w", c.name, err))
+			continue
+		}
+		fmt.Fprintf(&output, "[+]%s ok\n", c.name)
+	}
+	return output, errors.Join(errs...)
+}
+
+// healthChecksSnapshot returns a copy of the registered health checks
+func (r *Registry) healthChecksSnapshot() []namedChecker {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return append([]namedChecker(nil), r.healthzChecks...)
+}
+
+// readyChecksSnapshot returns a copy of the registered readiness checks
+func (r *Registry) readyChecksSnapshot() []namedChecker {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return append([]namedChecker(nil), r.readyzChecks...)
+}
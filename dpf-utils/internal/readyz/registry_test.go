@@ -0,0 +1,99 @@
+/*
+Copyright 2024 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package readyz
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/klog/v2"
+)
+
+func TestRegistryHandler(t *testing.T) {
+	g := NewWithT(t)
+	r := NewRegistry()
+	var readyErr error
+	g.Expect(r.AddHealthzCheck("ping", Ping)).To(Succeed())
+	g.Expect(r.AddReadyzCheck("ping", Ping)).To(Succeed())
+	g.Expect(r.AddReadyzCheck("component", func() error { return readyErr })).To(Succeed())
+	g.Expect(r.AddReadyzCheck("component", Ping)).ToNot(Succeed())
+
+	tests := []struct {
+		name         string
+		path         string
+		readyErr     error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "healthy",
+			path:         "/healthz",
+			readyErr:     errors.New("broken"),
+			expectedCode: http.StatusOK,
+			expectedBody: "[+]ping ok\nhealthz check passed\n",
+		},
+		{
+			name:         "ready",
+			path:         "/readyz",
+			expectedCode: http.StatusOK,
+			expectedBody: "[+]ping ok\n[+]component ok\nreadyz check passed\n",
+		},
+		{
+			name:         "not ready",
+			path:         "/readyz",
+			readyErr:     errors.New("broken"),
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: "[+]ping ok\n[-]component failed: broken\nreadyz check failed\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			readyErr = tt.readyErr
+			rec := httptest.NewRecorder()
+			r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			g.Expect(rec.Code).To(Equal(tt.expectedCode))
+			g.Expect(rec.Body.String()).To(Equal(tt.expectedBody))
+		})
+	}
+}
+
+func TestRegistrySyncReadyFile(t *testing.T) {
+	g := NewWithT(t)
+	r := NewRegistry()
+	r.FilePath = filepath.Join(t.TempDir(), "readyz")
+	var readyErr error
+	g.Expect(r.AddReadyzCheck("component", func() error { return readyErr })).To(Succeed())
+
+	r.syncReadyFile(klog.Background())
+	g.Expect(r.FilePath).To(BeARegularFile())
+
+	readyErr = errors.New("broken")
+	r.syncReadyFile(klog.Background())
+	_, err := os.Stat(r.FilePath)
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+
+	// Removing a file that doesn't exist is not an error
+	r.syncReadyFile(klog.Background())
+	g.Expect(r.Readyz()).To(MatchError(ContainSubstring("check component failed: broken")))
+}
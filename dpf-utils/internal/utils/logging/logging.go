@@ -0,0 +1,132 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	"k8s.io/klog/v2"
+)
+
+// The keys the binaries use for the values they log, so that the logs of every component can be aggregated the same way
+const (
+	// KeyDPUNode is the name of the DPU Node
+	KeyDPUNode = "dpuNode"
+	// KeyHostNode is the name of the host Node the DPU belongs to
+	KeyHostNode = "hostNode"
+	// KeyReconcileID correlates the logs of a single run of the provisioning flow
+	KeyReconcileID = "reconcileID"
+	// KeyStep is the step of the provisioning flow
+	KeyStep = "step"
+	// KeyLink is the name of a network interface
+	KeyLink = "link"
+	// KeyCIDR is an IP network or address in CIDR notation
+	KeyCIDR = "cidr"
+	// KeyIP is an IP address
+	KeyIP = "ip"
+	// KeyMAC is a hardware address
+	KeyMAC = "mac"
+	// KeyOVSCommand is a command run against OVS
+	KeyOVSCommand = "ovsCommand"
+)
+
+const (
+	// FormatText is the klog text format
+	FormatText = "text"
+	// FormatJSON is a format where every log entry is a JSON object
+	FormatJSON = "json"
+)
+
+// Options are the logging options of a binary
+type Options struct {
+	// Format is the format of the logs, either FormatText or FormatJSON
+	Format string
+
+	flagSet *flag.FlagSet
+	// output is where the JSON logs are written. Defaults to stderr.
+	output io.Writer
+}
+
+// AddFlags registers the klog flags and the flags of the options in the given FlagSet
+func (o *Options) AddFlags(fs *flag.FlagSet) {
+	klog.InitFlags(fs)
+	fs.StringVar(&o.Format, "log-format", FormatText, "Format of the logs, either text or json.")
+	o.flagSet = fs
+}
+
+// Apply configures klog according to the options. It must be called once the flags are parsed and before anything is
+// logged.
+func (o *Options) Apply() error {
+	switch o.Format {
+	case "", FormatText:
+		return nil
+	case FormatJSON:
+	default:
+		return fmt.Errorf("unknown log format %q, expected %s or %s", o.Format, FormatText, FormatJSON)
+	}
+
+	verbosity, err := o.verbosity()
+	if err != nil {
+		return err
+	}
+	output := o.output
+	if output == nil {
+		output = os.Stderr
+	}
+	var lock sync.Mutex
+	logger := funcr.NewJSON(func(obj string) {
+		lock.Lock()
+		defer lock.Unlock()
+		fmt.Fprintln(output, obj)
+	}, funcr.Options{
+		LogTimestamp:    true,
+		TimestampFormat: time.RFC3339Nano,
+		Verbosity:       verbosity,
+	})
+	klog.SetLoggerWithOptions(logger, klog.ContextualLogger(true))
+	return nil
+}
+
+// verbosity returns the verbosity klog was configured with. The JSON logger filters the entries logged through a
+// contextual logger on its own, so it needs to know it.
+func (o *Options) verbosity() (int, error) {
+	if o.flagSet == nil {
+		return 0, nil
+	}
+	f := o.flagSet.Lookup("v")
+	if f == nil {
+		return 0, nil
+	}
+	verbosity, err := strconv.Atoi(f.Value.String())
+	if err != nil {
+		return 0, fmt.Errorf("error while parsing the log verbosity: %w", err)
+	}
+	return verbosity, nil
+}
+
+// Fatal logs the given error along with the message and the key/value pairs and exits
+func Fatal(err error, msg string, keysAndValues ...any) {
+	klog.ErrorS(err, msg, keysAndValues...)
+	klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+}
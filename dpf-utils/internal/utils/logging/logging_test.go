@@ -0,0 +1,111 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/klog/v2"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		expectError bool
+		expected    []map[string]any
+	}{
+		{
+			name: "text format keeps the klog output",
+			args: []string{"-log-format=text"},
+		},
+		{
+			name:        "unknown format",
+			args:        []string{"-log-format=yaml"},
+			expectError: true,
+		},
+		{
+			name: "json format",
+			args: []string{"-log-format=json"},
+			expected: []map[string]any{
+				{"msg": "Configuring br-ovn", "level": float64(0), KeyDPUNode: "dpu1", KeyStep: "br_ovn"},
+				{"msg": "Configuration failed", "error": "boom", KeyDPUNode: "dpu1"},
+			},
+		},
+		{
+			name: "json format honors the verbosity",
+			args: []string{"-log-format=json", "-v=2"},
+			expected: []map[string]any{
+				{"msg": "Configuring br-ovn", "level": float64(0), KeyDPUNode: "dpu1", KeyStep: "br_ovn"},
+				{"msg": "Link has IP, skipping configuration", "level": float64(2), KeyLink: "br-ovn"},
+				{"msg": "Configuration failed", "error": "boom", KeyDPUNode: "dpu1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			t.Cleanup(func() {
+				klog.ClearLogger()
+				var v klog.Level
+				g.Expect(v.Set("0")).To(Succeed())
+			})
+			var output bytes.Buffer
+			o := &Options{output: &output}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			o.AddFlags(fs)
+			g.Expect(fs.Parse(tt.args)).To(Succeed())
+
+			err := o.Apply()
+			if tt.expectError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+
+			logger := klog.LoggerWithValues(klog.Background(), KeyDPUNode, "dpu1")
+			logger.Info("Configuring br-ovn", KeyStep, "br_ovn")
+			logger.V(2).Info("Link has IP, skipping configuration", KeyLink, "br-ovn")
+			logger.V(3).Info("Not logged")
+			klog.ErrorS(errors.New("boom"), "Configuration failed", KeyDPUNode, "dpu1")
+			klog.Flush()
+
+			var entries []map[string]any
+			for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+				if line == "" {
+					continue
+				}
+				entry := map[string]any{}
+				g.Expect(json.Unmarshal([]byte(line), &entry)).To(Succeed())
+				g.Expect(entry).To(HaveKey("ts"))
+				entries = append(entries, entry)
+			}
+			g.Expect(entries).To(HaveLen(len(tt.expected)))
+			for i, expected := range tt.expected {
+				for key, value := range expected {
+					g.Expect(entries[i]).To(HaveKeyWithValue(key, value))
+				}
+			}
+		})
+	}
+}
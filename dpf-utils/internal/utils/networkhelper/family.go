@@ -0,0 +1,69 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networkhelper
+
+import (
+	"net"
+
+	"github.com/vishvananda/netlink"
+)
+
+// Family is an IP address family
+type Family int
+
+const (
+	// IPv4 is the IPv4 address family
+	IPv4 Family = netlink.FAMILY_V4
+	// IPv6 is the IPv6 address family
+	IPv6 Family = netlink.FAMILY_V6
+)
+
+// String returns the string representation of the family
+func (f Family) String() string {
+	switch f {
+	case IPv4:
+		return "IPv4"
+	case IPv6:
+		return "IPv6"
+	default:
+		return "unknown"
+	}
+}
+
+// FamilyOf returns the family of the given IP
+func FamilyOf(ip net.IP) Family {
+	if ip.To4() != nil {
+		return IPv4
+	}
+	return IPv6
+}
+
+// HostMask returns the mask of a single address of the given family, i.e. /32 for IPv4 and /128 for IPv6
+func (f Family) HostMask() net.IPMask {
+	if f == IPv4 {
+		return net.CIDRMask(8*net.IPv4len, 8*net.IPv4len)
+	}
+	return net.CIDRMask(8*net.IPv6len, 8*net.IPv6len)
+}
+
+// DefaultRouteNetwork returns the network the default route of the given family is for, i.e. 0.0.0.0/0 or ::/0
+func (f Family) DefaultRouteNetwork() *net.IPNet {
+	if f == IPv4 {
+		return &net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 8*net.IPv4len)}
+	}
+	return &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 8*net.IPv6len)}
+}
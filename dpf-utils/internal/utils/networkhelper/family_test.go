@@ -0,0 +1,47 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networkhelper
+
+import (
+	"net"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestFamily(t *testing.T) {
+	g := NewWithT(t)
+	cases := []struct {
+		ip                  string
+		family              Family
+		hostMask            string
+		defaultRouteNetwork string
+	}{
+		{ip: "192.168.1.1", family: IPv4, hostMask: "/32", defaultRouteNetwork: "0.0.0.0/0"},
+		{ip: "::ffff:192.168.1.1", family: IPv4, hostMask: "/32", defaultRouteNetwork: "0.0.0.0/0"},
+		{ip: "fd00::1", family: IPv6, hostMask: "/128", defaultRouteNetwork: "::/0"},
+	}
+	for _, tt := range cases {
+		ip := net.ParseIP(tt.ip)
+		family := FamilyOf(ip)
+		g.Expect(family).To(Equal(tt.family), tt.ip)
+		ones, _ := family.HostMask().Size()
+		g.Expect(ones).To(Equal(len(family.HostMask()) * 8))
+		g.Expect((&net.IPNet{IP: ip, Mask: family.HostMask()}).String()).To(HaveSuffix(tt.hostMask))
+		g.Expect(family.DefaultRouteNetwork().String()).To(Equal(tt.defaultRouteNetwork))
+	}
+}
@@ -0,0 +1,555 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networkhelper
+
+import (
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/nvidia/doca-platform/pkg/utils/networkhelper"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// neighborProbePort is the port of the datagram ProbeNeighbor sends, the discard protocol
+	neighborProbePort = 9
+	// neighborProbeInterval is how often ProbeNeighbor checks the neighbor table
+	neighborProbeInterval = 100 * time.Millisecond
+	// icmpHeaderSize is the size of the header of an ICMP echo message
+	icmpHeaderSize = 8
+)
+
+// networkHelper delegates to the doca-platform NetworkHelper and handles the IPv6 lookups itself
+type networkHelper struct {
+	networkhelper.NetworkHelper
+}
+
+// New creates a NetworkHelper
+func New() NetworkHelper {
+	return &networkHelper{NetworkHelper: networkhelper.New()}
+}
+
+// LinkIPAddressExists checks whether a link has the given IP.
+func (n *networkHelper) LinkIPAddressExists(link string, ipNet *net.IPNet) (bool, error) {
+	if ipNet == nil || FamilyOf(ipNet.IP) == IPv4 {
+		return n.NetworkHelper.LinkIPAddressExists(link, ipNet)
+	}
+	addrs, err := n.GetLinkIPAddressesByFamily(link, IPv6)
+	if err != nil {
+		return false, err
+	}
+	for _, addr := range addrs {
+		if addr.String() == ipNet.String() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetLinkIPAddressesByFamily returns the global unicast IP addresses of the given family of a link
+func (n *networkHelper) GetLinkIPAddressesByFamily(link string, family Family) ([]*net.IPNet, error) {
+	l, err := netlink.LinkByName(link)
+	if err != nil {
+		return nil, fmt.Errorf("netlink.LinkByName() failed: %w", err)
+	}
+	ips, err := netlink.AddrList(l, int(family))
+	if err != nil {
+		return nil, fmt.Errorf("netlink.AddrList() failed: %w", err)
+	}
+	addrs := make([]*net.IPNet, 0, len(ips))
+	for _, ip := range ips {
+		if !ip.IP.IsGlobalUnicast() {
+			continue
+		}
+		addrs = append(addrs, ip.IPNet)
+	}
+	return addrs, nil
+}
+
+// RouteExists checks whether a route tagged with Protocol exists. A matching route that isn't tagged, e.g. one added
+// before the routes were tagged, isn't reported so that AddRoute adopts it.
+func (n *networkHelper) RouteExists(network *net.IPNet, gateway net.IP, device string, table *int) (bool, error) {
+	if network == nil {
+		return false, errors.New("network is empty, can't check whether route exists")
+	}
+	routes, err := matchingRoutes(network, gateway, device, table)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range routes {
+		if r.Protocol == Protocol {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matchingRoutes returns the routes of the family of the given network to the network via the gateway and device, in
+// the given table when not nil, whatever their protocol
+func matchingRoutes(network *net.IPNet, gateway net.IP, device string, table *int) ([]netlink.Route, error) {
+	l, err := netlink.LinkByName(device)
+	if err != nil {
+		return nil, fmt.Errorf("netlink.LinkByName() failed: %w", err)
+	}
+
+	routeFilter := &netlink.Route{
+		LinkIndex: l.Attrs().Index,
+	}
+	filterMask := netlink.RT_FILTER_OIF
+	if table != nil {
+		routeFilter.Table = *table
+		filterMask += netlink.RT_FILTER_TABLE
+	}
+	routes, err := netlink.RouteListFiltered(int(FamilyOf(network.IP)), routeFilter, filterMask)
+	if err != nil {
+		return nil, fmt.Errorf("netlink.RouteList() failed: %w", err)
+	}
+
+	var matching []netlink.Route
+	for _, r := range routes {
+		if r.Dst.String() == network.String() && r.Gw.String() == gateway.String() {
+			matching = append(matching, r)
+		}
+	}
+	return matching, nil
+}
+
+// AddRoute adds a route tagged with Protocol. A matching route with the same metric that isn't tagged is replaced in
+// place, which tags it without the route ever being missing.
+func (n *networkHelper) AddRoute(network *net.IPNet, gateway net.IP, device string, metric *int, table *int) error {
+	if network == nil {
+		return errors.New("network is empty, can't add route")
+	}
+	l, err := netlink.LinkByName(device)
+	if err != nil {
+		return fmt.Errorf("netlink.LinkByName() failed: %w", err)
+	}
+	r := &netlink.Route{
+		Dst:       network,
+		Gw:        gateway,
+		LinkIndex: l.Attrs().Index,
+		Priority:  ptr.Deref(metric, 0),
+		Table:     ptr.Deref(table, 0),
+		Protocol:  Protocol,
+	}
+	if err := netlink.RouteReplace(r); err != nil {
+		return fmt.Errorf("netlink.RouteReplace() failed: %w", err)
+	}
+	return nil
+}
+
+// DeleteRouteFromTable deletes a route from the given table, the main table when nil
+func (n *networkHelper) DeleteRouteFromTable(network *net.IPNet, gateway net.IP, device string, table *int) error {
+	if network == nil {
+		return errors.New("network is empty, can't delete route")
+	}
+	l, err := netlink.LinkByName(device)
+	if err != nil {
+		return fmt.Errorf("netlink.LinkByName() failed: %w", err)
+	}
+	r := &netlink.Route{
+		Dst:       network,
+		Gw:        gateway,
+		LinkIndex: l.Attrs().Index,
+		Table:     ptr.Deref(table, 0),
+	}
+	if err := netlink.RouteDel(r); err != nil {
+		return fmt.Errorf("netlink.RouteDel() failed: %w", err)
+	}
+	return nil
+}
+
+// GetGateway returns the gateway for the given network with the lower metric
+func (n *networkHelper) GetGateway(network *net.IPNet) (net.IP, error) {
+	if network == nil || FamilyOf(network.IP) == IPv4 {
+		return n.NetworkHelper.GetGateway(network)
+	}
+	routes, err := netlink.RouteList(nil, netlink.FAMILY_V6)
+	if err != nil {
+		return nil, fmt.Errorf("netlink.RouteList() failed: %w", err)
+	}
+
+	var gateway net.IP
+	lowestPriority := math.MaxUint32 + 1
+	for _, r := range routes {
+		if r.Dst.String() == network.String() && r.Gw != nil && r.Priority < lowestPriority {
+			lowestPriority = r.Priority
+			gateway = r.Gw
+		}
+	}
+
+	if gateway == nil {
+		return nil, errors.New("no gateway found")
+	}
+
+	return gateway, nil
+}
+
+// GetDefaultRouteDevices returns the devices of the default routes of the given family in the main table, the one of
+// the preferred route first
+func (n *networkHelper) GetDefaultRouteDevices(family Family) ([]string, error) {
+	routes, err := netlink.RouteList(nil, int(family))
+	if err != nil {
+		return nil, fmt.Errorf("netlink.RouteList() failed: %w", err)
+	}
+
+	defaultRouteNetwork := family.DefaultRouteNetwork().String()
+	var defaultRoutes []netlink.Route
+	for _, r := range routes {
+		if r.Dst == nil || r.Dst.String() == defaultRouteNetwork {
+			defaultRoutes = append(defaultRoutes, r)
+		}
+	}
+	slices.SortStableFunc(defaultRoutes, func(a, b netlink.Route) int {
+		return cmp.Compare(a.Priority, b.Priority)
+	})
+
+	devices := make([]string, 0, len(defaultRoutes))
+	for _, r := range defaultRoutes {
+		l, err := netlink.LinkByIndex(r.LinkIndex)
+		if err != nil {
+			return nil, fmt.Errorf("netlink.LinkByIndex() failed: %w", err)
+		}
+		devices = append(devices, l.Attrs().Name)
+	}
+	return devices, nil
+}
+
+// LinkAdminUp returns whether the administrative state of a link is "up"
+func (n *networkHelper) LinkAdminUp(link string) (bool, error) {
+	l, err := netlink.LinkByName(link)
+	if err != nil {
+		return false, fmt.Errorf("netlink.LinkByName() failed: %w", err)
+	}
+	return l.Attrs().Flags&net.FlagUp != 0, nil
+}
+
+// LinkOperUp returns whether a link is operationally up, i.e. it's up and has a carrier. Links whose driver doesn't
+// report an operational state are considered up when they have a carrier.
+func (n *networkHelper) LinkOperUp(link string) (bool, error) {
+	l, err := netlink.LinkByName(link)
+	if err != nil {
+		return false, fmt.Errorf("netlink.LinkByName() failed: %w", err)
+	}
+	attrs := l.Attrs()
+	switch attrs.OperState {
+	case netlink.OperUp:
+		return true, nil
+	case netlink.OperUnknown:
+		return attrs.RawFlags&unix.IFF_LOWER_UP != 0, nil
+	default:
+		return false, nil
+	}
+}
+
+// GetLinkIndex returns the index of a link
+func (n *networkHelper) GetLinkIndex(link string) (int, error) {
+	l, err := netlink.LinkByName(link)
+	if err != nil {
+		return 0, fmt.Errorf("netlink.LinkByName() failed: %w", err)
+	}
+	return l.Attrs().Index, nil
+}
+
+// GetLinkMTU returns the MTU of a link
+func (n *networkHelper) GetLinkMTU(link string) (int, error) {
+	l, err := netlink.LinkByName(link)
+	if err != nil {
+		return 0, fmt.Errorf("netlink.LinkByName() failed: %w", err)
+	}
+	return l.Attrs().MTU, nil
+}
+
+// SetLinkMTU sets the MTU of a link
+func (n *networkHelper) SetLinkMTU(link string, mtu int) error {
+	l, err := netlink.LinkByName(link)
+	if err != nil {
+		return fmt.Errorf("netlink.LinkByName() failed: %w", err)
+	}
+	if err := netlink.LinkSetMTU(l, mtu); err != nil {
+		return fmt.Errorf("netlink.LinkSetMTU() failed: %w", err)
+	}
+	return nil
+}
+
+// ProbeNeighbor returns whether the given IP answers ARP (IPv4) or neighbor discovery (IPv6) on a link within the
+// timeout. A datagram sent to the IP makes the kernel resolve its link layer address, whose outcome is then read from
+// the neighbor table.
+func (n *networkHelper) ProbeNeighbor(link string, ip net.IP, timeout time.Duration) (bool, error) {
+	l, err := netlink.LinkByName(link)
+	if err != nil {
+		return false, fmt.Errorf("netlink.LinkByName() failed: %w", err)
+	}
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: ip, Port: neighborProbePort, Zone: link})
+	if err != nil {
+		return false, fmt.Errorf("error while dialing %s: %w", ip, err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte{0}); err != nil {
+		return false, fmt.Errorf("error while sending a datagram to %s: %w", ip, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		neighs, err := netlink.NeighList(l.Attrs().Index, int(FamilyOf(ip)))
+		if err != nil {
+			return false, fmt.Errorf("netlink.NeighList() failed: %w", err)
+		}
+		for _, neigh := range neighs {
+			if !neigh.IP.Equal(ip) {
+				continue
+			}
+			if neigh.State&(netlink.NUD_REACHABLE|netlink.NUD_PERMANENT) != 0 {
+				return true, nil
+			}
+			if neigh.State&netlink.NUD_FAILED != 0 {
+				return false, nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return false, nil
+		}
+		time.Sleep(neighborProbeInterval)
+	}
+}
+
+// Ping returns whether the given IP answers an ICMP echo request of the given size, IP header included, sent with
+// the DF bit set within the timeout. The request is sent regardless of the path MTU the kernel has learnt so that the
+// path is actually probed. A request that doesn't fit in the MTU of the outgoing link is never answered.
+func (n *networkHelper) Ping(ip net.IP, size int, timeout time.Duration) (bool, error) {
+	network, echoType, echoReplyType, ipHeaderSize := "ip4:icmp", byte(8), byte(0), 20
+	level, opt, value := unix.IPPROTO_IP, unix.IP_MTU_DISCOVER, unix.IP_PMTUDISC_PROBE
+	if FamilyOf(ip) == IPv6 {
+		network, echoType, echoReplyType, ipHeaderSize = "ip6:ipv6-icmp", 128, 129, 40
+		level, opt, value = unix.IPPROTO_IPV6, unix.IPV6_MTU_DISCOVER, unix.IPV6_PMTUDISC_PROBE
+	}
+	if size < ipHeaderSize+icmpHeaderSize {
+		return false, fmt.Errorf("size %d is smaller than the headers of an ICMP echo request", size)
+	}
+
+	conn, err := net.ListenPacket(network, "")
+	if err != nil {
+		return false, fmt.Errorf("error while opening an ICMP socket: %w", err)
+	}
+	defer conn.Close()
+	rawConn, err := conn.(*net.IPConn).SyscallConn()
+	if err != nil {
+		return false, fmt.Errorf("error while getting the ICMP socket: %w", err)
+	}
+	var sockoptErr error
+	if err := rawConn.Control(func(fd uintptr) {
+		sockoptErr = unix.SetsockoptInt(int(fd), level, opt, value)
+	}); err != nil {
+		return false, fmt.Errorf("error while getting the ICMP socket: %w", err)
+	}
+	if sockoptErr != nil {
+		return false, fmt.Errorf("error while setting the DF bit: %w", sockoptErr)
+	}
+
+	id, seq := uint16(rand.Uint32()), uint16(rand.Uint32())
+	request := make([]byte, size-ipHeaderSize)
+	request[0] = echoType
+	binary.BigEndian.PutUint16(request[4:], id)
+	binary.BigEndian.PutUint16(request[6:], seq)
+	// The kernel computes the checksum of ICMPv6 messages
+	if echoType == 8 {
+		binary.BigEndian.PutUint16(request[2:], icmpChecksum(request))
+	}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return false, fmt.Errorf("error while setting the deadline of the ICMP socket: %w", err)
+	}
+	if _, err := conn.WriteTo(request, &net.IPAddr{IP: ip}); err != nil {
+		if errors.Is(err, unix.EMSGSIZE) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error while sending an ICMP echo request to %s: %w", ip, err)
+	}
+
+	// The socket receives every ICMP message of the host, the reply is the one that matches the request
+	reply := make([]byte, size)
+	for {
+		length, from, err := conn.ReadFrom(reply)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("error while receiving an ICMP echo reply from %s: %w", ip, err)
+		}
+		if length < icmpHeaderSize || !from.(*net.IPAddr).IP.Equal(ip) {
+			continue
+		}
+		if reply[0] == echoReplyType && binary.BigEndian.Uint16(reply[4:]) == id && binary.BigEndian.Uint16(reply[6:]) == seq {
+			return true, nil
+		}
+	}
+}
+
+// icmpChecksum returns the internet checksum of an ICMP message
+func icmpChecksum(message []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(message); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(message[i:]))
+	}
+	if len(message)%2 == 1 {
+		sum += uint32(message[len(message)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+// RuleExists checks whether a rule tagged with Protocol exists in the routing policy database that controls the route
+// selection algorithm. A matching rule that isn't tagged, e.g. one added before the rules were tagged, isn't reported
+// so that AddRule adopts it.
+func (n *networkHelper) RuleExists(src *net.IPNet, table int, priority int) (bool, error) {
+	if src == nil {
+		return false, errors.New("src is empty, can't check whether rule exists")
+	}
+	rules, err := matchingRules(src, table, priority)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range rules {
+		if r.Protocol == Protocol {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matchingRules returns the rules of the family of the given source that match it, the table and the priority,
+// whatever their protocol
+func matchingRules(src *net.IPNet, table int, priority int) ([]netlink.Rule, error) {
+	rules, err := netlink.RuleList(int(FamilyOf(src.IP)))
+	if err != nil {
+		return nil, fmt.Errorf("netlink.RuleList() failed: %w", err)
+	}
+
+	var matching []netlink.Rule
+	for _, r := range rules {
+		if r.Src.String() == src.String() && r.Table == table && r.Priority == priority {
+			matching = append(matching, r)
+		}
+	}
+	return matching, nil
+}
+
+// AddRule adds a rule tagged with Protocol in the routing policy database. The rules can't be replaced in place, hence
+// a matching rule that isn't tagged is deleted right before the tagged one is added.
+func (n *networkHelper) AddRule(src *net.IPNet, table int, priority int) error {
+	if src == nil {
+		return errors.New("src is empty, can't add rule")
+	}
+	rules, err := matchingRules(src, table, priority)
+	if err != nil {
+		return err
+	}
+	for _, r := range rules {
+		if r.Protocol == Protocol {
+			continue
+		}
+		if err := netlink.RuleDel(&r); err != nil {
+			return fmt.Errorf("netlink.RuleDel() failed: %w", err)
+		}
+	}
+	r := netlink.NewRule()
+	r.Family = int(FamilyOf(src.IP))
+	r.Src = src
+	r.Table = table
+	r.Priority = priority
+	r.Protocol = Protocol
+	if err := netlink.RuleAdd(r); err != nil {
+		return fmt.Errorf("netlink.RuleAdd() failed: %w", err)
+	}
+	return nil
+}
+
+// DeleteRule deletes a rule from the routing policy database
+func (n *networkHelper) DeleteRule(src *net.IPNet, table int, priority int) error {
+	if src == nil {
+		return errors.New("src is empty, can't delete rule")
+	}
+	r := netlink.NewRule()
+	r.Family = int(FamilyOf(src.IP))
+	r.Src = src
+	r.Table = table
+	r.Priority = priority
+	if err := netlink.RuleDel(r); err != nil {
+		return fmt.Errorf("netlink.RuleDel() failed: %w", err)
+	}
+	return nil
+}
+
+// ListOwnedRoutes returns the routes of all the IP families and tables that are tagged with Protocol
+func (n *networkHelper) ListOwnedRoutes() ([]Route, error) {
+	routeFilter := &netlink.Route{
+		Protocol: Protocol,
+		Table:    unix.RT_TABLE_UNSPEC,
+	}
+	routes, err := netlink.RouteListFiltered(netlink.FAMILY_ALL, routeFilter, netlink.RT_FILTER_PROTOCOL|netlink.RT_FILTER_TABLE)
+	if err != nil {
+		return nil, fmt.Errorf("netlink.RouteListFiltered() failed: %w", err)
+	}
+
+	owned := make([]Route, 0, len(routes))
+	for _, r := range routes {
+		l, err := netlink.LinkByIndex(r.LinkIndex)
+		if err != nil {
+			return nil, fmt.Errorf("netlink.LinkByIndex() failed: %w", err)
+		}
+		owned = append(owned, Route{
+			Network: r.Dst,
+			Gateway: r.Gw,
+			Device:  l.Attrs().Name,
+			Metric:  r.Priority,
+			Table:   r.Table,
+		})
+	}
+	return owned, nil
+}
+
+// ListOwnedRules returns the rules of all the IP families that are tagged with Protocol
+func (n *networkHelper) ListOwnedRules() ([]Rule, error) {
+	rules, err := netlink.RuleList(netlink.FAMILY_ALL)
+	if err != nil {
+		return nil, fmt.Errorf("netlink.RuleList() failed: %w", err)
+	}
+
+	var owned []Rule
+	for _, r := range rules {
+		if r.Protocol != Protocol {
+			continue
+		}
+		owned = append(owned, Rule{
+			Src:      r.Src,
+			Table:    r.Table,
+			Priority: r.Priority,
+		})
+	}
+	return owned, nil
+}
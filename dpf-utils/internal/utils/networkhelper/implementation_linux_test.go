@@ -0,0 +1,477 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networkhelper
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+)
+
+const testLink = "dual-stack"
+
+// enterTestNetworkNamespace moves the test into a new network namespace that has a veth link with an IPv4 and an IPv6
+// address and a default route per family.
+func enterTestNetworkNamespace(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("creating network namespaces requires root")
+	}
+	g := NewWithT(t)
+
+	runtime.LockOSThread()
+	origin, err := netns.Get()
+	g.Expect(err).ToNot(HaveOccurred())
+	ns, err := netns.New()
+	if err != nil {
+		runtime.UnlockOSThread()
+		origin.Close()
+		t.Skipf("creating network namespaces is not permitted: %s", err.Error())
+	}
+	t.Cleanup(func() {
+		g.Expect(netns.Set(origin)).To(Succeed())
+		origin.Close()
+		ns.Close()
+		runtime.UnlockOSThread()
+	})
+
+	g.Expect(netlink.LinkAdd(&netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: testLink}, PeerName: testLink + "-peer"})).To(Succeed())
+	link, err := netlink.LinkByName(testLink)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(netlink.LinkSetUp(link)).To(Succeed())
+	for _, addr := range []string{"192.168.1.1/24", "fd00:1::1/64"} {
+		ipNet, err := netlink.ParseIPNet(addr)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(netlink.AddrAdd(link, &netlink.Addr{IPNet: ipNet, Flags: unix.IFA_F_NODAD})).To(Succeed())
+	}
+	for _, gateway := range []string{"192.168.1.254", "fd00:1::fe"} {
+		g.Expect(netlink.RouteAdd(&netlink.Route{LinkIndex: link.Attrs().Index, Gw: net.ParseIP(gateway)})).To(Succeed())
+	}
+}
+
+func mustParseCIDR(t *testing.T, cidr string) *net.IPNet {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	NewWithT(t).Expect(err).ToNot(HaveOccurred())
+	return ipNet
+}
+
+func TestGetLinkIPAddressesByFamily(t *testing.T) {
+	enterTestNetworkNamespace(t)
+	g := NewWithT(t)
+	n := New()
+
+	v4, err := n.GetLinkIPAddressesByFamily(testLink, IPv4)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(v4).To(HaveLen(1))
+	g.Expect(v4[0].String()).To(Equal("192.168.1.1/24"))
+
+	// The link-local address is not returned
+	v6, err := n.GetLinkIPAddressesByFamily(testLink, IPv6)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(v6).To(HaveLen(1))
+	g.Expect(v6[0].String()).To(Equal("fd00:1::1/64"))
+
+	for _, addr := range []string{"192.168.1.1/24", "fd00:1::1/64"} {
+		ipNet, err := netlink.ParseIPNet(addr)
+		g.Expect(err).ToNot(HaveOccurred())
+		exists, err := n.LinkIPAddressExists(testLink, ipNet)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(exists).To(BeTrue(), addr)
+	}
+	exists, err := n.LinkIPAddressExists(testLink, &net.IPNet{IP: net.ParseIP("fd00:1::2"), Mask: net.CIDRMask(64, 128)})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(exists).To(BeFalse())
+}
+
+func TestGetGateway(t *testing.T) {
+	enterTestNetworkNamespace(t)
+	g := NewWithT(t)
+	n := New()
+
+	gateway, err := n.GetGateway(IPv4.DefaultRouteNetwork())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(gateway.String()).To(Equal("192.168.1.254"))
+
+	gateway, err = n.GetGateway(IPv6.DefaultRouteNetwork())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(gateway.String()).To(Equal("fd00:1::fe"))
+
+	_, err = n.GetGateway(mustParseCIDR(t, "fd00:2::/64"))
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestGetDefaultRouteDevices(t *testing.T) {
+	enterTestNetworkNamespace(t)
+	g := NewWithT(t)
+	n := New()
+
+	// A less preferred default route via the peer of the test link
+	peer, err := netlink.LinkByName(testLink + "-peer")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(netlink.LinkSetUp(peer)).To(Succeed())
+	ipNet, err := netlink.ParseIPNet("192.168.2.1/24")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(netlink.AddrAdd(peer, &netlink.Addr{IPNet: ipNet})).To(Succeed())
+	g.Expect(netlink.RouteAdd(&netlink.Route{LinkIndex: peer.Attrs().Index, Gw: net.ParseIP("192.168.2.254"), Priority: 100})).To(Succeed())
+
+	devices, err := n.GetDefaultRouteDevices(IPv4)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(devices).To(Equal([]string{testLink, testLink + "-peer"}))
+
+	devices, err = n.GetDefaultRouteDevices(IPv6)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(devices).To(Equal([]string{testLink}))
+}
+
+func TestLinkAdminUp(t *testing.T) {
+	enterTestNetworkNamespace(t)
+	g := NewWithT(t)
+	n := New()
+
+	up, err := n.LinkAdminUp(testLink)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(up).To(BeTrue())
+
+	up, err = n.LinkAdminUp(testLink + "-peer")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(up).To(BeFalse())
+
+	_, err = n.LinkAdminUp("missing")
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestLinkOperUp(t *testing.T) {
+	enterTestNetworkNamespace(t)
+	g := NewWithT(t)
+	n := New()
+
+	// The veth has no carrier until its peer is up
+	up, err := n.LinkOperUp(testLink)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(up).To(BeFalse())
+
+	peer, err := netlink.LinkByName(testLink + "-peer")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(netlink.LinkSetUp(peer)).To(Succeed())
+	// The operational state is updated asynchronously by the kernel
+	g.Eventually(func() (bool, error) { return n.LinkOperUp(testLink) }).Should(BeTrue())
+
+	_, err = n.LinkOperUp("missing")
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestGetLinkIndex(t *testing.T) {
+	enterTestNetworkNamespace(t)
+	g := NewWithT(t)
+	n := New()
+
+	link, err := netlink.LinkByName(testLink)
+	g.Expect(err).ToNot(HaveOccurred())
+	index, err := n.GetLinkIndex(testLink)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(index).To(Equal(link.Attrs().Index))
+
+	_, err = n.GetLinkIndex("missing")
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestLinkMTU(t *testing.T) {
+	enterTestNetworkNamespace(t)
+	g := NewWithT(t)
+	n := New()
+
+	g.Expect(n.SetLinkMTU(testLink, 1400)).To(Succeed())
+	mtu, err := n.GetLinkMTU(testLink)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(mtu).To(Equal(1400))
+
+	_, err = n.GetLinkMTU("missing")
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(n.SetLinkMTU("missing", 1400)).ToNot(Succeed())
+}
+
+func TestProbeNeighbor(t *testing.T) {
+	enterTestNetworkNamespace(t)
+	g := NewWithT(t)
+	n := New()
+
+	link, err := netlink.LinkByName(testLink)
+	g.Expect(err).ToNot(HaveOccurred())
+	mac, err := net.ParseMAC("02:00:00:00:00:0a")
+	g.Expect(err).ToNot(HaveOccurred())
+	for _, ip := range []string{"192.168.1.10", "fd00:1::a"} {
+		neigh := &netlink.Neigh{
+			LinkIndex:    link.Attrs().Index,
+			Family:       int(FamilyOf(net.ParseIP(ip))),
+			State:        netlink.NUD_PERMANENT,
+			IP:           net.ParseIP(ip),
+			HardwareAddr: mac,
+		}
+		g.Expect(netlink.NeighAdd(neigh)).To(Succeed())
+	}
+
+	cases := []struct {
+		ip       string
+		expected bool
+	}{
+		{ip: "192.168.1.10", expected: true},
+		{ip: "fd00:1::a", expected: true},
+		// Nothing answers on the other side of the veth
+		{ip: "192.168.1.20", expected: false},
+		{ip: "fd00:1::14", expected: false},
+	}
+	for _, c := range cases {
+		answered, err := n.ProbeNeighbor(testLink, net.ParseIP(c.ip), 300*time.Millisecond)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(answered).To(Equal(c.expected), c.ip)
+	}
+
+	_, err = n.ProbeNeighbor("missing", net.ParseIP("192.168.1.10"), time.Second)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestPing(t *testing.T) {
+	enterTestNetworkNamespace(t)
+	g := NewWithT(t)
+	n := New()
+
+	// The addresses of the namespace are reached via lo
+	lo, err := netlink.LinkByName("lo")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(netlink.LinkSetUp(lo)).To(Succeed())
+	g.Expect(netlink.LinkSetMTU(lo, 1500)).To(Succeed())
+
+	cases := []struct {
+		ip       string
+		size     int
+		expected bool
+	}{
+		{ip: "192.168.1.1", size: 1500, expected: true},
+		{ip: "fd00:1::1", size: 1500, expected: true},
+		{ip: "192.168.1.1", size: 1501, expected: false},
+		{ip: "fd00:1::1", size: 1501, expected: false},
+		// Nothing answers on the other side of the veth
+		{ip: "192.168.1.20", size: 100, expected: false},
+		{ip: "fd00:1::14", size: 100, expected: false},
+	}
+	for _, c := range cases {
+		answered, err := n.Ping(net.ParseIP(c.ip), c.size, 300*time.Millisecond)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(answered).To(Equal(c.expected), fmt.Sprintf("%s with size %d", c.ip, c.size))
+	}
+
+	_, err = n.Ping(net.ParseIP("192.168.1.1"), 20, time.Second)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestRoutesAndRules(t *testing.T) {
+	enterTestNetworkNamespace(t)
+	g := NewWithT(t)
+	n := New()
+	table := 60
+
+	cases := []struct {
+		network string
+		gateway string
+	}{
+		{network: "10.0.0.0/16", gateway: "192.168.1.254"},
+		{network: "fd00:2::/64", gateway: "fd00:1::fe"},
+	}
+	for _, tt := range cases {
+		network := mustParseCIDR(t, tt.network)
+		gateway := net.ParseIP(tt.gateway)
+
+		exists, err := n.RouteExists(network, gateway, testLink, &table)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(exists).To(BeFalse(), tt.network)
+		g.Expect(n.AddRoute(network, gateway, testLink, nil, &table)).To(Succeed())
+		exists, err = n.RouteExists(network, gateway, testLink, &table)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(exists).To(BeTrue(), tt.network)
+		exists, err = n.RouteExists(network, gateway, testLink, nil)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(exists).To(BeFalse(), tt.network)
+
+		g.Expect(n.DeleteRouteFromTable(network, gateway, testLink, &table)).To(Succeed(), tt.network)
+		exists, err = n.RouteExists(network, gateway, testLink, &table)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(exists).To(BeFalse(), tt.network)
+
+		exists, err = n.RuleExists(network, table, 31000)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(exists).To(BeFalse(), tt.network)
+		g.Expect(n.AddRule(network, table, 31000)).To(Succeed())
+		exists, err = n.RuleExists(network, table, 31000)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(exists).To(BeTrue(), tt.network)
+	}
+}
+
+func TestOwnedRoutesAndRules(t *testing.T) {
+	enterTestNetworkNamespace(t)
+	g := NewWithT(t)
+	n := New()
+	table := 60
+
+	link, err := netlink.LinkByName(testLink)
+	g.Expect(err).ToNot(HaveOccurred())
+	// Routes and rules that are not tagged with the protocol are not owned
+	foreign := mustParseCIDR(t, "10.1.0.0/16")
+	g.Expect(netlink.RouteAdd(&netlink.Route{Dst: foreign, Gw: net.ParseIP("192.168.1.254"), LinkIndex: link.Attrs().Index})).To(Succeed())
+	foreignRule := netlink.NewRule()
+	foreignRule.Src = foreign
+	foreignRule.Table = table
+	foreignRule.Priority = 30000
+	g.Expect(netlink.RuleAdd(foreignRule)).To(Succeed())
+
+	routes := []Route{
+		{Network: mustParseCIDR(t, "10.0.0.0/16"), Gateway: net.ParseIP("192.168.1.254"), Device: testLink, Metric: 10000, Table: unix.RT_TABLE_MAIN},
+		{Network: mustParseCIDR(t, "10.0.0.0/16"), Gateway: net.ParseIP("192.168.1.254"), Device: testLink, Table: table},
+		// The kernel defaults the metric of IPv6 routes to 1024
+		{Network: mustParseCIDR(t, "fd00:2::/64"), Gateway: net.ParseIP("fd00:1::fe"), Device: testLink, Metric: 1024, Table: table},
+	}
+	for _, r := range routes {
+		var metric *int
+		if r.Metric != 0 {
+			metric = &r.Metric
+		}
+		g.Expect(n.AddRoute(r.Network, r.Gateway, r.Device, metric, &r.Table)).To(Succeed())
+	}
+	rules := []Rule{
+		{Src: mustParseCIDR(t, "10.244.6.0/24"), Table: table, Priority: 31000},
+		{Src: mustParseCIDR(t, "fd00:3::/64"), Table: table, Priority: 31000},
+	}
+	for _, r := range rules {
+		g.Expect(n.AddRule(r.Src, r.Table, r.Priority)).To(Succeed())
+	}
+
+	owned, err := n.ListOwnedRoutes()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(routeStrings(owned)).To(ConsistOf(routeStrings(routes)))
+	ownedRules, err := n.ListOwnedRules()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(ownedRules).To(ConsistOf(rules))
+
+	g.Expect(n.DeleteRouteFromTable(routes[2].Network, routes[2].Gateway, routes[2].Device, &routes[2].Table)).To(Succeed())
+	g.Expect(n.DeleteRule(rules[0].Src, rules[0].Table, rules[0].Priority)).To(Succeed())
+
+	owned, err = n.ListOwnedRoutes()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(routeStrings(owned)).To(ConsistOf(routeStrings(routes[:2])))
+	ownedRules, err = n.ListOwnedRules()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(ownedRules).To(ConsistOf(rules[1:]))
+	exists, err := n.RuleExists(foreign, table, 30000)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(exists).To(BeFalse())
+}
+
+func TestAdoptRoutesAndRules(t *testing.T) {
+	enterTestNetworkNamespace(t)
+	g := NewWithT(t)
+	n := New()
+	table := 60
+
+	// Routes and rules added before they were tagged with the protocol, e.g. by a previous version of the provisioner
+	link, err := netlink.LinkByName(testLink)
+	g.Expect(err).ToNot(HaveOccurred())
+	routes := []Route{
+		{Network: mustParseCIDR(t, "10.0.0.0/16"), Gateway: net.ParseIP("192.168.1.254"), Device: testLink, Metric: 10000, Table: unix.RT_TABLE_MAIN},
+		{Network: mustParseCIDR(t, "fd00:2::/64"), Gateway: net.ParseIP("fd00:1::fe"), Device: testLink, Metric: 1024, Table: table},
+	}
+	for _, r := range routes {
+		g.Expect(netlink.RouteAdd(&netlink.Route{Dst: r.Network, Gw: r.Gateway, LinkIndex: link.Attrs().Index, Priority: r.Metric, Table: r.Table})).To(Succeed())
+	}
+	rules := []Rule{
+		{Src: mustParseCIDR(t, "10.244.6.0/24"), Table: table, Priority: 31000},
+		{Src: mustParseCIDR(t, "fd00:3::/64"), Table: table, Priority: 31000},
+	}
+	for _, r := range rules {
+		legacy := netlink.NewRule()
+		legacy.Family = int(FamilyOf(r.Src.IP))
+		legacy.Src = r.Src
+		legacy.Table = r.Table
+		legacy.Priority = r.Priority
+		g.Expect(netlink.RuleAdd(legacy)).To(Succeed())
+	}
+
+	// They aren't reported as existing, hence they're added again, which adopts them
+	for _, r := range routes {
+		exists, err := n.RouteExists(r.Network, r.Gateway, r.Device, &r.Table)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(exists).To(BeFalse(), r.Network.String())
+		g.Expect(n.AddRoute(r.Network, r.Gateway, r.Device, &r.Metric, &r.Table)).To(Succeed())
+		exists, err = n.RouteExists(r.Network, r.Gateway, r.Device, &r.Table)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(exists).To(BeTrue(), r.Network.String())
+		matching, err := matchingRoutes(r.Network, r.Gateway, r.Device, &r.Table)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(matching).To(HaveLen(1), r.Network.String())
+	}
+	for _, r := range rules {
+		exists, err := n.RuleExists(r.Src, r.Table, r.Priority)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(exists).To(BeFalse(), r.Src.String())
+		g.Expect(n.AddRule(r.Src, r.Table, r.Priority)).To(Succeed())
+		exists, err = n.RuleExists(r.Src, r.Table, r.Priority)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(exists).To(BeTrue(), r.Src.String())
+		matching, err := matchingRules(r.Src, r.Table, r.Priority)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(matching).To(HaveLen(1), r.Src.String())
+	}
+
+	// Once adopted, they're owned and removed like any other when they're dropped from the configuration
+	owned, err := n.ListOwnedRoutes()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(routeStrings(owned)).To(ConsistOf(routeStrings(routes)))
+	ownedRules, err := n.ListOwnedRules()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(ownedRules).To(ConsistOf(rules))
+	for _, r := range owned {
+		g.Expect(n.DeleteRouteFromTable(r.Network, r.Gateway, r.Device, &r.Table)).To(Succeed())
+	}
+	for _, r := range ownedRules {
+		g.Expect(n.DeleteRule(r.Src, r.Table, r.Priority)).To(Succeed())
+	}
+	for _, r := range routes {
+		matching, err := matchingRoutes(r.Network, r.Gateway, r.Device, &r.Table)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(matching).To(BeEmpty(), r.Network.String())
+	}
+	for _, r := range rules {
+		matching, err := matchingRules(r.Src, r.Table, r.Priority)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(matching).To(BeEmpty(), r.Src.String())
+	}
+}
+
+// routeStrings returns the routes in a form that doesn't depend on the length of the byte slices of their IPs
+func routeStrings(routes []Route) []string {
+	out := make([]string, 0, len(routes))
+	for _, r := range routes {
+		out = append(out, fmt.Sprintf("%s via %s dev %s metric %d table %d", r.Network, r.Gateway, r.Device, r.Metric, r.Table))
+	}
+	return out
+}
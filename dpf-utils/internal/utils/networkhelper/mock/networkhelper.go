@@ -0,0 +1,572 @@
+// /*
+// Copyright 2026 NVIDIA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// */
+//
+
+// Code generated by MockGen. DO NOT EDIT.
+// Source: types.go
+//
+// Generated by this command:
+//
+//	mockgen -copyright_file ../../../hack/boilerplate.go.txt -destination mock/networkhelper.go -source types.go
+//
+
+// Package mock_networkhelper is a generated GoMock package.
+package mock_networkhelper
+
+import (
+	net "net"
+	reflect "reflect"
+	time "time"
+
+	networkhelper "github.com/nvidia/ovn-kubernetes-components/internal/utils/networkhelper"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockNetworkHelper is a mock of NetworkHelper interface.
+type MockNetworkHelper struct {
+	ctrl     *gomock.Controller
+	recorder *MockNetworkHelperMockRecorder
+	isgomock struct{}
+}
+
+// MockNetworkHelperMockRecorder is the mock recorder for MockNetworkHelper.
+type MockNetworkHelperMockRecorder struct {
+	mock *MockNetworkHelper
+}
+
+// NewMockNetworkHelper creates a new mock instance.
+func NewMockNetworkHelper(ctrl *gomock.Controller) *MockNetworkHelper {
+	mock := &MockNetworkHelper{ctrl: ctrl}
+	mock.recorder = &MockNetworkHelperMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNetworkHelper) EXPECT() *MockNetworkHelperMockRecorder {
+	return m.recorder
+}
+
+// AddDummyLink mocks base method.
+func (m *MockNetworkHelper) AddDummyLink(link string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddDummyLink", link)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddDummyLink indicates an expected call of AddDummyLink.
+func (mr *MockNetworkHelperMockRecorder) AddDummyLink(link any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDummyLink", reflect.TypeOf((*MockNetworkHelper)(nil).AddDummyLink), link)
+}
+
+// AddRoute mocks base method.
+func (m *MockNetworkHelper) AddRoute(network *net.IPNet, gateway net.IP, device string, metric, table *int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddRoute", network, gateway, device, metric, table)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddRoute indicates an expected call of AddRoute.
+func (mr *MockNetworkHelperMockRecorder) AddRoute(network, gateway, device, metric, table any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRoute", reflect.TypeOf((*MockNetworkHelper)(nil).AddRoute), network, gateway, device, metric, table)
+}
+
+// AddRule mocks base method.
+func (m *MockNetworkHelper) AddRule(src *net.IPNet, table, priority int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddRule", src, table, priority)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddRule indicates an expected call of AddRule.
+func (mr *MockNetworkHelperMockRecorder) AddRule(src, table, priority any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRule", reflect.TypeOf((*MockNetworkHelper)(nil).AddRule), src, table, priority)
+}
+
+// DeleteLinkIPAddress mocks base method.
+func (m *MockNetworkHelper) DeleteLinkIPAddress(link string, ipNet *net.IPNet) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteLinkIPAddress", link, ipNet)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteLinkIPAddress indicates an expected call of DeleteLinkIPAddress.
+func (mr *MockNetworkHelperMockRecorder) DeleteLinkIPAddress(link, ipNet any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLinkIPAddress", reflect.TypeOf((*MockNetworkHelper)(nil).DeleteLinkIPAddress), link, ipNet)
+}
+
+// DeleteNeighbor mocks base method.
+func (m *MockNetworkHelper) DeleteNeighbor(ip net.IP, device string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteNeighbor", ip, device)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteNeighbor indicates an expected call of DeleteNeighbor.
+func (mr *MockNetworkHelperMockRecorder) DeleteNeighbor(ip, device any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteNeighbor", reflect.TypeOf((*MockNetworkHelper)(nil).DeleteNeighbor), ip, device)
+}
+
+// DeleteRoute mocks base method.
+func (m *MockNetworkHelper) DeleteRoute(network *net.IPNet, gateway net.IP, device string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRoute", network, gateway, device)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRoute indicates an expected call of DeleteRoute.
+func (mr *MockNetworkHelperMockRecorder) DeleteRoute(network, gateway, device any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRoute", reflect.TypeOf((*MockNetworkHelper)(nil).DeleteRoute), network, gateway, device)
+}
+
+// DeleteRouteFromTable mocks base method.
+func (m *MockNetworkHelper) DeleteRouteFromTable(network *net.IPNet, gateway net.IP, device string, table *int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRouteFromTable", network, gateway, device, table)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRouteFromTable indicates an expected call of DeleteRouteFromTable.
+func (mr *MockNetworkHelperMockRecorder) DeleteRouteFromTable(network, gateway, device, table any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRouteFromTable", reflect.TypeOf((*MockNetworkHelper)(nil).DeleteRouteFromTable), network, gateway, device, table)
+}
+
+// DeleteRule mocks base method.
+func (m *MockNetworkHelper) DeleteRule(src *net.IPNet, table, priority int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRule", src, table, priority)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRule indicates an expected call of DeleteRule.
+func (mr *MockNetworkHelperMockRecorder) DeleteRule(src, table, priority any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRule", reflect.TypeOf((*MockNetworkHelper)(nil).DeleteRule), src, table, priority)
+}
+
+// DummyLinkExists mocks base method.
+func (m *MockNetworkHelper) DummyLinkExists(link string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DummyLinkExists", link)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DummyLinkExists indicates an expected call of DummyLinkExists.
+func (mr *MockNetworkHelperMockRecorder) DummyLinkExists(link any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DummyLinkExists", reflect.TypeOf((*MockNetworkHelper)(nil).DummyLinkExists), link)
+}
+
+// GetDefaultRouteDevices mocks base method.
+func (m *MockNetworkHelper) GetDefaultRouteDevices(family networkhelper.Family) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDefaultRouteDevices", family)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDefaultRouteDevices indicates an expected call of GetDefaultRouteDevices.
+func (mr *MockNetworkHelperMockRecorder) GetDefaultRouteDevices(family any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDefaultRouteDevices", reflect.TypeOf((*MockNetworkHelper)(nil).GetDefaultRouteDevices), family)
+}
+
+// GetGateway mocks base method.
+func (m *MockNetworkHelper) GetGateway(network *net.IPNet) (net.IP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGateway", network)
+	ret0, _ := ret[0].(net.IP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGateway indicates an expected call of GetGateway.
+func (mr *MockNetworkHelperMockRecorder) GetGateway(network any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGateway", reflect.TypeOf((*MockNetworkHelper)(nil).GetGateway), network)
+}
+
+// GetHostPFMACAddressDPU mocks base method.
+func (m *MockNetworkHelper) GetHostPFMACAddressDPU(pfID string) (net.HardwareAddr, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHostPFMACAddressDPU", pfID)
+	ret0, _ := ret[0].(net.HardwareAddr)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHostPFMACAddressDPU indicates an expected call of GetHostPFMACAddressDPU.
+func (mr *MockNetworkHelperMockRecorder) GetHostPFMACAddressDPU(pfID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHostPFMACAddressDPU", reflect.TypeOf((*MockNetworkHelper)(nil).GetHostPFMACAddressDPU), pfID)
+}
+
+// GetLinkIPAddresses mocks base method.
+func (m *MockNetworkHelper) GetLinkIPAddresses(link string) ([]*net.IPNet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLinkIPAddresses", link)
+	ret0, _ := ret[0].([]*net.IPNet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLinkIPAddresses indicates an expected call of GetLinkIPAddresses.
+func (mr *MockNetworkHelperMockRecorder) GetLinkIPAddresses(link any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLinkIPAddresses", reflect.TypeOf((*MockNetworkHelper)(nil).GetLinkIPAddresses), link)
+}
+
+// GetLinkIPAddressesByFamily mocks base method.
+func (m *MockNetworkHelper) GetLinkIPAddressesByFamily(link string, family networkhelper.Family) ([]*net.IPNet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLinkIPAddressesByFamily", link, family)
+	ret0, _ := ret[0].([]*net.IPNet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLinkIPAddressesByFamily indicates an expected call of GetLinkIPAddressesByFamily.
+func (mr *MockNetworkHelperMockRecorder) GetLinkIPAddressesByFamily(link, family any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLinkIPAddressesByFamily", reflect.TypeOf((*MockNetworkHelper)(nil).GetLinkIPAddressesByFamily), link, family)
+}
+
+// GetLinkIndex mocks base method.
+func (m *MockNetworkHelper) GetLinkIndex(link string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLinkIndex", link)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLinkIndex indicates an expected call of GetLinkIndex.
+func (mr *MockNetworkHelperMockRecorder) GetLinkIndex(link any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLinkIndex", reflect.TypeOf((*MockNetworkHelper)(nil).GetLinkIndex), link)
+}
+
+// GetLinkMTU mocks base method.
+func (m *MockNetworkHelper) GetLinkMTU(link string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLinkMTU", link)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLinkMTU indicates an expected call of GetLinkMTU.
+func (mr *MockNetworkHelperMockRecorder) GetLinkMTU(link any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLinkMTU", reflect.TypeOf((*MockNetworkHelper)(nil).GetLinkMTU), link)
+}
+
+// GetPFRepresentorDPU mocks base method.
+func (m *MockNetworkHelper) GetPFRepresentorDPU(pfID string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPFRepresentorDPU", pfID)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPFRepresentorDPU indicates an expected call of GetPFRepresentorDPU.
+func (mr *MockNetworkHelperMockRecorder) GetPFRepresentorDPU(pfID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPFRepresentorDPU", reflect.TypeOf((*MockNetworkHelper)(nil).GetPFRepresentorDPU), pfID)
+}
+
+// GetUplinkRepresentor mocks base method.
+func (m *MockNetworkHelper) GetUplinkRepresentor(pciAddress string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUplinkRepresentor", pciAddress)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUplinkRepresentor indicates an expected call of GetUplinkRepresentor.
+func (mr *MockNetworkHelperMockRecorder) GetUplinkRepresentor(pciAddress any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUplinkRepresentor", reflect.TypeOf((*MockNetworkHelper)(nil).GetUplinkRepresentor), pciAddress)
+}
+
+// GetVFRepresentorDPU mocks base method.
+func (m *MockNetworkHelper) GetVFRepresentorDPU(pfID, vfIndex string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVFRepresentorDPU", pfID, vfIndex)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVFRepresentorDPU indicates an expected call of GetVFRepresentorDPU.
+func (mr *MockNetworkHelperMockRecorder) GetVFRepresentorDPU(pfID, vfIndex any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVFRepresentorDPU", reflect.TypeOf((*MockNetworkHelper)(nil).GetVFRepresentorDPU), pfID, vfIndex)
+}
+
+// LinkAdminUp mocks base method.
+func (m *MockNetworkHelper) LinkAdminUp(link string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkAdminUp", link)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LinkAdminUp indicates an expected call of LinkAdminUp.
+func (mr *MockNetworkHelperMockRecorder) LinkAdminUp(link any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkAdminUp", reflect.TypeOf((*MockNetworkHelper)(nil).LinkAdminUp), link)
+}
+
+// LinkExists mocks base method.
+func (m *MockNetworkHelper) LinkExists(link string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkExists", link)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LinkExists indicates an expected call of LinkExists.
+func (mr *MockNetworkHelperMockRecorder) LinkExists(link any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkExists", reflect.TypeOf((*MockNetworkHelper)(nil).LinkExists), link)
+}
+
+// LinkIPAddressExists mocks base method.
+func (m *MockNetworkHelper) LinkIPAddressExists(link string, ipNet *net.IPNet) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkIPAddressExists", link, ipNet)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LinkIPAddressExists indicates an expected call of LinkIPAddressExists.
+func (mr *MockNetworkHelperMockRecorder) LinkIPAddressExists(link, ipNet any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkIPAddressExists", reflect.TypeOf((*MockNetworkHelper)(nil).LinkIPAddressExists), link, ipNet)
+}
+
+// LinkOperUp mocks base method.
+func (m *MockNetworkHelper) LinkOperUp(link string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkOperUp", link)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LinkOperUp indicates an expected call of LinkOperUp.
+func (mr *MockNetworkHelperMockRecorder) LinkOperUp(link any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkOperUp", reflect.TypeOf((*MockNetworkHelper)(nil).LinkOperUp), link)
+}
+
+// ListOwnedRoutes mocks base method.
+func (m *MockNetworkHelper) ListOwnedRoutes() ([]networkhelper.Route, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOwnedRoutes")
+	ret0, _ := ret[0].([]networkhelper.Route)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOwnedRoutes indicates an expected call of ListOwnedRoutes.
+func (mr *MockNetworkHelperMockRecorder) ListOwnedRoutes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOwnedRoutes", reflect.TypeOf((*MockNetworkHelper)(nil).ListOwnedRoutes))
+}
+
+// ListOwnedRules mocks base method.
+func (m *MockNetworkHelper) ListOwnedRules() ([]networkhelper.Rule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOwnedRules")
+	ret0, _ := ret[0].([]networkhelper.Rule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOwnedRules indicates an expected call of ListOwnedRules.
+func (mr *MockNetworkHelperMockRecorder) ListOwnedRules() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOwnedRules", reflect.TypeOf((*MockNetworkHelper)(nil).ListOwnedRules))
+}
+
+// NeighborExists mocks base method.
+func (m *MockNetworkHelper) NeighborExists(ip net.IP, device string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NeighborExists", ip, device)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NeighborExists indicates an expected call of NeighborExists.
+func (mr *MockNetworkHelperMockRecorder) NeighborExists(ip, device any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NeighborExists", reflect.TypeOf((*MockNetworkHelper)(nil).NeighborExists), ip, device)
+}
+
+// Ping mocks base method.
+func (m *MockNetworkHelper) Ping(ip net.IP, size int, timeout time.Duration) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ip, size, timeout)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockNetworkHelperMockRecorder) Ping(ip, size, timeout any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockNetworkHelper)(nil).Ping), ip, size, timeout)
+}
+
+// ProbeNeighbor mocks base method.
+func (m *MockNetworkHelper) ProbeNeighbor(link string, ip net.IP, timeout time.Duration) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProbeNeighbor", link, ip, timeout)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ProbeNeighbor indicates an expected call of ProbeNeighbor.
+func (mr *MockNetworkHelperMockRecorder) ProbeNeighbor(link, ip, timeout any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProbeNeighbor", reflect.TypeOf((*MockNetworkHelper)(nil).ProbeNeighbor), link, ip, timeout)
+}
+
+// RenameLink mocks base method.
+func (m *MockNetworkHelper) RenameLink(link, newName string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenameLink", link, newName)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RenameLink indicates an expected call of RenameLink.
+func (mr *MockNetworkHelperMockRecorder) RenameLink(link, newName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenameLink", reflect.TypeOf((*MockNetworkHelper)(nil).RenameLink), link, newName)
+}
+
+// RouteExists mocks base method.
+func (m *MockNetworkHelper) RouteExists(network *net.IPNet, gateway net.IP, device string, table *int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RouteExists", network, gateway, device, table)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RouteExists indicates an expected call of RouteExists.
+func (mr *MockNetworkHelperMockRecorder) RouteExists(network, gateway, device, table any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RouteExists", reflect.TypeOf((*MockNetworkHelper)(nil).RouteExists), network, gateway, device, table)
+}
+
+// RuleExists mocks base method.
+func (m *MockNetworkHelper) RuleExists(src *net.IPNet, table, priority int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RuleExists", src, table, priority)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RuleExists indicates an expected call of RuleExists.
+func (mr *MockNetworkHelperMockRecorder) RuleExists(src, table, priority any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RuleExists", reflect.TypeOf((*MockNetworkHelper)(nil).RuleExists), src, table, priority)
+}
+
+// SetLinkDown mocks base method.
+func (m *MockNetworkHelper) SetLinkDown(link string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetLinkDown", link)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetLinkDown indicates an expected call of SetLinkDown.
+func (mr *MockNetworkHelperMockRecorder) SetLinkDown(link any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLinkDown", reflect.TypeOf((*MockNetworkHelper)(nil).SetLinkDown), link)
+}
+
+// SetLinkIPAddress mocks base method.
+func (m *MockNetworkHelper) SetLinkIPAddress(link string, ipNet *net.IPNet) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetLinkIPAddress", link, ipNet)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetLinkIPAddress indicates an expected call of SetLinkIPAddress.
+func (mr *MockNetworkHelperMockRecorder) SetLinkIPAddress(link, ipNet any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLinkIPAddress", reflect.TypeOf((*MockNetworkHelper)(nil).SetLinkIPAddress), link, ipNet)
+}
+
+// SetLinkMTU mocks base method.
+func (m *MockNetworkHelper) SetLinkMTU(link string, mtu int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetLinkMTU", link, mtu)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetLinkMTU indicates an expected call of SetLinkMTU.
+func (mr *MockNetworkHelperMockRecorder) SetLinkMTU(link, mtu any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLinkMTU", reflect.TypeOf((*MockNetworkHelper)(nil).SetLinkMTU), link, mtu)
+}
+
+// SetLinkUp mocks base method.
+func (m *MockNetworkHelper) SetLinkUp(link string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetLinkUp", link)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetLinkUp indicates an expected call of SetLinkUp.
+func (mr *MockNetworkHelperMockRecorder) SetLinkUp(link any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLinkUp", reflect.TypeOf((*MockNetworkHelper)(nil).SetLinkUp), link)
+}
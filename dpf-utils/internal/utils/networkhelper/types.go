@@ -0,0 +1,83 @@
+/*
+Copyright 2026 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networkhelper
+
+import (
+	"net"
+	"time"
+
+	"github.com/nvidia/doca-platform/pkg/utils/networkhelper"
+)
+
+// Protocol is the protocol the routes and rules added by the NetworkHelper are tagged with. It marks them as owned by
+// the NetworkHelper so that they can be listed and removed without touching the ones added by anyone else.
+const Protocol = 201
+
+// Route is a route owned by the NetworkHelper
+type Route struct {
+	Network *net.IPNet
+	Gateway net.IP
+	Device  string
+	Metric  int
+	Table   int
+}
+
+// Rule is a rule in the routing policy database owned by the NetworkHelper
+type Rule struct {
+	Src      *net.IPNet
+	Table    int
+	Priority int
+}
+
+//go:generate mockgen -copyright_file ../../../hack/boilerplate.go.txt -destination mock/networkhelper.go -source types.go
+
+// NetworkHelper is the doca-platform NetworkHelper made aware of IP families. The lookups that upstream restricts to
+// IPv4 (LinkIPAddressExists, RouteExists, GetGateway and RuleExists) use the family of their input instead. AddRoute
+// and AddRule tag what they add with Protocol, and RouteExists and RuleExists only report what is tagged so that the
+// matching routes and rules added before they were tagged are adopted by AddRoute and AddRule.
+type NetworkHelper interface {
+	networkhelper.NetworkHelper
+	// GetLinkIPAddressesByFamily returns the global unicast IP addresses of the given family of a link
+	GetLinkIPAddressesByFamily(link string, family Family) ([]*net.IPNet, error)
+	// GetDefaultRouteDevices returns the devices of the default routes of the given family in the main table, the one
+	// of the preferred route first
+	GetDefaultRouteDevices(family Family) ([]string, error)
+	// LinkAdminUp returns whether the administrative state of a link is "up"
+	LinkAdminUp(link string) (bool, error)
+	// LinkOperUp returns whether a link is operationally up, i.e. it's up and has a carrier
+	LinkOperUp(link string) (bool, error)
+	// GetLinkIndex returns the index of a link
+	GetLinkIndex(link string) (int, error)
+	// GetLinkMTU returns the MTU of a link
+	GetLinkMTU(link string) (int, error)
+	// SetLinkMTU sets the MTU of a link
+	SetLinkMTU(link string, mtu int) error
+	// ProbeNeighbor returns whether the given IP answers ARP (IPv4) or neighbor discovery (IPv6) on a link within the
+	// timeout
+	ProbeNeighbor(link string, ip net.IP, timeout time.Duration) (bool, error)
+	// Ping returns whether the given IP answers an ICMP echo request of the given size, IP header included, sent with
+	// the DF bit set within the timeout
+	Ping(ip net.IP, size int, timeout time.Duration) (bool, error)
+	// DeleteRouteFromTable deletes a route from the given table, the main table when nil
+	DeleteRouteFromTable(network *net.IPNet, gateway net.IP, device string, table *int) error
+	// DeleteRule deletes a rule from the routing policy database
+	DeleteRule(src *net.IPNet, table int, priority int) error
+	// ListOwnedRoutes returns the routes of all the IP families and tables that are tagged with Protocol
+	ListOwnedRoutes() ([]Route, error)
+	// ListOwnedRules returns the rules of all the IP families that are tagged with Protocol
+	ListOwnedRules() ([]Rule, error)
+}
@@ -27,6 +27,9 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/logging"
+
+	"k8s.io/klog/v2"
 	kexec "k8s.io/utils/exec"
 )
 
@@ -57,6 +60,7 @@ func newOvsClient(exec kexec.Interface) (OVSClient, error) {
 }
 
 func (c *ovsClient) runOVSVsctl(args ...string) (string, error) {
+	klog.V(4).InfoS("Running OVS command", logging.KeyOVSCommand, strings.Join(append([]string{ovsVsctl}, args...), " "))
 	cmd := c.exec.Command(c.ovsVsctlPath, args...)
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
@@ -77,6 +81,7 @@ func (c *ovsClient) runOVSAppctl(args ...string) (string, error) {
 	finalArgs := make([]string, 0, len(args)+2)
 	finalArgs = append(finalArgs, "-t", socketPath)
 	finalArgs = append(finalArgs, args...)
+	klog.V(4).InfoS("Running OVS command", logging.KeyOVSCommand, strings.Join(append([]string{ovsAppctl}, finalArgs...), " "))
 	cmd := c.exec.Command(c.ovsAppCtlPath, finalArgs...)
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
@@ -136,6 +141,16 @@ func (c *ovsClient) SetBridgeDataPathType(bridge string, bridgeType BridgeDataPa
 	return err
 }
 
+// GetBridgeDataPathType returns the datapath type of a bridge. It's empty when the bridge uses the default datapath
+// type, which is System.
+func (c *ovsClient) GetBridgeDataPathType(bridge string) (BridgeDataPathType, error) {
+	out, err := c.runOVSVsctl("get", "bridge", bridge, "datapath_type")
+	if err != nil {
+		return "", err
+	}
+	return BridgeDataPathType(unquoteOVSString(strings.TrimSpace(out))), nil
+}
+
 // SetBridgeMAC sets the MAC address for the bridge interface
 func (c *ovsClient) SetBridgeMAC(bridge string, mac net.HardwareAddr) error {
 	_, err := c.runOVSVsctl("set", "bridge", bridge, fmt.Sprintf("other-config:hwaddr=%s", mac.String()))
@@ -180,7 +195,7 @@ func (c *ovsClient) SetPatchPortPeer(port string, peer string) error {
 
 // SetOVNEncapIP sets the ovn-encap-ip external ID in the Open_vSwitch table in OVS
 func (c *ovsClient) SetOVNEncapIP(ip net.IP) error {
-	_, err := c.runOVSVsctl("set", "Open_vSwitch", ".", fmt.Sprintf("external_ids:ovn-encap-ip=%s", ip.String()))
+	_, err := c.runOVSVsctl("set", "Open_vSwitch", ".", "external_ids:ovn-encap-ip="+encapIPValue(ip))
 	return err
 }
 
@@ -218,6 +233,48 @@ func (c *ovsClient) GetSystemID() (string, error) {
 	return systemID, nil
 }
 
+// GetOpenVSwitchExternalIDs returns the external_ids of the Open_vSwitch table. Unlike the external IDs of ports and
+// interfaces, the keys and values are returned unquoted.
+func (c *ovsClient) GetOpenVSwitchExternalIDs() (map[string]string, error) {
+	return c.getOpenVSwitchMap("external_ids")
+}
+
+// GetOpenVSwitchOtherConfig returns the other_config of the Open_vSwitch table. The keys and values are returned
+// unquoted.
+func (c *ovsClient) GetOpenVSwitchOtherConfig() (map[string]string, error) {
+	return c.getOpenVSwitchMap("other_config")
+}
+
+// getOpenVSwitchMap returns a map column of the Open_vSwitch table with its keys and values unquoted
+func (c *ovsClient) getOpenVSwitchMap(column string) (map[string]string, error) {
+	out, err := c.runOVSVsctl("get", "Open_vSwitch", ".", column)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(out) == "" {
+		return nil, fmt.Errorf("error finding the %s in command output: %s", column, out)
+	}
+
+	rawMap, err := getExternalIDsAsMap(out)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]string, len(rawMap))
+	for key, value := range rawMap {
+		m[unquoteOVSString(key)] = unquoteOVSString(value)
+	}
+	return m, nil
+}
+
+// unquoteOVSString returns the given string without the quotes ovs-vsctl adds around strings that contain special
+// characters
+func unquoteOVSString(s string) string {
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		return unquoted
+	}
+	return s
+}
+
 // InterfaceToBridge returns the bridge an interface exists in
 func (c *ovsClient) InterfaceToBridge(iface string) (string, error) {
 	out, err := c.runOVSVsctl("iface-to-br", iface)
@@ -364,6 +421,11 @@ func (c *ovsClient) GetInterfacesWithPMDRXQueue() (map[string]interface{}, error
 		return nil, err
 	}
 
+	return parsePMDRXQueuePorts(out)
+}
+
+// parsePMDRXQueuePorts returns the ports found in the output of the dpif-netdev/pmd-rxq-show command
+func parsePMDRXQueuePorts(out string) (map[string]interface{}, error) {
 	ports := make(map[string]interface{})
 	outReader := strings.NewReader(out)
 	s := bufio.NewScanner(outReader)
@@ -393,3 +455,151 @@ func (c *ovsClient) GetInterfacesWithPMDRXQueue() (map[string]interface{}, error
 
 	return ports, nil
 }
+
+// Transaction returns a new Transaction that chains all the queued ovs-vsctl commands in a single invocation
+func (c *ovsClient) Transaction() Transaction {
+	return &ovsVsctlTransaction{client: c}
+}
+
+// ovsVsctlTransaction is a Transaction that relies on ovs-vsctl executing all the commands given in a single
+// invocation, separated by "--", as one OVSDB transaction
+type ovsVsctlTransaction struct {
+	client   *ovsClient
+	commands [][]string
+}
+
+// queue adds an ovs-vsctl command to the transaction
+func (t *ovsVsctlTransaction) queue(args ...string) {
+	t.commands = append(t.commands, args)
+}
+
+// SetBridgeDataPathType queues setting the datapath type of a bridge
+func (t *ovsVsctlTransaction) SetBridgeDataPathType(bridge string, bridgeType BridgeDataPathType) {
+	t.queue("set", "bridge", bridge, fmt.Sprintf("datapath_type=%s", bridgeType))
+}
+
+// SetBridgeMAC queues setting the MAC address for the bridge interface
+func (t *ovsVsctlTransaction) SetBridgeMAC(bridge string, mac net.HardwareAddr) {
+	t.queue("set", "bridge", bridge, fmt.Sprintf("other-config:hwaddr=%s", mac.String()))
+}
+
+// SetBridgeUplinkPort queues setting the bridge-uplink external ID of the bridge
+func (t *ovsVsctlTransaction) SetBridgeUplinkPort(bridge string, port string) {
+	t.queue("br-set-external-id", bridge, "bridge-uplink", port)
+}
+
+// SetBridgeHostToServicePort queues setting the host-to-service external ID of the bridge
+func (t *ovsVsctlTransaction) SetBridgeHostToServicePort(bridge string, port string) {
+	t.queue("br-set-external-id", bridge, "host-to-service-interface", port)
+}
+
+// SetPortExternalID queues setting an external ID of a port. It overrides if already exists.
+func (t *ovsVsctlTransaction) SetPortExternalID(port string, key string, value string) {
+	t.queue("set", "port", port, fmt.Sprintf("external_ids:%s=%s", key, value))
+}
+
+// SetPortType queues setting the type of a port
+func (t *ovsVsctlTransaction) SetPortType(port string, portType PortType) {
+	t.queue("set", "interface", port, fmt.Sprintf("type=%s", portType))
+}
+
+// SetPatchPortPeer queues setting the peer for a patch port
+func (t *ovsVsctlTransaction) SetPatchPortPeer(port string, peer string) {
+	t.queue("set", "interface", port, fmt.Sprintf("options:peer=%s", peer))
+}
+
+// SetInterfaceExternalID queues setting an external ID of an interface. It overrides if already exists.
+func (t *ovsVsctlTransaction) SetInterfaceExternalID(iface string, key string, value string) {
+	t.queue("set", "interface", iface, fmt.Sprintf("external_ids:%s=%s", key, value))
+}
+
+// SetOVNEncapIP queues setting the ovn-encap-ip external ID in the Open_vSwitch table
+func (t *ovsVsctlTransaction) SetOVNEncapIP(ip net.IP) {
+	t.queue("set", "Open_vSwitch", ".", "external_ids:ovn-encap-ip="+encapIPValue(ip))
+}
+
+// SetOVNEncapIPs queues setting the ovn-encap-ip external ID in the Open_vSwitch table to a list of IPs
+func (t *ovsVsctlTransaction) SetOVNEncapIPs(ips []net.IP) {
+	t.queue("set", "Open_vSwitch", ".", "external_ids:ovn-encap-ip="+encapIPsValue(ips))
+}
+
+// SetDOCAInit queues setting the doca-init other_config in the Open_vSwitch table
+func (t *ovsVsctlTransaction) SetDOCAInit(enable bool) {
+	t.queue("set", "Open_vSwitch", ".", fmt.Sprintf("other_config:doca-init=%t", enable))
+}
+
+// SetOpenVSwitchOtherConfig queues setting an other_config key in the Open_vSwitch table. The value is quoted as
+// ovs-vsctl would otherwise split values such as dpdk-socket-mem on their commas.
+func (t *ovsVsctlTransaction) SetOpenVSwitchOtherConfig(key string, value string) {
+	t.queue("set", "Open_vSwitch", ".", fmt.Sprintf("other_config:%s=%s", key, strconv.Quote(value)))
+}
+
+// RemoveOpenVSwitchOtherConfig queues removing an other_config key from the Open_vSwitch table
+func (t *ovsVsctlTransaction) RemoveOpenVSwitchOtherConfig(key string) {
+	t.queue("remove", "Open_vSwitch", ".", "other_config", key)
+}
+
+// SetKubernetesHostNodeName queues setting the host-k8s-nodename external ID in the Open_vSwitch table
+func (t *ovsVsctlTransaction) SetKubernetesHostNodeName(name string) {
+	t.queue("set", "Open_vSwitch", ".", fmt.Sprintf("external_ids:host-k8s-nodename=%s", name))
+}
+
+// SetHostName queues setting the hostname external ID in the Open_vSwitch table
+func (t *ovsVsctlTransaction) SetHostName(name string) {
+	t.queue("set", "Open_vSwitch", ".", fmt.Sprintf("external_ids:hostname=%s", name))
+}
+
+// RemoveOVNEncapIP queues removing the ovn-encap-ip external ID from the Open_vSwitch table
+func (t *ovsVsctlTransaction) RemoveOVNEncapIP() {
+	t.queue("remove", "Open_vSwitch", ".", "external_ids", "ovn-encap-ip")
+}
+
+// RemoveKubernetesHostNodeName queues removing the host-k8s-nodename external ID from the Open_vSwitch table
+func (t *ovsVsctlTransaction) RemoveKubernetesHostNodeName() {
+	t.queue("remove", "Open_vSwitch", ".", "external_ids", "host-k8s-nodename")
+}
+
+// RemoveHostName queues removing the hostname external ID from the Open_vSwitch table
+func (t *ovsVsctlTransaction) RemoveHostName() {
+	t.queue("remove", "Open_vSwitch", ".", "external_ids", "hostname")
+}
+
+// Commit applies all the queued changes in a single ovs-vsctl invocation
+func (t *ovsVsctlTransaction) Commit() error {
+	if len(t.commands) == 0 {
+		return nil
+	}
+
+	args := []string{}
+	for i, command := range t.commands {
+		if i > 0 {
+			args = append(args, "--")
+		}
+		args = append(args, command...)
+	}
+
+	_, err := t.client.runOVSVsctl(args...)
+	if err != nil {
+		return err
+	}
+	t.commands = nil
+	return nil
+}
+
+// encapIPValue returns the ovs-vsctl representation of an ovn-encap-ip. IPv6 addresses are quoted as ovs-vsctl would
+// otherwise split them on their colons.
+func encapIPValue(ip net.IP) string {
+	if ip.To4() != nil {
+		return ip.String()
+	}
+	return strconv.Quote(ip.String())
+}
+
+// encapIPsValue returns the ovs-vsctl representation of a list of ovn-encap-ip. A list is quoted as ovs-vsctl would
+// otherwise split it on its commas.
+func encapIPsValue(ips []net.IP) string {
+	if len(ips) == 1 {
+		return encapIPValue(ips[0])
+	}
+	return strconv.Quote(joinIPs(ips))
+}
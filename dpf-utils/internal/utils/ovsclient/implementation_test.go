@@ -17,6 +17,7 @@ limitations under the License.
 package ovsclient
 
 import (
+	"net"
 	"os"
 	"path/filepath"
 	"testing"
@@ -293,6 +294,118 @@ func TestGetInterfaceExternalIDs(t *testing.T) {
 	}
 }
 
+func TestGetOpenVSwitchExternalIDs(t *testing.T) {
+	g := NewWithT(t)
+	cases := []struct {
+		msg               string
+		fakeCommandOutput string
+		expectedOutput    map[string]string
+		expectedError     bool
+	}{
+		{
+			msg:               "usual command output",
+			fakeCommandOutput: `{hostname=host1, "ovn-encap-ip"="192.168.1.1,192.168.2.1", system-id="8f5b4c1e"}`,
+			expectedOutput: map[string]string{
+				"hostname":     "host1",
+				"ovn-encap-ip": "192.168.1.1,192.168.2.1",
+				"system-id":    "8f5b4c1e",
+			},
+		},
+		{
+			msg:               "empty external ids",
+			fakeCommandOutput: "{}",
+			expectedOutput:    make(map[string]string),
+		},
+		{
+			msg:               "empty command output",
+			fakeCommandOutput: "",
+			expectedError:     true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.msg, func(t *testing.T) {
+			fakeExec := &kexecTesting.FakeExec{LookPathFunc: func(s string) (string, error) { return s, nil }}
+			c, err := newOvsClient(fakeExec)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+				g.Expect(cmd).To(Equal("ovs-vsctl"))
+				g.Expect(args).To(Equal([]string{"get", "Open_vSwitch", ".", "external_ids"}))
+				return kexec.New().Command("echo", tt.fakeCommandOutput)
+			}))
+
+			output, err := c.GetOpenVSwitchExternalIDs()
+			if tt.expectedError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(output).To(BeComparableTo(tt.expectedOutput))
+		})
+	}
+}
+
+func TestGetOpenVSwitchOtherConfig(t *testing.T) {
+	g := NewWithT(t)
+	fakeExec := &kexecTesting.FakeExec{LookPathFunc: func(s string) (string, error) { return s, nil }}
+	c, err := newOvsClient(fakeExec)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+		g.Expect(cmd).To(Equal("ovs-vsctl"))
+		g.Expect(args).To(Equal([]string{"get", "Open_vSwitch", ".", "other_config"}))
+		return kexec.New().Command("echo", `{doca-init="true", dpdk-socket-mem="1024,1024", hw-offload="true"}`)
+	}))
+
+	output, err := c.GetOpenVSwitchOtherConfig()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(output).To(BeComparableTo(map[string]string{
+		"doca-init":       "true",
+		"dpdk-socket-mem": "1024,1024",
+		"hw-offload":      "true",
+	}))
+}
+
+func TestGetBridgeDataPathType(t *testing.T) {
+	g := NewWithT(t)
+	cases := []struct {
+		msg               string
+		fakeCommandOutput string
+		expectedOutput    BridgeDataPathType
+	}{
+		{
+			msg:               "netdev",
+			fakeCommandOutput: "netdev",
+			expectedOutput:    NetDev,
+		},
+		{
+			msg:               "default datapath type",
+			fakeCommandOutput: `""`,
+			expectedOutput:    "",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.msg, func(t *testing.T) {
+			fakeExec := &kexecTesting.FakeExec{LookPathFunc: func(s string) (string, error) { return s, nil }}
+			c, err := newOvsClient(fakeExec)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+				g.Expect(cmd).To(Equal("ovs-vsctl"))
+				g.Expect(args).To(Equal([]string{"get", "bridge", "br-ovn", "datapath_type"}))
+				return kexec.New().Command("echo", tt.fakeCommandOutput)
+			}))
+
+			output, err := c.GetBridgeDataPathType("br-ovn")
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(output).To(Equal(tt.expectedOutput))
+		})
+	}
+}
+
 func TestAddPortWithMetadata(t *testing.T) {
 	g := NewWithT(t)
 	cases := []struct {
@@ -416,6 +529,122 @@ func TestAddPortWithMetadata(t *testing.T) {
 	}
 }
 
+func TestTransaction(t *testing.T) {
+	g := NewWithT(t)
+	cases := []struct {
+		msg                 string
+		queue               func(txn Transaction)
+		expectedCommandArgs []string
+	}{
+		{
+			msg:   "empty",
+			queue: func(txn Transaction) {},
+		},
+		{
+			msg: "open vswitch table",
+			queue: func(txn Transaction) {
+				txn.SetKubernetesHostNodeName("host1")
+				txn.SetHostName("host1")
+				txn.SetOVNEncapIP(net.ParseIP("192.168.1.1"))
+			},
+			expectedCommandArgs: []string{
+				"set", "Open_vSwitch", ".", "external_ids:host-k8s-nodename=host1",
+				"--", "set", "Open_vSwitch", ".", "external_ids:hostname=host1",
+				"--", "set", "Open_vSwitch", ".", "external_ids:ovn-encap-ip=192.168.1.1",
+			},
+		},
+		{
+			msg: "remove open vswitch external ids",
+			queue: func(txn Transaction) {
+				txn.RemoveOVNEncapIP()
+				txn.RemoveKubernetesHostNodeName()
+				txn.RemoveHostName()
+			},
+			expectedCommandArgs: []string{
+				"remove", "Open_vSwitch", ".", "external_ids", "ovn-encap-ip",
+				"--", "remove", "Open_vSwitch", ".", "external_ids", "host-k8s-nodename",
+				"--", "remove", "Open_vSwitch", ".", "external_ids", "hostname",
+			},
+		},
+		{
+			msg: "ipv6 encap ip",
+			queue: func(txn Transaction) {
+				txn.SetOVNEncapIP(net.ParseIP("fd00::1"))
+			},
+			expectedCommandArgs: []string{
+				"set", "Open_vSwitch", ".", `external_ids:ovn-encap-ip="fd00::1"`,
+			},
+		},
+		{
+			msg: "multiple encap ips",
+			queue: func(txn Transaction) {
+				txn.SetOVNEncapIPs([]net.IP{net.ParseIP("192.168.1.1"), net.ParseIP("192.168.2.1")})
+			},
+			expectedCommandArgs: []string{
+				"set", "Open_vSwitch", ".", `external_ids:ovn-encap-ip="192.168.1.1,192.168.2.1"`,
+			},
+		},
+		{
+			msg: "single encap ip in a list",
+			queue: func(txn Transaction) {
+				txn.SetOVNEncapIPs([]net.IP{net.ParseIP("192.168.1.1")})
+			},
+			expectedCommandArgs: []string{
+				"set", "Open_vSwitch", ".", "external_ids:ovn-encap-ip=192.168.1.1",
+			},
+		},
+		{
+			msg: "open vswitch other config",
+			queue: func(txn Transaction) {
+				txn.SetOpenVSwitchOtherConfig("dpdk-socket-mem", "1024,1024")
+				txn.RemoveOpenVSwitchOtherConfig("pmd-cpu-mask")
+			},
+			expectedCommandArgs: []string{
+				"set", "Open_vSwitch", ".", `other_config:dpdk-socket-mem="1024,1024"`,
+				"--", "remove", "Open_vSwitch", ".", "other_config", "pmd-cpu-mask",
+			},
+		},
+		{
+			msg: "bridge, port and interface",
+			queue: func(txn Transaction) {
+				txn.SetBridgeDataPathType("br-ovn", NetDev)
+				txn.SetBridgeUplinkPort("br-ovn", "p0")
+				txn.SetPortExternalID("p0", "key", "value")
+				txn.SetPortType("p0", DPDK)
+				txn.SetInterfaceExternalID("p0", "iface-id", "p0")
+			},
+			expectedCommandArgs: []string{
+				"set", "bridge", "br-ovn", "datapath_type=netdev",
+				"--", "br-set-external-id", "br-ovn", "bridge-uplink", "p0",
+				"--", "set", "port", "p0", "external_ids:key=value",
+				"--", "set", "interface", "p0", "type=dpdk",
+				"--", "set", "interface", "p0", "external_ids:iface-id=p0",
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.msg, func(t *testing.T) {
+			fakeExec := &kexecTesting.FakeExec{LookPathFunc: func(s string) (string, error) { return s, nil }}
+			c, err := newOvsClient(fakeExec)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			if tt.expectedCommandArgs != nil {
+				fakeExec.CommandScript = append(fakeExec.CommandScript, kexecTesting.FakeCommandAction(func(cmd string, args ...string) kexec.Cmd {
+					g.Expect(cmd).To(Equal("ovs-vsctl"))
+					g.Expect(args).To(Equal(tt.expectedCommandArgs))
+					return kexec.New().Command("echo")
+				}))
+			}
+
+			txn := c.Transaction()
+			tt.queue(txn)
+			g.Expect(txn.Commit()).To(Succeed())
+			g.Expect(fakeExec.CommandCalls).To(Equal(len(fakeExec.CommandScript)))
+		})
+	}
+}
+
 func TestListInterfaces(t *testing.T) {
 	g := NewWithT(t)
 	cases := []struct {
@@ -0,0 +1,238 @@
+/*
+Copyright 2024 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ovsclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// rpcDialTimeout is the timeout used when connecting to an OVS unix socket
+const rpcDialTimeout = 5 * time.Second
+
+// rpcCallTimeout is the maximum time a single JSON-RPC call is allowed to take
+const rpcCallTimeout = 30 * time.Second
+
+// errRPCClosed is returned for calls that are pending when the connection is closed
+var errRPCClosed = errors.New("json-rpc connection closed")
+
+// rpcRequest is a JSON-RPC 1.0 request as used by OVSDB (RFC 7047) and the OVS unixctl interface
+type rpcRequest struct {
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+	ID     uint64        `json:"id"`
+}
+
+// rpcReply is a JSON-RPC 1.0 reply to a request initiated by the server
+type rpcReply struct {
+	Result json.RawMessage `json:"result"`
+	ID     json.RawMessage `json:"id"`
+}
+
+// rpcInboundMessage is the decoded form of a message received from the server
+type rpcInboundMessage struct {
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+	Result json.RawMessage   `json:"result"`
+	Error  json.RawMessage   `json:"error"`
+	ID     json.RawMessage   `json:"id"`
+}
+
+// rpcResponse is the outcome of a call that is handed over to the waiting caller
+type rpcResponse struct {
+	result json.RawMessage
+	err    error
+}
+
+// rpcNotificationHandler is called for every notification (message without id) received from the server
+type rpcNotificationHandler func(method string, params []json.RawMessage)
+
+// rpcClient is a minimal JSON-RPC 1.0 client over a unix socket. Messages are JSON objects written back to back
+// on the stream, without any additional framing.
+type rpcClient struct {
+	conn    net.Conn
+	encoder *json.Encoder
+
+	writeLock sync.Mutex
+	lock      sync.Mutex
+	nextID    uint64
+	pending   map[uint64]chan rpcResponse
+	closed    bool
+	done      chan struct{}
+
+	notificationHandler rpcNotificationHandler
+}
+
+// dialRPC connects to the given unix socket and starts reading responses from it
+func dialRPC(socketPath string, handler rpcNotificationHandler) (*rpcClient, error) {
+	conn, err := net.DialTimeout("unix", socketPath, rpcDialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("error while connecting to %s: %w", socketPath, err)
+	}
+
+	c := &rpcClient{
+		conn:                conn,
+		encoder:             json.NewEncoder(conn),
+		pending:             make(map[uint64]chan rpcResponse),
+		done:                make(chan struct{}),
+		notificationHandler: handler,
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+// call issues a request and waits for its response. The raw result is returned so that the caller can decode it in
+// the type it expects.
+func (c *rpcClient) call(method string, params ...interface{}) (json.RawMessage, error) {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return nil, errRPCClosed
+	}
+	id := c.nextID
+	c.nextID++
+	ch := make(chan rpcResponse, 1)
+	c.pending[id] = ch
+	c.lock.Unlock()
+
+	if err := c.send(rpcRequest{Method: method, Params: params, ID: id}); err != nil {
+		c.lock.Lock()
+		delete(c.pending, id)
+		c.lock.Unlock()
+		c.Close()
+		return nil, fmt.Errorf("error while sending %s request: %w", method, err)
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.err != nil {
+			return nil, fmt.Errorf("%s request failed: %w", method, resp.err)
+		}
+		return resp.result, nil
+	case <-time.After(rpcCallTimeout):
+		c.lock.Lock()
+		delete(c.pending, id)
+		c.lock.Unlock()
+		return nil, fmt.Errorf("%s request timed out after %s", method, rpcCallTimeout)
+	}
+}
+
+// send writes a message on the connection
+func (c *rpcClient) send(msg interface{}) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	return c.encoder.Encode(msg)
+}
+
+// Done returns a channel that is closed when the connection is closed
+func (c *rpcClient) Done() <-chan struct{} {
+	return c.done
+}
+
+// Closed returns whether the connection is closed
+func (c *rpcClient) Closed() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.closed
+}
+
+// Close closes the connection and fails all the pending calls
+func (c *rpcClient) Close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	_ = c.conn.Close()
+	for id, ch := range c.pending {
+		ch <- rpcResponse{err: errRPCClosed}
+		delete(c.pending, id)
+	}
+	close(c.done)
+}
+
+// readLoop reads messages from the connection until it's closed and dispatches them
+func (c *rpcClient) readLoop() {
+	defer c.Close()
+	decoder := json.NewDecoder(c.conn)
+	for {
+		var msg rpcInboundMessage
+		if err := decoder.Decode(&msg); err != nil {
+			return
+		}
+
+		isRequest := msg.Method != ""
+		hasID := len(msg.ID) > 0 && string(msg.ID) != "null"
+		switch {
+		case isRequest && hasID:
+			c.handleRequest(msg)
+		case isRequest:
+			if c.notificationHandler != nil {
+				c.notificationHandler(msg.Method, msg.Params)
+			}
+		default:
+			c.handleResponse(msg)
+		}
+	}
+}
+
+// handleRequest replies to requests initiated by the server. The only one the OVS servers send is the echo keepalive,
+// anything else is ignored.
+func (c *rpcClient) handleRequest(msg rpcInboundMessage) {
+	if msg.Method != "echo" {
+		return
+	}
+	if msg.Params == nil {
+		msg.Params = []json.RawMessage{}
+	}
+	result, err := json.Marshal(msg.Params)
+	if err != nil {
+		return
+	}
+	_ = c.send(rpcReply{Result: result, ID: msg.ID})
+}
+
+// handleResponse hands a response over to the caller waiting for it
+func (c *rpcClient) handleResponse(msg rpcInboundMessage) {
+	var id uint64
+	if err := json.Unmarshal(msg.ID, &id); err != nil {
+		return
+	}
+
+	c.lock.Lock()
+	ch, ok := c.pending[id]
+	delete(c.pending, id)
+	c.lock.Unlock()
+	if !ok {
+		return
+	}
+
+	if len(msg.Error) > 0 && string(msg.Error) != "null" {
+		ch <- rpcResponse{err: fmt.Errorf("server returned error: %s", string(msg.Error))}
+		return
+	}
+	ch <- rpcResponse{result: msg.Result}
+}
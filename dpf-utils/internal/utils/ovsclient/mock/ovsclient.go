@@ -20,7 +20,7 @@
 //
 // Generated by this command:
 //
-//	mockgen -copyright_file /Users/hareeshp/dev/dpf/doca-platform-foundation/hack/boilerplate.go.txt -destination mock/ovsclient.go -source types.go
+//	mockgen -copyright_file ../../../hack/boilerplate.go.txt -destination mock/ovsclient.go -source types.go
 //
 
 // Package mock_ovsclient is a generated GoMock package.
@@ -143,6 +143,21 @@ func (mr *MockOVSClientMockRecorder) DeletePort(port any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePort", reflect.TypeOf((*MockOVSClient)(nil).DeletePort), port)
 }
 
+// GetBridgeDataPathType mocks base method.
+func (m *MockOVSClient) GetBridgeDataPathType(bridge string) (ovsclient.BridgeDataPathType, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBridgeDataPathType", bridge)
+	ret0, _ := ret[0].(ovsclient.BridgeDataPathType)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBridgeDataPathType indicates an expected call of GetBridgeDataPathType.
+func (mr *MockOVSClientMockRecorder) GetBridgeDataPathType(bridge any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBridgeDataPathType", reflect.TypeOf((*MockOVSClient)(nil).GetBridgeDataPathType), bridge)
+}
+
 // GetInterfaceExternalIDs mocks base method.
 func (m *MockOVSClient) GetInterfaceExternalIDs(iface string) (map[string]string, error) {
 	m.ctrl.T.Helper()
@@ -188,19 +203,34 @@ func (mr *MockOVSClientMockRecorder) GetInterfacesWithPMDRXQueue() *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInterfacesWithPMDRXQueue", reflect.TypeOf((*MockOVSClient)(nil).GetInterfacesWithPMDRXQueue))
 }
 
-// GetSystemID mocks base method.
-func (m *MockOVSClient) GetSystemID() (string, error) {
+// GetOpenVSwitchExternalIDs mocks base method.
+func (m *MockOVSClient) GetOpenVSwitchExternalIDs() (map[string]string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetSystemID")
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "GetOpenVSwitchExternalIDs")
+	ret0, _ := ret[0].(map[string]string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetSystemID indicates an expected call of GetSystemID.
-func (mr *MockOVSClientMockRecorder) GetSystemID() *gomock.Call {
+// GetOpenVSwitchExternalIDs indicates an expected call of GetOpenVSwitchExternalIDs.
+func (mr *MockOVSClientMockRecorder) GetOpenVSwitchExternalIDs() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSystemID", reflect.TypeOf((*MockOVSClient)(nil).GetSystemID))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOpenVSwitchExternalIDs", reflect.TypeOf((*MockOVSClient)(nil).GetOpenVSwitchExternalIDs))
+}
+
+// GetOpenVSwitchOtherConfig mocks base method.
+func (m *MockOVSClient) GetOpenVSwitchOtherConfig() (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOpenVSwitchOtherConfig")
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOpenVSwitchOtherConfig indicates an expected call of GetOpenVSwitchOtherConfig.
+func (mr *MockOVSClientMockRecorder) GetOpenVSwitchOtherConfig() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOpenVSwitchOtherConfig", reflect.TypeOf((*MockOVSClient)(nil).GetOpenVSwitchOtherConfig))
 }
 
 // GetPortExternalIDs mocks base method.
@@ -218,6 +248,21 @@ func (mr *MockOVSClientMockRecorder) GetPortExternalIDs(port any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPortExternalIDs", reflect.TypeOf((*MockOVSClient)(nil).GetPortExternalIDs), port)
 }
 
+// GetSystemID mocks base method.
+func (m *MockOVSClient) GetSystemID() (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSystemID")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSystemID indicates an expected call of GetSystemID.
+func (mr *MockOVSClientMockRecorder) GetSystemID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSystemID", reflect.TypeOf((*MockOVSClient)(nil).GetSystemID))
+}
+
 // InterfaceToBridge mocks base method.
 func (m *MockOVSClient) InterfaceToBridge(iface string) (string, error) {
 	m.ctrl.T.Helper()
@@ -401,3 +446,271 @@ func (mr *MockOVSClientMockRecorder) SetPortType(port, portType any) *gomock.Cal
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPortType", reflect.TypeOf((*MockOVSClient)(nil).SetPortType), port, portType)
 }
+
+// Transaction mocks base method.
+func (m *MockOVSClient) Transaction() ovsclient.Transaction {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transaction")
+	ret0, _ := ret[0].(ovsclient.Transaction)
+	return ret0
+}
+
+// Transaction indicates an expected call of Transaction.
+func (mr *MockOVSClientMockRecorder) Transaction() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transaction", reflect.TypeOf((*MockOVSClient)(nil).Transaction))
+}
+
+// MockTransaction is a mock of Transaction interface.
+type MockTransaction struct {
+	ctrl     *gomock.Controller
+	recorder *MockTransactionMockRecorder
+	isgomock struct{}
+}
+
+// MockTransactionMockRecorder is the mock recorder for MockTransaction.
+type MockTransactionMockRecorder struct {
+	mock *MockTransaction
+}
+
+// NewMockTransaction creates a new mock instance.
+func NewMockTransaction(ctrl *gomock.Controller) *MockTransaction {
+	mock := &MockTransaction{ctrl: ctrl}
+	mock.recorder = &MockTransactionMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTransaction) EXPECT() *MockTransactionMockRecorder {
+	return m.recorder
+}
+
+// Commit mocks base method.
+func (m *MockTransaction) Commit() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Commit")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Commit indicates an expected call of Commit.
+func (mr *MockTransactionMockRecorder) Commit() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Commit", reflect.TypeOf((*MockTransaction)(nil).Commit))
+}
+
+// RemoveHostName mocks base method.
+func (m *MockTransaction) RemoveHostName() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RemoveHostName")
+}
+
+// RemoveHostName indicates an expected call of RemoveHostName.
+func (mr *MockTransactionMockRecorder) RemoveHostName() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveHostName", reflect.TypeOf((*MockTransaction)(nil).RemoveHostName))
+}
+
+// RemoveKubernetesHostNodeName mocks base method.
+func (m *MockTransaction) RemoveKubernetesHostNodeName() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RemoveKubernetesHostNodeName")
+}
+
+// RemoveKubernetesHostNodeName indicates an expected call of RemoveKubernetesHostNodeName.
+func (mr *MockTransactionMockRecorder) RemoveKubernetesHostNodeName() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveKubernetesHostNodeName", reflect.TypeOf((*MockTransaction)(nil).RemoveKubernetesHostNodeName))
+}
+
+// RemoveOVNEncapIP mocks base method.
+func (m *MockTransaction) RemoveOVNEncapIP() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RemoveOVNEncapIP")
+}
+
+// RemoveOVNEncapIP indicates an expected call of RemoveOVNEncapIP.
+func (mr *MockTransactionMockRecorder) RemoveOVNEncapIP() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveOVNEncapIP", reflect.TypeOf((*MockTransaction)(nil).RemoveOVNEncapIP))
+}
+
+// RemoveOpenVSwitchOtherConfig mocks base method.
+func (m *MockTransaction) RemoveOpenVSwitchOtherConfig(key string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RemoveOpenVSwitchOtherConfig", key)
+}
+
+// RemoveOpenVSwitchOtherConfig indicates an expected call of RemoveOpenVSwitchOtherConfig.
+func (mr *MockTransactionMockRecorder) RemoveOpenVSwitchOtherConfig(key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveOpenVSwitchOtherConfig", reflect.TypeOf((*MockTransaction)(nil).RemoveOpenVSwitchOtherConfig), key)
+}
+
+// SetBridgeDataPathType mocks base method.
+func (m *MockTransaction) SetBridgeDataPathType(bridge string, bridgeType ovsclient.BridgeDataPathType) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetBridgeDataPathType", bridge, bridgeType)
+}
+
+// SetBridgeDataPathType indicates an expected call of SetBridgeDataPathType.
+func (mr *MockTransactionMockRecorder) SetBridgeDataPathType(bridge, bridgeType any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBridgeDataPathType", reflect.TypeOf((*MockTransaction)(nil).SetBridgeDataPathType), bridge, bridgeType)
+}
+
+// SetBridgeHostToServicePort mocks base method.
+func (m *MockTransaction) SetBridgeHostToServicePort(bridge, port string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetBridgeHostToServicePort", bridge, port)
+}
+
+// SetBridgeHostToServicePort indicates an expected call of SetBridgeHostToServicePort.
+func (mr *MockTransactionMockRecorder) SetBridgeHostToServicePort(bridge, port any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBridgeHostToServicePort", reflect.TypeOf((*MockTransaction)(nil).SetBridgeHostToServicePort), bridge, port)
+}
+
+// SetBridgeMAC mocks base method.
+func (m *MockTransaction) SetBridgeMAC(bridge string, mac net.HardwareAddr) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetBridgeMAC", bridge, mac)
+}
+
+// SetBridgeMAC indicates an expected call of SetBridgeMAC.
+func (mr *MockTransactionMockRecorder) SetBridgeMAC(bridge, mac any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBridgeMAC", reflect.TypeOf((*MockTransaction)(nil).SetBridgeMAC), bridge, mac)
+}
+
+// SetBridgeUplinkPort mocks base method.
+func (m *MockTransaction) SetBridgeUplinkPort(bridge, port string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetBridgeUplinkPort", bridge, port)
+}
+
+// SetBridgeUplinkPort indicates an expected call of SetBridgeUplinkPort.
+func (mr *MockTransactionMockRecorder) SetBridgeUplinkPort(bridge, port any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBridgeUplinkPort", reflect.TypeOf((*MockTransaction)(nil).SetBridgeUplinkPort), bridge, port)
+}
+
+// SetDOCAInit mocks base method.
+func (m *MockTransaction) SetDOCAInit(enable bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetDOCAInit", enable)
+}
+
+// SetDOCAInit indicates an expected call of SetDOCAInit.
+func (mr *MockTransactionMockRecorder) SetDOCAInit(enable any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDOCAInit", reflect.TypeOf((*MockTransaction)(nil).SetDOCAInit), enable)
+}
+
+// SetHostName mocks base method.
+func (m *MockTransaction) SetHostName(name string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetHostName", name)
+}
+
+// SetHostName indicates an expected call of SetHostName.
+func (mr *MockTransactionMockRecorder) SetHostName(name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHostName", reflect.TypeOf((*MockTransaction)(nil).SetHostName), name)
+}
+
+// SetInterfaceExternalID mocks base method.
+func (m *MockTransaction) SetInterfaceExternalID(iface, key, value string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetInterfaceExternalID", iface, key, value)
+}
+
+// SetInterfaceExternalID indicates an expected call of SetInterfaceExternalID.
+func (mr *MockTransactionMockRecorder) SetInterfaceExternalID(iface, key, value any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetInterfaceExternalID", reflect.TypeOf((*MockTransaction)(nil).SetInterfaceExternalID), iface, key, value)
+}
+
+// SetKubernetesHostNodeName mocks base method.
+func (m *MockTransaction) SetKubernetesHostNodeName(name string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetKubernetesHostNodeName", name)
+}
+
+// SetKubernetesHostNodeName indicates an expected call of SetKubernetesHostNodeName.
+func (mr *MockTransactionMockRecorder) SetKubernetesHostNodeName(name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetKubernetesHostNodeName", reflect.TypeOf((*MockTransaction)(nil).SetKubernetesHostNodeName), name)
+}
+
+// SetOVNEncapIP mocks base method.
+func (m *MockTransaction) SetOVNEncapIP(ip net.IP) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetOVNEncapIP", ip)
+}
+
+// SetOVNEncapIP indicates an expected call of SetOVNEncapIP.
+func (mr *MockTransactionMockRecorder) SetOVNEncapIP(ip any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetOVNEncapIP", reflect.TypeOf((*MockTransaction)(nil).SetOVNEncapIP), ip)
+}
+
+// SetOVNEncapIPs mocks base method.
+func (m *MockTransaction) SetOVNEncapIPs(ips []net.IP) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetOVNEncapIPs", ips)
+}
+
+// SetOVNEncapIPs indicates an expected call of SetOVNEncapIPs.
+func (mr *MockTransactionMockRecorder) SetOVNEncapIPs(ips any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetOVNEncapIPs", reflect.TypeOf((*MockTransaction)(nil).SetOVNEncapIPs), ips)
+}
+
+// SetOpenVSwitchOtherConfig mocks base method.
+func (m *MockTransaction) SetOpenVSwitchOtherConfig(key, value string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetOpenVSwitchOtherConfig", key, value)
+}
+
+// SetOpenVSwitchOtherConfig indicates an expected call of SetOpenVSwitchOtherConfig.
+func (mr *MockTransactionMockRecorder) SetOpenVSwitchOtherConfig(key, value any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetOpenVSwitchOtherConfig", reflect.TypeOf((*MockTransaction)(nil).SetOpenVSwitchOtherConfig), key, value)
+}
+
+// SetPatchPortPeer mocks base method.
+func (m *MockTransaction) SetPatchPortPeer(port, peer string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPatchPortPeer", port, peer)
+}
+
+// SetPatchPortPeer indicates an expected call of SetPatchPortPeer.
+func (mr *MockTransactionMockRecorder) SetPatchPortPeer(port, peer any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPatchPortPeer", reflect.TypeOf((*MockTransaction)(nil).SetPatchPortPeer), port, peer)
+}
+
+// SetPortExternalID mocks base method.
+func (m *MockTransaction) SetPortExternalID(port, key, value string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPortExternalID", port, key, value)
+}
+
+// SetPortExternalID indicates an expected call of SetPortExternalID.
+func (mr *MockTransactionMockRecorder) SetPortExternalID(port, key, value any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPortExternalID", reflect.TypeOf((*MockTransaction)(nil).SetPortExternalID), port, key, value)
+}
+
+// SetPortType mocks base method.
+func (m *MockTransaction) SetPortType(port string, portType ovsclient.PortType) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPortType", port, portType)
+}
+
+// SetPortType indicates an expected call of SetPortType.
+func (mr *MockTransactionMockRecorder) SetPortType(port, portType any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPortType", reflect.TypeOf((*MockTransaction)(nil).SetPortType), port, portType)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2024 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ovsclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// openVSwitchMonitorID is the id of the monitor registered on the Open_vSwitch table. The server sends it back on every
+// update notification.
+const openVSwitchMonitorID = "open-vswitch-row"
+
+// OpenVSwitchMonitor watches the external_ids and other_config columns of the Open_vSwitch table via an OVSDB monitor.
+// It talks to ovsdb-server directly, regardless of the backend the OVSClient uses.
+type OpenVSwitchMonitor struct {
+	dbSocketPath string
+}
+
+// NewOpenVSwitchMonitor creates an OpenVSwitchMonitor for the ovsdb-server running under the given file system root
+func NewOpenVSwitchMonitor(fileSystemRoot string) *OpenVSwitchMonitor {
+	return &OpenVSwitchMonitor{
+		dbSocketPath: filepath.Join(fileSystemRoot, ovsDBSocketPath),
+	}
+}
+
+// Run registers the monitor and calls notify every time the Open_vSwitch row changes. It blocks until the context is
+// cancelled, in which case it returns nil, or until the connection to ovsdb-server breaks.
+func (m *OpenVSwitchMonitor) Run(ctx context.Context, notify func(reason string)) error {
+	handler := func(method string, params []json.RawMessage) {
+		if method != "update" || len(params) != 2 {
+			return
+		}
+		var id string
+		if err := json.Unmarshal(params[0], &id); err != nil || id != openVSwitchMonitorID {
+			return
+		}
+		notify("Open_vSwitch table changed")
+	}
+
+	rpc, err := dialRPC(m.dbSocketPath, handler)
+	if err != nil {
+		return err
+	}
+	defer rpc.Close()
+
+	requests := map[string]interface{}{
+		openVSwitchTable: map[string]interface{}{
+			"columns": []string{"external_ids", "other_config"},
+			// The initial contents are not a change, so there is no need to receive them
+			"select": map[string]bool{"initial": false, "insert": true, "delete": true, "modify": true},
+		},
+	}
+	if _, err := rpc.call("monitor", openVSwitchDatabase, openVSwitchMonitorID, requests); err != nil {
+		return fmt.Errorf("error while registering Open_vSwitch monitor: %w", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-rpc.Done():
+		return errors.New("connection to ovsdb-server closed")
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ovsclient
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestOpenVSwitchMonitor(t *testing.T) {
+	g := NewWithT(t)
+	tmpDir := newTestFileSystemRoot(t)
+	m := NewOpenVSwitchMonitor(tmpDir)
+
+	g.Expect(os.MkdirAll(filepath.Dir(m.dbSocketPath), 0755)).To(Succeed())
+	l, err := net.Listen("unix", m.dbSocketPath)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer l.Close()
+
+	monitorRequests := make(chan fakeJSONRPCRequest, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		decoder := json.NewDecoder(conn)
+		encoder := json.NewEncoder(conn)
+
+		var req fakeJSONRPCRequest
+		if err := decoder.Decode(&req); err != nil {
+			return
+		}
+		monitorRequests <- req
+		_ = encoder.Encode(map[string]interface{}{"result": map[string]interface{}{}, "error": nil, "id": req.ID})
+		// An update for another monitor must be ignored
+		_ = encoder.Encode(map[string]interface{}{"method": "update", "params": []interface{}{"other", map[string]interface{}{}}, "id": nil})
+		_ = encoder.Encode(map[string]interface{}{"method": "update", "params": []interface{}{openVSwitchMonitorID, map[string]interface{}{}}, "id": nil})
+		// Wait for the client to go away
+		_ = decoder.Decode(&req)
+	}()
+
+	notifications := make(chan string, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- m.Run(ctx, func(reason string) { notifications <- reason })
+	}()
+
+	var req fakeJSONRPCRequest
+	g.Eventually(monitorRequests).Should(Receive(&req))
+	g.Expect(req.Method).To(Equal("monitor"))
+	g.Expect(req.Params).To(HaveLen(3))
+	g.Expect(toJSONObject(t, string(req.Params[2]))).To(BeComparableTo(toJSONObject(t, `{
+		"Open_vSwitch": {
+			"columns": ["external_ids", "other_config"],
+			"select": {"initial": false, "insert": true, "delete": true, "modify": true}
+		}
+	}`)))
+
+	g.Eventually(notifications).Should(Receive(Equal("Open_vSwitch table changed")))
+	g.Consistently(notifications).ShouldNot(Receive())
+
+	cancel()
+	g.Eventually(done).Should(Receive(BeNil()))
+}
+
+func TestOpenVSwitchMonitorNoServer(t *testing.T) {
+	g := NewWithT(t)
+	m := NewOpenVSwitchMonitor(newTestFileSystemRoot(t))
+
+	err := m.Run(context.Background(), func(string) {})
+	g.Expect(err).To(HaveOccurred())
+}
@@ -16,9 +16,42 @@ limitations under the License.
 
 package ovsclient
 
-import kexec "k8s.io/utils/exec"
+import (
+	"fmt"
+	"net"
+	"strings"
 
-// New creates a new OVSClient
-func New(exec kexec.Interface) (OVSClient, error) {
-	return newOvsClient(exec)
+	kexec "k8s.io/utils/exec"
+)
+
+// Backend is the mechanism the OVSClient uses to talk to OVS
+type Backend string
+
+const (
+	// VsctlBackend forks the ovs-vsctl and ovs-appctl utilities for every operation
+	VsctlBackend Backend = "vsctl"
+	// OVSDBBackend talks OVSDB JSON-RPC directly over the ovsdb-server and ovs-vswitchd unix sockets
+	OVSDBBackend Backend = "ovsdb"
+)
+
+// New creates a new OVSClient that uses the given backend
+func New(backend Backend, exec kexec.Interface) (OVSClient, error) {
+	switch backend {
+	case VsctlBackend, "":
+		return newOvsClient(exec)
+	case OVSDBBackend:
+		return newOVSDBClient("")
+	default:
+		return nil, fmt.Errorf("unknown OVS client backend %q", backend)
+	}
+}
+
+// joinIPs returns the comma separated list of the given IPs, the format of the ovn-encap-ip external ID when
+// ovn-controller has to use several of them
+func joinIPs(ips []net.IP) string {
+	values := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		values = append(values, ip.String())
+	}
+	return strings.Join(values, ",")
 }
@@ -0,0 +1,436 @@
+/*
+Copyright 2024 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ovsclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// This file contains the subset of the OVSDB wire format (RFC 7047) that is needed to manage the Open_vSwitch
+// database, together with typed representations of the rows we operate on.
+
+const (
+	// openVSwitchDatabase is the name of the database served by ovsdb-server on the OVS db.sock
+	openVSwitchDatabase = "Open_vSwitch"
+
+	openVSwitchTable = "Open_vSwitch"
+	bridgeTable      = "Bridge"
+	portTable        = "Port"
+	interfaceTable   = "Interface"
+	controllerTable  = "Controller"
+)
+
+// ovsdbUUID is an OVSDB uuid or named-uuid atom
+type ovsdbUUID struct {
+	// UUID is the actual UUID of a row
+	UUID string
+	// Named is the named-uuid that refers to a row inserted in the same transaction
+	Named string
+}
+
+// MarshalJSON implements json.Marshaler
+func (u ovsdbUUID) MarshalJSON() ([]byte, error) {
+	if u.Named != "" {
+		return json.Marshal([]string{"named-uuid", u.Named})
+	}
+	return json.Marshal([]string{"uuid", u.UUID})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (u *ovsdbUUID) UnmarshalJSON(data []byte) error {
+	var pair []string
+	if err := json.Unmarshal(data, &pair); err != nil {
+		return fmt.Errorf("error while decoding uuid %s: %w", string(data), err)
+	}
+	if len(pair) != 2 || (pair[0] != "uuid" && pair[0] != "named-uuid") {
+		return fmt.Errorf("unexpected uuid notation %s", string(data))
+	}
+	if pair[0] == "named-uuid" {
+		u.Named = pair[1]
+		return nil
+	}
+	u.UUID = pair[1]
+	return nil
+}
+
+// namedUUID returns a reference to a row inserted with the given uuid-name in the same transaction
+func namedUUID(name string) ovsdbUUID {
+	return ovsdbUUID{Named: name}
+}
+
+// ovsdbSet is an OVSDB set. A set with exactly one element may be encoded on the wire as the bare atom.
+type ovsdbSet []interface{}
+
+// MarshalJSON implements json.Marshaler
+func (s ovsdbSet) MarshalJSON() ([]byte, error) {
+	elems := []interface{}(s)
+	if elems == nil {
+		elems = []interface{}{}
+	}
+	return json.Marshal([]interface{}{"set", elems})
+}
+
+// decodeOVSDBSet decodes a set column into its elements which are kept in their raw form
+func decodeOVSDBSet(data []byte) ([]json.RawMessage, error) {
+	var tagged []json.RawMessage
+	if err := json.Unmarshal(data, &tagged); err == nil && len(tagged) == 2 {
+		var tag string
+		if err := json.Unmarshal(tagged[0], &tag); err == nil && tag == "set" {
+			var elems []json.RawMessage
+			if err := json.Unmarshal(tagged[1], &elems); err != nil {
+				return nil, fmt.Errorf("error while decoding set %s: %w", string(data), err)
+			}
+			return elems, nil
+		}
+	}
+	// A set with a single element is represented by the element itself
+	return []json.RawMessage{data}, nil
+}
+
+// ovsdbMap is an OVSDB map with string keys and values, e.g. external_ids, other_config or options
+type ovsdbMap map[string]string
+
+// MarshalJSON implements json.Marshaler
+func (m ovsdbMap) MarshalJSON() ([]byte, error) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	// Sort to keep requests deterministic
+	sort.Strings(keys)
+	pairs := make([][]string, 0, len(m))
+	for _, k := range keys {
+		pairs = append(pairs, []string{k, m[k]})
+	}
+	return json.Marshal([]interface{}{"map", pairs})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (m *ovsdbMap) UnmarshalJSON(data []byte) error {
+	var tagged []json.RawMessage
+	if err := json.Unmarshal(data, &tagged); err != nil || len(tagged) != 2 {
+		return fmt.Errorf("unexpected map notation %s", string(data))
+	}
+	var tag string
+	if err := json.Unmarshal(tagged[0], &tag); err != nil || tag != "map" {
+		return fmt.Errorf("unexpected map notation %s", string(data))
+	}
+	var pairs [][]string
+	if err := json.Unmarshal(tagged[1], &pairs); err != nil {
+		return fmt.Errorf("error while decoding map %s: %w", string(data), err)
+	}
+	out := make(ovsdbMap, len(pairs))
+	for _, pair := range pairs {
+		if len(pair) != 2 {
+			return fmt.Errorf("unexpected map pair %v", pair)
+		}
+		out[pair[0]] = pair[1]
+	}
+	*m = out
+	return nil
+}
+
+// ovsdbUUIDSet is a set of uuid references, e.g. the ports of a bridge
+type ovsdbUUIDSet []ovsdbUUID
+
+// MarshalJSON implements json.Marshaler
+func (s ovsdbUUIDSet) MarshalJSON() ([]byte, error) {
+	elems := make(ovsdbSet, 0, len(s))
+	for _, u := range s {
+		elems = append(elems, u)
+	}
+	return elems.MarshalJSON()
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *ovsdbUUIDSet) UnmarshalJSON(data []byte) error {
+	elems, err := decodeOVSDBSet(data)
+	if err != nil {
+		return err
+	}
+	out := make(ovsdbUUIDSet, 0, len(elems))
+	for _, elem := range elems {
+		var u ovsdbUUID
+		if err := json.Unmarshal(elem, &u); err != nil {
+			return err
+		}
+		out = append(out, u)
+	}
+	*s = out
+	return nil
+}
+
+// ovsdbOptionalInt is a set of at most one integer, e.g. the ofport of an interface
+type ovsdbOptionalInt struct {
+	Value *int
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (o *ovsdbOptionalInt) UnmarshalJSON(data []byte) error {
+	elems, err := decodeOVSDBSet(data)
+	if err != nil {
+		return err
+	}
+	switch len(elems) {
+	case 0:
+		o.Value = nil
+	case 1:
+		var v int
+		if err := json.Unmarshal(elems[0], &v); err != nil {
+			return fmt.Errorf("error while decoding integer %s: %w", string(elems[0]), err)
+		}
+		o.Value = &v
+	default:
+		return fmt.Errorf("expected at most one integer, got %s", string(data))
+	}
+	return nil
+}
+
+// openVSwitchRow is a row of the Open_vSwitch table
+type openVSwitchRow struct {
+	UUID        ovsdbUUID    `json:"_uuid"`
+	Bridges     ovsdbUUIDSet `json:"bridges"`
+	ExternalIDs ovsdbMap     `json:"external_ids"`
+	OtherConfig ovsdbMap     `json:"other_config"`
+}
+
+// bridgeRow is a row of the Bridge table
+type bridgeRow struct {
+	UUID         ovsdbUUID    `json:"_uuid"`
+	Name         string       `json:"name"`
+	Ports        ovsdbUUIDSet `json:"ports"`
+	DatapathType string       `json:"datapath_type"`
+	ExternalIDs  ovsdbMap     `json:"external_ids"`
+	OtherConfig  ovsdbMap     `json:"other_config"`
+}
+
+// portRow is a row of the Port table
+type portRow struct {
+	UUID        ovsdbUUID    `json:"_uuid"`
+	Name        string       `json:"name"`
+	Interfaces  ovsdbUUIDSet `json:"interfaces"`
+	ExternalIDs ovsdbMap     `json:"external_ids"`
+}
+
+// interfaceRow is a row of the Interface table
+type interfaceRow struct {
+	UUID          ovsdbUUID        `json:"_uuid"`
+	Name          string           `json:"name"`
+	Type          string           `json:"type"`
+	OFPort        ovsdbOptionalInt `json:"ofport"`
+	OFPortRequest ovsdbOptionalInt `json:"ofport_request"`
+	ExternalIDs   ovsdbMap         `json:"external_ids"`
+	Options       ovsdbMap         `json:"options"`
+}
+
+// ovsdbCondition is a where clause condition, encoded as [column, function, value]
+type ovsdbCondition [3]interface{}
+
+// conditionEquals returns a condition that matches rows where column equals value
+func conditionEquals(column string, value interface{}) ovsdbCondition {
+	return ovsdbCondition{column, "==", value}
+}
+
+// conditionIncludes returns a condition that matches rows where the set column includes value
+func conditionIncludes(column string, value interface{}) ovsdbCondition {
+	return ovsdbCondition{column, "includes", value}
+}
+
+// ovsdbMutation is a mutation, encoded as [column, mutator, value]
+type ovsdbMutation [3]interface{}
+
+// mutationInsert returns a mutation that inserts value (a set or a map) into column
+func mutationInsert(column string, value interface{}) ovsdbMutation {
+	return ovsdbMutation{column, "insert", value}
+}
+
+// mutationDelete returns a mutation that deletes value (a set, a map or a set of map keys) from column
+func mutationDelete(column string, value interface{}) ovsdbMutation {
+	return ovsdbMutation{column, "delete", value}
+}
+
+// mutationsSetMapKey returns the mutations that set key to value in a map column, overriding any existing value. The
+// OVSDB insert mutator leaves existing keys untouched, hence the key is deleted first.
+func mutationsSetMapKey(column string, key string, value string) []ovsdbMutation {
+	return []ovsdbMutation{
+		mutationDelete(column, ovsdbSet{key}),
+		mutationInsert(column, ovsdbMap{key: value}),
+	}
+}
+
+// ovsdbOperation is a single operation of a transact request
+type ovsdbOperation struct {
+	Op        string                 `json:"op"`
+	Table     string                 `json:"table,omitempty"`
+	Where     []ovsdbCondition       `json:"where,omitempty"`
+	Row       map[string]interface{} `json:"row,omitempty"`
+	Rows      []interface{}          `json:"rows,omitempty"`
+	Columns   []string               `json:"columns,omitempty"`
+	Mutations []ovsdbMutation        `json:"mutations,omitempty"`
+	UUIDName  string                 `json:"uuid-name,omitempty"`
+	Until     string                 `json:"until,omitempty"`
+	Timeout   *int                   `json:"timeout,omitempty"`
+	Comment   string                 `json:"comment,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler. The where clause must always be sent for the operations that expect it,
+// even when it matches all the rows.
+func (o ovsdbOperation) MarshalJSON() ([]byte, error) {
+	type plain ovsdbOperation
+	raw, err := json.Marshal(plain(o))
+	if err != nil {
+		return nil, err
+	}
+	switch o.Op {
+	case "select", "update", "mutate", "delete", "wait":
+	default:
+		return raw, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	if _, ok := fields["where"]; !ok {
+		fields["where"] = json.RawMessage("[]")
+	}
+	if _, ok := fields["rows"]; !ok && o.Op == "wait" {
+		fields["rows"] = json.RawMessage("[]")
+	}
+	return json.Marshal(fields)
+}
+
+// ovsdbOperationResult is the result of a single operation of a transact request
+type ovsdbOperationResult struct {
+	Count   int               `json:"count"`
+	UUID    *ovsdbUUID        `json:"uuid"`
+	Rows    []json.RawMessage `json:"rows"`
+	Error   string            `json:"error"`
+	Details string            `json:"details"`
+}
+
+// opSelect returns a select operation
+func opSelect(table string, where []ovsdbCondition, columns ...string) ovsdbOperation {
+	return ovsdbOperation{Op: "select", Table: table, Where: where, Columns: columns}
+}
+
+// opInsert returns an insert operation whose row can be referenced via uuidName in the same transaction
+func opInsert(table string, uuidName string, row map[string]interface{}) ovsdbOperation {
+	return ovsdbOperation{Op: "insert", Table: table, UUIDName: uuidName, Row: row}
+}
+
+// opUpdate returns an update operation
+func opUpdate(table string, where []ovsdbCondition, row map[string]interface{}) ovsdbOperation {
+	return ovsdbOperation{Op: "update", Table: table, Where: where, Row: row}
+}
+
+// opMutate returns a mutate operation
+func opMutate(table string, where []ovsdbCondition, mutations ...ovsdbMutation) ovsdbOperation {
+	return ovsdbOperation{Op: "mutate", Table: table, Where: where, Mutations: mutations}
+}
+
+// opWaitNotExists returns a wait operation that fails the transaction immediately if any row matches where
+func opWaitNotExists(table string, where []ovsdbCondition) ovsdbOperation {
+	timeout := 0
+	return ovsdbOperation{Op: "wait", Table: table, Where: where, Columns: []string{"_uuid"}, Until: "==", Rows: []interface{}{}, Timeout: &timeout}
+}
+
+// opWaitExists returns a wait operation that fails the transaction immediately if no row matches where
+func opWaitExists(table string, where []ovsdbCondition) ovsdbOperation {
+	timeout := 0
+	return ovsdbOperation{Op: "wait", Table: table, Where: where, Columns: []string{"_uuid"}, Until: "!=", Rows: []interface{}{}, Timeout: &timeout}
+}
+
+// opComment returns a comment operation that is logged by ovsdb-server
+func opComment(comment string) ovsdbOperation {
+	return ovsdbOperation{Op: "comment", Comment: comment}
+}
+
+// ovsdbTransactionError is returned when the server rejects a transaction
+type ovsdbTransactionError struct {
+	// Index is the index of the failing operation
+	Index int
+	// Op is the failing operation
+	Op string
+	// Err is the error reported by ovsdb-server
+	Err string
+	// Details is the error detail reported by ovsdb-server
+	Details string
+}
+
+// Error implements the error interface
+func (e *ovsdbTransactionError) Error() string {
+	msg := fmt.Sprintf("ovsdb operation %d (%s) failed: %s", e.Index, e.Op, e.Err)
+	if e.Details != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Details)
+	}
+	return msg
+}
+
+// parseTransactResult decodes the result of a transact request and returns an error if any operation failed
+func parseTransactResult(ops []ovsdbOperation, raw json.RawMessage) ([]ovsdbOperationResult, error) {
+	var results []ovsdbOperationResult
+	if err := json.Unmarshal(raw, &results); err != nil {
+		return nil, fmt.Errorf("error while decoding transact result: %w", err)
+	}
+
+	// The server may return one more result than operations to report a commit error
+	for i, r := range results {
+		if r.Error == "" {
+			continue
+		}
+		op := "commit"
+		if i < len(ops) {
+			op = ops[i].Op
+		}
+		return results, &ovsdbTransactionError{Index: i, Op: op, Err: r.Error, Details: r.Details}
+	}
+
+	if len(results) < len(ops) {
+		return results, errors.New("ovsdb transaction returned fewer results than operations")
+	}
+
+	return results, nil
+}
+
+// decodeRows decodes the rows returned by a select operation into out, which must be a pointer to a slice of rows
+func decodeRows(rows []json.RawMessage, out interface{}) error {
+	raw, err := json.Marshal(rows)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("error while decoding rows: %w", err)
+	}
+	return nil
+}
+
+// describeOperations returns a short human readable description of a list of operations, used in error messages
+func describeOperations(ops []ovsdbOperation) string {
+	parts := make([]string, 0, len(ops))
+	for _, op := range ops {
+		if op.Op == "comment" {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s %s", op.Op, op.Table))
+	}
+	return strings.Join(parts, ", ")
+}
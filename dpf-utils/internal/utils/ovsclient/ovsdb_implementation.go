@@ -0,0 +1,524 @@
+/*
+Copyright 2024 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ovsclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/nvidia/ovn-kubernetes-components/internal/utils/logging"
+
+	"k8s.io/klog/v2"
+)
+
+// ovsDBSocketPath is the unix socket on which ovsdb-server serves the Open_vSwitch database
+const ovsDBSocketPath = "/var/run/openvswitch/db.sock"
+
+// ovsdbClient is an OVSClient that talks to ovsdb-server and ovs-vswitchd via JSON-RPC over their unix sockets
+// instead of forking the OVS utilities.
+type ovsdbClient struct {
+	dbSocketPath   string
+	fileSystemRoot string
+
+	lock sync.Mutex
+	rpc  *rpcClient
+}
+
+// newOVSDBClient creates an OVSClient that uses the OVSDB JSON-RPC protocol. The connection is established lazily and
+// re-established on the next call if it breaks.
+func newOVSDBClient(fileSystemRoot string) (OVSClient, error) {
+	return &ovsdbClient{
+		dbSocketPath:   filepath.Join(fileSystemRoot, ovsDBSocketPath),
+		fileSystemRoot: fileSystemRoot,
+	}, nil
+}
+
+// connection returns the connection to ovsdb-server, connecting if needed
+func (c *ovsdbClient) connection() (*rpcClient, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.rpc != nil && !c.rpc.Closed() {
+		return c.rpc, nil
+	}
+	rpc, err := dialRPC(c.dbSocketPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.rpc = rpc
+	return rpc, nil
+}
+
+// transact runs the given operations in a single OVSDB transaction
+func (c *ovsdbClient) transact(ops ...ovsdbOperation) ([]ovsdbOperationResult, error) {
+	rpc, err := c.connection()
+	if err != nil {
+		return nil, err
+	}
+
+	params := make([]interface{}, 0, len(ops)+1)
+	params = append(params, openVSwitchDatabase)
+	for _, op := range ops {
+		params = append(params, op)
+	}
+
+	if klogV := klog.V(4); klogV.Enabled() {
+		klogV.InfoS("Running OVS command", logging.KeyOVSCommand, "transact "+describeOperations(ops))
+	}
+	raw, err := rpc.call("transact", params...)
+	if err != nil {
+		return nil, fmt.Errorf("error while running ovsdb transaction [%s]: %w", describeOperations(ops), err)
+	}
+
+	results, err := parseTransactResult(ops, raw)
+	if err != nil {
+		return nil, fmt.Errorf("error while running ovsdb transaction [%s]: %w", describeOperations(ops), err)
+	}
+	return results, nil
+}
+
+// selectRows selects the rows of table matching where and decodes them into out
+func (c *ovsdbClient) selectRows(table string, where []ovsdbCondition, out interface{}, columns ...string) error {
+	results, err := c.transact(opSelect(table, where, columns...))
+	if err != nil {
+		return err
+	}
+	return decodeRows(results[0].Rows, out)
+}
+
+// getPort returns the port with the given name or nil if it doesn't exist
+func (c *ovsdbClient) getPort(name string) (*portRow, error) {
+	var rows []portRow
+	if err := c.selectRows(portTable, []ovsdbCondition{conditionEquals("name", name)}, &rows); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return &rows[0], nil
+}
+
+// getInterface returns the interface with the given name or an error if it doesn't exist
+func (c *ovsdbClient) getInterface(name string) (*interfaceRow, error) {
+	var rows []interfaceRow
+	if err := c.selectRows(interfaceTable, []ovsdbCondition{conditionEquals("name", name)}, &rows); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no row %s in table Interface", name)
+	}
+	return &rows[0], nil
+}
+
+// bridgeOfPort returns the bridge the given port is attached to
+func (c *ovsdbClient) bridgeOfPort(port *portRow) (*bridgeRow, error) {
+	var rows []bridgeRow
+	if err := c.selectRows(bridgeTable, []ovsdbCondition{conditionIncludes("ports", port.UUID)}, &rows); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("port %s is not attached to any bridge", port.Name)
+	}
+	return &rows[0], nil
+}
+
+// BridgeExists checks if a bridge exists
+func (c *ovsdbClient) BridgeExists(name string) (bool, error) {
+	var rows []bridgeRow
+	if err := c.selectRows(bridgeTable, []ovsdbCondition{conditionEquals("name", name)}, &rows, "name"); err != nil {
+		return false, err
+	}
+	return len(rows) > 0, nil
+}
+
+// AddBridgeIfNotExists adds a bridge if it doesn't exist
+func (c *ovsdbClient) AddBridgeIfNotExists(name string) error {
+	exists, err := c.BridgeExists(name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	// Mimic ovs-vsctl add-br which creates the bridge together with its local internal port
+	_, err = c.transact(
+		opWaitNotExists(bridgeTable, []ovsdbCondition{conditionEquals("name", name)}),
+		opInsert(interfaceTable, "iface", map[string]interface{}{"name": name, "type": string(Internal)}),
+		opInsert(portTable, "port", map[string]interface{}{"name": name, "interfaces": namedUUID("iface")}),
+		opInsert(bridgeTable, "bridge", map[string]interface{}{"name": name, "ports": namedUUID("port")}),
+		opMutate(openVSwitchTable, nil, mutationInsert("bridges", ovsdbSet{namedUUID("bridge")})),
+		opComment(fmt.Sprintf("add-br %s", name)),
+	)
+	return err
+}
+
+// DeleteBridgeIfExists deletes a bridge if it exists
+func (c *ovsdbClient) DeleteBridgeIfExists(name string) error {
+	var rows []bridgeRow
+	if err := c.selectRows(bridgeTable, []ovsdbCondition{conditionEquals("name", name)}, &rows, "_uuid"); err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	// Bridge, Port and Interface are not root tables, so ovsdb-server garbage collects the bridge together with its
+	// ports and interfaces once it's no longer referenced.
+	_, err := c.transact(
+		opMutate(openVSwitchTable, nil, mutationDelete("bridges", ovsdbSet{rows[0].UUID})),
+		opComment(fmt.Sprintf("del-br %s", name)),
+	)
+	return err
+}
+
+// SetBridgeDataPathType sets the datapath type of a bridge
+func (c *ovsdbClient) SetBridgeDataPathType(bridge string, bridgeType BridgeDataPathType) error {
+	t := c.newTransaction()
+	t.SetBridgeDataPathType(bridge, bridgeType)
+	return t.Commit()
+}
+
+// GetBridgeDataPathType returns the datapath type of a bridge. It's empty when the bridge uses the default datapath
+// type, which is System.
+func (c *ovsdbClient) GetBridgeDataPathType(bridge string) (BridgeDataPathType, error) {
+	var rows []bridgeRow
+	if err := c.selectRows(bridgeTable, []ovsdbCondition{conditionEquals("name", bridge)}, &rows, "datapath_type"); err != nil {
+		return "", err
+	}
+	if len(rows) == 0 {
+		return "", fmt.Errorf("no bridge named %s", bridge)
+	}
+	return BridgeDataPathType(rows[0].DatapathType), nil
+}
+
+// SetBridgeMAC sets the MAC address for the bridge interface
+func (c *ovsdbClient) SetBridgeMAC(bridge string, mac net.HardwareAddr) error {
+	t := c.newTransaction()
+	t.SetBridgeMAC(bridge, mac)
+	return t.Commit()
+}
+
+// SetBridgeUplinkPort sets the bridge-uplink external ID of the bridge. It overrides if already exists.
+func (c *ovsdbClient) SetBridgeUplinkPort(bridge string, port string) error {
+	t := c.newTransaction()
+	t.SetBridgeUplinkPort(bridge, port)
+	return t.Commit()
+}
+
+// SetBridgeHostToServicePort sets the host-to-service external ID of the bridge. It overrides if already exists.
+func (c *ovsdbClient) SetBridgeHostToServicePort(bridge string, port string) error {
+	t := c.newTransaction()
+	t.SetBridgeHostToServicePort(bridge, port)
+	return t.Commit()
+}
+
+// SetBridgeController sets the controller for a bridge
+func (c *ovsdbClient) SetBridgeController(bridge string, controller string) error {
+	where := []ovsdbCondition{conditionEquals("name", bridge)}
+	// The previous controllers are garbage collected by ovsdb-server since Controller is not a root table
+	results, err := c.transact(
+		opInsert(controllerTable, "controller", map[string]interface{}{"target": controller}),
+		opUpdate(bridgeTable, where, map[string]interface{}{"controller": ovsdbSet{namedUUID("controller")}}),
+		opComment(fmt.Sprintf("set-controller %s %s", bridge, controller)),
+	)
+	if err != nil {
+		return err
+	}
+	if results[1].Count == 0 {
+		return fmt.Errorf("no bridge named %s", bridge)
+	}
+	return nil
+}
+
+// AddPortIfNotExists adds a port to a bridge if it doesn't exist
+func (c *ovsdbClient) AddPortIfNotExists(bridge string, port string) error {
+	existing, err := c.getPort(port)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		// ovs-vsctl --may-exist add-port fails if the port exists on another bridge
+		br, err := c.bridgeOfPort(existing)
+		if err != nil {
+			return err
+		}
+		if br.Name != bridge {
+			return fmt.Errorf("port %s already exists on bridge %s instead of %s", port, br.Name, bridge)
+		}
+		return nil
+	}
+
+	return c.addPort(bridge, port, nil, nil, nil, nil)
+}
+
+// addPort adds a port with a single interface to a bridge in a single transaction. The transaction fails if the port
+// already exists.
+func (c *ovsdbClient) addPort(bridge string, port string, portType *PortType, portExternalIDs map[string]string, interfaceExternalIDs map[string]string, ofport *int) error {
+	ifaceColumns := map[string]interface{}{"name": port}
+	if portType != nil {
+		ifaceColumns["type"] = string(*portType)
+	}
+	if ofport != nil {
+		ifaceColumns["ofport_request"] = *ofport
+	}
+	if len(interfaceExternalIDs) > 0 {
+		ifaceColumns["external_ids"] = ovsdbMap(interfaceExternalIDs)
+	}
+	portColumns := map[string]interface{}{"name": port, "interfaces": namedUUID("iface")}
+	if len(portExternalIDs) > 0 {
+		portColumns["external_ids"] = ovsdbMap(portExternalIDs)
+	}
+
+	where := []ovsdbCondition{conditionEquals("name", bridge)}
+	results, err := c.transact(
+		opWaitNotExists(portTable, []ovsdbCondition{conditionEquals("name", port)}),
+		opInsert(interfaceTable, "iface", ifaceColumns),
+		opInsert(portTable, "port", portColumns),
+		opMutate(bridgeTable, where, mutationInsert("ports", ovsdbSet{namedUUID("port")})),
+		opComment(fmt.Sprintf("add-port %s %s", bridge, port)),
+	)
+	if err != nil {
+		return err
+	}
+	if results[3].Count == 0 {
+		return fmt.Errorf("no bridge named %s", bridge)
+	}
+	return nil
+}
+
+// SetPortType sets the type of a port
+func (c *ovsdbClient) SetPortType(port string, portType PortType) error {
+	t := c.newTransaction()
+	t.SetPortType(port, portType)
+	return t.Commit()
+}
+
+// SetPatchPortPeer sets the peer for a patch port
+func (c *ovsdbClient) SetPatchPortPeer(port string, peer string) error {
+	t := c.newTransaction()
+	t.SetPatchPortPeer(port, peer)
+	return t.Commit()
+}
+
+// SetOVNEncapIP sets the ovn-encap-ip external ID in the Open_vSwitch table in OVS
+func (c *ovsdbClient) SetOVNEncapIP(ip net.IP) error {
+	t := c.newTransaction()
+	t.SetOVNEncapIP(ip)
+	return t.Commit()
+}
+
+// SetDOCAInit sets the doca-init other_config in the Open_vSwitch table in OVS
+func (c *ovsdbClient) SetDOCAInit(enable bool) error {
+	t := c.newTransaction()
+	t.SetDOCAInit(enable)
+	return t.Commit()
+}
+
+// SetKubernetesHostNodeName sets the host-k8s-nodename external ID in the Open_vSwitch table in OVS
+func (c *ovsdbClient) SetKubernetesHostNodeName(name string) error {
+	t := c.newTransaction()
+	t.SetKubernetesHostNodeName(name)
+	return t.Commit()
+}
+
+// SetHostName sets the hostname external ID in the Open_vSwitch table in OVS
+func (c *ovsdbClient) SetHostName(name string) error {
+	t := c.newTransaction()
+	t.SetHostName(name)
+	return t.Commit()
+}
+
+// GetSystemID returns the local OVS system-id from the Open_vSwitch table.
+func (c *ovsdbClient) GetSystemID() (string, error) {
+	var rows []openVSwitchRow
+	if err := c.selectRows(openVSwitchTable, nil, &rows, "external_ids"); err != nil {
+		return "", err
+	}
+	if len(rows) == 0 {
+		return "", nil
+	}
+	return rows[0].ExternalIDs["system-id"], nil
+}
+
+// GetOpenVSwitchExternalIDs returns the external_ids of the Open_vSwitch table
+func (c *ovsdbClient) GetOpenVSwitchExternalIDs() (map[string]string, error) {
+	var rows []openVSwitchRow
+	if err := c.selectRows(openVSwitchTable, nil, &rows, "external_ids"); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no row in table %s", openVSwitchTable)
+	}
+	return nonNilMap(rows[0].ExternalIDs), nil
+}
+
+// GetOpenVSwitchOtherConfig returns the other_config of the Open_vSwitch table
+func (c *ovsdbClient) GetOpenVSwitchOtherConfig() (map[string]string, error) {
+	var rows []openVSwitchRow
+	if err := c.selectRows(openVSwitchTable, nil, &rows, "other_config"); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no row in table %s", openVSwitchTable)
+	}
+	return nonNilMap(rows[0].OtherConfig), nil
+}
+
+// InterfaceToBridge returns the bridge an interface exists in
+func (c *ovsdbClient) InterfaceToBridge(iface string) (string, error) {
+	ifaceRow, err := c.getInterface(iface)
+	if err != nil {
+		return "", err
+	}
+
+	var ports []portRow
+	if err := c.selectRows(portTable, []ovsdbCondition{conditionIncludes("interfaces", ifaceRow.UUID)}, &ports, "_uuid", "name"); err != nil {
+		return "", err
+	}
+	if len(ports) == 0 {
+		return "", fmt.Errorf("interface %s is not attached to any port", iface)
+	}
+
+	br, err := c.bridgeOfPort(&ports[0])
+	if err != nil {
+		return "", err
+	}
+	return br.Name, nil
+}
+
+// DeletePort deletes a port
+func (c *ovsdbClient) DeletePort(port string) error {
+	row, err := c.getPort(port)
+	if err != nil {
+		return err
+	}
+	if row == nil {
+		return fmt.Errorf("no port named %s", port)
+	}
+
+	// The port and its interfaces are garbage collected once the bridge no longer references them
+	_, err = c.transact(
+		opMutate(bridgeTable, []ovsdbCondition{conditionIncludes("ports", row.UUID)}, mutationDelete("ports", ovsdbSet{row.UUID})),
+		opComment(fmt.Sprintf("del-port %s", port)),
+	)
+	return err
+}
+
+// GetInterfaceOfPort returns the ofport number of a port
+func (c *ovsdbClient) GetInterfaceOfPort(port string) (int, error) {
+	row, err := c.getInterface(port)
+	if err != nil {
+		return 0, err
+	}
+	if row.OFPort.Value == nil {
+		return 0, fmt.Errorf("interface %s has no ofport assigned", port)
+	}
+	return *row.OFPort.Value, nil
+}
+
+// GetPortExternalIDs returns the external_ids of an OVS port
+func (c *ovsdbClient) GetPortExternalIDs(port string) (map[string]string, error) {
+	row, err := c.getPort(port)
+	if err != nil {
+		return nil, err
+	}
+	if row == nil {
+		return nil, fmt.Errorf("no row %s in table Port", port)
+	}
+	return nonNilMap(row.ExternalIDs), nil
+}
+
+// GetInterfaceExternalIDs returns the external_ids of an OVS interface
+func (c *ovsdbClient) GetInterfaceExternalIDs(iface string) (map[string]string, error) {
+	row, err := c.getInterface(iface)
+	if err != nil {
+		return nil, err
+	}
+	return nonNilMap(row.ExternalIDs), nil
+}
+
+// nonNilMap returns the map or an empty map if it's nil
+func nonNilMap(m ovsdbMap) map[string]string {
+	if m == nil {
+		return make(map[string]string)
+	}
+	return m
+}
+
+// AddPortWithMetadata adds a port to the given bridge with the specified external IDs and ofport request in a single
+// transaction
+func (c *ovsdbClient) AddPortWithMetadata(bridge string, port string, portType PortType, portExternalIDs map[string]string, interfaceExternalIDs map[string]string, ofport int) error {
+	return c.addPort(bridge, port, &portType, portExternalIDs, interfaceExternalIDs, &ofport)
+}
+
+// ListInterfaces lists all the interfaces that exist in OVS of a particular type
+func (c *ovsdbClient) ListInterfaces(portType PortType) (map[string]interface{}, error) {
+	var rows []interfaceRow
+	if err := c.selectRows(interfaceTable, []ovsdbCondition{conditionEquals("type", string(portType))}, &rows, "name"); err != nil {
+		return nil, err
+	}
+
+	ports := make(map[string]interface{})
+	for _, row := range rows {
+		ports[row.Name] = struct{}{}
+	}
+	return ports, nil
+}
+
+// GetInterfacesWithPMDRXQueue returns all the interfaces that have a PMD Rx queue
+func (c *ovsdbClient) GetInterfacesWithPMDRXQueue() (map[string]interface{}, error) {
+	out, err := c.runUnixctl("dpif-netdev/pmd-rxq-show")
+	if err != nil {
+		return nil, err
+	}
+	return parsePMDRXQueuePorts(out)
+}
+
+// runUnixctl runs a command against the ovs-vswitchd control socket. This is the same JSON-RPC interface that
+// ovs-appctl uses.
+func (c *ovsdbClient) runUnixctl(command string, args ...string) (string, error) {
+	socketPath, err := getVSwitchDSocketPath(c.fileSystemRoot)
+	if err != nil {
+		return "", fmt.Errorf("failed to construct ovs-vswitchd socket path: %w", err)
+	}
+
+	rpc, err := dialRPC(socketPath, nil)
+	if err != nil {
+		return "", err
+	}
+	defer rpc.Close()
+
+	params := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		params = append(params, arg)
+	}
+	klog.V(4).InfoS("Running OVS command", logging.KeyOVSCommand, strings.Join(append([]string{command}, args...), " "))
+	raw, err := rpc.call(command, params...)
+	if err != nil {
+		return "", fmt.Errorf("error running ovs-vswitchd command %s with args %v: %w", command, args, err)
+	}
+
+	var out string
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return "", fmt.Errorf("error while decoding ovs-vswitchd command %s output: %w", command, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,469 @@
+/*
+Copyright 2024 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ovsclient
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// fakeJSONRPCServer is a JSON-RPC server listening on a unix socket that replies to requests using a handler and
+// records the requests it received.
+type fakeJSONRPCServer struct {
+	listener net.Listener
+	handler  func(method string, params []json.RawMessage) (interface{}, interface{})
+
+	lock     sync.Mutex
+	requests []fakeJSONRPCRequest
+}
+
+// fakeJSONRPCRequest is a request received by the fakeJSONRPCServer
+type fakeJSONRPCRequest struct {
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+	ID     json.RawMessage   `json:"id"`
+}
+
+func newFakeJSONRPCServer(t *testing.T, socketPath string, handler func(method string, params []json.RawMessage) (interface{}, interface{})) *fakeJSONRPCServer {
+	g := NewWithT(t)
+	g.Expect(os.MkdirAll(filepath.Dir(socketPath), 0755)).To(Succeed())
+	l, err := net.Listen("unix", socketPath)
+	g.Expect(err).ToNot(HaveOccurred())
+	s := &fakeJSONRPCServer{listener: l, handler: handler}
+	go s.serve()
+	t.Cleanup(func() { _ = l.Close() })
+	return s
+}
+
+func (s *fakeJSONRPCServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			decoder := json.NewDecoder(conn)
+			encoder := json.NewEncoder(conn)
+			for {
+				var req fakeJSONRPCRequest
+				if err := decoder.Decode(&req); err != nil {
+					return
+				}
+				s.lock.Lock()
+				s.requests = append(s.requests, req)
+				s.lock.Unlock()
+				result, rpcErr := s.handler(req.Method, req.Params)
+				if err := encoder.Encode(map[string]interface{}{"result": result, "error": rpcErr, "id": req.ID}); err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+// transactions returns the operations of all the transact requests received by the server
+func (s *fakeJSONRPCServer) transactions() [][]map[string]interface{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	out := [][]map[string]interface{}{}
+	for _, req := range s.requests {
+		if req.Method != "transact" {
+			continue
+		}
+		ops := []map[string]interface{}{}
+		for _, raw := range req.Params[1:] {
+			op := map[string]interface{}{}
+			if err := json.Unmarshal(raw, &op); err != nil {
+				panic(err)
+			}
+			ops = append(ops, op)
+		}
+		out = append(out, ops)
+	}
+	return out
+}
+
+// newTestFileSystemRoot returns a short temporary directory, given that unix socket paths are limited in length
+func newTestFileSystemRoot(t *testing.T) string {
+	g := NewWithT(t)
+	tmpDir, err := os.MkdirTemp("", "ovsclient")
+	g.Expect(err).ToNot(HaveOccurred())
+	t.Cleanup(func() {
+		g.Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+	return tmpDir
+}
+
+// newTestOVSDBClient returns an ovsdbClient and a fake ovsdb-server that replies to every transact with the given
+// results, in order.
+func newTestOVSDBClient(t *testing.T, results ...string) (*ovsdbClient, *fakeJSONRPCServer) {
+	g := NewWithT(t)
+	tmpDir := newTestFileSystemRoot(t)
+	c, err := newOVSDBClient(tmpDir)
+	g.Expect(err).ToNot(HaveOccurred())
+	cImpl := c.(*ovsdbClient)
+
+	var lock sync.Mutex
+	server := newFakeJSONRPCServer(t, cImpl.dbSocketPath, func(method string, params []json.RawMessage) (interface{}, interface{}) {
+		lock.Lock()
+		defer lock.Unlock()
+		if method != "transact" || len(results) == 0 {
+			return nil, "unexpected request"
+		}
+		result := json.RawMessage(results[0])
+		results = results[1:]
+		return result, nil
+	})
+	return cImpl, server
+}
+
+func toJSONObject(t *testing.T, raw string) interface{} {
+	var out interface{}
+	NewWithT(t).Expect(json.Unmarshal([]byte(raw), &out)).To(Succeed())
+	return out
+}
+
+func toJSONOperations(t *testing.T, raw string) []map[string]interface{} {
+	var out []map[string]interface{}
+	NewWithT(t).Expect(json.Unmarshal([]byte(raw), &out)).To(Succeed())
+	return out
+}
+
+func TestOVSDBSetOVNEncapIP(t *testing.T) {
+	g := NewWithT(t)
+	c, server := newTestOVSDBClient(t, `[{}, {"count":1}]`)
+
+	g.Expect(c.SetOVNEncapIP(net.ParseIP("192.168.1.1"))).To(Succeed())
+
+	g.Expect(server.transactions()).To(HaveLen(1))
+	g.Expect(server.transactions()[0]).To(BeComparableTo(toJSONOperations(t, `[
+		{"op":"wait","table":"Open_vSwitch","where":[],"columns":["_uuid"],"until":"!=","rows":[],"timeout":0},
+		{"op":"mutate","table":"Open_vSwitch","where":[],"mutations":[
+			["external_ids","delete",["set",["ovn-encap-ip"]]],
+			["external_ids","insert",["map",[["ovn-encap-ip","192.168.1.1"]]]]
+		]}
+	]`)))
+}
+
+func TestOVSDBTransactionSetOVNEncapIPs(t *testing.T) {
+	g := NewWithT(t)
+	c, server := newTestOVSDBClient(t, `[{}, {"count":1}]`)
+
+	txn := c.Transaction()
+	txn.SetOVNEncapIPs([]net.IP{net.ParseIP("192.168.1.1"), net.ParseIP("192.168.2.1")})
+	g.Expect(txn.Commit()).To(Succeed())
+
+	g.Expect(server.transactions()).To(HaveLen(1))
+	g.Expect(server.transactions()[0]).To(BeComparableTo(toJSONOperations(t, `[
+		{"op":"wait","table":"Open_vSwitch","where":[],"columns":["_uuid"],"until":"!=","rows":[],"timeout":0},
+		{"op":"mutate","table":"Open_vSwitch","where":[],"mutations":[
+			["external_ids","delete",["set",["ovn-encap-ip"]]],
+			["external_ids","insert",["map",[["ovn-encap-ip","192.168.1.1,192.168.2.1"]]]]
+		]}
+	]`)))
+}
+
+func TestOVSDBTransactionRemoveOpenVSwitchExternalIDs(t *testing.T) {
+	g := NewWithT(t)
+	c, server := newTestOVSDBClient(t, `[{}, {}, {"count":1}, {"count":1}]`)
+
+	txn := c.Transaction()
+	txn.RemoveOVNEncapIP()
+	txn.RemoveHostName()
+	g.Expect(txn.Commit()).To(Succeed())
+
+	g.Expect(server.transactions()).To(HaveLen(1))
+	g.Expect(server.transactions()[0]).To(BeComparableTo(toJSONOperations(t, `[
+		{"op":"wait","table":"Open_vSwitch","where":[],"columns":["_uuid"],"until":"!=","rows":[],"timeout":0},
+		{"op":"wait","table":"Open_vSwitch","where":[],"columns":["_uuid"],"until":"!=","rows":[],"timeout":0},
+		{"op":"mutate","table":"Open_vSwitch","where":[],"mutations":[["external_ids","delete",["set",["ovn-encap-ip"]]]]},
+		{"op":"mutate","table":"Open_vSwitch","where":[],"mutations":[["external_ids","delete",["set",["hostname"]]]]}
+	]`)))
+}
+
+func TestOVSDBTransactionOpenVSwitchOtherConfig(t *testing.T) {
+	g := NewWithT(t)
+	c, server := newTestOVSDBClient(t, `[{}, {}, {"count":1}, {"count":1}]`)
+
+	txn := c.Transaction()
+	txn.SetOpenVSwitchOtherConfig("dpdk-socket-mem", "1024,1024")
+	txn.RemoveOpenVSwitchOtherConfig("pmd-cpu-mask")
+	g.Expect(txn.Commit()).To(Succeed())
+
+	g.Expect(server.transactions()).To(HaveLen(1))
+	g.Expect(server.transactions()[0]).To(BeComparableTo(toJSONOperations(t, `[
+		{"op":"wait","table":"Open_vSwitch","where":[],"columns":["_uuid"],"until":"!=","rows":[],"timeout":0},
+		{"op":"wait","table":"Open_vSwitch","where":[],"columns":["_uuid"],"until":"!=","rows":[],"timeout":0},
+		{"op":"mutate","table":"Open_vSwitch","where":[],"mutations":[
+			["other_config","delete",["set",["dpdk-socket-mem"]]],
+			["other_config","insert",["map",[["dpdk-socket-mem","1024,1024"]]]]
+		]},
+		{"op":"mutate","table":"Open_vSwitch","where":[],"mutations":[["other_config","delete",["set",["pmd-cpu-mask"]]]]}
+	]`)))
+}
+
+func TestOVSDBSetBridgeDataPathTypeMissingBridge(t *testing.T) {
+	g := NewWithT(t)
+	c, _ := newTestOVSDBClient(t, `[{"error":"timed out"}]`)
+
+	err := c.SetBridgeDataPathType("br-ovn", NetDev)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("no bridge named br-ovn"))
+}
+
+func TestOVSDBTransaction(t *testing.T) {
+	g := NewWithT(t)
+	c, server := newTestOVSDBClient(t, `[{}, {}, {}, {"count":1}, {"count":1}, {"count":1}]`)
+
+	txn := c.Transaction()
+	txn.SetKubernetesHostNodeName("host1")
+	txn.SetBridgeDataPathType("br-ovn", NetDev)
+	txn.SetPortExternalID("p0", "key", "value")
+	g.Expect(txn.Commit()).To(Succeed())
+
+	g.Expect(server.transactions()).To(HaveLen(1))
+	g.Expect(server.transactions()[0]).To(BeComparableTo(toJSONOperations(t, `[
+		{"op":"wait","table":"Open_vSwitch","where":[],"columns":["_uuid"],"until":"!=","rows":[],"timeout":0},
+		{"op":"wait","table":"Bridge","where":[["name","==","br-ovn"]],"columns":["_uuid"],"until":"!=","rows":[],"timeout":0},
+		{"op":"wait","table":"Port","where":[["name","==","p0"]],"columns":["_uuid"],"until":"!=","rows":[],"timeout":0},
+		{"op":"mutate","table":"Open_vSwitch","where":[],"mutations":[
+			["external_ids","delete",["set",["host-k8s-nodename"]]],
+			["external_ids","insert",["map",[["host-k8s-nodename","host1"]]]]
+		]},
+		{"op":"update","table":"Bridge","where":[["name","==","br-ovn"]],"row":{"datapath_type":"netdev"}},
+		{"op":"mutate","table":"Port","where":[["name","==","p0"]],"mutations":[
+			["external_ids","delete",["set",["key"]]],
+			["external_ids","insert",["map",[["key","value"]]]]
+		]}
+	]`)))
+
+	// Committing again is a no-op since the queued changes are cleared on success
+	g.Expect(txn.Commit()).To(Succeed())
+	g.Expect(server.transactions()).To(HaveLen(1))
+}
+
+func TestOVSDBTransactionMissingRow(t *testing.T) {
+	g := NewWithT(t)
+	c, _ := newTestOVSDBClient(t, `[{}, {"error":"timed out"}]`)
+
+	txn := c.Transaction()
+	txn.SetOVNEncapIP(net.ParseIP("192.168.1.1"))
+	txn.SetPatchPortPeer("patch-to-br-sfc", "patch-to-br-ovn")
+	err := txn.Commit()
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("no interface named patch-to-br-sfc"))
+}
+
+func TestOVSDBTransactionError(t *testing.T) {
+	g := NewWithT(t)
+	c, _ := newTestOVSDBClient(t, `[{}, {"error":"constraint violation","details":"duplicate name"}, null]`)
+
+	err := c.AddPortWithMetadata("br-ovn", "p0", DPDK, nil, nil, 1)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("constraint violation: duplicate name"))
+}
+
+func TestOVSDBGetPortExternalIDs(t *testing.T) {
+	g := NewWithT(t)
+	cases := []struct {
+		msg            string
+		selectResult   string
+		expectedOutput map[string]string
+		expectedError  bool
+	}{
+		{
+			msg:            "empty",
+			selectResult:   `[{"rows":[{"_uuid":["uuid","7b5f4c5f-57b1-4c0d-9a51-0f3e1e1c0b1a"],"name":"p0","interfaces":["uuid","8b5f4c5f-57b1-4c0d-9a51-0f3e1e1c0b1a"],"external_ids":["map",[]]}]}]`,
+			expectedOutput: map[string]string{},
+		},
+		{
+			msg:          "values containing commas, equals and quotes",
+			selectResult: `[{"rows":[{"_uuid":["uuid","7b5f4c5f-57b1-4c0d-9a51-0f3e1e1c0b1a"],"name":"p0","interfaces":["set",[]],"external_ids":["map",[["dpf-id","dpf-operator-system/dpu-cplane, \"tenant1\"=p1_if"],["iface-id",""]]]}]}]`,
+			expectedOutput: map[string]string{
+				"dpf-id":   `dpf-operator-system/dpu-cplane, "tenant1"=p1_if`,
+				"iface-id": "",
+			},
+		},
+		{
+			msg:           "port doesn't exist",
+			selectResult:  `[{"rows":[]}]`,
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.msg, func(t *testing.T) {
+			c, server := newTestOVSDBClient(t, tt.selectResult)
+
+			output, err := c.GetPortExternalIDs("p0")
+			g.Expect(server.transactions()[0][0]["where"]).To(BeComparableTo(toJSONObject(t, `[["name", "==", "p0"]]`)))
+			if tt.expectedError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(output).To(BeComparableTo(tt.expectedOutput))
+		})
+	}
+}
+
+func TestOVSDBGetOpenVSwitchExternalIDs(t *testing.T) {
+	g := NewWithT(t)
+	c, _ := newTestOVSDBClient(t, `[{"rows":[{"external_ids":["map",[["hostname","host1"],["ovn-encap-ip","192.168.1.1,192.168.2.1"]]]}]}]`)
+
+	output, err := c.GetOpenVSwitchExternalIDs()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(output).To(BeComparableTo(map[string]string{
+		"hostname":     "host1",
+		"ovn-encap-ip": "192.168.1.1,192.168.2.1",
+	}))
+}
+
+func TestOVSDBGetOpenVSwitchOtherConfig(t *testing.T) {
+	g := NewWithT(t)
+	c, _ := newTestOVSDBClient(t, `[{"rows":[{"other_config":["map",[["doca-init","true"],["hw-offload","true"]]]}]}]`)
+
+	output, err := c.GetOpenVSwitchOtherConfig()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(output).To(BeComparableTo(map[string]string{
+		"doca-init":  "true",
+		"hw-offload": "true",
+	}))
+}
+
+func TestOVSDBGetBridgeDataPathType(t *testing.T) {
+	g := NewWithT(t)
+	c, server := newTestOVSDBClient(t, `[{"rows":[{"datapath_type":"netdev"}]}]`)
+
+	output, err := c.GetBridgeDataPathType("br-ovn")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(output).To(Equal(NetDev))
+	g.Expect(server.transactions()[0][0]["where"]).To(BeComparableTo(toJSONObject(t, `[["name", "==", "br-ovn"]]`)))
+
+	c, _ = newTestOVSDBClient(t, `[{"rows":[]}]`)
+	_, err = c.GetBridgeDataPathType("br-ovn")
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("no bridge named br-ovn"))
+}
+
+func TestOVSDBGetInterfaceOfPort(t *testing.T) {
+	g := NewWithT(t)
+	cases := []struct {
+		msg            string
+		selectResult   string
+		expectedOutput int
+		expectedError  bool
+	}{
+		{
+			msg:            "ofport assigned",
+			selectResult:   `[{"rows":[{"_uuid":["uuid","7b5f4c5f-57b1-4c0d-9a51-0f3e1e1c0b1a"],"name":"pf0hpf","ofport":4}]}]`,
+			expectedOutput: 4,
+		},
+		{
+			msg:           "ofport not yet assigned",
+			selectResult:  `[{"rows":[{"_uuid":["uuid","7b5f4c5f-57b1-4c0d-9a51-0f3e1e1c0b1a"],"name":"pf0hpf","ofport":["set",[]]}]}]`,
+			expectedError: true,
+		},
+		{
+			msg:           "interface doesn't exist",
+			selectResult:  `[{"rows":[]}]`,
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.msg, func(t *testing.T) {
+			c, _ := newTestOVSDBClient(t, tt.selectResult)
+
+			output, err := c.GetInterfaceOfPort("pf0hpf")
+			if tt.expectedError {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(output).To(Equal(tt.expectedOutput))
+		})
+	}
+}
+
+func TestOVSDBAddPortWithMetadata(t *testing.T) {
+	g := NewWithT(t)
+	c, server := newTestOVSDBClient(t, `[{}, {"uuid":["uuid","a"]}, {"uuid":["uuid","b"]}, {"count":1}, {}]`)
+
+	err := c.AddPortWithMetadata("br-sfc", "pf0vf0", DPDK, map[string]string{"dpf-id": "ns/name"}, map[string]string{"iface-id": "x"}, 3)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(server.transactions()[0]).To(BeComparableTo(toJSONOperations(t, `[
+		{"op":"wait","table":"Port","where":[["name","==","pf0vf0"]],"columns":["_uuid"],"until":"==","rows":[],"timeout":0},
+		{"op":"insert","table":"Interface","uuid-name":"iface","row":{"name":"pf0vf0","type":"dpdk","ofport_request":3,"external_ids":["map",[["iface-id","x"]]]}},
+		{"op":"insert","table":"Port","uuid-name":"port","row":{"name":"pf0vf0","interfaces":["named-uuid","iface"],"external_ids":["map",[["dpf-id","ns/name"]]]}},
+		{"op":"mutate","table":"Bridge","where":[["name","==","br-sfc"]],"mutations":[["ports","insert",["set",[["named-uuid","port"]]]]]},
+		{"op":"comment","comment":"add-port br-sfc pf0vf0"}
+	]`)))
+}
+
+func TestOVSDBListInterfaces(t *testing.T) {
+	g := NewWithT(t)
+	c, _ := newTestOVSDBClient(t, `[{"rows":[{"name":"pf0hpf"},{"name":"p0"}]}]`)
+
+	output, err := c.ListInterfaces(DPDK)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(output).To(BeComparableTo(map[string]interface{}{
+		"pf0hpf": struct{}{},
+		"p0":     struct{}{},
+	}))
+}
+
+func TestOVSDBGetInterfacesWithPMDRXQueue(t *testing.T) {
+	g := NewWithT(t)
+	tmpDir := newTestFileSystemRoot(t)
+	c, err := newOVSDBClient(tmpDir)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	ovsSocketDir := filepath.Join(tmpDir, "/var/run/openvswitch")
+	g.Expect(os.MkdirAll(ovsSocketDir, 0755)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(ovsSocketDir, "ovs-vswitchd.pid"), []byte("12345\n"), 0644)).To(Succeed())
+
+	output := `
+pmd thread numa_id 0 core_id 11:
+  isolated : true
+  port: p0                queue-id:  0 (enabled)   pmd usage:  0 %
+  port: pf0hpf            queue-id:  0 (enabled)   pmd usage:  0 %
+  overhead:  0 %`
+	newFakeJSONRPCServer(t, filepath.Join(ovsSocketDir, "ovs-vswitchd.12345.ctl"), func(method string, params []json.RawMessage) (interface{}, interface{}) {
+		if method != "dpif-netdev/pmd-rxq-show" || len(params) != 0 {
+			return nil, "unknown command"
+		}
+		return output, nil
+	})
+
+	ports, err := c.GetInterfacesWithPMDRXQueue()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(ports).To(BeComparableTo(map[string]interface{}{
+		"p0":     struct{}{},
+		"pf0hpf": struct{}{},
+	}))
+}
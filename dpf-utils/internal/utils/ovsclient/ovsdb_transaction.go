@@ -0,0 +1,186 @@
+/*
+Copyright 2024 NVIDIA
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ovsclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// ovsdbTransaction is a Transaction that sends all the queued operations in a single OVSDB transact request
+type ovsdbTransaction struct {
+	client *ovsdbClient
+	ops    []ovsdbOperation
+	// targets describes the row each operation must match. The transaction fails if any of them doesn't exist, which
+	// mimics ovs-vsctl failing on a missing record.
+	targets []string
+}
+
+// Transaction returns a new Transaction that is committed as a single OVSDB transaction
+func (c *ovsdbClient) Transaction() Transaction {
+	return c.newTransaction()
+}
+
+// newTransaction returns a new empty ovsdbTransaction
+func (c *ovsdbClient) newTransaction() *ovsdbTransaction {
+	return &ovsdbTransaction{client: c}
+}
+
+// queue adds an update or mutate operation that must match the row described by target
+func (t *ovsdbTransaction) queue(op ovsdbOperation, target string) {
+	t.ops = append(t.ops, op)
+	t.targets = append(t.targets, target)
+}
+
+// setMapKey queues setting key to value in a map column of the row named name in table
+func (t *ovsdbTransaction) setMapKey(table string, name string, column string, key string, value string, target string) {
+	where := []ovsdbCondition{conditionEquals("name", name)}
+	t.queue(opMutate(table, where, mutationsSetMapKey(column, key, value)...), target)
+}
+
+// setOpenVSwitchMapKey queues setting key to value in a map column of the Open_vSwitch table
+func (t *ovsdbTransaction) setOpenVSwitchMapKey(column string, key string, value string) {
+	t.queue(opMutate(openVSwitchTable, nil, mutationsSetMapKey(column, key, value)...), "Open_vSwitch row")
+}
+
+// removeOpenVSwitchMapKey queues removing key from a map column of the Open_vSwitch table. Removing a missing key is a
+// no-op.
+func (t *ovsdbTransaction) removeOpenVSwitchMapKey(column string, key string) {
+	t.queue(opMutate(openVSwitchTable, nil, mutationDelete(column, ovsdbSet{key})), "Open_vSwitch row")
+}
+
+// SetBridgeDataPathType queues setting the datapath type of a bridge
+func (t *ovsdbTransaction) SetBridgeDataPathType(bridge string, bridgeType BridgeDataPathType) {
+	where := []ovsdbCondition{conditionEquals("name", bridge)}
+	t.queue(opUpdate(bridgeTable, where, map[string]interface{}{"datapath_type": string(bridgeType)}), fmt.Sprintf("bridge named %s", bridge))
+}
+
+// SetBridgeMAC queues setting the MAC address for the bridge interface
+func (t *ovsdbTransaction) SetBridgeMAC(bridge string, mac net.HardwareAddr) {
+	t.setMapKey(bridgeTable, bridge, "other_config", "hwaddr", mac.String(), fmt.Sprintf("bridge named %s", bridge))
+}
+
+// SetBridgeUplinkPort queues setting the bridge-uplink external ID of the bridge
+func (t *ovsdbTransaction) SetBridgeUplinkPort(bridge string, port string) {
+	t.setMapKey(bridgeTable, bridge, "external_ids", "bridge-uplink", port, fmt.Sprintf("bridge named %s", bridge))
+}
+
+// SetBridgeHostToServicePort queues setting the host-to-service external ID of the bridge
+func (t *ovsdbTransaction) SetBridgeHostToServicePort(bridge string, port string) {
+	t.setMapKey(bridgeTable, bridge, "external_ids", "host-to-service-interface", port, fmt.Sprintf("bridge named %s", bridge))
+}
+
+// SetPortExternalID queues setting an external ID of a port. It overrides if already exists.
+func (t *ovsdbTransaction) SetPortExternalID(port string, key string, value string) {
+	t.setMapKey(portTable, port, "external_ids", key, value, fmt.Sprintf("port named %s", port))
+}
+
+// SetPortType queues setting the type of a port
+func (t *ovsdbTransaction) SetPortType(port string, portType PortType) {
+	where := []ovsdbCondition{conditionEquals("name", port)}
+	t.queue(opUpdate(interfaceTable, where, map[string]interface{}{"type": string(portType)}), fmt.Sprintf("interface named %s", port))
+}
+
+// SetPatchPortPeer queues setting the peer for a patch port
+func (t *ovsdbTransaction) SetPatchPortPeer(port string, peer string) {
+	t.setMapKey(interfaceTable, port, "options", "peer", peer, fmt.Sprintf("interface named %s", port))
+}
+
+// SetInterfaceExternalID queues setting an external ID of an interface. It overrides if already exists.
+func (t *ovsdbTransaction) SetInterfaceExternalID(iface string, key string, value string) {
+	t.setMapKey(interfaceTable, iface, "external_ids", key, value, fmt.Sprintf("interface named %s", iface))
+}
+
+// SetOVNEncapIP queues setting the ovn-encap-ip external ID in the Open_vSwitch table
+func (t *ovsdbTransaction) SetOVNEncapIP(ip net.IP) {
+	t.setOpenVSwitchMapKey("external_ids", "ovn-encap-ip", ip.String())
+}
+
+// SetOVNEncapIPs queues setting the ovn-encap-ip external ID in the Open_vSwitch table to a list of IPs
+func (t *ovsdbTransaction) SetOVNEncapIPs(ips []net.IP) {
+	t.setOpenVSwitchMapKey("external_ids", "ovn-encap-ip", joinIPs(ips))
+}
+
+// SetDOCAInit queues setting the doca-init other_config in the Open_vSwitch table
+func (t *ovsdbTransaction) SetDOCAInit(enable bool) {
+	t.setOpenVSwitchMapKey("other_config", "doca-init", strconv.FormatBool(enable))
+}
+
+// SetOpenVSwitchOtherConfig queues setting an other_config key in the Open_vSwitch table
+func (t *ovsdbTransaction) SetOpenVSwitchOtherConfig(key string, value string) {
+	t.setOpenVSwitchMapKey("other_config", key, value)
+}
+
+// RemoveOpenVSwitchOtherConfig queues removing an other_config key from the Open_vSwitch table
+func (t *ovsdbTransaction) RemoveOpenVSwitchOtherConfig(key string) {
+	t.removeOpenVSwitchMapKey("other_config", key)
+}
+
+// SetKubernetesHostNodeName queues setting the host-k8s-nodename external ID in the Open_vSwitch table
+func (t *ovsdbTransaction) SetKubernetesHostNodeName(name string) {
+	t.setOpenVSwitchMapKey("external_ids", "host-k8s-nodename", name)
+}
+
+// SetHostName queues setting the hostname external ID in the Open_vSwitch table
+func (t *ovsdbTransaction) SetHostName(name string) {
+	t.setOpenVSwitchMapKey("external_ids", "hostname", name)
+}
+
+// RemoveOVNEncapIP queues removing the ovn-encap-ip external ID from the Open_vSwitch table
+func (t *ovsdbTransaction) RemoveOVNEncapIP() {
+	t.removeOpenVSwitchMapKey("external_ids", "ovn-encap-ip")
+}
+
+// RemoveKubernetesHostNodeName queues removing the host-k8s-nodename external ID from the Open_vSwitch table
+func (t *ovsdbTransaction) RemoveKubernetesHostNodeName() {
+	t.removeOpenVSwitchMapKey("external_ids", "host-k8s-nodename")
+}
+
+// RemoveHostName queues removing the hostname external ID from the Open_vSwitch table
+func (t *ovsdbTransaction) RemoveHostName() {
+	t.removeOpenVSwitchMapKey("external_ids", "hostname")
+}
+
+// Commit applies all the queued changes in a single OVSDB transaction. Every operation is preceded by a wait that
+// aborts the whole transaction if the row it targets doesn't exist, given that OVSDB would otherwise commit the
+// operations that matched and silently skip the rest.
+func (t *ovsdbTransaction) Commit() error {
+	if len(t.ops) == 0 {
+		return nil
+	}
+
+	ops := make([]ovsdbOperation, 0, 2*len(t.ops))
+	for _, op := range t.ops {
+		ops = append(ops, opWaitExists(op.Table, op.Where))
+	}
+	ops = append(ops, t.ops...)
+
+	_, err := t.client.transact(ops...)
+	if err != nil {
+		var txnErr *ovsdbTransactionError
+		if errors.As(err, &txnErr) && txnErr.Index < len(t.targets) && txnErr.Err == "timed out" {
+			return fmt.Errorf("no %s found", t.targets[txnErr.Index])
+		}
+		return err
+	}
+
+	t.ops = nil
+	t.targets = nil
+	return nil
+}
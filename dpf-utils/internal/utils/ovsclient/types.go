@@ -40,6 +40,9 @@ type OVSClient interface {
 	SetBridgeHostToServicePort(bridge string, port string) error
 	// SetBridgeController sets the controller for a bridge
 	SetBridgeController(bridge string, controller string) error
+	// GetBridgeDataPathType returns the datapath type of a bridge. It's empty when the bridge uses the default datapath
+	// type, which is System.
+	GetBridgeDataPathType(bridge string) (BridgeDataPathType, error)
 
 	// AddPortIfNotExists adds a port to a bridge if it doesn't exist
 	AddPortIfNotExists(bridge string, port string) error
@@ -58,6 +61,10 @@ type OVSClient interface {
 	SetHostName(name string) error
 	// GetSystemID returns the local OVS system-id from the Open_vSwitch table.
 	GetSystemID() (string, error)
+	// GetOpenVSwitchExternalIDs returns the external_ids of the Open_vSwitch table
+	GetOpenVSwitchExternalIDs() (map[string]string, error)
+	// GetOpenVSwitchOtherConfig returns the other_config of the Open_vSwitch table
+	GetOpenVSwitchOtherConfig() (map[string]string, error)
 
 	// InterfaceToBridge returns the bridge an interface exists in
 	InterfaceToBridge(iface string) (string, error)
@@ -76,6 +83,57 @@ type OVSClient interface {
 	ListInterfaces(portType PortType) (map[string]interface{}, error)
 	// GetInterfacesWithPMDRXQueue returns all the interfaces that have a PMD Rx queue
 	GetInterfacesWithPMDRXQueue() (map[string]interface{}, error)
+
+	// Transaction returns a new Transaction that can be used to apply multiple changes atomically
+	Transaction() Transaction
+}
+
+// Transaction queues changes to existing OVS rows and applies them atomically on Commit. Either all the queued changes
+// are applied or none of them is.
+type Transaction interface {
+	// SetBridgeDataPathType queues setting the datapath type of a bridge
+	SetBridgeDataPathType(bridge string, bridgeType BridgeDataPathType)
+	// SetBridgeMAC queues setting the MAC address for the bridge interface
+	SetBridgeMAC(bridge string, mac net.HardwareAddr)
+	// SetBridgeUplinkPort queues setting the bridge-uplink external ID of the bridge
+	SetBridgeUplinkPort(bridge string, port string)
+	// SetBridgeHostToServicePort queues setting the host-to-service external ID of the bridge
+	SetBridgeHostToServicePort(bridge string, port string)
+
+	// SetPortExternalID queues setting an external ID of a port. It overrides if already exists.
+	SetPortExternalID(port string, key string, value string)
+	// SetPortType queues setting the type of a port
+	SetPortType(port string, portType PortType)
+	// SetPatchPortPeer queues setting the peer for a patch port
+	SetPatchPortPeer(port string, peer string)
+	// SetInterfaceExternalID queues setting an external ID of an interface. It overrides if already exists.
+	SetInterfaceExternalID(iface string, key string, value string)
+
+	// SetOVNEncapIP queues setting the ovn-encap-ip external ID in the Open_vSwitch table
+	SetOVNEncapIP(ip net.IP)
+	// SetOVNEncapIPs queues setting the ovn-encap-ip external ID in the Open_vSwitch table to a list of IPs, which makes
+	// ovn-controller establish the geneve tunnels from each of them
+	SetOVNEncapIPs(ips []net.IP)
+	// SetDOCAInit queues setting the doca-init other_config in the Open_vSwitch table. Requires OVS daemon restart.
+	SetDOCAInit(enable bool)
+	// SetOpenVSwitchOtherConfig queues setting an other_config key in the Open_vSwitch table. It overrides if already
+	// exists.
+	SetOpenVSwitchOtherConfig(key string, value string)
+	// RemoveOpenVSwitchOtherConfig queues removing an other_config key from the Open_vSwitch table
+	RemoveOpenVSwitchOtherConfig(key string)
+	// SetKubernetesHostNodeName queues setting the host-k8s-nodename external ID in the Open_vSwitch table
+	SetKubernetesHostNodeName(name string)
+	// SetHostName queues setting the hostname external ID in the Open_vSwitch table
+	SetHostName(name string)
+	// RemoveOVNEncapIP queues removing the ovn-encap-ip external ID from the Open_vSwitch table
+	RemoveOVNEncapIP()
+	// RemoveKubernetesHostNodeName queues removing the host-k8s-nodename external ID from the Open_vSwitch table
+	RemoveKubernetesHostNodeName()
+	// RemoveHostName queues removing the hostname external ID from the Open_vSwitch table
+	RemoveHostName()
+
+	// Commit applies all the queued changes in a single transaction. Committing an empty transaction is a no-op.
+	Commit() error
 }
 
 // BridgeDataPathType represents the various datapath types a bridge can be configured with
@@ -83,6 +141,7 @@ type BridgeDataPathType string
 
 const (
 	NetDev BridgeDataPathType = "netdev"
+	System BridgeDataPathType = "system"
 )
 
 // PortType represents the various types a port can be configured with